@@ -0,0 +1,4 @@
+// Package urlshortenerv1 contains the generated gRPC stubs of the URL shortener API.
+package urlshortenerv1
+
+//go:generate protoc -I . --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative urlshortener.proto
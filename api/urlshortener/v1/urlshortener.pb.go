@@ -0,0 +1,463 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: urlshortener.proto
+
+package urlshortenerv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type CreateURLRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Url   string                 `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
+	// Empty means the alias is generated.
+	Alias     string `protobuf:"bytes,2,opt,name=alias,proto3" json:"alias,omitempty"`
+	Permanent *bool  `protobuf:"varint,3,opt,name=permanent,proto3,oneof" json:"permanent,omitempty"`
+	// Link lifetime as a Go duration, e.g. "72h". Empty means no expiration.
+	Ttl           string `protobuf:"bytes,4,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateURLRequest) Reset() {
+	*x = CreateURLRequest{}
+	mi := &file_urlshortener_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateURLRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateURLRequest) ProtoMessage() {}
+
+func (x *CreateURLRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_urlshortener_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateURLRequest.ProtoReflect.Descriptor instead.
+func (*CreateURLRequest) Descriptor() ([]byte, []int) {
+	return file_urlshortener_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *CreateURLRequest) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *CreateURLRequest) GetAlias() string {
+	if x != nil {
+		return x.Alias
+	}
+	return ""
+}
+
+func (x *CreateURLRequest) GetPermanent() bool {
+	if x != nil && x.Permanent != nil {
+		return *x.Permanent
+	}
+	return false
+}
+
+func (x *CreateURLRequest) GetTtl() string {
+	if x != nil {
+		return x.Ttl
+	}
+	return ""
+}
+
+type CreateURLResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Alias         string                 `protobuf:"bytes,1,opt,name=alias,proto3" json:"alias,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateURLResponse) Reset() {
+	*x = CreateURLResponse{}
+	mi := &file_urlshortener_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateURLResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateURLResponse) ProtoMessage() {}
+
+func (x *CreateURLResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_urlshortener_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateURLResponse.ProtoReflect.Descriptor instead.
+func (*CreateURLResponse) Descriptor() ([]byte, []int) {
+	return file_urlshortener_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CreateURLResponse) GetAlias() string {
+	if x != nil {
+		return x.Alias
+	}
+	return ""
+}
+
+type GetURLRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Alias         string                 `protobuf:"bytes,1,opt,name=alias,proto3" json:"alias,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetURLRequest) Reset() {
+	*x = GetURLRequest{}
+	mi := &file_urlshortener_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetURLRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetURLRequest) ProtoMessage() {}
+
+func (x *GetURLRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_urlshortener_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetURLRequest.ProtoReflect.Descriptor instead.
+func (*GetURLRequest) Descriptor() ([]byte, []int) {
+	return file_urlshortener_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetURLRequest) GetAlias() string {
+	if x != nil {
+		return x.Alias
+	}
+	return ""
+}
+
+type GetURLResponse struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Id             int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Alias          string                 `protobuf:"bytes,2,opt,name=alias,proto3" json:"alias,omitempty"`
+	Url            string                 `protobuf:"bytes,3,opt,name=url,proto3" json:"url,omitempty"`
+	Permanent      *bool                  `protobuf:"varint,4,opt,name=permanent,proto3,oneof" json:"permanent,omitempty"`
+	ExpiresAt      *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	CreatedAt      *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	LastAccessedAt *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=last_accessed_at,json=lastAccessedAt,proto3" json:"last_accessed_at,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *GetURLResponse) Reset() {
+	*x = GetURLResponse{}
+	mi := &file_urlshortener_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetURLResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetURLResponse) ProtoMessage() {}
+
+func (x *GetURLResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_urlshortener_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetURLResponse.ProtoReflect.Descriptor instead.
+func (*GetURLResponse) Descriptor() ([]byte, []int) {
+	return file_urlshortener_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetURLResponse) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *GetURLResponse) GetAlias() string {
+	if x != nil {
+		return x.Alias
+	}
+	return ""
+}
+
+func (x *GetURLResponse) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *GetURLResponse) GetPermanent() bool {
+	if x != nil && x.Permanent != nil {
+		return *x.Permanent
+	}
+	return false
+}
+
+func (x *GetURLResponse) GetExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return nil
+}
+
+func (x *GetURLResponse) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *GetURLResponse) GetLastAccessedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastAccessedAt
+	}
+	return nil
+}
+
+type DeleteURLRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Alias         string                 `protobuf:"bytes,1,opt,name=alias,proto3" json:"alias,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteURLRequest) Reset() {
+	*x = DeleteURLRequest{}
+	mi := &file_urlshortener_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteURLRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteURLRequest) ProtoMessage() {}
+
+func (x *DeleteURLRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_urlshortener_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteURLRequest.ProtoReflect.Descriptor instead.
+func (*DeleteURLRequest) Descriptor() ([]byte, []int) {
+	return file_urlshortener_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *DeleteURLRequest) GetAlias() string {
+	if x != nil {
+		return x.Alias
+	}
+	return ""
+}
+
+type DeleteURLResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CountDeleted  int64                  `protobuf:"varint,1,opt,name=count_deleted,json=countDeleted,proto3" json:"count_deleted,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteURLResponse) Reset() {
+	*x = DeleteURLResponse{}
+	mi := &file_urlshortener_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteURLResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteURLResponse) ProtoMessage() {}
+
+func (x *DeleteURLResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_urlshortener_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteURLResponse.ProtoReflect.Descriptor instead.
+func (*DeleteURLResponse) Descriptor() ([]byte, []int) {
+	return file_urlshortener_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *DeleteURLResponse) GetCountDeleted() int64 {
+	if x != nil {
+		return x.CountDeleted
+	}
+	return 0
+}
+
+var File_urlshortener_proto protoreflect.FileDescriptor
+
+const file_urlshortener_proto_rawDesc = "" +
+	"\n" +
+	"\x12urlshortener.proto\x12\x0furlshortener.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"}\n" +
+	"\x10CreateURLRequest\x12\x10\n" +
+	"\x03url\x18\x01 \x01(\tR\x03url\x12\x14\n" +
+	"\x05alias\x18\x02 \x01(\tR\x05alias\x12!\n" +
+	"\tpermanent\x18\x03 \x01(\bH\x00R\tpermanent\x88\x01\x01\x12\x10\n" +
+	"\x03ttl\x18\x04 \x01(\tR\x03ttlB\f\n" +
+	"\n" +
+	"_permanent\")\n" +
+	"\x11CreateURLResponse\x12\x14\n" +
+	"\x05alias\x18\x01 \x01(\tR\x05alias\"%\n" +
+	"\rGetURLRequest\x12\x14\n" +
+	"\x05alias\x18\x01 \x01(\tR\x05alias\"\xb5\x02\n" +
+	"\x0eGetURLResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x14\n" +
+	"\x05alias\x18\x02 \x01(\tR\x05alias\x12\x10\n" +
+	"\x03url\x18\x03 \x01(\tR\x03url\x12!\n" +
+	"\tpermanent\x18\x04 \x01(\bH\x00R\tpermanent\x88\x01\x01\x129\n" +
+	"\n" +
+	"expires_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\texpiresAt\x129\n" +
+	"\n" +
+	"created_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x12D\n" +
+	"\x10last_accessed_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\x0elastAccessedAtB\f\n" +
+	"\n" +
+	"_permanent\"(\n" +
+	"\x10DeleteURLRequest\x12\x14\n" +
+	"\x05alias\x18\x01 \x01(\tR\x05alias\"8\n" +
+	"\x11DeleteURLResponse\x12#\n" +
+	"\rcount_deleted\x18\x01 \x01(\x03R\fcountDeleted2\x81\x02\n" +
+	"\fURLShortener\x12R\n" +
+	"\tCreateURL\x12!.urlshortener.v1.CreateURLRequest\x1a\".urlshortener.v1.CreateURLResponse\x12I\n" +
+	"\x06GetURL\x12\x1e.urlshortener.v1.GetURLRequest\x1a\x1f.urlshortener.v1.GetURLResponse\x12R\n" +
+	"\tDeleteURL\x12!.urlshortener.v1.DeleteURLRequest\x1a\".urlshortener.v1.DeleteURLResponseB2Z0url-shortener/api/urlshortener/v1;urlshortenerv1b\x06proto3"
+
+var (
+	file_urlshortener_proto_rawDescOnce sync.Once
+	file_urlshortener_proto_rawDescData []byte
+)
+
+func file_urlshortener_proto_rawDescGZIP() []byte {
+	file_urlshortener_proto_rawDescOnce.Do(func() {
+		file_urlshortener_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_urlshortener_proto_rawDesc), len(file_urlshortener_proto_rawDesc)))
+	})
+	return file_urlshortener_proto_rawDescData
+}
+
+var file_urlshortener_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_urlshortener_proto_goTypes = []any{
+	(*CreateURLRequest)(nil),      // 0: urlshortener.v1.CreateURLRequest
+	(*CreateURLResponse)(nil),     // 1: urlshortener.v1.CreateURLResponse
+	(*GetURLRequest)(nil),         // 2: urlshortener.v1.GetURLRequest
+	(*GetURLResponse)(nil),        // 3: urlshortener.v1.GetURLResponse
+	(*DeleteURLRequest)(nil),      // 4: urlshortener.v1.DeleteURLRequest
+	(*DeleteURLResponse)(nil),     // 5: urlshortener.v1.DeleteURLResponse
+	(*timestamppb.Timestamp)(nil), // 6: google.protobuf.Timestamp
+}
+var file_urlshortener_proto_depIdxs = []int32{
+	6, // 0: urlshortener.v1.GetURLResponse.expires_at:type_name -> google.protobuf.Timestamp
+	6, // 1: urlshortener.v1.GetURLResponse.created_at:type_name -> google.protobuf.Timestamp
+	6, // 2: urlshortener.v1.GetURLResponse.last_accessed_at:type_name -> google.protobuf.Timestamp
+	0, // 3: urlshortener.v1.URLShortener.CreateURL:input_type -> urlshortener.v1.CreateURLRequest
+	2, // 4: urlshortener.v1.URLShortener.GetURL:input_type -> urlshortener.v1.GetURLRequest
+	4, // 5: urlshortener.v1.URLShortener.DeleteURL:input_type -> urlshortener.v1.DeleteURLRequest
+	1, // 6: urlshortener.v1.URLShortener.CreateURL:output_type -> urlshortener.v1.CreateURLResponse
+	3, // 7: urlshortener.v1.URLShortener.GetURL:output_type -> urlshortener.v1.GetURLResponse
+	5, // 8: urlshortener.v1.URLShortener.DeleteURL:output_type -> urlshortener.v1.DeleteURLResponse
+	6, // [6:9] is the sub-list for method output_type
+	3, // [3:6] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_urlshortener_proto_init() }
+func file_urlshortener_proto_init() {
+	if File_urlshortener_proto != nil {
+		return
+	}
+	file_urlshortener_proto_msgTypes[0].OneofWrappers = []any{}
+	file_urlshortener_proto_msgTypes[3].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_urlshortener_proto_rawDesc), len(file_urlshortener_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   6,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_urlshortener_proto_goTypes,
+		DependencyIndexes: file_urlshortener_proto_depIdxs,
+		MessageInfos:      file_urlshortener_proto_msgTypes,
+	}.Build()
+	File_urlshortener_proto = out.File
+	file_urlshortener_proto_goTypes = nil
+	file_urlshortener_proto_depIdxs = nil
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"url-shortener/internal/config"
+	"url-shortener/internal/lib/reserved"
+	"url-shortener/internal/storage"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// usage - справка по подкомандам. Все подкоманды читают ту же конфигурацию (CONFIG_PATH), что и сервер,
+// и работают с хранилищем напрямую, поэтому ссылками можно управлять и при остановленном сервере.
+const usage = `usage: url-shortener [command] [flags]
+
+commands:
+  serve    start the HTTP and gRPC servers (default)
+  create   create a link: create --url URL --alias ALIAS [--namespace NS] [--ttl 72h]
+  delete   delete a link: delete --alias ALIAS
+  list     print all links as a table
+  export   write all links as JSON lines: export [--output FILE]
+
+The config is read from CONFIG_PATH, as for serve.
+Run "url-shortener <command> -h" for the flags of a command.
+`
+
+// errUsage - ошибка неверного вызова подкоманды. Сообщение к этому моменту уже напечатано, main только выходит с кодом 2.
+var errUsage = errors.New("invalid usage")
+
+// exportPageSize - сколько ссылок читается из хранилища за один запрос в list и export.
+const exportPageSize = 500
+
+// runCreate - подкоманда create: сохраняет ссылку с заданным псевдонимом.
+// Проверки те же, что у POST /url: адрес, символы псевдонима и зарезервированные псевдонимы.
+func runCreate(args []string) error {
+	fs := newFlagSet("create", "url-shortener create --url URL --alias ALIAS [flags]")
+	urlToSave := fs.String("url", "", "destination URL (required)")
+	alias := fs.String("alias", "", "alias of the link (required)")
+	namespace := fs.String("namespace", "", "namespace of the link; empty means the default namespace")
+	ttl := fs.Duration("ttl", 0, "link lifetime, e.g. 72h; 0 means the link does not expire")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if *urlToSave == "" || *alias == "" {
+		return usageErrorf(fs, "--url and --alias are required")
+	}
+
+	validate := validator.New()
+	if err := validate.Var(*urlToSave, "http_url"); err != nil {
+		return usageErrorf(fs, "--url is not a valid http or https URL")
+	}
+	if err := validate.Var(*alias, "alphanum"); err != nil {
+		return usageErrorf(fs, "--alias must contain only letters and digits")
+	}
+	if err := validate.Var(*namespace, "omitempty,alphanum"); err != nil {
+		return usageErrorf(fs, "--namespace must contain only letters and digits")
+	}
+	if *ttl < 0 {
+		return usageErrorf(fs, "--ttl must not be negative")
+	}
+
+	cfg, st, err := openAdminStorage()
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	reservedAliases := reserved.New(cfg.ReservedAliases)
+	if *namespace != "" && reservedAliases.Contains(*namespace) {
+		return fmt.Errorf("namespace %q is reserved", *namespace)
+	}
+	if reservedAliases.Contains(*alias) {
+		return fmt.Errorf("alias %q is reserved", *alias)
+	}
+
+	opts := storage.URLOptions{Namespace: *namespace}
+	if *ttl > 0 {
+		expiresAt := time.Now().Add(*ttl)
+		opts.ExpiresAt = &expiresAt
+	}
+
+	if _, err := st.SaveURL(*urlToSave, *alias, opts); err != nil {
+		switch {
+		case errors.Is(err, storage.ErrAliasHeld):
+			return fmt.Errorf("alias %q is reserved by another client", *alias)
+		case errors.Is(err, storage.ErrAliasConfusable):
+			return fmt.Errorf("alias %q is too similar to an existing alias", *alias)
+		case errors.Is(err, storage.ErrURLExists):
+			return fmt.Errorf("alias %q is already taken", *alias)
+		case errors.Is(err, storage.ErrURLDuplicate):
+			return fmt.Errorf("url %q is already shortened", *urlToSave)
+		}
+		return fmt.Errorf("save url: %w", err)
+	}
+
+	fmt.Printf("created %s -> %s\n", displayAlias(*namespace, *alias), *urlToSave)
+
+	return nil
+}
+
+// runDelete - подкоманда delete: удаляет ссылку пространства имён по умолчанию.
+func runDelete(args []string) error {
+	fs := newFlagSet("delete", "url-shortener delete --alias ALIAS")
+	alias := fs.String("alias", "", "alias of the link to delete (required)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if *alias == "" {
+		return usageErrorf(fs, "--alias is required")
+	}
+
+	_, st, err := openAdminStorage()
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	deleted, err := st.DeleteURL(*alias)
+	if err != nil {
+		return fmt.Errorf("delete url: %w", err)
+	}
+	if deleted == 0 {
+		return fmt.Errorf("alias %q not found", *alias)
+	}
+
+	fmt.Printf("deleted %s\n", *alias)
+
+	return nil
+}
+
+// runList - подкоманда list: печатает все ссылки таблицей в порядке создания.
+func runList(args []string) error {
+	fs := newFlagSet("list", "url-shortener list")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	_, st, err := openAdminStorage()
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ALIAS\tURL\tCLICKS\tEXPIRES\tSTATUS")
+
+	err = eachURL(st, func(rec storage.URLRecord) error {
+		expires := "-"
+		if rec.ExpiresAt != nil {
+			expires = rec.ExpiresAt.Format(time.RFC3339)
+		}
+
+		status := "active"
+		switch {
+		case rec.Disabled:
+			status = "disabled"
+		case rec.ExpiresAt != nil && !rec.ExpiresAt.After(time.Now()):
+			status = "expired"
+		case rec.MaxClicks > 0 && rec.Clicks >= rec.MaxClicks:
+			status = "exhausted"
+		}
+
+		_, err := fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\n", displayAlias(rec.Namespace, rec.Alias), rec.URL, rec.Clicks, expires, status)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return tw.Flush()
+}
+
+// runExport - подкоманда export: выгружает все ссылки в формате JSON Lines, по записи storage.URLRecord на строку.
+// Ссылки читаются страницами, поэтому выгрузка не держит всю базу в памяти.
+func runExport(args []string) error {
+	fs := newFlagSet("export", "url-shortener export [--output FILE]")
+	output := fs.String("output", "-", `file to write to; "-" means stdout`)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	_, st, err := openAdminStorage()
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	var (
+		out  io.Writer = os.Stdout
+		file *os.File
+	)
+	if *output != "-" {
+		file, err = os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("create output file: %w", err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	enc := json.NewEncoder(out)
+	exported := 0
+	err = eachURL(st, func(rec storage.URLRecord) error {
+		exported++
+		return enc.Encode(rec)
+	})
+	if err != nil {
+		return err
+	}
+
+	if file != nil {
+		// Ошибка записи на диск может проявиться только при закрытии файла.
+		if err := file.Close(); err != nil {
+			return fmt.Errorf("close output file: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "exported %d links to %s\n", exported, *output)
+	}
+
+	return nil
+}
+
+// eachURL вызывает fn для каждой ссылки хранилища в порядке ID, читая их страницами по exportPageSize.
+func eachURL(st storage.URLStorage, fn func(rec storage.URLRecord) error) error {
+	var after int64
+	for {
+		records, err := st.ListURLsAfter(after, exportPageSize)
+		if err != nil {
+			return fmt.Errorf("list urls: %w", err)
+		}
+
+		for _, rec := range records {
+			if err := fn(rec); err != nil {
+				return err
+			}
+		}
+
+		if len(records) < exportPageSize {
+			return nil
+		}
+		after = records[len(records)-1].ID
+	}
+}
+
+// openAdminStorage загружает конфигурацию и открывает хранилище для подкоманды.
+// Лог хранилища (миграции, медленные запросы) пишется в stderr начиная с warn, чтобы не смешиваться с выводом команды.
+func openAdminStorage() (*config.Config, storage.URLStorage, error) {
+	cfg := config.MustLoad()
+
+	log := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+	st, err := openStorage(log, cfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("init storage: %w", err)
+	}
+
+	return cfg, st, nil
+}
+
+// newFlagSet создаёт набор флагов подкоманды, который при ошибке возвращает её, а не завершает программу.
+func newFlagSet(name, synopsis string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s\n", synopsis)
+		fs.PrintDefaults()
+	}
+
+	return fs
+}
+
+// parseFlags разбирает флаги подкоманды. Позиционные аргументы подкоманды не принимают.
+// Ошибку разбора FlagSet печатает сам вместе со справкой, поэтому она возвращается как errUsage.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+
+	if fs.NArg() > 0 {
+		return usageErrorf(fs, "unexpected argument %q", fs.Arg(0))
+	}
+
+	return nil
+}
+
+// usageErrorf печатает сообщение и справку подкоманды и возвращает errUsage.
+func usageErrorf(fs *flag.FlagSet, format string, args ...any) error {
+	fmt.Fprintf(fs.Output(), format+"\n", args...)
+	fs.Usage()
+
+	return errUsage
+}
+
+// displayAlias возвращает псевдоним в том виде, в каком он входит в короткую ссылку: namespace/alias или alias.
+func displayAlias(namespace, alias string) string {
+	if namespace == "" {
+		return alias
+	}
+
+	return namespace + "/" + alias
+}
@@ -1,30 +1,106 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
 	// Пакет log/slog используется для логирования
 	"log/slog"
+	"net"
 	"net/http"
 
 	// Пакет os предоставляет функции для работы с операционной системой (например, чтение переменных окружения)
 	"os"
+	"os/signal"
+	"path"
+	"strings"
+	"syscall"
+	"time"
 	// Импортируем модуль конфигурации приложения
 	"url-shortener/internal/config"
+	// Импортируем реализацию gRPC API
+	"url-shortener/internal/grpc-server/urlshortener"
+	"url-shortener/internal/http-server/handlers/admin/keys/create"
+	"url-shortener/internal/http-server/handlers/admin/keys/revoke"
 	// Импортируем middleware (промежуточный обработчик) для логирования HTTP-запросов
+	"url-shortener/internal/http-server/handlers/health"
+	"url-shortener/internal/http-server/handlers/notfound"
+	"url-shortener/internal/http-server/handlers/openapi"
 	"url-shortener/internal/http-server/handlers/redirect"
+	"url-shortener/internal/http-server/handlers/root"
+	"url-shortener/internal/http-server/handlers/stats/summary"
+	"url-shortener/internal/http-server/handlers/url/bulkdelete"
+	"url-shortener/internal/http-server/handlers/url/bytarget"
+	"url-shortener/internal/http-server/handlers/url/confirm"
 	"url-shortener/internal/http-server/handlers/url/delete"
+	"url-shortener/internal/http-server/handlers/url/deleteall"
+	"url-shortener/internal/http-server/handlers/url/exists"
+	"url-shortener/internal/http-server/handlers/url/expand"
+	"url-shortener/internal/http-server/handlers/url/expiry"
+	"url-shortener/internal/http-server/handlers/url/get"
+	"url-shortener/internal/http-server/handlers/url/list"
+	"url-shortener/internal/http-server/handlers/url/preview"
+	"url-shortener/internal/http-server/handlers/url/reserve"
+	"url-shortener/internal/http-server/handlers/url/resolve"
+	"url-shortener/internal/http-server/handlers/url/rotate"
 	"url-shortener/internal/http-server/handlers/url/save"
+	"url-shortener/internal/http-server/handlers/url/search"
+	"url-shortener/internal/http-server/handlers/url/stale"
+	"url-shortener/internal/http-server/handlers/url/toggle"
+	versionHandler "url-shortener/internal/http-server/handlers/version"
+	"url-shortener/internal/http-server/middleware/allowlist"
+	"url-shortener/internal/http-server/middleware/apikeyauth"
+	"url-shortener/internal/http-server/middleware/bodylog"
+	"url-shortener/internal/http-server/middleware/concurrency"
+	"url-shortener/internal/http-server/middleware/contenttype"
+	"url-shortener/internal/http-server/middleware/etag"
+	"url-shortener/internal/http-server/middleware/inflight"
+	"url-shortener/internal/http-server/middleware/jwtauth"
 	mwLogger "url-shortener/internal/http-server/middleware/logger"
+	"url-shortener/internal/http-server/middleware/ratelimit"
+	"url-shortener/internal/http-server/middleware/readonly"
+	"url-shortener/internal/http-server/middleware/recoverer"
+	"url-shortener/internal/http-server/middleware/requestid"
+	"url-shortener/internal/http-server/middleware/timeout"
+	"url-shortener/internal/lib/access"
+	"url-shortener/internal/lib/aliaslen"
+	"url-shortener/internal/lib/buildinfo"
+	"url-shortener/internal/lib/clientip"
+	"url-shortener/internal/lib/listen"
 
 	// Импортируем кастомный обработчик логирования slogpretty для красивого форматирования логов
 	"url-shortener/internal/lib/logger/handlers/slogpretty"
+	"url-shortener/internal/lib/logger/logfile"
 	// Импортируем вспомогательный пакет sl для работы с логами
 	"url-shortener/internal/lib/logger/sl"
-	// Импортируем пакет для работы с хранилищем SQLite
-	"url-shortener/internal/storage/sqlite"
+	"url-shortener/internal/lib/random"
+	"url-shortener/internal/lib/random/words"
+	"url-shortener/internal/lib/redirectheaders"
+	"url-shortener/internal/lib/reserved"
+	"url-shortener/internal/lib/sequence"
+	"url-shortener/internal/lib/tlsconfig"
+	"url-shortener/internal/lib/trustedhosts"
+	"url-shortener/internal/lib/webhook"
+	// Импортируем фабрику хранилищ, выбирающую бэкенд по конфигурации
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/factory"
+	"url-shortener/internal/storage/instrumented"
 	// Импортируем роутер chi v5 для работы с HTTP-маршрутизацией
 	"github.com/go-chi/chi/v5"
 	// Импортируем middleware из chi для различных вспомогательных функций (например, логирования, восстановления после паники)
 	"github.com/go-chi/chi/v5/middleware"
+	// Импортируем клиент Prometheus для метрик на /metrics
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	// Импортируем gRPC-сервер и TLS-учётные данные для него
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 const (
@@ -34,7 +110,71 @@ const (
 	envProd  = "prod"  // Продакшен-среда (используется в боевом окружении)
 )
 
+// Информация о сборке. Значения подставляются при сборке через -ldflags, например:
+//
+//	go build -ldflags "-X main.version=v1.2.0 -X main.commit=$(git rev-parse --short HEAD) -X main.buildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd/url-shortener
+var (
+	version   = "dev"
+	commit    = "none"
+	buildDate = "unknown"
+)
+
 func main() {
+	// Первый аргумент без дефиса - имя подкоманды. Без подкоманды запускается сервер, как и раньше.
+	command, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	var run func(args []string) error
+	switch command {
+	case "serve":
+		run = runServe
+	case "create":
+		run = runCreate
+	case "delete":
+		run = runDelete
+	case "list":
+		run = runList
+	case "export":
+		run = runExport
+	case "help":
+		fmt.Fprint(os.Stdout, usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", command, usage)
+		os.Exit(2)
+	}
+
+	if err := run(args); err != nil {
+		switch {
+		case errors.Is(err, flag.ErrHelp):
+			return
+		case errors.Is(err, errUsage):
+			// Сообщение и справку уже напечатал разбор флагов.
+			os.Exit(2)
+		default:
+			fmt.Fprintf(os.Stderr, "%s: %v\n", command, err)
+			os.Exit(1)
+		}
+	}
+}
+
+// runServe - подкоманда serve: запускает HTTP- и gRPC-серверы. Флагов у неё нет, разбор нужен для -h,
+// который без подкоманды попадает сюда и печатает общую справку.
+func runServe(args []string) error {
+	fs := newFlagSet("serve", "url-shortener serve")
+	fs.Usage = func() { fmt.Fprint(fs.Output(), usage) }
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	serve()
+
+	return nil
+}
+
+func serve() {
 	// TODO: init config: cleanenv
 
 	// Вызываем функцию MustLoad из пакета config.
@@ -48,26 +188,57 @@ func main() {
 	// Вызываем функцию setupLogger, передавая в неё переменную среды cfg.Env.
 	// setupLogger – это кастомная функция, которая настраивает логгер в зависимости от среды (local, dev, prod).
 	// Возвращает объект log, который мы будем использовать для логирования событий.
-	log := setupLogger(cfg.Env)
+	// Лог пишется в stdout, stderr или ротируемый файл (log_output). Логгера ещё нет, поэтому ошибка печатается в stderr.
+	logOut, closeLog, err := openLogOutput(cfg.LogOutput, cfg.LogRotation)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to open log output %q: %v\n", cfg.LogOutput, err)
+		os.Exit(1)
+	}
+
+	log := setupLogger(cfg.Env, logOut)
+
+	// Делаем логгер логгером по умолчанию: slog.SetDefault перенаправляет в него и стандартный пакет log,
+	// через который http.Server без ErrorLog пишет свои ошибки (например, неудачные TLS-рукопожатия).
+	// Остальные пакеты получают логгер явно.
+	slog.SetDefault(log)
 
 	// Вызываем метод Info у объекта log.
 	// log.Info() – это метод логгера, который записывает информационное сообщение.
-	// slog.String("env", cfg.Env) – добавляет в лог строковый параметр "env" со значением из конфигурации.
-	log.Info("starting url-shortener", slog.String("env", cfg.Env), slog.String("version", "123"))
+	// slog.String("env", cfg.Env) – добавляет в лог строковый параметр "env" со значением из конфигурации,
+	// остальные атрибуты – версия, коммит и дата сборки.
+	log.Info("starting url-shortener",
+		slog.String("env", cfg.Env),
+		slog.String("version", version),
+		slog.String("commit", commit),
+		slog.String("build_date", buildDate),
+	)
+
+	// Сводка действующей конфигурации одной строкой: адрес, хранилище, режим аутентификации, таймауты.
+	// Секреты в ней скрыты (см. config.Config.LogValue).
+	log.Info("effective config", slog.Any("config", cfg))
 
-	// Вызываем метод Debug у логгера log.
-	// log.Debug() записывает отладочное сообщение, но оно будет видно только если включён debug-уровень логирования.
-	log.Debug("debug messages are enabled")
+	if cfg.ReadOnly {
+		log.Warn("READ-ONLY MODE: creating, changing and deleting links is disabled, redirects and reads keep working")
+	}
 
-	// Вызываем метод Error у логгера log.
-	// log.Error() записывает сообщение об ошибке.
-	log.Error("error messages are enabled")
+	// Пробные сообщения проверяют, что лог доходит до получателя. Они пишутся только с log_self_test:
+	// сообщение уровня error при каждом старте давало бы ложные срабатывания мониторинга ошибок.
+	if cfg.LogSelfTest {
+		// Вызываем метод Debug у логгера log.
+		// log.Debug() записывает отладочное сообщение, но оно будет видно только если включён debug-уровень логирования.
+		log.Debug("debug messages are enabled")
+
+		// Вызываем метод Error у логгера log.
+		// log.Error() записывает сообщение об ошибке.
+		log.Error("error messages are enabled")
+	}
 
 	// TODO: init storage: sqlLite
 
-	// Вызываем функцию sqlite.New(), передавая путь к файлу базы данных из конфигурации.
-	// sqlite.New() возвращает объект storage (хранилище) и ошибку err.
-	storage, err := sqlite.New(cfg.StoragePath)
+	// Вызываем функцию factory.New(), которая создаёт хранилище выбранного в конфигурации бэкенда (storage_type).
+	// factory.New() возвращает объект storage (хранилище) и ошибку err.
+	// Логгер передаётся в хранилище для журнала медленных запросов.
+	storage, err := openStorage(log, cfg)
 	if err != nil {
 		// Если err не nil (т.е. произошла ошибка), логируем её через log.Error().
 		// sl.Err(err) – это вспомогательная функция для форматирования ошибки в логах.
@@ -77,9 +248,28 @@ func main() {
 		os.Exit(1)
 	}
 
-	// _ = storage – временная заглушка, чтобы компилятор не ругался на неиспользуемую переменную.
-	// В будущем здесь будет код работы с хранилищем.
-	_ = storage
+	// build – информация о сборке для /version и метрики url_shortener_build_info.
+	build := buildinfo.Info{
+		Version:   version,
+		Commit:    commit,
+		BuildDate: buildDate,
+	}
+
+	// metricsRegistry – реестр метрик Prometheus, которые отдаются на /metrics.
+	// url_shortener_build_info всегда равна 1 и несёт версию в метках, чтобы разбивать метрики по версиям при выкатке.
+	metricsRegistry := prometheus.NewRegistry()
+	metricsRegistry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		build.Collector(),
+	)
+
+	// instrumented.New оборачивает хранилище и измеряет длительность его операций для /metrics.
+	storage, err = instrumented.New(storage, metricsRegistry)
+	if err != nil {
+		log.Error("failed to instrument storage", sl.Err(err))
+		os.Exit(1)
+	}
 
 	// TODO: init router: chi
 
@@ -89,55 +279,648 @@ func main() {
 
 	// Подключаем middleware (промежуточные обработчики, которые выполняются перед основным обработчиком запроса).
 
-	// middleware.RequestID – это встроенный middleware из chi, который добавляет уникальный идентификатор (UUID) к каждому HTTP-запросу.
-	router.Use(middleware.RequestID)
+	// trustedProxies – сети доверенных прокси, от которых принимаются заголовки с реальным IP клиента.
+	trustedProxies, err := clientip.ParseNetworks(cfg.TrustedProxies)
+	if err != nil {
+		log.Error("failed to parse trusted proxies", sl.Err(err))
+		os.Exit(1)
+	}
+
+	// requestid.New – кастомный middleware, который добавляет уникальный идентификатор к каждому HTTP-запросу
+	// и возвращает его клиенту в заголовке X-Request-ID. Идентификатор, присланный клиентом, используется повторно.
+	router.Use(requestid.New())
 
 	// middleware.Logger – логирует входящие HTTP-запросы (метод, URL, время обработки и код ответа).
-	router.Use(middleware.Logger)
+	// Он пишет каждый запрос, поэтому при выборочном логировании (log_sample_rate > 1) не подключается.
+	if cfg.LogSampleRate <= 1 {
+		router.Use(middleware.Logger)
+	}
 
-	// mwLogger.New(log) – кастомный middleware, который использует наш логгер log для логирования запросов.
-	router.Use(mwLogger.New(log))
+	// mwLogger.New(log, trustedProxies, sampling) – кастомный middleware, который использует наш логгер log для логирования запросов.
+	// При log_sample_rate > 1 успешные быстрые запросы логируются выборочно, ошибки и медленные запросы - всегда.
+	router.Use(mwLogger.New(log, trustedProxies, mwLogger.Sampling{
+		Rate:          cfg.LogSampleRate,
+		SlowThreshold: cfg.LogSlowThreshold,
+	}))
 
-	// middleware.Recoverer – встроенный middleware из chi, который обрабатывает паники внутри обработчиков.
-	// Если в коде произойдёт panic, сервер не упадёт, а вернёт клиенту 500 Internal Server Error.
-	router.Use(middleware.Recoverer)
+	// recoverer.New – кастомный middleware, который обрабатывает паники внутри обработчиков.
+	// Если в коде произойдёт panic, сервер не упадёт: паника со стеком и ID запроса пишется в лог,
+	// а клиент получает JSON с 500 Internal Server Error. Стек попадает в ответ только в среде local.
+	if cfg.HTTPServer.PanicStack && cfg.Env != envLocal {
+		log.Warn("http_server.panic_stack is ignored outside the local environment", slog.String("env", cfg.Env))
+	}
+	router.Use(recoverer.New(log, recoverer.Options{
+		IncludeRequestID: !cfg.HTTPServer.HidePanicRequestID,
+		IncludeStack:     cfg.HTTPServer.PanicStack && cfg.Env == envLocal,
+	}))
 
 	// middleware.URLFormat – встроенный middleware, который позволяет работать с URL-форматами.
 	router.Use(middleware.URLFormat)
 
-	router.Route("/url", func(r chi.Router) {
-		r.Use(middleware.BasicAuth("url-shortener", map[string]string{
-			cfg.Auth.User: cfg.Auth.Password, 
+	// inFlight – счётчик запросов, которые обрабатываются в данный момент. При остановке сервера
+	// по нему видно, сколько запросов не успели завершиться.
+	inFlight := inflight.New()
+	router.Use(inFlight.Middleware)
+
+	// concurrency.New – кастомный middleware, который ограничивает число одновременно обрабатываемых запросов,
+	// чтобы не перегружать SQLite. Сверх лимита – сразу 503 с Retry-After, без очереди.
+	concurrencyLimit := concurrency.New(log, cfg.HTTPServer.MaxConcurrentRequests)
+
+	// ratelimit.New – кастомный middleware, который ограничивает число запросов с одного IP-адреса за период
+	// и сообщает клиенту остаток лимита в заголовках X-RateLimit-*. При rate_limit.requests = 0 выключен.
+	// Оба лимита подключаются при монтировании app, а /health монтируется в обход них (см. ниже).
+	rateLimit := ratelimit.New(log, cfg.RateLimit.Requests, cfg.RateLimit.Period, trustedProxies)
+
+	// allowlist.New – кастомный middleware, который ограничивает доступ к административным маршрутам списком сетей.
+	adminAllowlist, err := allowlist.New(log, cfg.Auth.AllowedCIDRs, trustedProxies)
+	if err != nil {
+		log.Error("failed to init admin allowlist", sl.Err(err))
+		os.Exit(1)
+	}
+
+	// aliasGenerator – генератор псевдонимов для ссылок, сохраняемых без своего псевдонима.
+	// aliasSymbols – сколько различимых символов у генерируемых псевдонимов, от него зависит их длина.
+	// С reject_confusable_aliases из алфавита убираются символы, похожие на другие.
+	aliasAlphabet := cfg.AliasAlphabet
+	if cfg.RejectConfusableAliases {
+		aliasAlphabet = random.WithoutConfusable(aliasAlphabet)
+	}
+	aliasGenerator, aliasSymbols, err := newAliasGenerator(cfg.AliasStyle, aliasAlphabet, cfg.AliasLength)
+	if err != nil {
+		log.Error("failed to init alias generator", sl.Err(err))
+		os.Exit(1)
+	}
+
+	// К генерируемым псевдонимам добавляются alias_prefix и alias_suffix. Проверка зарезервированных
+	// псевдонимов в обработчиках видит уже полный псевдоним, а на случайную часть остаётся
+	// alias_max_length за вычетом длины префикса и суффикса.
+	affixedGenerator, err := random.NewAffixed(aliasGenerator, cfg.AliasPrefix, cfg.AliasSuffix)
+	if err != nil {
+		log.Error("invalid alias_prefix or alias_suffix", sl.Err(err))
+		os.Exit(1)
+	}
+	aliasGenerator = affixedGenerator
+
+	// aliasEncoder – псевдонимы из ID ссылок для alias_style: sequential, для остальных стилей nil.
+	// Генератор aliasGenerator при этом используется только для ротации псевдонимов.
+	var aliasEncoder save.AliasEncoder
+	if cfg.AliasStyle == "sequential" {
+		encoder, err := sequence.New(cfg.AliasSequenceOffset, cfg.AliasSequenceMultiplier, cfg.AliasPrefix, cfg.AliasSuffix)
+		if err != nil {
+			log.Error("invalid alias_sequence_offset or alias_sequence_multiplier", sl.Err(err))
+			os.Exit(1)
+		}
+		aliasEncoder = encoder
+	}
+
+	aliasMaxLength := cfg.AliasMaxLength - affixedGenerator.AffixLength()
+	if aliasMaxLength < cfg.AliasLength {
+		log.Error("alias_max_length leaves no room for alias_length with alias_prefix and alias_suffix",
+			slog.Int("alias_max_length", cfg.AliasMaxLength),
+			slog.Int("alias_length", cfg.AliasLength),
+			slog.Int("affix_length", affixedGenerator.AffixLength()),
+		)
+		os.Exit(1)
+	}
+
+	// aliasLength – длина генерируемых псевдонимов. Начальное значение зависит от числа уже сохранённых ссылок,
+	// дальше длина растёт при частых коллизиях.
+	urlCount, err := storage.CountURLs(context.Background())
+	if err != nil {
+		log.Error("failed to count urls", sl.Err(err))
+		os.Exit(1)
+	}
+	aliasLength := aliaslen.New(log, aliaslen.ForCount(urlCount, aliasSymbols, cfg.AliasLength, aliasMaxLength), aliasMaxLength)
+	log.Info("alias length", slog.Int("length", aliasLength.Length()), slog.Int64("urls", urlCount))
+	metricsRegistry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: "url_shortener",
+		Name:      "alias_length",
+		Help:      "Current length of generated aliases.",
+	}, func() float64 { return float64(aliasLength.Length()) }))
+
+	// reservedAliases – псевдонимы, которые нельзя занять ссылками (пути маршрутов сервиса и заданные в конфигурации).
+	reservedAliases := reserved.New(cfg.ReservedAliases)
+
+	// accessRecorder – фоновая запись времени последнего обращения к ссылкам и переходов по ссылкам без max_clicks
+	// пачками, не замедляющая редирект.
+	accessRecorder := access.NewRecorder(log, storage, cfg.Redirect.AccessFlushInterval)
+
+	// webhooks – асинхронная отправка событий link.created и link.redirected во внешние системы.
+	// Без заданных адресов события отбрасываются, и фоновые обработчики не запускаются.
+	webhooks := webhook.New(log, webhook.Options{
+		LinkCreatedURL:    cfg.Webhooks.LinkCreatedURL,
+		LinkRedirectedURL: cfg.Webhooks.LinkRedirectedURL,
+		RedactTarget:      !cfg.Webhooks.SendFullTarget,
+		Workers:           cfg.Webhooks.Workers,
+		QueueSize:         cfg.Webhooks.QueueSize,
+		MaxRetries:        cfg.Webhooks.MaxRetries,
+		Backoff:           cfg.Webhooks.Backoff,
+		Timeout:           cfg.Webhooks.Timeout,
+	})
+
+	// expiredTemplate – шаблон страницы истёкшей ссылки. nil означает встроенную страницу.
+	expiredTemplate, err := loadTemplate(cfg.Redirect.ExpiredTemplate)
+	if err != nil {
+		log.Error("failed to load expired page template", sl.Err(err))
+		os.Exit(1)
+	}
+
+	// redirectTemplate – шаблон страницы редиректа в режиме redirect.mode: html. nil означает встроенную страницу.
+	if cfg.Redirect.Mode != redirect.ModeHTTP && cfg.Redirect.Mode != redirect.ModeHTML {
+		log.Error("invalid redirect mode: expected \"http\" or \"html\"", slog.String("mode", cfg.Redirect.Mode))
+		os.Exit(1)
+	}
+	redirectTemplate, err := loadTemplate(cfg.Redirect.HTMLTemplate)
+	if err != nil {
+		log.Error("failed to load redirect page template", sl.Err(err))
+		os.Exit(1)
+	}
+
+	// redirect.headers проверяются при старте так же, как заголовки ссылок при сохранении.
+	if err := redirectheaders.Validate(cfg.Redirect.Headers); err != nil {
+		log.Error("invalid redirect headers", sl.Err(err))
+		os.Exit(1)
+	}
+
+	// trustedHosts – доверенные хосты назначения. nil означает, что страница подтверждения перехода не показывается.
+	var (
+		trustedHosts  redirect.HostClassifier
+		confirmSecret []byte
+	)
+	if len(cfg.TrustedRedirectHosts) > 0 {
+		trustedHosts = trustedhosts.New(cfg.TrustedRedirectHosts)
+
+		confirmSecret = []byte(cfg.Redirect.ConfirmSecret)
+		if len(confirmSecret) == 0 {
+			confirmSecret = make([]byte, 32)
+			if _, err := rand.Read(confirmSecret); err != nil {
+				log.Error("failed to generate confirm secret", sl.Err(err))
+				os.Exit(1)
+			}
+		}
+	}
+
+	// notFoundTemplate – шаблон страниц 404 и 405 для браузеров. nil означает встроенную страницу.
+	notFoundTemplate, err := loadTemplate(cfg.NotFoundTemplate)
+	if err != nil {
+		log.Error("failed to load not found page template", sl.Err(err))
+		os.Exit(1)
+	}
+	notFoundHandler := notfound.New(log, notFoundTemplate)
+	methodNotAllowedHandler := notfound.MethodNotAllowed(log, notFoundTemplate)
+
+	// Вместо текстового "404 page not found" из chi браузеры получают страницу, а API-клиенты – JSON.
+	// Обработчики задаются до монтирования вложенных роутеров: chi передаёт их вложенным роутерам при Mount.
+	router.NotFound(notFoundHandler)
+	router.MethodNotAllowed(methodNotAllowedHandler)
+
+	// basePath – префикс, под которым монтируются все маршруты приложения (например, "/s").
+	// По умолчанию "/" – маршруты доступны без префикса.
+	basePath := "/" + strings.Trim(cfg.BasePath, "/")
+
+	// app – роутер с маршрутами приложения, который монтируется в корневой роутер под basePath.
+	app := chi.NewRouter()
+	app.NotFound(notFoundHandler)
+	app.MethodNotAllowed(methodNotAllowedHandler)
+
+	// adminAuth – аутентификация на маршрутах /url в режиме из auth.mode.
+	adminAuth, err := newAuthMiddleware(log, cfg.Auth, storage)
+	if err != nil {
+		log.Error("failed to init auth", sl.Err(err))
+		os.Exit(1)
+	}
+
+	// timeout.New – кастомный middleware, который ограничивает время обработки запроса дедлайном контекста.
+	// Таймауты задаются на группах маршрутов, а не на всём роутере: вложенный дедлайн не может быть дольше внешнего,
+	// поэтому общий таймаут не дал бы административным маршрутам работать дольше редиректов.
+	// Все группы подключаются после логгера, поэтому запросы, прерванные по таймауту, попадают в лог со статусом 504.
+	// Контекст запроса передаётся в поиск ссылки в хранилище (редирект, expand, проверка псевдонима при сохранении),
+	// поэтому медленный поиск прерывается по дедлайну, а не дожидается ответа хранилища.
+	warnTimeoutExceedsWrite(log, cfg.HTTPServer)
+
+	if cfg.Auth.AllowAnonymousCreate {
+		log.Warn("link creation is open to anonymous clients: auth.allow_anonymous_create is true",
+			slog.Int("rate_limit_requests", cfg.RateLimit.Requests),
+			slog.Int("max_links_per_ip", cfg.MaxLinksPerIP),
+		)
+	}
+
+	app.Route("/url", func(r chi.Router) {
+		r.Use(timeout.New(log, cfg.HTTPServer.AdminTimeout))
+
+		// bodyLog – отладочный лог тел запросов и ответов изменяющих маршрутов (log_bodies), по умолчанию выключен.
+		bodyLog := bodylog.New(log, cfg.LogBodies, bodylog.Options{MaxBytes: cfg.LogBodyMaxBytes})
+
+		saveHandler := save.New(log, storage, save.Options{
+			MaxURLLength:     cfg.MaxURLLength,
+			BasePath:         basePath,
+			AliasGenerator:   aliasGenerator,
+			AliasLength:      aliasLength,
+			Reserved:         reservedAliases,
+			MaxLinksPerIP:    cfg.MaxLinksPerIP,
+			LinkCounter:      storage,
+			MaxTotalLinks:    cfg.MaxTotalLinks,
+			TotalLinkCounter: storage,
+			TrustedProxies:   trustedProxies,
+			FormResultURL:    cfg.FormResultURL,
+			AliasChecker:     storage,
+			Notifier:         webhooks,
+			Sequential:       storage,
+			AliasEncoder:     aliasEncoder,
+			Duplicates:       storage,
+			Idempotency:      storage,
+			IdempotencyTTL:   cfg.IdempotencyTTL,
+		})
+
+		// Создание ссылок аутентифицируется, если не включён auth.allow_anonymous_create.
+		// Режим read_only и проверка Content-Type действуют в любом случае.
+		r.Group(func(r chi.Router) {
+			if !cfg.Auth.AllowAnonymousCreate {
+				r.Use(adminAllowlist)
+				r.Use(adminAuth)
+			}
+			r.Use(readonly.New(log, cfg.ReadOnly))
+			r.Use(contenttype.New(log, cfg.AcceptedContentTypes))
+			r.Use(bodyLog)
+
+			r.Post("/", saveHandler)
+			// Сохранение ссылки в пространство имён из пути, например POST /url/ns/docs.
+			r.Post("/ns/{namespace}", saveHandler)
+
+			// Резервация псевдонима на время заполнения формы и создание ссылки по токену резервации.
+			r.Post("/reservations", reserve.New(log, storage, reserve.Options{Reserved: reservedAliases}))
+			r.Post("/reservations/confirm", confirm.New(log, storage, confirm.Options{
+				MaxURLLength:   cfg.MaxURLLength,
+				BasePath:       basePath,
+				TrustedProxies: trustedProxies,
+				Notifier:       webhooks,
+			}))
+		})
+
+		// Остальные изменяющие маршруты собраны в одну группу, которую закрывает режим read_only.
+		// Тело с неподходящим Content-Type отклоняется с 415 до декодирования в обработчике.
+		r.Group(func(r chi.Router) {
+			r.Use(adminAllowlist)
+			r.Use(adminAuth)
+			r.Use(readonly.New(log, cfg.ReadOnly))
+			r.Use(contenttype.New(log, cfg.AcceptedContentTypes))
+			r.Use(bodyLog)
+
+			r.Delete("/", deleteall.New(log, storage))
+			// Удаление списка ссылок одним запросом: тело - JSON-массив псевдонимов.
+			r.Delete("/batch", bulkdelete.New(log, storage))
+			r.Delete("/{alias}", delete.New(log, storage))
+
+			r.Post("/{alias}/rotate", rotate.New(log, storage, rotate.Options{
+				BasePath:       basePath,
+				AliasGenerator: aliasGenerator,
+				AliasLength:    aliasLength,
+				Reserved:       reservedAliases,
+				TrustedProxies: trustedProxies,
+			}))
+
+			// Временное выключение ссылки без удаления: данные и история переходов сохраняются.
+			r.Post("/{alias}/disable", toggle.New(log, storage, false))
+			r.Post("/{alias}/enable", toggle.New(log, storage, true))
+
+			// Продление или сокращение срока действия ссылки без пересоздания: {"expires_at": ...} или {"ttl": "72h"}.
+			r.Patch("/{alias}/expiry", expiry.New(log, storage))
+		})
+
+		// Получение адресов для списка псевдонимов одним запросом: тело - JSON-массив псевдонимов.
+		// Это чтение, поэтому в режиме read_only маршрут доступен, хотя и использует POST.
+		r.With(adminAllowlist, adminAuth).Post("/resolve", resolve.New(log, storage))
+
+		// Читающие маршруты отдают ETag и отвечают 304, если данные не изменились с прошлого запроса клиента.
+		r.Group(func(r chi.Router) {
+			r.Use(adminAllowlist)
+			r.Use(adminAuth)
+			r.Use(etag.New())
+
+			// Постраничный список ссылок по курсору: GET /url?after=<id>&limit=<n>.
+			r.Get("/", list.New(log, storage))
+			r.Get("/stale", stale.New(log, storage))
+			// Псевдонимы всех ссылок на адрес назначения: GET /url/by-target?url=<адрес>.
+			r.Get("/by-target", bytarget.New(log, storage))
+			// Поиск ссылок по подстроке адреса или псевдонима: GET /url/search?q=<подстрока>. Полный просмотр таблицы.
+			r.Get("/search", search.New(log, storage))
+			r.Get("/{alias}", get.New(log, storage))
+			r.Get("/{alias}/exists", exists.New(log, storage))
+		})
+	})
+
+	// Управление API-ключами в режиме api_key. Ключ нельзя выдать по самому ключу, поэтому маршруты
+	// закрыты логином и паролем из конфигурации.
+	if cfg.Auth.Mode == authModeAPIKey {
+		app.Route("/admin/keys", func(r chi.Router) {
+			r.Use(timeout.New(log, cfg.HTTPServer.AdminTimeout))
+			r.Use(adminAllowlist)
+			r.Use(middleware.BasicAuth("url-shortener", map[string]string{
+				cfg.Auth.User: cfg.Auth.Password,
+			}))
+
+			r.Post("/", create.New(log, storage))
+			r.Delete("/{id}", revoke.New(log, storage))
+		})
+	}
+
+	// Сводная статистика для дашборда доступна с той же аутентификацией, что и /url.
+	app.Route("/stats", func(r chi.Router) {
+		r.Use(timeout.New(log, cfg.HTTPServer.AdminTimeout))
+		r.Use(adminAllowlist)
+		r.Use(adminAuth)
+
+		r.Get("/summary", summary.New(log, storage, summary.Options{CacheTTL: cfg.StatsCacheTTL}))
+	})
+
+	// Остальные служебные маршруты ограничены общим request_timeout.
+	app.Group(func(r chi.Router) {
+		r.Use(timeout.New(log, cfg.HTTPServer.RequestTimeout))
+
+		// Предпросмотр ссылки доступен без аутентификации, в отличие от остальных маршрутов /url.
+		// chi сначала пробует этот маршрут и только потом передаёт запрос в смонтированный /url.
+		r.Get("/url/{alias}/preview", preview.New(log, storage, preview.Options{
+			FetchTitle:   cfg.Preview.FetchTitle,
+			TitleTimeout: cfg.Preview.TitleTimeout,
 		}))
 
-		r.Post("/", save.New(log, storage))
-		r.Delete("/{alias}", delete.New(log, storage))
+		// Раскрытие короткой ссылки в JSON без редиректа, без аутентификации – как и сам редирект.
+		r.Get("/api/expand", expand.New(log, storage, expand.Options{BasePath: basePath}))
+
+		// Корень сервиса перенаправляет на root_redirect. Маршрут "/" не пересекается с /{alias}: пустой псевдоним не сопоставляется.
+		r.Get("/", root.New(cfg.RootRedirect))
+		r.Get("/version", versionHandler.New(build))
+
+		// Описание API в формате OpenAPI 3 и Swagger UI для него доступны без аутентификации.
+		// middleware.URLFormat отрезает расширение перед маршрутизацией, поэтому /openapi.json попадает в маршрут /openapi.
+		r.Get("/openapi", openapi.New())
+		r.Get("/docs", openapi.Docs())
+
+		// Метрики Prometheus доступны без Basic Auth, чтобы их мог собирать Prometheus, но только из сетей auth.allowed_cidrs.
+		r.With(adminAllowlist).Get("/metrics", promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{}).ServeHTTP)
 	})
 
-	router.Get("/{alias}", redirect.New(log, storage))
+	redirectHandler := redirect.New(log, storage, redirect.Options{
+		Permanent:        cfg.Redirect.Permanent,
+		PreserveMethod:   cfg.Redirect.PreserveMethod,
+		CacheTTL:         cfg.Redirect.CacheTTL,
+		ExpiredTemplate:  expiredTemplate,
+		AccessRecorder:   accessRecorder,
+		NotFound:         notFoundHandler,
+		LogTarget:        cfg.LogRedirectTarget,
+		ClickCounter:     storage,
+		Notifier:         webhooks,
+		SlowThreshold:    cfg.Redirect.SlowThreshold,
+		Mode:             cfg.Redirect.Mode,
+		RedirectTemplate: redirectTemplate,
+		TrustedHosts:     trustedHosts,
+		ConfirmSecret:    confirmSecret,
+		InterstitialJSON: cfg.Redirect.InterstitialJSON,
+		ForwardQuery:     cfg.Redirect.ForwardQuery,
+		Headers:          cfg.Redirect.Headers,
+	})
+
+	// Редиректы получают короткий redirect_timeout: медленный редирект лучше быстро завершить ошибкой.
+	app.Group(func(r chi.Router) {
+		r.Use(timeout.New(log, cfg.HTTPServer.RedirectTimeout))
+
+		// /{alias} обслуживает пространство имён по умолчанию, /{namespace}/{alias} – остальные пространства имён.
+		// HEAD отдаёт те же статус и заголовки без тела – так мониторинг проверяет ссылки, не переходя по ним.
+		// Ссылки с завершающим слешем (/myalias/) обслуживаются так же, если не включён redirect.strict_trailing_slash.
+		redirect.Register(r, redirectHandler, !cfg.Redirect.StrictTrailingSlash)
+	})
+
+	// /health монтируется в корневой роутер в обход лимитов одновременных запросов и частоты: проверки живости
+	// проходят под нагрузкой и не расходуют лимит клиента, как бы ни был записан путь (/health или /health/).
+	// /health без параметров не обращается к хранилищу; /health?verbose=true добавляет задержку запроса к базе
+	// и число ссылок, ограниченные http_server.health_timeout.
+	healthHandler := health.New(log, version, storage, health.Options{Timeout: cfg.HTTPServer.HealthTimeout})
+	healthPath := path.Join(basePath, "health")
+	router.With(timeout.New(log, cfg.HTTPServer.RequestTimeout)).Get(healthPath, healthHandler)
+	if !cfg.Redirect.StrictTrailingSlash {
+		router.With(timeout.New(log, cfg.HTTPServer.RequestTimeout)).Get(healthPath+"/", healthHandler)
+	}
+
+	router.With(concurrencyLimit, rateLimit).Mount(basePath, app)
 
-	log.Info("starting server", slog.String("address", cfg.Address))
+	log.Info("starting server", slog.String("address", cfg.Address), slog.String("base_path", basePath))
+
+	// tlsConfig – минимальная версия TLS и наборы шифров. Проверяется и при выключенном HTTPS,
+	// чтобы ошибка в конфигурации обнаружилась сразу, а не при включении сертификата.
+	tlsConfig, err := tlsconfig.New(cfg.TLS.MinVersion, cfg.TLS.CipherSuites)
+	if err != nil {
+		log.Error("invalid tls config", sl.Err(err))
+		os.Exit(1)
+	}
+	useTLS := cfg.TLS.CertFile != "" && cfg.TLS.KeyFile != ""
+
+	// Нулевое значение MaxHeaderBytes означает в net/http значение по умолчанию (1 МБ), а не "без заголовков",
+	// поэтому неположительное значение в конфигурации - скорее ошибка, и сервер не запускается.
+	if cfg.HTTPServer.MaxHeaderBytes <= 0 {
+		log.Error("invalid max_header_bytes: must be positive", slog.Int("max_header_bytes", cfg.HTTPServer.MaxHeaderBytes))
+		os.Exit(1)
+	}
 
 	srv := &http.Server{
-		Addr:         cfg.Address,
-		Handler:      router,
-		ReadTimeout:  cfg.HTTPServer.Timeout,
-		WriteTimeout: cfg.HTTPServer.Timeout,
-		IdleTimeout:  cfg.HTTPServer.IdleTimeout,
+		Addr:           cfg.Address,
+		Handler:        router,
+		ReadTimeout:    cfg.HTTPServer.Timeout,
+		WriteTimeout:   cfg.HTTPServer.Timeout,
+		IdleTimeout:    cfg.HTTPServer.IdleTimeout,
+		MaxHeaderBytes: cfg.HTTPServer.MaxHeaderBytes,
+		TLSConfig:      tlsConfig,
+	}
+
+	// httpListener – TCP-адрес или Unix-сокет ("unix:/path/to/sock") HTTP-сервера. Слушать начинаем до запуска
+	// сервера, чтобы занятый адрес или сокет остановил приложение сразу.
+	httpListener, err := listen.New(cfg.Address)
+	if err != nil {
+		log.Error("failed to listen http address", slog.String("address", cfg.Address), sl.Err(err))
+		os.Exit(1)
+	}
+
+	// grpcServer – gRPC API, работающий с тем же хранилищем. nil, если адрес gRPC не задан.
+	var grpcServer *grpc.Server
+	var grpcListener net.Listener
+	if cfg.GRPC.Address != "" {
+		grpcListener, err = net.Listen("tcp", cfg.GRPC.Address)
+		if err != nil {
+			log.Error("failed to listen grpc address", slog.String("address", cfg.GRPC.Address), sl.Err(err))
+			os.Exit(1)
+		}
+
+		grpcAuth, err := newGRPCAuth(log, cfg.Auth, storage)
+		if err != nil {
+			log.Error("failed to init grpc auth", sl.Err(err))
+			os.Exit(1)
+		}
+
+		interceptors := []grpc.UnaryServerInterceptor{grpcAuth}
+		if cfg.ReadOnly {
+			interceptors = append(interceptors, urlshortener.ReadOnly())
+		}
+
+		serverOpts := []grpc.ServerOption{grpc.ChainUnaryInterceptor(interceptors...)}
+
+		// gRPC использует тот же сертификат и те же настройки TLS, что и HTTPS. Без TLS логин и пароль, токены
+		// и ключи API передаются открытым текстом, поэтому вне локальной среды это нужно разрешить явно.
+		switch {
+		case useTLS:
+			grpcTLS, err := grpcTLSConfig(tlsConfig, cfg.TLS)
+			if err != nil {
+				log.Error("failed to load grpc tls certificate", sl.Err(err))
+				os.Exit(1)
+			}
+			serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(grpcTLS)))
+		case cfg.Env != envLocal && !cfg.GRPC.AllowPlaintext:
+			log.Error("grpc server requires tls outside env local: set tls.cert_file and tls.key_file or grpc.allow_plaintext")
+			os.Exit(1)
+		default:
+			log.Warn("grpc server runs without tls, credentials are sent in plaintext", slog.String("address", cfg.GRPC.Address))
+		}
+
+		grpcServer = grpc.NewServer(serverOpts...)
+		urlshortener.Register(grpcServer, log, storage, urlshortener.Options{
+			MaxURLLength:     cfg.MaxURLLength,
+			AliasGenerator:   aliasGenerator,
+			AliasLength:      aliasLength,
+			Reserved:         reservedAliases,
+			Notifier:         webhooks,
+			Sequential:       storage,
+			AliasEncoder:     aliasEncoder,
+			MaxLinksPerIP:    cfg.MaxLinksPerIP,
+			LinkCounter:      storage,
+			MaxTotalLinks:    cfg.MaxTotalLinks,
+			TotalLinkCounter: storage,
+			Duplicates:       storage,
+		})
+	}
+
+	// ctx отменяется при получении SIGINT или SIGTERM – это сигнал к корректной остановке сервера.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// serverErr получает ошибку любого из серверов, после которой приложение останавливается.
+	serverErr := make(chan error, 2)
+	go func() {
+		var err error
+		if useTLS {
+			log.Info("https enabled", slog.String("min_version", cfg.TLS.MinVersion))
+			err = srv.ServeTLS(httpListener, cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		} else {
+			err = srv.Serve(httpListener)
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- fmt.Errorf("http server: %w", err)
+		}
+	}()
+
+	if grpcServer != nil {
+		log.Info("starting grpc server", slog.String("address", cfg.GRPC.Address), slog.Bool("tls", useTLS))
+
+		go func() {
+			if err := grpcServer.Serve(grpcListener); err != nil {
+				serverErr <- fmt.Errorf("grpc server: %w", err)
+			}
+		}()
+	}
+
+	select {
+	case err := <-serverErr:
+		log.Error("server failed", sl.Err(err))
+	case <-ctx.Done():
+	}
+
+	log.Info("stopping server",
+		slog.Int64("in_flight", inFlight.Count()),
+		slog.String("shutdown_timeout", cfg.HTTPServer.ShutdownTimeout.String()),
+	)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.HTTPServer.ShutdownTimeout)
+	defer cancel()
+
+	// gRPC-сервер останавливается параллельно с HTTP-сервером и укладывается в тот же дедлайн.
+	grpcStopped := make(chan error, 1)
+	go func() {
+		grpcStopped <- stopGRPC(shutdownCtx, grpcServer)
+	}()
+
+	// srv.Shutdown перестаёт принимать новые соединения и ждёт завершения текущих запросов до дедлайна.
+	// Закрытие слушателя Unix-сокета удаляет файл сокета.
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Error("server shutdown timed out, in-flight requests were cut",
+			slog.Int64("in_flight", inFlight.Count()),
+			sl.Err(err),
+		)
+	} else {
+		log.Info("server shutdown completed, in-flight requests drained",
+			slog.Int64("in_flight", inFlight.Count()),
+		)
 	}
 
-	// TODO: run server
-	if err := srv.ListenAndServe(); err != nil {
-		log.Error("failed to start server")
+	if err := <-grpcStopped; err != nil {
+		log.Error("grpc server shutdown timed out, in-flight calls were cut", sl.Err(err))
 	}
 
-	log.Error("server stopped")
+	// Записываем накопленные времена обращений перед выходом.
+	accessRecorder.Close()
 
+	// Отправляем события из очереди вебхуков, пока не истёк дедлайн остановки.
+	webhooks.Close(shutdownCtx)
+
+	// Хранилище закрывается последним: выше в него ещё записываются времена обращений.
+	if err := storage.Close(); err != nil {
+		log.Error("failed to close storage", sl.Err(err))
+	}
+
+	log.Info("server stopped")
+
+	if err := closeLog(); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to close log output: %v\n", err)
+	}
+
+}
+
+// openStorage создаёт хранилище по настройкам конфигурации. Сервер и подкоманды обслуживания
+// открывают хранилище одинаково, чтобы работать с одной и той же базой.
+func openStorage(log *slog.Logger, cfg *config.Config) (storage.URLStorage, error) {
+	return factory.New(log, factory.Config{
+		Type:                 cfg.StorageType,
+		Path:                 cfg.StoragePath,
+		SlowQueryThreshold:   cfg.SlowQueryThreshold,
+		SQLiteParams:         cfg.SQLiteParams,
+		UniqueURL:            cfg.DedupeURLs,
+		UniqueCanonicalAlias: cfg.RejectConfusableAliases,
+		VerifySchema:         !cfg.SkipSchemaVerify,
+		RecoverCorrupt:       cfg.RecoverCorrupt,
+	})
 }
 
-// setupLogger принимает строковый параметр env (среду выполнения)
+// openLogOutput возвращает, куда писать лог: os.Stdout для "stdout" (и пустого значения), os.Stderr для "stderr",
+// иначе – файл по указанному пути с ротацией по настройкам rotation. Функция close закрывает файл при остановке.
+func openLogOutput(output string, rotation config.LogRotation) (out io.Writer, close func() error, err error) {
+	switch output {
+	case "", "stdout":
+		return os.Stdout, func() error { return nil }, nil
+	case "stderr":
+		return os.Stderr, func() error { return nil }, nil
+	}
+
+	w, err := logfile.New(output, logfile.Options{
+		MaxSize:    int64(rotation.MaxSizeMB) << 20,
+		MaxAge:     rotation.MaxAge,
+		MaxBackups: rotation.MaxBackups,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return w, w.Close, nil
+}
+
+// setupLogger принимает строковый параметр env (среду выполнения) и out – куда писать лог,
 // и возвращает указатель на объект slog.Logger.
-func setupLogger(env string) *slog.Logger {
+func setupLogger(env string, out io.Writer) *slog.Logger {
 	// Объявляем переменную log, которая будет хранить указатель на объект slog.Logger.
 	var log *slog.Logger
 
@@ -145,13 +928,13 @@ func setupLogger(env string) *slog.Logger {
 	switch env {
 	case envLocal:
 		// Если среда - локальная (local), используем кастомный логгер.
-		log = setupPrettySlog() // setupPrettySlog() — самописная функция для красивого вывода логов в локальной среде.
+		log = setupPrettySlog(out) // setupPrettySlog() — самописная функция для красивого вывода логов в локальной среде.
 
 	case envDev:
 		// Если среда - dev (разработка), создаём JSON-логгер.
 		log = slog.New( // slog.New создаёт новый объект логгера.
 			slog.NewJSONHandler( // slog.NewJSONHandler создаёт обработчик логов, который выводит данные в JSON-формате.
-				os.Stdout, // os.Stdout — стандартный вывод (терминал или лог-файл, если перенаправить вывод).
+				out, // out — стандартный вывод, stderr или файл лога (log_output).
 				&slog.HandlerOptions{Level: slog.LevelDebug}, // Указываем уровень логирования - Debug.
 			),
 		)
@@ -160,7 +943,7 @@ func setupLogger(env string) *slog.Logger {
 		// Если среда - продакшен (prod), создаём JSON-логгер с уровнем Info (меньше подробностей).
 		log = slog.New(
 			slog.NewJSONHandler(
-				os.Stdout,
+				out,
 				&slog.HandlerOptions{Level: slog.LevelInfo}, // В продакшене уровень логирования - Info (без debug).
 			),
 		)
@@ -172,7 +955,7 @@ func setupLogger(env string) *slog.Logger {
 
 // setupPrettySlog создаёт и настраивает логгер с красивым форматированием для локальной среды.
 // Возвращает указатель на объект slog.Logger.
-func setupPrettySlog() *slog.Logger {
+func setupPrettySlog(out io.Writer) *slog.Logger {
 	// Создаём объект настроек PrettyHandlerOptions из пакета slogpretty.
 	// PrettyHandlerOptions отвечает за стилизацию логов (например, добавление цветов, форматирование строк).
 	opts := slogpretty.PrettyHandlerOptions{
@@ -182,10 +965,171 @@ func setupPrettySlog() *slog.Logger {
 	}
 
 	// Создаём обработчик логов с красивым форматированием.
-	// opts.NewPrettyHandler(os.Stdout) создаёт новый обработчик, который пишет логи в стандартный вывод (терминал).
-	handler := opts.NewPrettyHandler(os.Stdout)
+	// opts.NewPrettyHandler(out) создаёт новый обработчик, который пишет логи в out (обычно стандартный вывод).
+	handler := opts.NewPrettyHandler(out)
 
 	// Создаём новый логгер, передавая в него обработчик handler.
 	// slog.New(handler) возвращает объект slog.Logger, который будет использовать этот обработчик.
 	return slog.New(handler)
 }
+
+// newAliasGenerator возвращает генератор псевдонимов для стиля из конфигурации и число различимых символов
+// в его псевдонимах. Для стиля random алфавит проверяется на пригодность при длине length. Для стиля sequential
+// возвращается случайный генератор: псевдонимы из ID назначает хранилище, а генератор нужен для ротации.
+func newAliasGenerator(style, alphabet string, length int) (random.AliasGenerator, int, error) {
+	switch style {
+	case "", "random", "sequential":
+		g, err := random.NewGeneratorWithAlphabet(alphabet)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid alias_alphabet: %w", err)
+		}
+
+		// Проверяем, что при заданной длине алфавит даёт достаточно псевдонимов.
+		if err := aliaslen.Check(g.Symbols(), length); err != nil {
+			return nil, 0, fmt.Errorf("alias_alphabet is too small for alias_length: %w", err)
+		}
+
+		return g, g.Symbols(), nil
+	case "words":
+		// Длина не влияет на псевдонимы из слов, поэтому алфавит и его размер не важны.
+		return words.NewGenerator(), random.NewGenerator().Symbols(), nil
+	default:
+		return nil, 0, fmt.Errorf("unknown alias_style %q: expected \"random\", \"words\" or \"sequential\"", style)
+	}
+}
+
+// warnTimeoutExceedsWrite предупреждает о таймаутах групп маршрутов, которые длиннее WriteTimeout сервера.
+// Сервер обрывает запись ответа по WriteTimeout, поэтому такой запрос не получит ни ответа, ни 504.
+func warnTimeoutExceedsWrite(log *slog.Logger, cfg config.HTTPServer) {
+	timeouts := []struct {
+		name string
+		d    time.Duration
+	}{
+		{"request_timeout", cfg.RequestTimeout},
+		{"redirect_timeout", cfg.RedirectTimeout},
+		{"admin_timeout", cfg.AdminTimeout},
+	}
+
+	for _, t := range timeouts {
+		if cfg.Timeout > 0 && (t.d <= 0 || t.d >= cfg.Timeout) {
+			log.Warn("route timeout is not shorter than server write timeout, slow requests are cut off without a response",
+				slog.String("setting", t.name),
+				slog.String("value", t.d.String()),
+				slog.String("timeout", cfg.Timeout.String()),
+			)
+		}
+	}
+}
+
+// authModeAPIKey - режим auth.mode с API-ключами из хранилища.
+const authModeAPIKey = "api_key"
+
+// newAuthMiddleware возвращает middleware аутентификации для режима auth.mode.
+// keys используется для проверки ключей в режиме api_key.
+func newAuthMiddleware(log *slog.Logger, cfg config.Auth, keys apikeyauth.KeyGetter) (func(next http.Handler) http.Handler, error) {
+	switch cfg.Mode {
+	case "", "basic":
+		// chi пропускает заголовок с пустыми логином и паролем, если они совпадают с настроенными,
+		// поэтому пустые значения открыли бы административные маршруты всем.
+		if cfg.User == "" || cfg.Password == "" {
+			return nil, errors.New("auth mode basic requires user and password")
+		}
+		return middleware.BasicAuth("url-shortener", map[string]string{
+			cfg.User: cfg.Password,
+		}), nil
+	case "jwt":
+		return jwtauth.New(log, jwtauth.Config{
+			Algorithm:     cfg.JWT.Algorithm,
+			Secret:        cfg.JWT.Secret,
+			PublicKeyPath: cfg.JWT.PublicKeyPath,
+			Scope:         cfg.JWT.Scope,
+		})
+	case authModeAPIKey:
+		// Логин и пароль закрывают выдачу ключей: пустые значения открыли бы её всем.
+		if cfg.User == "" || cfg.Password == "" {
+			return nil, errors.New("auth mode api_key requires user and password for /admin/keys")
+		}
+		return apikeyauth.New(log, keys), nil
+	default:
+		return nil, fmt.Errorf("unknown auth mode %q: expected \"basic\", \"jwt\" or \"api_key\"", cfg.Mode)
+	}
+}
+
+// newGRPCAuth возвращает interceptor аутентификации gRPC для режима auth.mode. Ключи и токены проверяются
+// так же, как в newAuthMiddleware, чтобы gRPC не принимал то, что отклоняет HTTP.
+func newGRPCAuth(log *slog.Logger, cfg config.Auth, keys apikeyauth.KeyGetter) (grpc.UnaryServerInterceptor, error) {
+	switch cfg.Mode {
+	case "", "basic":
+		// Пустые логин и пароль пропустили бы заголовок "Basic Og==".
+		if cfg.User == "" || cfg.Password == "" {
+			return nil, errors.New("auth mode basic requires user and password")
+		}
+		return urlshortener.BasicAuth(cfg.User, cfg.Password), nil
+	case "jwt":
+		v, err := jwtauth.NewValidator(jwtauth.Config{
+			Algorithm:     cfg.JWT.Algorithm,
+			Secret:        cfg.JWT.Secret,
+			PublicKeyPath: cfg.JWT.PublicKeyPath,
+			Scope:         cfg.JWT.Scope,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return urlshortener.JWTAuth(v), nil
+	case authModeAPIKey:
+		return urlshortener.APIKeyAuth(log, keys), nil
+	default:
+		return nil, fmt.Errorf("unknown auth mode %q: expected \"basic\", \"jwt\" or \"api_key\"", cfg.Mode)
+	}
+}
+
+// grpcTLSConfig возвращает настройки TLS для gRPC: копию настроек HTTPS с сертификатом из cfg.
+// http.Server загружает сертификат сам в ServeTLS, а gRPC-серверу его нужно передать в настройках.
+func grpcTLSConfig(base *tls.Config, cfg config.TLS) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load key pair: %w", err)
+	}
+
+	c := base.Clone()
+	c.Certificates = []tls.Certificate{cert}
+
+	return c, nil
+}
+
+// loadTemplate загружает HTML-шаблон из файла. Для пустого пути возвращает nil,
+// чтобы обработчик использовал свой встроенный шаблон.
+func loadTemplate(path string) (*template.Template, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	tmpl, err := template.ParseFiles(path)
+	if err != nil {
+		return nil, fmt.Errorf("parse template %s: %w", path, err)
+	}
+
+	return tmpl, nil
+}
+
+// stopGRPC ждёт завершения текущих вызовов gRPC до дедлайна ctx, после чего закрывает соединения принудительно.
+// Для nil-сервера ничего не делает.
+func stopGRPC(ctx context.Context, gs *grpc.Server) error {
+	if gs == nil {
+		return nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		gs.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		gs.Stop()
+		return ctx.Err()
+	}
+}
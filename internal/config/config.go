@@ -2,9 +2,16 @@ package config
 
 // Подключаем стандартные библиотеки и сторонние пакеты
 import (
-	"log"  // Стандартная библиотека для логирования. Предназначена для вывода сообщений в консоль или в файл.
-	"os"   // Стандартная библиотека для работы с операционной системой, например, для работы с файловой системой, переменными окружения и т.д.
-	"time" // Стандартная библиотека для работы с временем: функции для работы с временем, длительностью и датой.
+	"bytes"    // Стандартная библиотека для работы с байтовыми срезами (чтение встроенной конфигурации).
+	_ "embed"  // Встраивание конфигурации по умолчанию в бинарный файл.
+	"errors"   // Стандартная библиотека для работы с ошибками.
+	"fmt"      // Стандартная библиотека форматирования (сообщения об ошибках загрузки).
+	"io/fs"    // Стандартная библиотека файловых систем (проверка отсутствия файла .env).
+	"log"      // Стандартная библиотека для логирования. Предназначена для вывода сообщений в консоль или в файл.
+	"log/slog" // Стандартная библиотека структурированного логирования (сводка конфигурации при старте).
+	"net/url"  // Стандартная библиотека для разбора URL (скрытие адресов вебхуков в сводке конфигурации).
+	"os"       // Стандартная библиотека для работы с операционной системой, например, для работы с файловой системой, переменными окружения и т.д.
+	"time"     // Стандартная библиотека для работы с временем: функции для работы с временем, длительностью и датой.
 
 	// Сторонние библиотеки
 	"github.com/ilyakaznacheev/cleanenv" // cleanenv — библиотека для простого и удобного парсинга конфигурационных файлов и переменных окружения.
@@ -24,22 +31,396 @@ type Config struct {
 	// Это поле обязано быть задано в переменных окружения, и его значение не может быть пустым.
 	StoragePath string `yaml:"storage_path" env-required:"true"`
 
+	// SlowQueryThreshold - операции хранилища дольше этого порога записываются в лог как медленные запросы
+	// (с именем операции, длительностью и сводкой параметров). Значение 0 отключает журнал.
+	SlowQueryThreshold time.Duration `yaml:"slow_query_threshold" env:"SLOW_QUERY_THRESHOLD" env-default:"100ms"`
+
+	// StorageType - бэкенд хранилища. Сейчас поддерживается только "sqlite".
+	StorageType string `yaml:"storage_type" env:"STORAGE_TYPE" env-default:"sqlite"`
+
+	// SQLiteParams - прагмы SQLite, добавляемые в строку подключения (например, foreign_keys: "on").
+	// Переопределяют значения по умолчанию journal_mode=WAL и busy_timeout=5000; неизвестные ключи - ошибка при старте.
+	// В переменной окружения задаются как SQLITE_PARAMS="foreign_keys:on,synchronous:NORMAL".
+	SQLiteParams map[string]string `yaml:"sqlite_params" env:"SQLITE_PARAMS" env-separator:","`
+
+	// RecoverCorrupt - если файл базы повреждён или не является базой SQLite, переименовать его
+	// в "<storage_path>.corrupt-<время>" и начать с пустой базы вместо остановки с ошибкой.
+	// Все ссылки при этом пропадают из работы, поэтому по умолчанию выключено.
+	RecoverCorrupt bool `yaml:"recover_corrupt" env:"RECOVER_CORRUPT" env-default:"false"`
+
+	// SkipSchemaVerify - не проверять при запуске обязательные индексы. По умолчанию проверка включена:
+	// недостающие индексы создаются с предупреждением в лог, что чинит базы, потерявшие индекс, без ручной миграции.
+	SkipSchemaVerify bool `yaml:"skip_schema_verify" env:"SKIP_SCHEMA_VERIFY" env-default:"false"`
+
 	// HTTPServer - структура, содержащая конфигурацию для HTTP-сервера.
 	// В конфигурационном файле (YAML) и переменных окружения будет указано под полем "http_server".
 	// Эта структура содержит настройки для работы с сервером (например, адрес, таймауты и т.д.).
 	HTTPServer `yaml:"http_server"`
 	Auth        `yaml:"auth"`
+
+	// ReadOnly - режим только для чтения на время обслуживания: создание, удаление и другие изменяющие
+	// операции отвечают 503 (в gRPC - Unavailable), а редиректы и чтение продолжают работать.
+	ReadOnly bool `yaml:"read_only" env:"READ_ONLY" env-default:"false"`
+
+	// BasePath - префикс пути, под которым монтируются все маршруты сервиса (например, "/s").
+	// По умолчанию "/" - маршруты доступны от корня. Префикс также входит в короткие ссылки.
+	BasePath string `yaml:"base_path" env:"BASE_PATH" env-default:"/"`
+
+	// MaxURLLength - максимальная длина сохраняемого URL в байтах. Более длинные URL отклоняются с 422.
+	// Значение 0 означает жёсткий предел, зашитый в обработчик сохранения.
+	MaxURLLength int `yaml:"max_url_length" env:"MAX_URL_LENGTH" env-default:"2048"`
+
+	// AliasStyle - способ генерации псевдонимов, когда клиент не задал свой:
+	// "random" - случайная строка base62, "words" - читаемое сочетание слов вида brave-otter-12,
+	// "sequential" - ID ссылки в base36: самые короткие псевдонимы без коллизий, но их можно перебрать,
+	// зная соседние ссылки. При ротации такие ссылки получают случайный псевдоним.
+	AliasStyle string `yaml:"alias_style" env:"ALIAS_STYLE" env-default:"random"`
+
+	// AliasAlphabet - символы, из которых составляются случайные псевдонимы (для стиля random).
+	// Допустимы только символы, не требующие экранирования в URL: буквы, цифры и "-_~". Точка не допускается:
+	// middleware.URLFormat отрезает её вместе с концом пути, и ссылка не открывалась бы. Псевдонимы нечувствительны
+	// к регистру, поэтому буквы разного регистра считаются одним символом. При старте проверяется, что алфавит
+	// даёт достаточно псевдонимов длины alias_length. По умолчанию base62.
+	AliasAlphabet string `yaml:"alias_alphabet" env:"ALIAS_ALPHABET" env-default:"ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"`
+
+	// AliasSequenceOffset и AliasSequenceMultiplier - для стиля sequential псевдоним строится из
+	// id*multiplier + offset, чтобы порядок ссылок был не так очевиден. Это запутывание, а не защита.
+	// Удалённая последняя ссылка может освободить свой ID, и её псевдоним получит следующая ссылка.
+	AliasSequenceOffset     int64 `yaml:"alias_sequence_offset" env:"ALIAS_SEQUENCE_OFFSET" env-default:"0"`
+	AliasSequenceMultiplier int64 `yaml:"alias_sequence_multiplier" env:"ALIAS_SEQUENCE_MULTIPLIER" env-default:"1"`
+
+	// AliasPrefix и AliasSuffix - постоянные префикс и суффикс генерируемых псевдонимов (например, "go-"),
+	// чтобы выделить ссылки сервиса среди других путей. В базу сохраняется полный псевдоним, по нему же
+	// работает редирект. К псевдонимам, заданным пользователем, не добавляются.
+	// Допустимы буквы, цифры и "-_~". Точка запрещена: middleware.URLFormat отрезает её вместе с концом пути.
+	AliasPrefix string `yaml:"alias_prefix" env:"ALIAS_PREFIX"`
+	AliasSuffix string `yaml:"alias_suffix" env:"ALIAS_SUFFIX"`
+
+	// ReservedAliases - дополнительные псевдонимы, которые нельзя использовать для ссылок.
+	// Пути собственных маршрутов сервиса (url, health, version, openapi, docs, metrics, stats) и статические маршруты
+	// под /url/ (batch, stale, by-target, search, resolve, reservations, ns) зарезервированы всегда.
+	ReservedAliases []string `yaml:"reserved_aliases" env:"RESERVED_ALIASES" env-separator:","`
+
+	// AliasLength - начальная длина генерируемых псевдонимов (для стиля random), без alias_prefix и alias_suffix.
+	// При старте длина увеличивается, если ссылок уже слишком много для неё, а во время работы - при частых коллизиях.
+	AliasLength int `yaml:"alias_length" env:"ALIAS_LENGTH" env-default:"6"`
+
+	// AliasMaxLength - предел, до которого может вырасти длина генерируемых псевдонимов,
+	// вместе с alias_prefix и alias_suffix.
+	AliasMaxLength int `yaml:"alias_max_length" env:"ALIAS_MAX_LENGTH" env-default:"12"`
+
+	// MaxLinksPerIP - сколько ссылок может создать один IP-адрес клиента. 0 - без ограничения.
+	// IP определяется с учётом доверенных прокси (trusted_proxies). В gRPC - по адресу соединения, заголовки прокси
+	// не учитываются.
+	MaxLinksPerIP int `yaml:"max_links_per_ip" env:"MAX_LINKS_PER_IP" env-default:"0"`
+
+	// MaxTotalLinks - сколько всего ссылок может храниться в базе. Когда лимит достигнут, создание новых ссылок
+	// отвечает 507 (в gRPC - ResourceExhausted), чтобы база не заняла весь диск. 0 - без ограничения.
+	MaxTotalLinks int64 `yaml:"max_total_links" env:"MAX_TOTAL_LINKS" env-default:"0"`
+
+	// DedupeURLs - режим дедупликации: один адрес сокращается только один раз, повторное сохранение возвращает
+	// уже существующую ссылку, если она работает и создана с теми же параметрами (см. storage.URLRecord.Reusable),
+	// иначе отвечает 409 с её псевдонимом. Гарантируется уникальным индексом по url в базе, поэтому работает и при одновременных
+	// запросах. Индекс создаётся при запуске и удаляется, если режим выключен; уже имеющиеся дубликаты не дадут его создать.
+	DedupeURLs bool `yaml:"dedupe_urls" env:"DEDUPE_URLS" env-default:"false"`
+
+	// IdempotencyTTL - сколько помнится ключ Idempotency-Key запроса POST /url: повтор запроса с тем же ключом
+	// в течение этого времени возвращает уже созданную ссылку, а не создаёт новую.
+	IdempotencyTTL time.Duration `yaml:"idempotency_ttl" env:"IDEMPOTENCY_TTL" env-default:"24h"`
+
+	// RejectConfusableAliases - защита от фишинга похожими псевдонимами: псевдоним, который отличается от занятого
+	// только похожими на вид символами (rn и m, 0 и o, 1 и l), считается занятым, а случайные псевдонимы
+	// генерируются без таких символов вовсе. Проверяется уникальным индексом по каноническому виду псевдонима;
+	// если в базе уже есть похожие псевдонимы, индекс не создастся и сервис не запустится.
+	RejectConfusableAliases bool `yaml:"reject_confusable_aliases" env:"REJECT_CONFUSABLE_ALIASES" env-default:"false"`
+
+	// StatsCacheTTL - сколько отдаётся из кэша сводная статистика GET /stats/summary. Она считается по всей таблице
+	// и не обязана быть точной в реальном времени. 0 - без кэша.
+	StatsCacheTTL time.Duration `yaml:"stats_cache_ttl" env:"STATS_CACHE_TTL" env-default:"1m"`
+
+	// RootRedirect - адрес, на который перенаправляется (302) запрос к корню сервиса GET /, например главная страница.
+	// Пустое значение - корень отвечает 404.
+	RootRedirect string `yaml:"root_redirect" env:"ROOT_REDIRECT"`
+
+	// FormResultURL - адрес страницы результатов, на которую браузер перенаправляется (303) после отправки
+	// HTML-формы создания ссылки. В запрос добавляются alias и short_url. Пусто - форма получает JSON-ответ.
+	FormResultURL string `yaml:"form_result_url" env:"FORM_RESULT_URL"`
+
+	// AcceptedContentTypes - допустимые Content-Type для запросов с телом к изменяющим маршрутам /url.
+	// Запрос с другим типом получает 415 до обработчика. Пустой список отключает проверку.
+	AcceptedContentTypes []string `yaml:"accepted_content_types" env:"ACCEPTED_CONTENT_TYPES" env-separator:"," env-default:"application/json,application/x-www-form-urlencoded"`
+
+	// TrustedProxies - сети (CIDR или отдельные IP) доверенных обратных прокси. Заголовки X-Forwarded-For и X-Real-IP
+	// учитываются при определении IP клиента только для запросов от этих адресов, иначе берётся адрес соединения.
+	// Запросы, пришедшие через Unix-сокет (address: "unix:..."), доверенные всегда.
+	// От них же учитывается X-Forwarded-Proto: за прокси, завершающим TLS, short_url получает схему https.
+	TrustedProxies []string `yaml:"trusted_proxies" env:"TRUSTED_PROXIES" env-separator:","`
+
+	// LogRedirectTarget - записывать ли в лог редиректа адрес назначения. По умолчанию выключено:
+	// адреса могут содержать персональные данные, поэтому в лог попадает только псевдоним.
+	// Включается для расследования злоупотреблений и отладки.
+	LogRedirectTarget bool `yaml:"log_redirect_target" env:"LOG_REDIRECT_TARGET" env-default:"false"`
+
+	// TrustedRedirectHosts - доверенные хосты назначения ссылок; каждый хост доверяет и своим поддоменам.
+	// Браузер, переходящий по ссылке на другой хост, сначала видит страницу "вы переходите на <хост>"
+	// со ссылкой для продолжения. Пустой список - все адреса доверенные, страница не показывается.
+	TrustedRedirectHosts []string `yaml:"trusted_redirect_hosts" env:"TRUSTED_REDIRECT_HOSTS" env-separator:","`
+
+	// LogOutput - куда писать лог: "stdout", "stderr" или путь к файлу. Файл ротируется по настройкам LogRotation.
+	LogOutput string `yaml:"log_output" env:"LOG_OUTPUT" env-default:"stdout"`
+
+	// LogRotation - ротация файла лога, когда log_output - путь к файлу.
+	LogRotation LogRotation `yaml:"log_rotation"`
+
+	// LogSampleRate - выборочное логирование запросов при большом трафике: в лог попадает один из N успешных
+	// запросов. Ошибки (статус >= 400) и запросы дольше log_slow_threshold логируются всегда. 1 - логируются все.
+	LogSampleRate int `yaml:"log_sample_rate" env:"LOG_SAMPLE_RATE" env-default:"1"`
+
+	// LogSlowThreshold - запросы, обработка которых заняла не меньше этого времени, не отбрасываются выборкой.
+	// 0 - медленные запросы не выделяются.
+	LogSlowThreshold time.Duration `yaml:"log_slow_threshold" env:"LOG_SLOW_THRESHOLD" env-default:"1s"`
+
+	// LogBodies - ТОЛЬКО ДЛЯ ОТЛАДКИ: писать в лог (уровень debug) тела запросов и ответов изменяющих маршрутов /url
+	// для разбора интеграции клиента. Тела обрезаются до log_body_max_bytes, значения полей с секретами
+	// (пароли, токены, ключи) заменяются на [REDACTED], но адреса ссылок остаются. В продакшене не включать.
+	LogBodies bool `yaml:"log_bodies" env:"LOG_BODIES" env-default:"false"`
+
+	// LogBodyMaxBytes - сколько байт тела запроса и тела ответа попадает в лог при log_bodies.
+	LogBodyMaxBytes int `yaml:"log_body_max_bytes" env:"LOG_BODY_MAX_BYTES" env-default:"2048"`
+
+	// LogSelfTest - писать при старте пробные сообщения уровней debug и error, чтобы проверить, что лог доходит
+	// до получателя. По умолчанию выключено: пробная ошибка вызывает ложные срабатывания мониторинга ошибок.
+	LogSelfTest bool `yaml:"log_self_test" env:"LOG_SELF_TEST" env-default:"false"`
+
+	// NotFoundTemplate - путь к HTML-шаблону страниц 404 и 405, которые видят браузеры
+	// (несуществующие, удалённые и опечатанные ссылки). API-клиенты получают JSON. Пусто - встроенная страница.
+	NotFoundTemplate string `yaml:"not_found_template" env:"NOT_FOUND_TEMPLATE"`
+
+	// Redirect - настройки редиректа по коротким ссылкам.
+	Redirect Redirect `yaml:"redirect"`
+
+	// RateLimit - ограничение частоты запросов с одного IP-адреса клиента.
+	RateLimit RateLimit `yaml:"rate_limit"`
+
+	// Preview - настройки предпросмотра ссылок (GET /url/{alias}/preview).
+	Preview Preview `yaml:"preview"`
+
+	// GRPC - настройки gRPC API.
+	GRPC GRPC `yaml:"grpc"`
+
+	// TLS - настройки HTTPS для HTTP-сервера.
+	TLS TLS `yaml:"tls"`
+
+	// Webhooks - исходящие уведомления о событиях ссылок.
+	Webhooks Webhooks `yaml:"webhooks"`
+}
+
+// LogRotation - настройки ротации файла лога.
+type LogRotation struct {
+	// MaxSizeMB - размер файла в мегабайтах, при котором он переименовывается в <имя>-<время><расширение>
+	// и лог продолжается в новом файле. 0 - без ротации.
+	MaxSizeMB int `yaml:"max_size_mb" env:"LOG_MAX_SIZE_MB" env-default:"100"`
+
+	// MaxAge - старые копии удаляются при ротации. 0 - без ограничения по возрасту.
+	MaxAge time.Duration `yaml:"max_age" env:"LOG_MAX_AGE" env-default:"0"`
+
+	// MaxBackups - сколько последних копий хранить. 0 - все.
+	MaxBackups int `yaml:"max_backups" env:"LOG_MAX_BACKUPS" env-default:"0"`
+}
+
+// Redirect - структура для хранения настроек редиректа.
+type Redirect struct {
+	// Permanent - использовать ли постоянный редирект (301) для ссылок, у которых этот признак не задан явно.
+	// По умолчанию false (302), так как браузеры агрессивно кэшируют 301.
+	Permanent bool `yaml:"permanent" env-default:"false"`
+
+	// PreserveMethod - редирект с сохранением метода и тела запроса для ссылок, у которых этот признак не задан явно:
+	// 307 вместо 302 и 308 вместо 301. Нужен ссылкам на API, куда клиенты отправляют POST; браузерам достаточно 301/302.
+	// Такие ссылки перенаправляют и POST, PUT, PATCH, DELETE, остальные отвечают на эти методы 405.
+	PreserveMethod bool `yaml:"preserve_method" env:"REDIRECT_PRESERVE_METHOD" env-default:"false"`
+
+	// CacheTTL - max-age для заголовка Cache-Control у постоянных редиректов.
+	// Временные редиректы всегда отдаются с no-cache.
+	CacheTTL time.Duration `yaml:"cache_ttl" env:"REDIRECT_CACHE_TTL" env-default:"24h"`
+
+	// ExpiredTemplate - путь к HTML-шаблону страницы "ссылка истекла", которую видят браузеры.
+	// Пустое значение - встроенная страница.
+	ExpiredTemplate string `yaml:"expired_template" env:"REDIRECT_EXPIRED_TEMPLATE"`
+
+	// AccessFlushInterval - как часто накопленные времена последнего обращения к ссылкам и переходы по ссылкам
+	// без max_clicks записываются в хранилище. Запись идёт в фоне пачками, чтобы не замедлять редирект,
+	// поэтому счётчик clicks таких ссылок отстаёт не больше чем на этот интервал.
+	AccessFlushInterval time.Duration `yaml:"access_flush_interval" env:"REDIRECT_ACCESS_FLUSH_INTERVAL" env-default:"5s"`
+
+	// SlowThreshold - редиректы, обработка которых (поиск в хранилище и запись ответа) дольше порога, пишутся в лог
+	// предупреждением с псевдонимом и длительностями. Время сети и других middleware не учитывается. 0 - выключено.
+	SlowThreshold time.Duration `yaml:"slow_threshold" env:"REDIRECT_SLOW_THRESHOLD" env-default:"0"`
+
+	// StrictTrailingSlash - отвечать 404 на ссылки с завершающим слешем (/myalias/). По умолчанию выключено:
+	// такие ссылки часто появляются при копировании и обслуживаются так же, как /myalias.
+	StrictTrailingSlash bool `yaml:"strict_trailing_slash" env:"REDIRECT_STRICT_TRAILING_SLASH" env-default:"false"`
+
+	// Mode - способ редиректа: "http" - 301/302 с заголовком Location, "html" - ответ 200 со страницей,
+	// которая переходит по адресу через meta refresh и скрипт и показывает ссылку для перехода вручную.
+	// Режим html нужен клиентам за прокси, которые портят заголовок Location, и для промежуточных страниц.
+	Mode string `yaml:"mode" env:"REDIRECT_MODE" env-default:"http"`
+
+	// HTMLTemplate - путь к HTML-шаблону страницы редиректа в режиме html. Пустое значение - встроенная страница.
+	HTMLTemplate string `yaml:"html_template" env:"REDIRECT_HTML_TEMPLATE"`
+
+	// ConfirmSecret - секрет подписи токена подтверждения перехода на недоверенный хост (trusted_redirect_hosts).
+	// Пустое значение - случайный секрет при каждом запуске: после перезапуска страница подтверждения покажется снова.
+	// При нескольких экземплярах сервиса секрет должен быть общим.
+	ConfirmSecret string `yaml:"confirm_secret" env:"REDIRECT_CONFIRM_SECRET"`
+
+	// InterstitialJSON - что получают API-клиенты (без text/html в Accept) по ссылке на недоверенный хост:
+	// true - 200 с адресом назначения в JSON, false - обычный редирект.
+	InterstitialJSON bool `yaml:"interstitial_json" env:"REDIRECT_INTERSTITIAL_JSON" env-default:"false"`
+
+	// ForwardQuery - переносить ли query string запроса к короткой ссылке в адрес назначения:
+	// /promo?ref=twitter ведёт на адрес назначения с ref=twitter. При совпадении ключей побеждает значение
+	// из адреса назначения. false - query string запроса игнорируется.
+	ForwardQuery bool `yaml:"forward_query" env:"REDIRECT_FORWARD_QUERY" env-default:"false"`
+
+	// Headers - заголовки, которые добавляются ко всем редиректам (например, Referrer-Policy: no-referrer).
+	// Ссылка может переопределить их своими заголовками (поле headers в POST /url). Заголовки, которыми управляет
+	// сам сервис (Location, Cache-Control, Set-Cookie и т.п.), и некорректные имена и значения - ошибка при старте.
+	// В переменной окружения задаются как REDIRECT_HEADERS="Referrer-Policy:no-referrer,X-Robots-Tag:noindex".
+	Headers map[string]string `yaml:"headers" env:"REDIRECT_HEADERS" env-separator:","`
+}
+
+// RateLimit - структура для хранения настроек ограничения частоты запросов.
+type RateLimit struct {
+	// Requests - сколько запросов может сделать один IP-адрес клиента за период Period. 0 - без ограничения.
+	// Сверх лимита сервис отвечает 429 с заголовками X-RateLimit-* и Retry-After.
+	Requests int `yaml:"requests" env:"RATE_LIMIT_REQUESTS" env-default:"0"`
+
+	// Period - длина окна, в котором считаются запросы.
+	Period time.Duration `yaml:"period" env:"RATE_LIMIT_PERIOD" env-default:"1m"`
+}
+
+// Preview - структура для хранения настроек предпросмотра ссылок.
+type Preview struct {
+	// FetchTitle - загружать ли заголовок (<title>) целевой страницы для предпросмотра.
+	// По умолчанию выключено: сервис не обращается к чужим адресам по запросу анонимного клиента.
+	// Внутренние адреса (loopback, частные сети, link-local, в том числе после редиректов) не загружаются никогда.
+	FetchTitle bool `yaml:"fetch_title" env:"PREVIEW_FETCH_TITLE" env-default:"false"`
+
+	// TitleTimeout - сколько ждать загрузки заголовка. По истечении предпросмотр отдаётся без заголовка.
+	TitleTimeout time.Duration `yaml:"title_timeout" env:"PREVIEW_TITLE_TIMEOUT" env-default:"2s"`
+}
+
+// GRPC - структура для хранения настроек gRPC-сервера.
+type GRPC struct {
+	// Address - адрес, на котором слушает gRPC-сервер. Должен отличаться от адреса HTTP-сервера.
+	// Пустое значение отключает gRPC API. Аутентификация та же, что на маршрутах /url, в режиме из auth.mode:
+	// логин и пароль в метаданных authorization, Bearer-токен в authorization или ключ в x-api-key.
+	Address string `yaml:"address" env:"GRPC_ADDRESS"`
+
+	// AllowPlaintext - разрешить gRPC без TLS вне env: local. При заданных tls.cert_file и tls.key_file gRPC
+	// использует тот же сертификат, что и HTTPS; без них учётные данные передаются открытым текстом, поэтому
+	// по умолчанию сервер в такой конфигурации не запускается. Включайте, только если TLS завершает прокси.
+	AllowPlaintext bool `yaml:"allow_plaintext" env:"GRPC_ALLOW_PLAINTEXT"`
+}
+
+// Webhooks - структура для хранения настроек исходящих вебхуков.
+// События отправляются POST-запросом с JSON (event, namespace, alias, target, timestamp) из фоновых обработчиков,
+// поэтому медленный получатель не задерживает запросы к сервису.
+type Webhooks struct {
+	// LinkCreatedURL - адрес для событий link.created. Пусто - события не отправляются.
+	LinkCreatedURL string `yaml:"link_created_url" env:"WEBHOOKS_LINK_CREATED_URL"`
+
+	// LinkRedirectedURL - адрес для событий link.redirected. Пусто - события не отправляются.
+	LinkRedirectedURL string `yaml:"link_redirected_url" env:"WEBHOOKS_LINK_REDIRECTED_URL"`
+
+	// SendFullTarget - отправлять адрес назначения целиком. По умолчанию выключено и отправляются только
+	// схема и хост: в пути и параметрах адреса могут быть персональные данные.
+	SendFullTarget bool `yaml:"send_full_target" env:"WEBHOOKS_SEND_FULL_TARGET" env-default:"false"`
+
+	// Workers - число фоновых обработчиков, отправляющих события.
+	Workers int `yaml:"workers" env:"WEBHOOKS_WORKERS" env-default:"2"`
+
+	// QueueSize - сколько событий может ждать отправки. Сверх очереди события отбрасываются с записью в лог.
+	QueueSize int `yaml:"queue_size" env:"WEBHOOKS_QUEUE_SIZE" env-default:"1024"`
+
+	// MaxRetries - сколько раз повторять неудачную отправку, прежде чем отбросить событие.
+	// Паузы между попытками начинаются с Backoff и удваиваются.
+	MaxRetries int           `yaml:"max_retries" env:"WEBHOOKS_MAX_RETRIES" env-default:"3"`
+	Backoff    time.Duration `yaml:"backoff" env:"WEBHOOKS_BACKOFF" env-default:"1s"`
+
+	// Timeout - ограничение времени одной попытки отправки.
+	Timeout time.Duration `yaml:"timeout" env:"WEBHOOKS_TIMEOUT" env-default:"5s"`
+}
+
+// TLS - структура для хранения настроек HTTPS.
+type TLS struct {
+	// CertFile и KeyFile - пути к PEM-файлам сертификата и закрытого ключа.
+	// Если оба заданы, HTTP-сервер принимает только HTTPS; пустые значения - обычный HTTP.
+	CertFile string `yaml:"cert_file" env:"TLS_CERT_FILE"`
+	KeyFile  string `yaml:"key_file" env:"TLS_KEY_FILE"`
+
+	// MinVersion - минимальная версия протокола: "1.2" или "1.3" ("1.0" и "1.1" допустимы, но не рекомендуются).
+	// Неизвестное значение - ошибка при старте.
+	MinVersion string `yaml:"min_version" env:"TLS_MIN_VERSION" env-default:"1.2"`
+
+	// CipherSuites - разрешённые наборы шифров в именах crypto/tls (например, TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256).
+	// Действуют только для TLS 1.2 и ниже. Пустой список - безопасные наборы Go по умолчанию.
+	CipherSuites []string `yaml:"cipher_suites" env:"TLS_CIPHER_SUITES" env-separator:","`
 }
 
 type Auth struct {
+	// Mode - способ аутентификации на маршрутах /url: "basic" - логин и пароль (user, password),
+	// "jwt" - Bearer-токен, проверяемый по настройкам JWT, "api_key" - ключ в заголовке X-API-Key,
+	// проверяемый по bcrypt-хэшам в хранилище. В режиме api_key ключи выдаются и отзываются через /admin/keys
+	// с логином и паролем (user, password), поэтому они обязательны. В режиме basic они тоже обязательны:
+	// без них сервер не запустится.
+	Mode string `yaml:"mode" env:"AUTH_MODE" env-default:"basic"`
+
 	User     string `yaml:"user" env:"AUTH_USER"`
 	Password string `yaml:"password" env:"AUTH_PASSWORD"`
+
+	// JWT - настройки проверки токенов в режиме jwt.
+	JWT JWT `yaml:"jwt"`
+
+	// AllowedCIDRs - список сетей (CIDR или отдельных IP), из которых разрешён доступ к административным маршрутам /url.
+	// Пустой список означает отсутствие ограничений.
+	AllowedCIDRs []string `yaml:"allowed_cidrs" env:"AUTH_ALLOWED_CIDRS" env-separator:","`
+
+	// AllowAnonymousCreate - разрешить создание ссылок (POST /url, POST /url/ns/{namespace}) без аутентификации и allowed_cidrs.
+	// По умолчанию выключено. true открывает создание ссылок анонимным клиентам из любой сети: сервисом смогут
+	// пользоваться для рассылки спама и фишинговых ссылок, поэтому вместе с этим стоит включить rate_limit и max_links_per_ip.
+	// Удаление, чтение и остальные административные маршруты аутентифицируются всегда.
+	// Флаг сделан отключающим, а не включающим: cleanenv подставляет env-default поверх false из YAML,
+	// поэтому булев параметр со значением по умолчанию true нельзя было бы выключить в файле конфигурации.
+	AllowAnonymousCreate bool `yaml:"allow_anonymous_create" env:"AUTH_ALLOW_ANONYMOUS_CREATE" env-default:"false"`
+}
+
+// JWT - структура для хранения настроек проверки JWT.
+type JWT struct {
+	// Algorithm - алгоритм подписи токенов: "HS256" (общий секрет) или "RS256" (открытый ключ).
+	// Токены, подписанные другим алгоритмом, отклоняются.
+	Algorithm string `yaml:"algorithm" env:"AUTH_JWT_ALGORITHM" env-default:"HS256"`
+
+	// Secret - общий секрет для HS256.
+	Secret string `yaml:"secret" env:"AUTH_JWT_SECRET"`
+
+	// PublicKeyPath - путь к PEM-файлу с открытым ключом RSA для RS256.
+	PublicKeyPath string `yaml:"public_key_path" env:"AUTH_JWT_PUBLIC_KEY_PATH"`
+
+	// Scope - право, которое должно быть в claim scope токена для доступа к маршрутам /url.
+	// Токен без него получает 403, просроченный или неверно подписанный - 401.
+	Scope string `yaml:"scope" env:"AUTH_JWT_SCOPE" env-default:"links:write"`
 }
+
 // HTTPServer - структура для хранения конфигурации HTTP-сервера.
 // Включает параметры, такие как адрес, таймауты и другие настройки для работы с сервером.
 type HTTPServer struct {
 	// Address - адрес, на котором будет слушать HTTP-сервер.
 	// По умолчанию указывается "localhost:8080". Это значение будет использовано, если в конфигурации или переменных окружения не указано другое.
+	// Адрес вида "unix:/run/url-shortener.sock" - Unix-сокет для обратного прокси на той же машине: оставшийся после
+	// аварийного завершения файл сокета удаляется при запуске, а при остановке сервера файл удаляется.
+	// У соединений через сокет нет IP, поэтому прокси за ним доверяется без trusted_proxies: IP клиента берётся
+	// из X-Forwarded-For или X-Real-IP, которые прокси должен выставлять.
 	Address string `yaml:"address" env-default:"localhost:8080"`
 
 	// Timeout - общий таймаут для запросов к серверу. Указывает максимальное время ожидания для ответа.
@@ -50,45 +431,199 @@ type HTTPServer struct {
 	// IdleTimeout - время бездействия соединения. Указывает максимальное время, в течение которого соединение может оставаться неактивным.
 	// Если в конфигурации или переменных окружения не указано другое значение, используется значение 60 секунд.
 	IdleTimeout time.Duration `yaml:"idle_timeout" env-default:"60"`
+
+	// RequestTimeout - дедлайн контекста для каждого запроса. Обработчики и хранилище, учитывающие контекст,
+	// прерываются по его истечении, а клиент получает 504. Значение 0 отключает дедлайн.
+	RequestTimeout time.Duration `yaml:"request_timeout" env-default:"3s"`
+
+	// RedirectTimeout - дедлайн для публичных редиректов /{alias}. Редиректы должны быть быстрыми,
+	// поэтому по умолчанию он короче request_timeout. Значение 0 отключает дедлайн.
+	RedirectTimeout time.Duration `yaml:"redirect_timeout" env:"HTTP_SERVER_REDIRECT_TIMEOUT" env-default:"1s"`
+
+	// HealthTimeout - дедлайн проверок хранилища в GET /health?verbose=true (запрос к базе и подсчёт ссылок).
+	// По истечении проверка отвечает 503. Обычный /health хранилище не трогает.
+	HealthTimeout time.Duration `yaml:"health_timeout" env:"HTTP_SERVER_HEALTH_TIMEOUT" env-default:"1s"`
+
+	// AdminTimeout - дедлайн для административных маршрутов /url, где массовые операции могут идти дольше.
+	// Значение 0 отключает дедлайн.
+	//
+	// Все дедлайны должны быть короче timeout: по истечении timeout (WriteTimeout сервера) соединение
+	// обрывается, и клиент не получает даже 504. Если timeout меньше дедлайна, его нужно увеличить.
+	AdminTimeout time.Duration `yaml:"admin_timeout" env:"HTTP_SERVER_ADMIN_TIMEOUT" env-default:"10s"`
+
+	// MaxConcurrentRequests - сколько запросов может обрабатываться одновременно. Запросы сверх лимита
+	// сразу получают 503 с Retry-After, а не ждут в очереди; /health не ограничивается. 0 - без ограничения.
+	MaxConcurrentRequests int `yaml:"max_concurrent_requests" env:"HTTP_SERVER_MAX_CONCURRENT_REQUESTS" env-default:"0"`
+
+	// MaxHeaderBytes - предельный размер строки запроса и заголовков в байтах (http.Server.MaxHeaderBytes).
+	// Запрос с огромными cookie или заголовками получает 431 до обработчиков. Должен быть положительным.
+	MaxHeaderBytes int `yaml:"max_header_bytes" env:"HTTP_SERVER_MAX_HEADER_BYTES" env-default:"65536"`
+
+	// ShutdownTimeout - сколько ждать завершения запросов, которые уже обрабатываются, при остановке сервера.
+	// По истечении оставшиеся соединения закрываются принудительно.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"HTTP_SERVER_SHUTDOWN_TIMEOUT" env-default:"10s"`
+
+	// HidePanicRequestID - не добавлять ID запроса в ответ 500 после паники в обработчике. По умолчанию выключено:
+	// ID попадает в ответ, чтобы пользователь мог сообщить его при обращении в поддержку.
+	HidePanicRequestID bool `yaml:"hide_panic_request_id" env:"HTTP_SERVER_HIDE_PANIC_REQUEST_ID" env-default:"false"`
+
+	// PanicStack - добавлять ли стек паники в ответ 500. Действует только в среде local:
+	// в остальных средах стек пишется лишь в лог и не раскрывается клиенту.
+	PanicStack bool `yaml:"panic_stack" env:"HTTP_SERVER_PANIC_STACK" env-default:"false"`
+}
+
+// redacted - значение, которым в сводке конфигурации заменяются заданные секреты.
+const redacted = "[REDACTED]"
+
+// LogValue возвращает сводку действующей конфигурации для записи в лог при старте.
+// Секреты (пароль, секрет JWT) заменяются на [REDACTED], а у адресов вебхуков, в которых бывают токены,
+// остаются только схема и хост. Пустой секрет выводится пустым, чтобы было видно, что он не задан.
+func (c Config) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("env", c.Env),
+		slog.String("address", c.HTTPServer.Address),
+		slog.String("base_path", c.BasePath),
+		slog.String("alias_prefix", c.AliasPrefix),
+		slog.String("alias_suffix", c.AliasSuffix),
+		slog.Bool("read_only", c.ReadOnly),
+		slog.Any("accepted_content_types", c.AcceptedContentTypes),
+		slog.Group("storage",
+			slog.String("type", c.StorageType),
+			slog.String("path", c.StoragePath),
+			slog.Duration("slow_query_threshold", c.SlowQueryThreshold),
+			slog.Any("sqlite_params", c.SQLiteParams),
+			slog.Bool("recover_corrupt", c.RecoverCorrupt),
+			slog.Bool("skip_schema_verify", c.SkipSchemaVerify),
+		),
+		slog.Group("auth",
+			slog.String("mode", c.Auth.Mode),
+			slog.String("user", c.Auth.User),
+			slog.String("password", redact(c.Auth.Password)),
+			slog.String("jwt_algorithm", c.Auth.JWT.Algorithm),
+			slog.String("jwt_secret", redact(c.Auth.JWT.Secret)),
+			slog.Any("allowed_cidrs", c.Auth.AllowedCIDRs),
+			slog.Bool("allow_anonymous_create", c.Auth.AllowAnonymousCreate),
+		),
+		slog.Group("timeouts",
+			slog.Duration("server", c.HTTPServer.Timeout),
+			slog.Duration("idle", c.HTTPServer.IdleTimeout),
+			slog.Duration("request", c.HTTPServer.RequestTimeout),
+			slog.Duration("redirect", c.HTTPServer.RedirectTimeout),
+			slog.Duration("admin", c.HTTPServer.AdminTimeout),
+			slog.Duration("shutdown", c.HTTPServer.ShutdownTimeout),
+		),
+		slog.Group("tls",
+			slog.Bool("enabled", c.TLS.CertFile != "" && c.TLS.KeyFile != ""),
+			slog.String("min_version", c.TLS.MinVersion),
+		),
+		slog.String("grpc_address", c.GRPC.Address),
+		slog.Int("max_concurrent_requests", c.HTTPServer.MaxConcurrentRequests),
+		slog.Int("max_header_bytes", c.HTTPServer.MaxHeaderBytes),
+		slog.Bool("panic_stack", c.HTTPServer.PanicStack),
+		slog.Int("rate_limit_requests", c.RateLimit.Requests),
+		slog.String("log_output", c.LogOutput),
+		slog.Int("log_sample_rate", c.LogSampleRate),
+		slog.Bool("log_bodies", c.LogBodies),
+		slog.Int("max_links_per_ip", c.MaxLinksPerIP),
+		slog.Int64("max_total_links", c.MaxTotalLinks),
+		slog.Bool("dedupe_urls", c.DedupeURLs),
+		slog.Bool("reject_confusable_aliases", c.RejectConfusableAliases),
+		slog.Group("webhooks",
+			slog.String("link_created_url", redactURL(c.Webhooks.LinkCreatedURL)),
+			slog.String("link_redirected_url", redactURL(c.Webhooks.LinkRedirectedURL)),
+		),
+	)
+}
+
+// redact скрывает заданный секрет.
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return redacted
+}
+
+// redactURL оставляет от адреса только схему и хост: в пути и параметрах могут быть токены.
+func redactURL(raw string) string {
+	if raw == "" {
+		return ""
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return redacted
+	}
+
+	return u.Scheme + "://" + u.Host + "/" + redacted
 }
 
+// defaultConfig - конфигурация по умолчанию, встроенная в бинарный файл.
+// Она позволяет запустить сервис из одного файла без CONFIG_PATH и файла конфигурации.
+//
+//go:embed default.yaml
+var defaultConfig []byte
+
 // MustLoad - функция для загрузки конфигурации приложения.
-// 1. Загружает переменные окружения из файла .env.
+// 1. Загружает переменные окружения из файла .env, если он есть.
 // 2. Получает путь к конфигурационному файлу из переменной окружения CONFIG_PATH.
-// 3. Проверяет существование конфигурационного файла.
-// 4. Читает конфигурацию с помощью cleanenv и возвращает структуру с данными конфигурации.
+// 3. Читает конфигурацию с помощью Load и возвращает структуру с данными конфигурации.
 func MustLoad() *Config {
 	// Загружаем переменные окружения из файла .env.
 	// godotenv.Load загружает все переменные из файла ".env" в окружение приложения.
-	// В случае ошибки загрузки .env файла программа завершится с выводом сообщения об ошибке.
+	// Файла может не быть (например, в контейнере переменные задаются окружением), но найденный файл
+	// с ошибкой завершает программу.
 	err := godotenv.Load("../../.env")
-	if err != nil {
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
 		log.Fatal("Error loading .env file") // Если возникла ошибка при загрузке, выводим сообщение и завершаем программу.
 	}
 
 	// Получаем путь к конфигурационному файлу из переменной окружения CONFIG_PATH.
-	// Если переменная окружения не установлена, то это будет пустая строка.
+	// Если переменная окружения не установлена, используется встроенная конфигурация.
 	configPath := os.Getenv("CONFIG_PATH")
 	if configPath == "" {
-		log.Fatal("CONFIG_PATH is not set") // Если переменная окружения пустая, выводим ошибку и завершаем выполнение.
+		log.Print("CONFIG_PATH is not set, using the embedded default config")
 	}
 
-	// Проверяем, существует ли файл конфигурации по указанному пути.
-	// Если файл не существует, выводим ошибку с указанием пути к отсутствующему файлу.
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		log.Fatalf("config file does not exist: %s", configPath) // Завершаем выполнение, если файл не найден.
+	cfg, err := Load(configPath)
+	if err != nil {
+		log.Fatal(err) // Завершаем выполнение с ошибкой, если не удается прочитать конфигурацию.
 	}
 
+	// Возвращаем указатель на загруженную структуру конфигурации.
+	return cfg
+}
+
+// Load читает конфигурацию из файла configPath, а при пустом configPath - из встроенной конфигурации
+// по умолчанию. В обоих случаях переменные окружения переопределяют отдельные поля.
+// Заданный, но отсутствующий файл - ошибка: внешняя конфигурация не подменяется встроенной молча.
+func Load(configPath string) (*Config, error) {
 	// Создаем переменную для хранения конфигурации.
 	var cfg Config
 
+	if configPath == "" {
+		// cleanenv.ReadConfig умеет читать только файл, поэтому встроенная конфигурация разбирается
+		// так же, как он: сначала YAML, затем переменные окружения и значения по умолчанию.
+		if err := cleanenv.ParseYAML(bytes.NewReader(defaultConfig), &cfg); err != nil {
+			return nil, fmt.Errorf("cannot read embedded config: %w", err)
+		}
+		if err := cleanenv.ReadEnv(&cfg); err != nil {
+			return nil, fmt.Errorf("cannot read embedded config: %w", err)
+		}
+
+		return &cfg, nil
+	}
+
+	// Проверяем, существует ли файл конфигурации по указанному пути.
+	// Если файл не существует, возвращаем ошибку с указанием пути к отсутствующему файлу.
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("config file does not exist: %s", configPath)
+	}
+
 	// Читаем конфигурацию из файла с помощью библиотеки cleanenv.
 	// cleanenv.ReadConfig читает данные из файла конфигурации и заполняет структуру cfg.
-	// Если произошла ошибка при чтении, выводим сообщение с ошибкой и завершаем программу.
 	if err := cleanenv.ReadConfig(configPath, &cfg); err != nil {
-		log.Fatalf("cannot read config: %s", err) // Завершаем выполнение с ошибкой, если не удается прочитать конфигурацию.
+		return nil, fmt.Errorf("cannot read config: %w", err)
 	}
 
-	// Возвращаем указатель на загруженную структуру конфигурации.
-	return &cfg
+	return &cfg, nil
 }
@@ -0,0 +1,146 @@
+package config_test
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/config"
+)
+
+func TestConfig_LogValue(t *testing.T) {
+	cfg := &config.Config{Env: "prod", StorageType: "sqlite"}
+	cfg.HTTPServer.Address = "0.0.0.0:8080"
+	cfg.Auth.Mode = "basic"
+	cfg.Auth.User = "admin"
+	cfg.Auth.Password = "s3cret-password"
+	cfg.Auth.JWT.Secret = "jwt-signing-key"
+	cfg.Webhooks.LinkCreatedURL = "https://hooks.example.com/ingest?token=hook-token"
+
+	var logs bytes.Buffer
+	slog.New(slog.NewJSONHandler(&logs, nil)).Info("effective config", slog.Any("config", cfg))
+
+	require.Contains(t, logs.String(), `"address":"0.0.0.0:8080"`)
+	require.Contains(t, logs.String(), `"mode":"basic"`)
+	require.Contains(t, logs.String(), `"user":"admin"`)
+	require.Contains(t, logs.String(), `"password":"[REDACTED]"`)
+	require.Contains(t, logs.String(), `"link_created_url":"https://hooks.example.com/[REDACTED]"`)
+	// Незаданный вебхук остаётся пустым, чтобы было видно, что он не настроен.
+	require.Contains(t, logs.String(), `"link_redirected_url":""`)
+
+	for _, secret := range []string{"s3cret-password", "jwt-signing-key", "hook-token"} {
+		require.NotContains(t, logs.String(), secret)
+	}
+}
+
+func TestLoad_Embedded(t *testing.T) {
+	t.Setenv("LOG_SAMPLE_RATE", "5")
+
+	cfg, err := config.Load("")
+	require.NoError(t, err)
+
+	// Values come from the embedded default config...
+	require.Equal(t, "prod", cfg.Env)
+	require.Equal(t, "./storage/storage.db", cfg.StoragePath)
+	require.Equal(t, "0.0.0.0:8080", cfg.HTTPServer.Address)
+	// ...env-default fills what it does not set...
+	require.Equal(t, "sqlite", cfg.StorageType)
+	// ...and environment variables override individual fields.
+	require.Equal(t, 5, cfg.LogSampleRate)
+}
+
+func TestLoad_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("env: local\nstorage_path: /tmp/us.db\nhttp_server:\n  address: localhost:9000\n  timeout: 5s\n  idle_timeout: 60s\n"), 0o644))
+
+	// An external config takes precedence over the embedded one.
+	cfg, err := config.Load(path)
+	require.NoError(t, err)
+	require.Equal(t, "local", cfg.Env)
+	require.Equal(t, "/tmp/us.db", cfg.StoragePath)
+	require.Equal(t, "localhost:9000", cfg.HTTPServer.Address)
+	// Fields missing from the file fall back to env-default.
+	require.Equal(t, 65536, cfg.HTTPServer.MaxHeaderBytes)
+	require.False(t, cfg.SkipSchemaVerify)
+	require.Equal(t, "stdout", cfg.LogOutput)
+	require.Equal(t, 100, cfg.LogRotation.MaxSizeMB)
+	require.False(t, cfg.LogSelfTest)
+	require.False(t, cfg.LogBodies)
+	require.Equal(t, 24*time.Hour, cfg.IdempotencyTTL)
+	require.Equal(t, time.Second, cfg.HTTPServer.HealthTimeout)
+	require.Empty(t, cfg.Redirect.Headers)
+
+	// A configured path that does not exist is an error, not a fallback.
+	_, err = config.Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	require.ErrorContains(t, err, "config file does not exist")
+}
+
+func TestLoad_Flags(t *testing.T) {
+	const base = "env: local\nstorage_path: /tmp/us.db\nhttp_server:\n  address: localhost:9000\n  timeout: 5s\n  idle_timeout: 60s\n"
+
+	cases := []struct {
+		name string
+		yaml string
+		get  func(*config.Config) bool
+	}{
+		{
+			name: "allow_anonymous_create",
+			yaml: "auth:\n  allow_anonymous_create: %v\n",
+			get:  func(c *config.Config) bool { return c.Auth.AllowAnonymousCreate },
+		},
+		{
+			name: "skip_schema_verify",
+			yaml: "skip_schema_verify: %v\n",
+			get:  func(c *config.Config) bool { return c.SkipSchemaVerify },
+		},
+		{
+			name: "strict_trailing_slash",
+			yaml: "redirect:\n  strict_trailing_slash: %v\n",
+			get:  func(c *config.Config) bool { return c.Redirect.StrictTrailingSlash },
+		},
+		{
+			name: "send_full_target",
+			yaml: "webhooks:\n  send_full_target: %v\n",
+			get:  func(c *config.Config) bool { return c.Webhooks.SendFullTarget },
+		},
+		{
+			name: "hide_panic_request_id",
+			// base заканчивается секцией http_server, поэтому ключ дописывается в неё с отступом.
+			yaml: "  hide_panic_request_id: %v\n",
+			get:  func(c *config.Config) bool { return c.HTTPServer.HidePanicRequestID },
+		},
+		{
+			name: "allow_plaintext",
+			yaml: "grpc:\n  allow_plaintext: %v\n",
+			get:  func(c *config.Config) bool { return c.GRPC.AllowPlaintext },
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Флаг выключен, если его нет в файле, и в файле принимает оба значения:
+			// env-default не должен перекрывать явное false.
+			for _, want := range []bool{false, true} {
+				path := filepath.Join(t.TempDir(), "config.yaml")
+				require.NoError(t, os.WriteFile(path, []byte(base+fmt.Sprintf(tc.yaml, want)), 0o644))
+
+				cfg, err := config.Load(path)
+				require.NoError(t, err)
+				require.Equal(t, want, tc.get(cfg))
+			}
+
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(base), 0o644))
+
+			cfg, err := config.Load(path)
+			require.NoError(t, err)
+			require.False(t, tc.get(cfg))
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package urlshortener
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"url-shortener/internal/http-server/middleware/apikeyauth"
+	"url-shortener/internal/http-server/middleware/jwtauth"
+	"url-shortener/internal/lib/logger/sl"
+)
+
+// apiKeyMetadata is the metadata key of the API key, the lower-cased
+// X-API-Key header of the HTTP API.
+const apiKeyMetadata = "x-api-key"
+
+// BasicAuth returns an interceptor that requires the same basic credentials
+// as the administrative HTTP routes, passed in the "authorization" metadata
+// as "Basic base64(user:password)".
+func BasicAuth(user, password string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+
+		for _, value := range md.Get("authorization") {
+			if checkBasic(value, user, password) {
+				return handler(ctx, req)
+			}
+		}
+
+		return nil, status.Error(codes.Unauthenticated, "invalid credentials")
+	}
+}
+
+// JWTAuth returns an interceptor that requires a bearer token in the
+// "authorization" metadata, checked by the same validator as the
+// administrative HTTP routes in jwt mode. Tokens without the required
+// scope get codes.PermissionDenied, like the 403 of the HTTP API.
+func JWTAuth(v *jwtauth.Validator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+
+		values := md.Get("authorization")
+		if len(values) == 0 {
+			return nil, status.Error(codes.Unauthenticated, "unauthorized")
+		}
+
+		raw, ok := jwtauth.BearerToken(values[0])
+		if !ok {
+			return nil, status.Error(codes.Unauthenticated, "unauthorized")
+		}
+
+		_, err := v.Validate(raw)
+		switch {
+		case errors.Is(err, jwtauth.ErrInsufficientScope):
+			return nil, status.Error(codes.PermissionDenied, "insufficient scope")
+		case errors.Is(err, jwtauth.ErrTokenExpired):
+			return nil, status.Error(codes.Unauthenticated, "token expired")
+		case err != nil:
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// APIKeyAuth returns an interceptor that requires an API key in the
+// "x-api-key" metadata, checked like the X-API-Key header of the
+// administrative HTTP routes in api_key mode.
+func APIKeyAuth(log *slog.Logger, keys apikeyauth.KeyGetter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		const op = "grpc.urlshortener.APIKeyAuth"
+
+		md, _ := metadata.FromIncomingContext(ctx)
+
+		values := md.Get(apiKeyMetadata)
+		if len(values) == 0 || values[0] == "" {
+			return nil, status.Error(codes.Unauthenticated, "missing api key")
+		}
+
+		prefix, err := apikeyauth.Check(keys, values[0])
+		switch {
+		case errors.Is(err, apikeyauth.ErrInvalidKey):
+			return nil, status.Error(codes.Unauthenticated, "invalid api key")
+		case err != nil:
+			log.Error("failed to check api key", slog.String("op", op), slog.String("prefix", prefix), sl.Err(err))
+			return nil, status.Error(codes.Internal, "internal error")
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func checkBasic(value, user, password string) bool {
+	encoded, ok := strings.CutPrefix(value, "Basic ")
+	if !ok {
+		return false
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return false
+	}
+
+	gotUser, gotPassword, ok := strings.Cut(string(decoded), ":")
+	if !ok {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(gotUser), []byte(user)) == 1 &&
+		subtle.ConstantTimeCompare([]byte(gotPassword), []byte(password)) == 1
+}
@@ -0,0 +1,127 @@
+package urlshortener_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	urlshortenerv1 "url-shortener/api/urlshortener/v1"
+	"url-shortener/internal/grpc-server/urlshortener"
+	"url-shortener/internal/http-server/middleware/jwtauth"
+	"url-shortener/internal/lib/apikey"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+// keyGetterFunc adapts a function to apikeyauth.KeyGetter.
+type keyGetterFunc func(prefix string) (storage.APIKey, error)
+
+func (f keyGetterFunc) GetAPIKey(prefix string) (storage.APIKey, error) {
+	return f(prefix)
+}
+
+func TestJWTAuth(t *testing.T) {
+	const secret = "secret"
+
+	v, err := jwtauth.NewValidator(jwtauth.Config{Algorithm: jwtauth.AlgHS256, Secret: secret, Scope: "urls:write"})
+	require.NoError(t, err)
+
+	client := newClientWith(t, urlshortener.Options{}, urlshortener.JWTAuth(v))
+
+	sign := func(claims jwt.MapClaims) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+		require.NoError(t, err)
+		return token
+	}
+	exp := time.Now().Add(time.Hour).Unix()
+
+	cases := []struct {
+		name          string
+		authorization string
+		code          codes.Code
+	}{
+		{
+			name:          "Valid token",
+			authorization: "Bearer " + sign(jwt.MapClaims{"sub": "ci", "scope": "urls:write", "exp": exp}),
+			code:          codes.NotFound,
+		},
+		{
+			name: "No token",
+			code: codes.Unauthenticated,
+		},
+		{
+			// Basic credentials must not unlock jwt mode.
+			name:          "Empty basic credentials",
+			authorization: "Basic Og==",
+			code:          codes.Unauthenticated,
+		},
+		{
+			name:          "Expired token",
+			authorization: "Bearer " + sign(jwt.MapClaims{"scope": "urls:write", "exp": time.Now().Add(-time.Hour).Unix()}),
+			code:          codes.Unauthenticated,
+		},
+		{
+			name:          "Missing scope",
+			authorization: "Bearer " + sign(jwt.MapClaims{"scope": "urls:read", "exp": exp}),
+			code:          codes.PermissionDenied,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tc.authorization != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, "authorization", tc.authorization)
+			}
+
+			_, err := client.GetURL(ctx, &urlshortenerv1.GetURLRequest{Alias: "missing"})
+			require.Equal(t, tc.code, status.Code(err))
+		})
+	}
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	key, prefix, hash, err := apikey.Generate()
+	require.NoError(t, err)
+
+	other, _, _, err := apikey.Generate()
+	require.NoError(t, err)
+
+	keys := keyGetterFunc(func(p string) (storage.APIKey, error) {
+		if p != prefix {
+			return storage.APIKey{}, storage.ErrAPIKeyNotFound
+		}
+		return storage.APIKey{ID: 1, Prefix: prefix, Hash: hash}, nil
+	})
+
+	client := newClientWith(t, urlshortener.Options{}, urlshortener.APIKeyAuth(slogdiscard.NewDiscardLogger(), keys))
+
+	cases := []struct {
+		name string
+		key  string
+		code codes.Code
+	}{
+		{name: "Valid key", key: key, code: codes.NotFound},
+		{name: "No key", code: codes.Unauthenticated},
+		{name: "Unknown key", key: other, code: codes.Unauthenticated},
+		{name: "Malformed key", key: "not-a-key", code: codes.Unauthenticated},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tc.key != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, "x-api-key", tc.key)
+			}
+
+			_, err := client.GetURL(ctx, &urlshortenerv1.GetURLRequest{Alias: "missing"})
+			require.Equal(t, tc.code, status.Code(err))
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package urlshortener
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	urlshortenerv1 "url-shortener/api/urlshortener/v1"
+)
+
+// writeMethods are the RPCs that modify links.
+var writeMethods = map[string]bool{
+	urlshortenerv1.URLShortener_CreateURL_FullMethodName: true,
+	urlshortenerv1.URLShortener_DeleteURL_FullMethodName: true,
+}
+
+// ReadOnly returns an interceptor that rejects RPCs modifying links with
+// codes.Unavailable while the service is in read-only mode, matching the
+// 503 of the HTTP API. Reads are passed through.
+func ReadOnly() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if writeMethods[info.FullMethod] {
+			return nil, status.Error(codes.Unavailable, "service is in read-only mode, writes are temporarily disabled")
+		}
+
+		return handler(ctx, req)
+	}
+}
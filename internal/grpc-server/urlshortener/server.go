@@ -0,0 +1,426 @@
+package urlshortener
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	urlshortenerv1 "url-shortener/api/urlshortener/v1"
+	"url-shortener/internal/lib/aliaslen"
+	"url-shortener/internal/lib/linkcap"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/random"
+	"url-shortener/internal/lib/reserved"
+	"url-shortener/internal/storage"
+)
+
+// generateAliasAttempts is how many generated aliases are tried before giving up.
+const generateAliasAttempts = 5
+
+// Options configures the gRPC server.
+type Options struct {
+	// MaxURLLength is the maximum accepted URL length in bytes. Zero disables the check.
+	MaxURLLength int
+	// AliasGenerator generates aliases when the client does not supply one.
+	// Nil means the crypto/rand-backed random.Generator.
+	AliasGenerator random.AliasGenerator
+	// AliasLength decides the length of generated aliases and grows it on collisions.
+	// Nil means a fixed length of random.DefaultLength.
+	AliasLength random.AliasLength
+	// Reserved lists aliases that cannot be used for links.
+	// Nil means the built-in reserved aliases only.
+	Reserved reserved.Set
+	// Notifier is told about every created link. Nil disables notifications.
+	Notifier CreationNotifier
+	// Sequential saves links without a client alias under an alias encoded
+	// from their ID by AliasEncoder instead of a generated one. Nil, or a
+	// nil AliasEncoder, means aliases are generated by AliasGenerator.
+	Sequential SequentialSaver
+	// AliasEncoder encodes link IDs into aliases for Sequential.
+	AliasEncoder AliasEncoder
+	// MaxLinksPerIP caps how many links a single client IP may own, like the
+	// HTTP API; creations beyond it get codes.ResourceExhausted. The IP is
+	// the peer address of the connection: forwarding headers are not
+	// honored. Zero disables the cap. LinkCounter must be set when the cap
+	// is enabled.
+	MaxLinksPerIP int
+	// LinkCounter counts links by the IP that created them.
+	LinkCounter LinkCounter
+	// MaxTotalLinks caps how many links the storage may hold, like the HTTP
+	// API; creations beyond it get codes.ResourceExhausted. Zero disables the
+	// cap. TotalLinkCounter must be set when the cap is enabled.
+	MaxTotalLinks int64
+	// TotalLinkCounter counts all stored links for MaxTotalLinks. The count
+	// is cached, see linkcap.Cap.
+	TotalLinkCounter TotalLinkCounter
+	// Duplicates looks up the existing link when the storage rejects a URL
+	// that is already shortened (dedupe mode), like the HTTP API: a live link
+	// with the requested options is returned instead of creating one. Nil,
+	// or an existing link that cannot be reused, means codes.AlreadyExists.
+	Duplicates DuplicateFinder
+}
+
+// LinkCounter is an interface for counting links created from an IP.
+type LinkCounter interface {
+	CountByCreator(ip string) (int, error)
+}
+
+// TotalLinkCounter is an interface for counting all stored links.
+type TotalLinkCounter interface {
+	CountURLs(ctx context.Context) (int64, error)
+}
+
+// SequentialSaver is an interface for saving links under aliases derived
+// from their ID. encode returns "" for an ID whose alias cannot be used.
+type SequentialSaver interface {
+	SaveURLSequential(urlToSave string, opts storage.URLOptions, encode func(id int64) string) (string, int64, error)
+}
+
+// AliasEncoder is an interface for turning link IDs into aliases.
+type AliasEncoder interface {
+	Encode(id int64) string
+}
+
+// DuplicateFinder is an interface for looking up the link of an already shortened URL.
+type DuplicateFinder interface {
+	GetURLRecordByURL(urlToSave string) (storage.URLRecord, error)
+}
+
+// CreationNotifier is an interface for announcing created links.
+// LinkCreated must not block.
+type CreationNotifier interface {
+	LinkCreated(namespace, alias, target string)
+}
+
+// Storage is the part of the storage used by the gRPC API.
+// It is the same set of methods the HTTP handlers depend on.
+type Storage interface {
+	SaveURL(urlToSave string, alias string, opts storage.URLOptions) (int64, error)
+	GetURLRecord(alias string) (storage.URLRecord, error)
+	DeleteURL(alias string) (int64, error)
+}
+
+type serverAPI struct {
+	urlshortenerv1.UnimplementedURLShortenerServer
+
+	log            *slog.Logger
+	storage        Storage
+	maxURLLength   int
+	maxLinksPerIP  int
+	linkCounter    LinkCounter
+	maxTotalLinks  int64
+	aliasGenerator random.AliasGenerator
+	aliasLength    random.AliasLength
+	reserved       reserved.Set
+	notifier       CreationNotifier
+	sequential     SequentialSaver
+	aliasEncoder   AliasEncoder
+	linkCap        *linkcap.Cap
+	duplicates     DuplicateFinder
+	validate       *validator.Validate
+}
+
+// Register registers the URLShortener service on gs.
+func Register(gs *grpc.Server, log *slog.Logger, s Storage, opts Options) {
+	aliasGenerator := opts.AliasGenerator
+	if aliasGenerator == nil {
+		aliasGenerator = random.NewGenerator()
+	}
+
+	length := opts.AliasLength
+	if length == nil {
+		length = aliaslen.New(log, random.DefaultLength, random.DefaultLength)
+	}
+
+	reservedAliases := opts.Reserved
+	if reservedAliases == nil {
+		reservedAliases = reserved.New(nil)
+	}
+
+	var linkCap *linkcap.Cap
+	if opts.MaxTotalLinks > 0 && opts.TotalLinkCounter != nil {
+		linkCap = linkcap.New(opts.TotalLinkCounter, opts.MaxTotalLinks)
+	}
+
+	urlshortenerv1.RegisterURLShortenerServer(gs, &serverAPI{
+		log:            log,
+		storage:        s,
+		maxURLLength:   opts.MaxURLLength,
+		maxLinksPerIP:  opts.MaxLinksPerIP,
+		linkCounter:    opts.LinkCounter,
+		maxTotalLinks:  opts.MaxTotalLinks,
+		aliasGenerator: aliasGenerator,
+		aliasLength:    length,
+		reserved:       reservedAliases,
+		notifier:       opts.Notifier,
+		sequential:     opts.Sequential,
+		aliasEncoder:   opts.AliasEncoder,
+		linkCap:        linkCap,
+		duplicates:     opts.Duplicates,
+		validate:       validator.New(),
+	})
+}
+
+func (s *serverAPI) CreateURL(ctx context.Context, req *urlshortenerv1.CreateURLRequest) (*urlshortenerv1.CreateURLResponse, error) {
+	const op = "grpc.urlshortener.CreateURL"
+
+	log := s.log.With(slog.String("op", op))
+
+	if err := s.validate.Var(req.GetUrl(), "required,http_url"); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "url must be a valid http or https URL")
+	}
+	if s.maxURLLength > 0 && len(req.GetUrl()) > s.maxURLLength {
+		return nil, status.Errorf(codes.InvalidArgument, "url is too long: max length is %d", s.maxURLLength)
+	}
+	if err := s.validate.Var(req.GetAlias(), "omitempty,alphanum"); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "alias must contain only letters and digits")
+	}
+
+	creatorIP := peerIP(ctx)
+
+	urlOpts := storage.URLOptions{
+		Permanent: req.Permanent,
+		CreatorIP: creatorIP,
+	}
+
+	if req.GetTtl() != "" {
+		ttl, err := time.ParseDuration(req.GetTtl())
+		if err != nil || ttl <= 0 {
+			return nil, status.Error(codes.InvalidArgument, "ttl must be a positive duration, e.g. 72h")
+		}
+
+		expiresAt := time.Now().Add(ttl)
+		urlOpts.ExpiresAt = &expiresAt
+	}
+
+	alias := req.GetAlias()
+	if alias != "" && s.reserved.Contains(alias) {
+		return nil, status.Error(codes.InvalidArgument, "alias is reserved")
+	}
+
+	if s.maxLinksPerIP > 0 && s.linkCounter != nil {
+		count, err := s.linkCounter.CountByCreator(creatorIP)
+		if err != nil {
+			log.Error("failed to count links by creator", sl.Err(err))
+			return nil, status.Error(codes.Internal, "failed to add url")
+		}
+		if count >= s.maxLinksPerIP {
+			log.Info("links per ip limit reached",
+				slog.String("ip", creatorIP),
+				slog.Int("count", count),
+				slog.Int("limit", s.maxLinksPerIP),
+			)
+			return nil, status.Errorf(codes.ResourceExhausted, "link limit reached: max %d links per client", s.maxLinksPerIP)
+		}
+	}
+
+	if s.linkCap != nil {
+		full, count, err := s.linkCap.Full(ctx)
+		if err != nil {
+			log.Error("failed to count links", sl.Err(err))
+			return nil, status.Error(codes.Internal, "failed to add url")
+		}
+		if full {
+			log.Warn("total links limit reached",
+				slog.Int64("count", count),
+				slog.Int64("limit", s.maxTotalLinks),
+			)
+			return nil, status.Errorf(codes.ResourceExhausted, "link storage is full: max %d links", s.maxTotalLinks)
+		}
+	}
+
+	var err error
+	if alias != "" {
+		_, err = s.storage.SaveURL(req.GetUrl(), alias, urlOpts)
+		if errors.Is(err, storage.ErrAliasConfusable) {
+			return nil, status.Error(codes.AlreadyExists, "alias is too similar to an existing alias")
+		}
+		if errors.Is(err, storage.ErrAliasHeld) {
+			return nil, status.Error(codes.AlreadyExists, "alias is reserved by another client")
+		}
+		if errors.Is(err, storage.ErrURLExists) {
+			return nil, status.Error(codes.AlreadyExists, "url already exists")
+		}
+	} else {
+		if s.sequential != nil && s.aliasEncoder != nil {
+			alias, err = s.saveWithSequentialAlias(req.GetUrl(), urlOpts)
+		} else {
+			alias, err = s.saveWithGeneratedAlias(req.GetUrl(), urlOpts)
+		}
+		if errors.Is(err, storage.ErrURLExists) {
+			log.Error("failed to generate unique alias", slog.Int("attempts", generateAliasAttempts))
+			return nil, status.Error(codes.Internal, "failed to generate unique alias")
+		}
+	}
+	if errors.Is(err, storage.ErrURLDuplicate) {
+		return s.existingURL(log, req.GetUrl(), urlOpts)
+	}
+	if err != nil {
+		log.Error("failed to add url", sl.Err(err))
+		return nil, status.Error(codes.Internal, "failed to add url")
+	}
+
+	log.Info("url added", slog.String("alias", alias))
+
+	// Aliases are stored in lower case; return the alias as stored.
+	alias = storage.NormalizeAlias(alias)
+
+	if s.linkCap != nil {
+		s.linkCap.Added()
+	}
+	if s.notifier != nil {
+		s.notifier.LinkCreated("", alias, req.GetUrl())
+	}
+
+	return &urlshortenerv1.CreateURLResponse{Alias: alias}, nil
+}
+
+func (s *serverAPI) GetURL(ctx context.Context, req *urlshortenerv1.GetURLRequest) (*urlshortenerv1.GetURLResponse, error) {
+	const op = "grpc.urlshortener.GetURL"
+
+	if req.GetAlias() == "" {
+		return nil, status.Error(codes.InvalidArgument, "alias is required")
+	}
+
+	rec, err := s.storage.GetURLRecord(req.GetAlias())
+	if errors.Is(err, storage.ErrURLNotFound) {
+		return nil, status.Error(codes.NotFound, "not found")
+	}
+	if err != nil {
+		s.log.Error("failed to get url", slog.String("op", op), sl.Err(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &urlshortenerv1.GetURLResponse{
+		Id:             rec.ID,
+		Alias:          rec.Alias,
+		Url:            rec.URL,
+		Permanent:      rec.Permanent,
+		ExpiresAt:      timestamp(rec.ExpiresAt),
+		CreatedAt:      timestamp(rec.CreatedAt),
+		LastAccessedAt: timestamp(rec.LastAccessedAt),
+	}, nil
+}
+
+func (s *serverAPI) DeleteURL(ctx context.Context, req *urlshortenerv1.DeleteURLRequest) (*urlshortenerv1.DeleteURLResponse, error) {
+	const op = "grpc.urlshortener.DeleteURL"
+
+	if req.GetAlias() == "" {
+		return nil, status.Error(codes.InvalidArgument, "alias is required")
+	}
+
+	countDeleted, err := s.storage.DeleteURL(req.GetAlias())
+	if err != nil {
+		s.log.Error("failed to delete url", slog.String("op", op), sl.Err(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	if countDeleted == 0 {
+		return nil, status.Error(codes.NotFound, "not found")
+	}
+
+	s.log.Info("deleted url", slog.String("op", op), slog.String("alias", req.GetAlias()))
+
+	return &urlshortenerv1.DeleteURLResponse{CountDeleted: countDeleted}, nil
+}
+
+// saveWithSequentialAlias saves the url under the alias encoded from its ID.
+// IDs whose alias is reserved are skipped by the storage like taken ones.
+func (s *serverAPI) saveWithSequentialAlias(urlToSave string, urlOpts storage.URLOptions) (string, error) {
+	alias, _, err := s.sequential.SaveURLSequential(urlToSave, urlOpts, func(id int64) string {
+		alias := s.aliasEncoder.Encode(id)
+		if s.reserved.Contains(alias) {
+			return ""
+		}
+		return alias
+	})
+
+	return alias, err
+}
+
+// existingURL answers a CreateURL of an already shortened URL with the alias
+// of its existing link, if that link is live and has the requested options.
+func (s *serverAPI) existingURL(log *slog.Logger, urlToSave string, urlOpts storage.URLOptions) (*urlshortenerv1.CreateURLResponse, error) {
+	if s.duplicates == nil {
+		log.Info("url already shortened")
+		return nil, status.Error(codes.AlreadyExists, "url already shortened")
+	}
+
+	rec, err := s.duplicates.GetURLRecordByURL(urlToSave)
+	if err != nil {
+		log.Error("failed to get existing link", sl.Err(err))
+		return nil, status.Error(codes.Internal, "failed to add url")
+	}
+
+	if !rec.Reusable(urlOpts, time.Now()) {
+		log.Info("url already shortened by a link that cannot be reused", slog.Int64("id", rec.ID), slog.String("alias", rec.Alias))
+		return nil, status.Errorf(codes.AlreadyExists, "url already shortened as %q by an inactive link or with different options", rec.Alias)
+	}
+
+	log.Info("url already shortened, returning existing link", slog.Int64("id", rec.ID), slog.String("alias", rec.Alias))
+
+	return &urlshortenerv1.CreateURLResponse{Alias: rec.Alias}, nil
+}
+
+// saveWithGeneratedAlias saves the url under a generated alias, retrying
+// with a fresh alias when the generated one is taken or reserved.
+func (s *serverAPI) saveWithGeneratedAlias(urlToSave string, urlOpts storage.URLOptions) (string, error) {
+	var err error
+
+	for attempt := 1; attempt <= generateAliasAttempts; attempt++ {
+		n := s.aliasLength.Length()
+
+		var alias string
+		alias, err = s.aliasGenerator.Generate(n)
+		if err != nil {
+			return "", fmt.Errorf("generate alias: %w", err)
+		}
+
+		if s.reserved.Contains(alias) {
+			err = storage.ErrURLExists
+			continue
+		}
+
+		_, err = s.storage.SaveURL(urlToSave, alias, urlOpts)
+		if !errors.Is(err, storage.ErrURLExists) {
+			return alias, err
+		}
+
+		s.aliasLength.Collision(n)
+	}
+
+	return "", err
+}
+
+func timestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+
+	return timestamppb.New(*t)
+}
+
+// peerIP returns the IP of the client connection, or "" when the peer is
+// not a TCP connection.
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	addr, ok := p.Addr.(*net.TCPAddr)
+	if !ok {
+		return ""
+	}
+
+	return addr.IP.String()
+}
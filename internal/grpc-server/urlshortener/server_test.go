@@ -0,0 +1,288 @@
+package urlshortener_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	urlshortenerv1 "url-shortener/api/urlshortener/v1"
+	"url-shortener/internal/grpc-server/urlshortener"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage/sqlite"
+)
+
+func newClient(t *testing.T, interceptors ...grpc.UnaryServerInterceptor) urlshortenerv1.URLShortenerClient {
+	t.Helper()
+
+	interceptors = append([]grpc.UnaryServerInterceptor{urlshortener.BasicAuth("user", "pass")}, interceptors...)
+
+	return newClientWith(t, urlshortener.Options{}, interceptors...)
+}
+
+// newClientWith is newClient without the basic auth interceptor.
+func newClientWith(t *testing.T, opts urlshortener.Options, interceptors ...grpc.UnaryServerInterceptor) urlshortenerv1.URLShortenerClient {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	db.SetMaxOpenConns(1)
+
+	s, err := sqlite.NewWithDB(slogdiscard.NewDiscardLogger(), db, sqlite.Options{})
+	require.NoError(t, err)
+
+	return newClientWithStorage(t, s, opts, interceptors...)
+}
+
+// newClientWithStorage is newClientWith over the given storage.
+func newClientWithStorage(t *testing.T, s urlshortener.Storage, opts urlshortener.Options, interceptors ...grpc.UnaryServerInterceptor) urlshortenerv1.URLShortenerClient {
+	t.Helper()
+
+	gs := grpc.NewServer(grpc.ChainUnaryInterceptor(interceptors...))
+	urlshortener.Register(gs, slogdiscard.NewDiscardLogger(), s, opts)
+
+	lis := bufconn.Listen(1 << 20)
+	go func() { _ = gs.Serve(lis) }()
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return urlshortenerv1.NewURLShortenerClient(conn)
+}
+
+func authContext(user, password string) context.Context {
+	creds := base64.StdEncoding.EncodeToString([]byte(user + ":" + password))
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Basic "+creds)
+}
+
+func TestServer(t *testing.T) {
+	client := newClient(t)
+	ctx := authContext("user", "pass")
+
+	created, err := client.CreateURL(ctx, &urlshortenerv1.CreateURLRequest{Url: "https://google.com", Alias: "google"})
+	require.NoError(t, err)
+	require.Equal(t, "google", created.GetAlias())
+
+	generated, err := client.CreateURL(ctx, &urlshortenerv1.CreateURLRequest{Url: "https://ya.ru", Ttl: "1h"})
+	require.NoError(t, err)
+	require.NotEmpty(t, generated.GetAlias())
+
+	got, err := client.GetURL(ctx, &urlshortenerv1.GetURLRequest{Alias: generated.GetAlias()})
+	require.NoError(t, err)
+	require.Equal(t, "https://ya.ru", got.GetUrl())
+	require.NotNil(t, got.GetExpiresAt())
+
+	deleted, err := client.DeleteURL(ctx, &urlshortenerv1.DeleteURLRequest{Alias: "google"})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), deleted.GetCountDeleted())
+}
+
+func TestServer_Errors(t *testing.T) {
+	client := newClient(t)
+	ctx := authContext("user", "pass")
+
+	_, err := client.CreateURL(ctx, &urlshortenerv1.CreateURLRequest{Url: "https://google.com", Alias: "google"})
+	require.NoError(t, err)
+
+	cases := []struct {
+		name string
+		call func() error
+		code codes.Code
+	}{
+		{
+			name: "Alias exists",
+			call: func() error {
+				_, err := client.CreateURL(ctx, &urlshortenerv1.CreateURLRequest{Url: "https://ya.ru", Alias: "google"})
+				return err
+			},
+			code: codes.AlreadyExists,
+		},
+		{
+			name: "Invalid URL",
+			call: func() error {
+				_, err := client.CreateURL(ctx, &urlshortenerv1.CreateURLRequest{Url: "not a url"})
+				return err
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "JavaScript URL",
+			call: func() error {
+				_, err := client.CreateURL(ctx, &urlshortenerv1.CreateURLRequest{Url: "javascript:alert(1)"})
+				return err
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "Reserved alias",
+			call: func() error {
+				_, err := client.CreateURL(ctx, &urlshortenerv1.CreateURLRequest{Url: "https://ya.ru", Alias: "health"})
+				return err
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "Get missing",
+			call: func() error {
+				_, err := client.GetURL(ctx, &urlshortenerv1.GetURLRequest{Alias: "missing"})
+				return err
+			},
+			code: codes.NotFound,
+		},
+		{
+			name: "Delete missing",
+			call: func() error {
+				_, err := client.DeleteURL(ctx, &urlshortenerv1.DeleteURLRequest{Alias: "missing"})
+				return err
+			},
+			code: codes.NotFound,
+		},
+		{
+			name: "Wrong credentials",
+			call: func() error {
+				_, err := client.GetURL(authContext("user", "wrong"), &urlshortenerv1.GetURLRequest{Alias: "google"})
+				return err
+			},
+			code: codes.Unauthenticated,
+		},
+		{
+			name: "No credentials",
+			call: func() error {
+				_, err := client.GetURL(context.Background(), &urlshortenerv1.GetURLRequest{Alias: "google"})
+				return err
+			},
+			code: codes.Unauthenticated,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.call()
+			require.Error(t, err)
+			require.Equal(t, tc.code, status.Code(err))
+		})
+	}
+}
+
+func TestServer_ReadOnly(t *testing.T) {
+	client := newClient(t, urlshortener.ReadOnly())
+	ctx := authContext("user", "pass")
+
+	_, err := client.CreateURL(ctx, &urlshortenerv1.CreateURLRequest{Url: "https://google.com", Alias: "google"})
+	require.Equal(t, codes.Unavailable, status.Code(err))
+
+	_, err = client.DeleteURL(ctx, &urlshortenerv1.DeleteURLRequest{Alias: "google"})
+	require.Equal(t, codes.Unavailable, status.Code(err))
+
+	// Reads keep working.
+	_, err = client.GetURL(ctx, &urlshortenerv1.GetURLRequest{Alias: "google"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+// totalCounterFunc adapts a function to urlshortener.TotalLinkCounter.
+type totalCounterFunc func(ctx context.Context) (int64, error)
+
+func (f totalCounterFunc) CountURLs(ctx context.Context) (int64, error) {
+	return f(ctx)
+}
+
+func TestServer_MaxTotalLinks(t *testing.T) {
+	client := newClientWith(t, urlshortener.Options{
+		MaxTotalLinks: 2,
+		TotalLinkCounter: totalCounterFunc(func(context.Context) (int64, error) {
+			return 1, nil
+		}),
+	})
+	ctx := context.Background()
+
+	_, err := client.CreateURL(ctx, &urlshortenerv1.CreateURLRequest{Url: "https://google.com", Alias: "google"})
+	require.NoError(t, err)
+
+	_, err = client.CreateURL(ctx, &urlshortenerv1.CreateURLRequest{Url: "https://ya.ru", Alias: "ya"})
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	require.Equal(t, "link storage is full: max 2 links", status.Convert(err).Message())
+}
+
+// linkCounterFunc adapts a function to urlshortener.LinkCounter.
+type linkCounterFunc func(ip string) (int, error)
+
+func (f linkCounterFunc) CountByCreator(ip string) (int, error) {
+	return f(ip)
+}
+
+func TestServer_MaxLinksPerIP(t *testing.T) {
+	// bufconn has no IP address, so the peer is set the way a TCP connection would set it.
+	withPeer := func(ip string) grpc.UnaryServerInterceptor {
+		return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			return handler(peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 50000}}), req)
+		}
+	}
+
+	counts := map[string]int{"203.0.113.7": 2, "203.0.113.8": 1}
+	opts := urlshortener.Options{
+		MaxLinksPerIP: 2,
+		LinkCounter: linkCounterFunc(func(ip string) (int, error) {
+			return counts[ip], nil
+		}),
+	}
+
+	_, err := newClientWith(t, opts, withPeer("203.0.113.7")).
+		CreateURL(context.Background(), &urlshortenerv1.CreateURLRequest{Url: "https://google.com", Alias: "google"})
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	require.Equal(t, "link limit reached: max 2 links per client", status.Convert(err).Message())
+
+	_, err = newClientWith(t, opts, withPeer("203.0.113.8")).
+		CreateURL(context.Background(), &urlshortenerv1.CreateURLRequest{Url: "https://google.com", Alias: "google"})
+	require.NoError(t, err)
+}
+
+func TestServer_Dedupe(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	db.SetMaxOpenConns(1)
+
+	s, err := sqlite.NewWithDB(slogdiscard.NewDiscardLogger(), db, sqlite.Options{UniqueURL: true})
+	require.NoError(t, err)
+
+	client := newClientWithStorage(t, s, urlshortener.Options{Duplicates: s})
+	ctx := context.Background()
+
+	created, err := client.CreateURL(ctx, &urlshortenerv1.CreateURLRequest{Url: "https://google.com"})
+	require.NoError(t, err)
+
+	// The same URL with the same options gets the existing link, as over HTTP.
+	again, err := client.CreateURL(ctx, &urlshortenerv1.CreateURLRequest{Url: "https://google.com", Alias: "mine"})
+	require.NoError(t, err)
+	require.Equal(t, created.GetAlias(), again.GetAlias())
+
+	// Different options cannot be served by the existing link.
+	permanent := true
+	_, err = client.CreateURL(ctx, &urlshortenerv1.CreateURLRequest{Url: "https://google.com", Permanent: &permanent})
+	require.Equal(t, codes.AlreadyExists, status.Code(err))
+	require.Contains(t, status.Convert(err).Message(), created.GetAlias())
+
+	// Neither can a disabled link.
+	err = s.SetEnabled(created.GetAlias(), false)
+	require.NoError(t, err)
+	_, err = client.CreateURL(ctx, &urlshortenerv1.CreateURLRequest{Url: "https://google.com"})
+	require.Equal(t, codes.AlreadyExists, status.Code(err))
+}
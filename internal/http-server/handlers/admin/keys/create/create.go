@@ -0,0 +1,98 @@
+package create
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/apikey"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+// Request is the optional body of POST /admin/keys.
+type Request struct {
+	// Name describes who the key is for, e.g. "ci".
+	Name string `json:"name,omitempty" validate:"max=100"`
+}
+
+type Response struct {
+	resp.Response
+	ID     int64  `json:"id,omitempty"`
+	Name   string `json:"name,omitempty"`
+	Prefix string `json:"prefix,omitempty"`
+	// Key is the generated API key. It is returned only here: the storage
+	// keeps just its hash, so a lost key cannot be recovered, only revoked.
+	Key       string     `json:"key,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.53.7 --name=KeySaver
+
+// KeySaver is an interface for storing the hash of a new API key.
+type KeySaver interface {
+	SaveAPIKey(name, prefix string, hash []byte) (storage.APIKey, error)
+}
+
+// New generates a new API key, stores its bcrypt hash and returns the key
+// once. A request without a body creates an unnamed key.
+func New(log *slog.Logger, saver KeySaver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.keys.create.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req Request
+		if r.ContentLength != 0 {
+			if err := render.DecodeJSON(r.Body, &req); err != nil {
+				log.Error("failed to decode request body", sl.Err(err))
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, resp.Error(resp.CodeInvalidRequest, "failed to decode request"))
+				return
+			}
+		}
+
+		if err := validator.New().Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+			log.Info("invalid request", sl.Err(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.ValidationError(validateErr))
+			return
+		}
+
+		key, prefix, hash, err := apikey.Generate()
+		if err != nil {
+			log.Error("failed to generate api key", sl.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(resp.CodeInternal, "internal error"))
+			return
+		}
+
+		saved, err := saver.SaveAPIKey(req.Name, prefix, hash)
+		if err != nil {
+			log.Error("failed to save api key", sl.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(resp.CodeInternal, "internal error"))
+			return
+		}
+
+		log.Info("api key created", slog.Int64("id", saved.ID), slog.String("prefix", saved.Prefix), slog.String("name", saved.Name))
+
+		render.JSON(w, r, Response{
+			Response:  resp.OK(),
+			ID:        saved.ID,
+			Name:      saved.Name,
+			Prefix:    saved.Prefix,
+			Key:       key,
+			CreatedAt: &saved.CreatedAt,
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package create_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/admin/keys/create"
+	"url-shortener/internal/http-server/handlers/admin/keys/create/mocks"
+	"url-shortener/internal/lib/apikey"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestCreateHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		body      string
+		wantName  string
+		mockError error
+		respCode  int
+		respError string
+	}{
+		{
+			name:     "Named key",
+			body:     `{"name": "ci"}`,
+			wantName: "ci",
+			respCode: http.StatusOK,
+		},
+		{
+			name:     "Without body",
+			respCode: http.StatusOK,
+		},
+		{
+			name:      "Invalid body",
+			body:      `{"name":`,
+			respCode:  http.StatusBadRequest,
+			respError: "failed to decode request",
+		},
+		{
+			name:      "Name too long",
+			body:      `{"name": "` + strings.Repeat("a", 101) + `"}`,
+			respCode:  http.StatusBadRequest,
+			respError: "field Name is not valid",
+		},
+		{
+			name:      "Storage error",
+			mockError: errors.New("unexpected error"),
+			respCode:  http.StatusInternalServerError,
+			respError: "internal error",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			saverMock := mocks.NewKeySaver(t)
+
+			var savedPrefix string
+			var savedHash []byte
+			if tc.respError == "" || tc.mockError != nil {
+				saverMock.On("SaveAPIKey", tc.wantName, mock.AnythingOfType("string"), mock.Anything).
+					Run(func(args mock.Arguments) {
+						savedPrefix = args.String(1)
+						savedHash = args.Get(2).([]byte)
+					}).
+					Return(func(name, prefix string, hash []byte) (storage.APIKey, error) {
+						return storage.APIKey{ID: 3, Name: name, Prefix: prefix, Hash: hash, CreatedAt: time.Now()}, tc.mockError
+					}).
+					Once()
+			}
+
+			var body io.Reader
+			if tc.body != "" {
+				body = strings.NewReader(tc.body)
+			}
+
+			rr := httptest.NewRecorder()
+			create.New(slogdiscard.NewDiscardLogger(), saverMock).
+				ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/admin/keys", body))
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var resp create.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+
+			if tc.respError != "" {
+				require.Empty(t, resp.Key)
+				return
+			}
+
+			// The key is returned once; only its hash reaches the storage.
+			require.Equal(t, int64(3), resp.ID)
+			require.Equal(t, tc.wantName, resp.Name)
+			require.Equal(t, savedPrefix, resp.Prefix)
+			require.NotContains(t, string(savedHash), resp.Key)
+			require.True(t, apikey.Verify(savedHash, resp.Key))
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package revoke
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+//go:generate go run github.com/vektra/mockery/v2@v2.53.7 --name=KeyDeleter
+
+// KeyDeleter is an interface for revoking an API key by its ID.
+type KeyDeleter interface {
+	DeleteAPIKey(id int64) error
+}
+
+// New revokes the API key with the id from the path. The key stops
+// working for the very next request.
+func New(log *slog.Logger, deleter KeyDeleter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.keys.revoke.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil || id <= 0 {
+			log.Info("invalid key id", slog.String("id", chi.URLParam(r, "id")))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(resp.CodeInvalidRequest, "invalid key id"))
+			return
+		}
+
+		err = deleter.DeleteAPIKey(id)
+		if errors.Is(err, storage.ErrAPIKeyNotFound) {
+			log.Info("api key not found", slog.Int64("id", id))
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, resp.Error(resp.CodeNotFound, "not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to revoke api key", slog.Int64("id", id), sl.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(resp.CodeInternal, "internal error"))
+			return
+		}
+
+		log.Info("api key revoked", slog.Int64("id", id))
+
+		render.JSON(w, r, resp.OK())
+	}
+}
@@ -0,0 +1,81 @@
+package revoke_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/admin/keys/revoke"
+	"url-shortener/internal/http-server/handlers/admin/keys/revoke/mocks"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestRevokeHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		id        string
+		mockError error
+		respCode  int
+		respError string
+	}{
+		{
+			name:     "Success",
+			id:       "3",
+			respCode: http.StatusOK,
+		},
+		{
+			name:      "Not found",
+			id:        "3",
+			mockError: storage.ErrAPIKeyNotFound,
+			respCode:  http.StatusNotFound,
+			respError: "not found",
+		},
+		{
+			name:      "Storage error",
+			id:        "3",
+			mockError: errors.New("unexpected error"),
+			respCode:  http.StatusInternalServerError,
+			respError: "internal error",
+		},
+		{
+			name:      "Invalid id",
+			id:        "abc",
+			respCode:  http.StatusBadRequest,
+			respError: "invalid key id",
+		},
+		{
+			name:      "Non-positive id",
+			id:        "0",
+			respCode:  http.StatusBadRequest,
+			respError: "invalid key id",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			deleterMock := mocks.NewKeyDeleter(t)
+			if tc.respCode != http.StatusBadRequest {
+				deleterMock.On("DeleteAPIKey", int64(3)).Return(tc.mockError).Once()
+			}
+
+			r := chi.NewRouter()
+			r.Delete("/admin/keys/{id}", revoke.New(slogdiscard.NewDiscardLogger(), deleterMock))
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/admin/keys/"+tc.id, nil))
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var body resp.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+			require.Equal(t, tc.respError, body.Error)
+		})
+	}
+}
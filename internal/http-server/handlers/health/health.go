@@ -0,0 +1,136 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+)
+
+// defaultTimeout bounds the storage checks when Options.Timeout is zero.
+const defaultTimeout = time.Second
+
+type Response struct {
+	resp.Response
+	Version string `json:"version"`
+	// Storage is only reported for ?verbose=true.
+	Storage *StorageStatus `json:"storage,omitempty"`
+}
+
+// StorageStatus is the result of the storage checks of ?verbose=true.
+type StorageStatus struct {
+	// LatencyMS is how long a trivial storage query took, in milliseconds.
+	LatencyMS float64 `json:"latency_ms"`
+	// TotalLinks is the number of links in all namespaces. It is omitted
+	// when the ping failed.
+	TotalLinks *int64 `json:"total_links,omitempty"`
+}
+
+// Options configures the health handler.
+type Options struct {
+	// Timeout bounds the storage checks of ?verbose=true together.
+	// Zero means defaultTimeout.
+	Timeout time.Duration
+}
+
+// StorageChecker is an interface for the storage checks of ?verbose=true.
+type StorageChecker interface {
+	Ping(ctx context.Context) error
+	CountURLs(ctx context.Context) (int64, error)
+}
+
+// New returns the /health handler. By default it answers without touching
+// the storage, which keeps liveness probes cheap. With ?verbose=true it also
+// pings the storage and counts the links, bounded by opts.Timeout, and
+// answers 503 when a check fails or times out. A nil checker makes verbose
+// requests answer like plain ones.
+func New(log *slog.Logger, version string, checker StorageChecker, opts Options) http.HandlerFunc {
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.health.New"
+
+		verbose := false
+		if v := r.URL.Query().Get("verbose"); v != "" {
+			var err error
+			verbose, err = strconv.ParseBool(v)
+			if err != nil {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, resp.Error(resp.CodeInvalidQuery, "query parameter verbose must be a boolean"))
+				return
+			}
+		}
+
+		if !verbose || checker == nil {
+			render.JSON(w, r, Response{
+				Response: resp.OK(),
+				Version:  version,
+			})
+			return
+		}
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		status, err := checkStorage(ctx, checker)
+		if err != nil {
+			log.Error("storage health check failed", slog.Float64("latency_ms", status.LatencyMS), sl.Err(err))
+
+			errResp := resp.Error(resp.CodeInternal, "storage check failed")
+			if errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				errResp = resp.Error(resp.CodeTimeout, "storage check timed out")
+			}
+
+			render.Status(r, http.StatusServiceUnavailable)
+			render.JSON(w, r, Response{
+				Response: errResp,
+				Version:  version,
+				Storage:  &status,
+			})
+			return
+		}
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Version:  version,
+			Storage:  &status,
+		})
+	}
+}
+
+// checkStorage pings the storage, measuring the latency, and then counts
+// the links. The latency is reported even when a check fails.
+func checkStorage(ctx context.Context, checker StorageChecker) (StorageStatus, error) {
+	var status StorageStatus
+
+	start := time.Now()
+	err := checker.Ping(ctx)
+	status.LatencyMS = float64(time.Since(start).Microseconds()) / 1000
+	if err != nil {
+		return status, err
+	}
+
+	count, err := checker.CountURLs(ctx)
+	if err != nil {
+		return status, err
+	}
+	status.TotalLinks = &count
+
+	return status, nil
+}
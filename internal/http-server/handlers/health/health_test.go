@@ -0,0 +1,126 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/health"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+// fakeChecker answers storage checks after delay, or when ctx is done, whichever is first.
+type fakeChecker struct {
+	delay   time.Duration
+	pingErr error
+	count   int64
+	pinged  bool
+}
+
+func (c *fakeChecker) Ping(ctx context.Context) error {
+	c.pinged = true
+
+	select {
+	case <-time.After(c.delay):
+		return c.pingErr
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *fakeChecker) CountURLs(_ context.Context) (int64, error) {
+	return c.count, nil
+}
+
+func TestHealth(t *testing.T) {
+	cases := []struct {
+		name       string
+		query      string
+		checker    *fakeChecker
+		wantCode   int
+		wantStatus string
+		errCode    string
+		wantLinks  *int64
+		wantPinged bool
+	}{
+		{
+			name:       "Plain request does not touch the storage",
+			checker:    &fakeChecker{count: 3},
+			wantCode:   http.StatusOK,
+			wantStatus: "OK",
+		},
+		{
+			name:       "Verbose reports the storage",
+			query:      "?verbose=true",
+			checker:    &fakeChecker{count: 3},
+			wantCode:   http.StatusOK,
+			wantStatus: "OK",
+			wantLinks:  ptr(int64(3)),
+			wantPinged: true,
+		},
+		{
+			name:       "Failed ping",
+			query:      "?verbose=1",
+			checker:    &fakeChecker{pingErr: errors.New("disk I/O error")},
+			wantCode:   http.StatusServiceUnavailable,
+			wantStatus: "Error",
+			errCode:    "INTERNAL_ERROR",
+			wantPinged: true,
+		},
+		{
+			name:       "Slow ping is cut by the timeout",
+			query:      "?verbose=true",
+			checker:    &fakeChecker{delay: time.Minute},
+			wantCode:   http.StatusServiceUnavailable,
+			wantStatus: "Error",
+			errCode:    "TIMEOUT",
+			wantPinged: true,
+		},
+		{
+			name:       "Invalid verbose",
+			query:      "?verbose=maybe",
+			checker:    &fakeChecker{},
+			wantCode:   http.StatusBadRequest,
+			wantStatus: "Error",
+			errCode:    "INVALID_QUERY",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := health.New(slogdiscard.NewDiscardLogger(), "v1.2.3", tc.checker, health.Options{
+				Timeout: 20 * time.Millisecond,
+			})
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/health"+tc.query, nil))
+
+			require.Equal(t, tc.wantCode, rr.Code)
+			require.Equal(t, tc.wantPinged, tc.checker.pinged)
+
+			var resp health.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.wantStatus, resp.Response.Response)
+			require.Equal(t, tc.errCode, resp.Code)
+
+			if !tc.wantPinged {
+				require.Nil(t, resp.Storage)
+				return
+			}
+
+			require.NotNil(t, resp.Storage)
+			require.Equal(t, "v1.2.3", resp.Version)
+			require.Equal(t, tc.wantLinks, resp.Storage.TotalLinks)
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
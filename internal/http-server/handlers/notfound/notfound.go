@@ -0,0 +1,73 @@
+package notfound
+
+import (
+	_ "embed"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/render"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+)
+
+//go:embed templates/error.html
+var errorHTML string
+
+var defaultTemplate = template.Must(template.New("error").Parse(errorHTML))
+
+// Page is the data passed to the error page template.
+type Page struct {
+	Code    int
+	Title   string
+	Message string
+	Path    string
+}
+
+// New answers requests that match no route, including dead short links:
+// browsers get an HTML page rendered from tmpl and API clients get JSON.
+// Nil tmpl means the built-in page.
+func New(log *slog.Logger, tmpl *template.Template) http.HandlerFunc {
+	return handler(log, tmpl, Page{
+		Code:    http.StatusNotFound,
+		Title:   "Link not found",
+		Message: "This short link does not exist. It may have been deleted or mistyped.",
+	}, resp.CodeNotFound, "not found")
+}
+
+// MethodNotAllowed answers requests whose path matches a route but whose
+// method does not, negotiating the response format like New.
+func MethodNotAllowed(log *slog.Logger, tmpl *template.Template) http.HandlerFunc {
+	return handler(log, tmpl, Page{
+		Code:    http.StatusMethodNotAllowed,
+		Title:   "Method not allowed",
+		Message: "This address does not support the requested method.",
+	}, resp.CodeMethodNotAllowed, "method not allowed")
+}
+
+func handler(log *slog.Logger, tmpl *template.Template, page Page, code, msg string) http.HandlerFunc {
+	if tmpl == nil {
+		tmpl = defaultTemplate
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Accept"), "text/html") {
+			render.Status(r, page.Code)
+			render.JSON(w, r, resp.Error(code, msg))
+
+			return
+		}
+
+		page := page
+		page.Path = r.URL.Path
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(page.Code)
+
+		if err := tmpl.Execute(w, page); err != nil {
+			log.Error("failed to render error page", slog.Int("code", page.Code), sl.Err(err))
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package notfound_test
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/notfound"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+func TestNotFound(t *testing.T) {
+	custom := template.Must(template.New("custom").Parse(`<p>Nothing at {{.Path}} ({{.Code}})</p>`))
+
+	cases := []struct {
+		name            string
+		method          string
+		path            string
+		accept          string
+		tmpl            *template.Template
+		wantCode        int
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			name:            "API client",
+			method:          http.MethodGet,
+			path:            "/missing/link",
+			accept:          "application/json",
+			wantCode:        http.StatusNotFound,
+			wantContentType: "application/json",
+			wantBody:        `"error":"not found"`,
+		},
+		{
+			name:            "Browser",
+			method:          http.MethodGet,
+			path:            "/missing/link",
+			accept:          "text/html,application/xhtml+xml,*/*;q=0.8",
+			wantCode:        http.StatusNotFound,
+			wantContentType: "text/html",
+			wantBody:        "Link not found",
+		},
+		{
+			name:            "Custom template",
+			method:          http.MethodGet,
+			path:            "/missing/link",
+			accept:          "text/html",
+			tmpl:            custom,
+			wantCode:        http.StatusNotFound,
+			wantContentType: "text/html",
+			wantBody:        "<p>Nothing at /missing/link (404)</p>",
+		},
+		{
+			name:            "Method not allowed",
+			method:          http.MethodPost,
+			path:            "/health",
+			wantCode:        http.StatusMethodNotAllowed,
+			wantContentType: "application/json",
+			wantBody:        `"error":"method not allowed"`,
+		},
+		{
+			name:            "Method not allowed in browser",
+			method:          http.MethodPost,
+			path:            "/health",
+			accept:          "text/html",
+			wantCode:        http.StatusMethodNotAllowed,
+			wantContentType: "text/html",
+			wantBody:        "Method not allowed",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			log := slogdiscard.NewDiscardLogger()
+
+			r := chi.NewRouter()
+			r.NotFound(notfound.New(log, tc.tmpl))
+			r.MethodNotAllowed(notfound.MethodNotAllowed(log, tc.tmpl))
+			r.Get("/health", func(w http.ResponseWriter, r *http.Request) {})
+
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			req.Header.Set("Accept", tc.accept)
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.wantCode, rr.Code)
+			require.Contains(t, rr.Header().Get("Content-Type"), tc.wantContentType)
+			require.Contains(t, rr.Body.String(), tc.wantBody)
+		})
+	}
+}
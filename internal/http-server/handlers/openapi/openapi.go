@@ -0,0 +1,33 @@
+package openapi
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// spec is the OpenAPI 3 document describing the HTTP API.
+// It is maintained by hand and must be updated together with the handlers.
+//
+//go:embed openapi.json
+var spec []byte
+
+//go:embed docs.html
+var docsPage []byte
+
+// New serves the OpenAPI document.
+func New() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(spec)
+	}
+}
+
+// Docs serves a Swagger UI page for the OpenAPI document.
+// The page loads openapi.json relative to its own URL, so it works
+// under any base path.
+func Docs() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write(docsPage)
+	}
+}
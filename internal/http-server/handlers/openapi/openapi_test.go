@@ -0,0 +1,41 @@
+package openapi_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/openapi"
+)
+
+func TestSpec(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+	rr := httptest.NewRecorder()
+
+	openapi.New().ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+	var spec struct {
+		OpenAPI string                    `json:"openapi"`
+		Paths   map[string]map[string]any `json:"paths"`
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &spec))
+
+	require.Equal(t, "3.0.3", spec.OpenAPI)
+
+	// Documented routes must match the ones registered in main.
+	for path, method := range map[string]string{
+		"/url":                "post",
+		"/url/{alias}":        "delete",
+		"/url/{alias}/rotate": "post",
+		"/{alias}":            "get",
+	} {
+		require.Contains(t, spec.Paths, path)
+		require.Contains(t, spec.Paths[path], method, path)
+	}
+}
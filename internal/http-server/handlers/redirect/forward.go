@@ -0,0 +1,121 @@
+package redirect
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"url-shortener/internal/storage"
+)
+
+// pathSuffixKey is the context key of the path after the alias on the
+// /{alias}/* route, in escaped form.
+type pathSuffixKey struct{}
+
+// forwardRoute serves the /{alias}/* route, which only path-forwarding
+// links answer. A bare trailing slash is left to the router, so that
+// /{alias}/ keeps answering 404 when trailing slashes are not stripped.
+func forwardRoute(handler http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		routed := chi.URLParam(r, "*")
+		if routed == "" {
+			routerNotFound(w, r)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), pathSuffixKey{}, pathTail(r, routed))
+		handler.ServeHTTP(w, r.WithContext(ctx))
+	}
+}
+
+// lookup finds the link of the request and, for path-forwarding links, the
+// path to forward. /{namespace}/{alias} also serves /{alias}/rest of a
+// path-forwarding link in the default namespace when the namespace has no
+// such link, since the two cannot be told apart by the route.
+func lookup(urlGetter URLRecordGetter, r *http.Request, namespace, alias string) (rec storage.URLRecord, suffix string, err error) {
+	suffix, forwarded := r.Context().Value(pathSuffixKey{}).(string)
+
+	rec, err = urlGetter.GetNamespacedURLRecord(r.Context(), namespace, alias)
+	if errors.Is(err, storage.ErrURLNotFound) && namespace != "" && !forwarded {
+		prefix, prefixErr := urlGetter.GetNamespacedURLRecord(r.Context(), "", namespace)
+		switch {
+		case prefixErr == nil && prefix.ForwardPath:
+			routed := alias
+			if strings.HasSuffix(r.URL.Path, "/") {
+				routed += "/"
+			}
+			return prefix, pathTail(r, routed), nil
+		case prefixErr != nil && !errors.Is(prefixErr, storage.ErrURLNotFound):
+			return storage.URLRecord{}, "", prefixErr
+		}
+	}
+	if err != nil {
+		return storage.URLRecord{}, "", err
+	}
+
+	// Other links do not serve the rest of a path.
+	if forwarded && !rec.ForwardPath {
+		return storage.URLRecord{}, "", storage.ErrURLNotFound
+	}
+
+	return rec, suffix, nil
+}
+
+// pathTail returns the part of the request path that routed, the routed
+// form of the end of the path, stands for. The routed form may be
+// unescaped and lack the extension that middleware.URLFormat cuts off, so
+// the same number of segments is taken from the escaped request path instead.
+func pathTail(r *http.Request, routed string) string {
+	escaped := r.URL.EscapedPath()
+
+	start := len(escaped)
+	for range strings.Count(routed, "/") + 1 {
+		start = strings.LastIndex(escaped[:start], "/")
+		if start < 0 {
+			return routed
+		}
+	}
+
+	return escaped[start+1:]
+}
+
+// forwardPath appends suffix, an escaped path, to the path of the
+// destination URL with a single slash between them, keeping the
+// destination's query and fragment and the suffix's trailing slash. Dot
+// segments are resolved within the suffix, so it cannot climb above the
+// destination's path. An empty suffix or a destination that does not parse
+// is returned unchanged.
+func forwardPath(target, suffix string) string {
+	if suffix == "" {
+		return target
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return target
+	}
+
+	clean := path.Clean("/" + suffix)
+	if strings.HasSuffix(suffix, "/") && clean != "/" {
+		clean += "/"
+	}
+
+	return u.JoinPath(clean).String()
+}
+
+// routerNotFound answers with the router's not found handler.
+func routerNotFound(w http.ResponseWriter, r *http.Request) {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		if router, ok := rctx.Routes.(interface{ NotFoundHandler() http.HandlerFunc }); ok {
+			router.NotFoundHandler()(w, r)
+			return
+		}
+	}
+
+	http.NotFound(w, r)
+}
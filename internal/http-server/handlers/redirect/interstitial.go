@@ -0,0 +1,116 @@
+package redirect
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/base64"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-chi/render"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+//go:embed templates/interstitial.html
+var interstitialHTML string
+
+var interstitialTemplate = template.Must(template.New("interstitial").Parse(interstitialHTML))
+
+// confirmParam is the query parameter carrying the confirm token of the
+// interstitial's continue link.
+const confirmParam = "confirm"
+
+// HostClassifier is an interface for telling trusted destinations from untrusted ones.
+type HostClassifier interface {
+	TrustedURL(rawURL string) bool
+}
+
+// InterstitialPage is the data passed to the interstitial template.
+type InterstitialPage struct {
+	Alias       string
+	URL         string
+	Host        string
+	ContinueURL string
+}
+
+// InterstitialResponse is returned to API clients for untrusted destinations
+// when Options.InterstitialJSON is set.
+type InterstitialResponse struct {
+	resp.Response
+	URL string `json:"url"`
+}
+
+// needsInterstitial reports whether the link must be confirmed before the
+// redirect: its destination is untrusted and the request carries no valid
+// confirm token.
+func needsInterstitial(r *http.Request, rec storage.URLRecord, opts Options) bool {
+	if opts.TrustedHosts == nil || opts.TrustedHosts.TrustedURL(rec.URL) {
+		return false
+	}
+
+	token := r.URL.Query().Get(confirmParam)
+
+	return token == "" || !hmac.Equal([]byte(token), []byte(confirmToken(opts.ConfirmSecret, rec)))
+}
+
+// responseInterstitial answers a request for an untrusted destination:
+// browsers get the confirmation page, API clients the destination in JSON.
+func responseInterstitial(log *slog.Logger, w http.ResponseWriter, r *http.Request, rec storage.URLRecord, opts Options) {
+	// The page depends on the confirm token, so it must not be cached in place of the redirect.
+	w.Header().Set("Cache-Control", "no-store")
+
+	if !wantsHTML(r) {
+		render.JSON(w, r, InterstitialResponse{
+			Response: resp.OK(),
+			URL:      rec.URL,
+		})
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	page := InterstitialPage{
+		Alias:       rec.Alias,
+		URL:         rec.URL,
+		ContinueURL: continueURL(r, confirmToken(opts.ConfirmSecret, rec)),
+	}
+	if u, err := url.Parse(rec.URL); err == nil {
+		page.Host = u.Hostname()
+	}
+
+	if err := interstitialTemplate.Execute(w, page); err != nil {
+		log.Error("failed to render interstitial page", sl.Err(err))
+	}
+}
+
+// continueURL is the request URL with the confirm token. The rest of the
+// query is kept as is, so a forwarded query reaches the destination and the
+// confirmed request produces the same destination the token was signed for.
+func continueURL(r *http.Request, token string) string {
+	query := append(queryParts(r.URL.RawQuery, confirmParam), url.Values{confirmParam: {token}}.Encode())
+
+	return r.URL.Path + "?" + strings.Join(query, "&")
+}
+
+// confirmToken signs the link with secret. The token is bound to the
+// destination, so a confirmation does not carry over to another link or
+// to the same alias pointing elsewhere.
+func confirmToken(secret []byte, rec storage.URLRecord) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(rec.Namespace + "/" + rec.Alias + "\n" + rec.URL))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
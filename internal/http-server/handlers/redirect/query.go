@@ -0,0 +1,68 @@
+package redirect
+
+import (
+	"net/url"
+	"strings"
+)
+
+// forwardQuery merges the query string of the incoming request into the
+// destination URL, so /promo?ref=twitter reaches the destination with
+// ref=twitter.
+//
+// On a key collision the destination's own value wins: the link owner
+// decides what the destination receives, and visitors cannot override
+// parameters such as UTM tags baked into the link. Incoming parameters are
+// appended after the destination's in their original order and encoding.
+// The confirm token of the interstitial page is never forwarded. A
+// destination that does not parse is returned unchanged.
+func forwardQuery(target, rawQuery string) string {
+	incoming := queryParts(rawQuery, confirmParam)
+	if len(incoming) == 0 {
+		return target
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return target
+	}
+
+	own := u.Query()
+
+	query := queryParts(u.RawQuery, "")
+	for _, part := range incoming {
+		if _, taken := own[partKey(part)]; taken {
+			continue
+		}
+
+		query = append(query, part)
+	}
+
+	u.RawQuery = strings.Join(query, "&")
+
+	return u.String()
+}
+
+// queryParts splits a raw query string into its key=value parts, keeping
+// their encoding and dropping empty parts and those named skip.
+func queryParts(rawQuery, skip string) []string {
+	var parts []string
+	for _, part := range strings.Split(rawQuery, "&") {
+		if part == "" || (skip != "" && partKey(part) == skip) {
+			continue
+		}
+
+		parts = append(parts, part)
+	}
+
+	return parts
+}
+
+// partKey returns the unescaped key of a key=value query part.
+func partKey(part string) string {
+	key, _, _ := strings.Cut(part, "=")
+	if unescaped, err := url.QueryUnescape(key); err == nil {
+		return unescaped
+	}
+
+	return key
+}
@@ -1,30 +1,183 @@
 package redirect
 
 import (
+	"context"
+	_ "embed"
 	"errors"
+	"fmt"
+	"html/template"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 	"log/slog"
 
+	"url-shortener/internal/lib/api"
 	resp "url-shortener/internal/lib/api/response"
 	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/redirectheaders"
 	"url-shortener/internal/storage"
 )
 
-// URLGetter is an interface for getting url by alias.
+//go:embed templates/expired.html
+var expiredHTML string
+
+var defaultExpiredTemplate = template.Must(template.New("expired").Parse(expiredHTML))
+
+//go:embed templates/disabled.html
+var disabledHTML string
+
+var disabledTemplate = template.Must(template.New("disabled").Parse(disabledHTML))
+
+//go:embed templates/redirect.html
+var redirectHTML string
+
+var defaultRedirectTemplate = template.Must(template.New("redirect").Parse(redirectHTML))
+
+// Redirect modes for Options.Mode.
+const (
+	// ModeHTTP answers with a 301 or 302 and a Location header.
+	ModeHTTP = "http"
+	// ModeHTML answers 200 with an HTML page that moves on with a meta
+	// refresh, a script and a clickable link, for clients behind proxies
+	// that mangle Location headers.
+	ModeHTML = "html"
+)
+
+// URLRecordGetter is an interface for getting url record by namespace and alias.
 //
-//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=URLGetter
-type URLGetter interface {
-	GetURL(alias string) (string, error)
+//go:generate go run github.com/vektra/mockery/v2@v2.53.7 --name=URLRecordGetter
+type URLRecordGetter interface {
+	GetNamespacedURLRecord(ctx context.Context, namespace, alias string) (storage.URLRecord, error)
+}
+
+// Options configures redirect behaviour.
+type Options struct {
+	// Permanent is used for links that have no explicit permanent flag.
+	Permanent bool
+	// PreserveMethod is used for links that have no explicit preserve_method
+	// flag. Such links are redirected with 307 or 308 instead of 302 or 301,
+	// which clients must follow with the same method and body, and they
+	// also redirect POST, PUT, PATCH and DELETE; other links answer those
+	// with 405. 301 and 302 suit links opened in browsers, 307 and 308 API
+	// endpoints that clients call with a body.
+	PreserveMethod bool
+	// CacheTTL is the max-age sent with permanent redirects.
+	CacheTTL time.Duration
+	// ExpiredTemplate renders the page shown to browsers for expired links.
+	// It receives an ExpiredPage. Nil means the built-in page.
+	ExpiredTemplate *template.Template
+	// AccessRecorder is notified of every served redirect. Nil disables tracking.
+	AccessRecorder AccessRecorder
+	// NotFound answers requests for aliases that do not exist.
+	// Nil means a JSON "not found" error.
+	NotFound http.Handler
+	// LogTarget adds the destination URL to the log entry of every served redirect.
+	// Destinations may carry personal data, so by default only the alias is logged.
+	LogTarget bool
+	// ClickCounter counts clicks of links with max_clicks and enforces the limit.
+	// Clicks of unlimited links are counted by AccessRecorder in batches, or by
+	// ClickCounter when AccessRecorder is nil.
+	// Nil means stored click counts are still honored but never incremented.
+	ClickCounter ClickCounter
+	// Notifier is told about every served redirect. Nil disables notifications.
+	Notifier RedirectNotifier
+	// SlowThreshold logs a warning for requests whose handling by this
+	// handler, the storage lookup plus writing the response, takes longer.
+	// The lookup time is logged separately to tell storage latency from the
+	// rest. Zero disables the log.
+	SlowThreshold time.Duration
+	// Mode is ModeHTTP or ModeHTML. Empty means ModeHTTP.
+	Mode string
+	// RedirectTemplate renders the ModeHTML page. It receives a RedirectPage.
+	// Nil means the built-in page.
+	RedirectTemplate *template.Template
+	// TrustedHosts classifies link destinations. Browsers following a link
+	// to an untrusted destination get a "you are leaving" confirmation page
+	// whose continue link carries a confirm token signed with ConfirmSecret.
+	// Nil means every destination is trusted.
+	TrustedHosts HostClassifier
+	// ConfirmSecret signs confirm tokens. It must be set with TrustedHosts.
+	ConfirmSecret []byte
+	// InterstitialJSON makes API clients get 200 with the untrusted
+	// destination in JSON instead of being redirected to it.
+	InterstitialJSON bool
+	// ForwardQuery merges the query string of the request into the
+	// destination URL; parameters the destination already has keep their
+	// value. Off means the request query is ignored.
+	ForwardQuery bool
+	// Headers are set on every redirect, such as Referrer-Policy. A link's
+	// own headers override them. They must have passed
+	// redirectheaders.Validate. Nil means no extra headers.
+	Headers map[string]string
+}
+
+// RedirectPage is the data passed to the ModeHTML redirect template.
+type RedirectPage struct {
+	Alias string
+	URL   string
+}
+
+// RedirectNotifier is an interface for announcing served redirects.
+// LinkRedirected must not block: it is called on the redirect hot path.
+type RedirectNotifier interface {
+	LinkRedirected(namespace, alias, target string)
+}
+
+// ClickCounter is an interface for counting link clicks. For links with a
+// click limit ConsumeClick returns storage.ErrClicksExhausted when no clicks are left.
+type ClickCounter interface {
+	ConsumeClick(id int64) error
+}
+
+// AccessRecorder is an interface for recording link accesses.
+// RecordClick also counts a click of a link without a click limit.
+// Neither method may block: they are called on the redirect hot path.
+type AccessRecorder interface {
+	Record(id int64, at time.Time)
+	RecordClick(id int64, at time.Time)
 }
 
-func New(log *slog.Logger, urlGetter URLGetter) http.HandlerFunc {
+// ExpiredPage is the data passed to the expired-link template.
+type ExpiredPage struct {
+	Alias     string
+	ExpiresAt *time.Time
+}
+
+// ExpiredResponse is returned to API clients for expired links.
+type ExpiredResponse struct {
+	resp.Response
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// New redirects to the link destination. It serves both GET and HEAD: HEAD
+// gets the same status and headers without a body and is not recorded as
+// an access, so monitoring tools can check links without skewing usage.
+// Links that preserve the method (see Options.PreserveMethod) serve the
+// methods in preservedMethods as well. Path-forwarding links
+// (storage.URLRecord.ForwardPath) append the rest of the request path to
+// their destination, /docs/intro leading to https://example.com/guide/intro
+// for a docs link to https://example.com/guide, and forward the request
+// query like Options.ForwardQuery; they need the routes of Register.
+func New(log *slog.Logger, urlGetter URLRecordGetter, opts Options) http.HandlerFunc {
+	expiredTemplate := opts.ExpiredTemplate
+	if expiredTemplate == nil {
+		expiredTemplate = defaultExpiredTemplate
+	}
+
+	redirectTemplate := opts.RedirectTemplate
+	if redirectTemplate == nil {
+		redirectTemplate = defaultRedirectTemplate
+	}
+
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.url.redirect.New"
 
+		start := time.Now()
+
 		log := log.With(
 			slog.String("op", op),
 			slog.String("request_id", middleware.GetReqID(r.Context())),
@@ -34,30 +187,347 @@ func New(log *slog.Logger, urlGetter URLGetter) http.HandlerFunc {
 		if alias == "" {
 			log.Info("alias is empty")
 
-			render.JSON(w, r, resp.Error("invalid request"))
+			render.JSON(w, r, resp.Error(resp.CodeInvalidRequest, "invalid request"))
 
 			return
 		}
 
-		resURL, err := urlGetter.GetURL(alias)
+		// namespace is empty for the flat /{alias} route, which serves the default namespace.
+		namespace := chi.URLParam(r, "namespace")
+
+		// suffix is the path after the alias that a path-forwarding link appends to its destination.
+		rec, suffix, err := lookup(urlGetter, r, namespace, alias)
+
+		if opts.SlowThreshold > 0 {
+			lookup := time.Since(start)
+			defer func() {
+				if elapsed := time.Since(start); elapsed > opts.SlowThreshold {
+					log.Warn("slow redirect",
+						slog.String("namespace", namespace),
+						slog.String("alias", alias),
+						slog.Duration("duration", elapsed),
+						slog.Duration("storage_duration", lookup),
+						slog.Duration("threshold", opts.SlowThreshold),
+					)
+				}
+			}()
+		}
 		if errors.Is(err, storage.ErrURLNotFound) {
-			log.Info("url not found", "alias", alias)
+			log.Info("url not found", slog.String("namespace", namespace), slog.String("alias", alias))
+
+			if opts.NotFound != nil {
+				opts.NotFound.ServeHTTP(w, r)
+				return
+			}
 
-			render.JSON(w, r, resp.Error("not found"))
+			render.JSON(w, r, resp.Error(resp.CodeNotFound, "not found"))
+
+			return
+		}
+		if err != nil && errors.Is(r.Context().Err(), context.DeadlineExceeded) {
+			// The timeout middleware answers with 504 when the handler returns without a response.
+			log.Warn("url lookup timed out", sl.Err(err))
 
 			return
 		}
 		if err != nil {
 			log.Error("failed to get url", sl.Err(err))
 
-			render.JSON(w, r, resp.Error("internal error"))
+			render.JSON(w, r, resp.Error(resp.CodeInternal, "internal error"))
 
 			return
 		}
 
-		log.Info("got url", slog.String("url", resURL))
+		preserveMethod := isPreserveMethod(rec, opts)
+
+		// A 301 or 302 would turn the request into a GET, so other methods are only redirected by links that keep them.
+		if !preserveMethod && r.Method != http.MethodGet && r.Method != http.MethodHead {
+			log.Info("method not allowed for link", slog.String("alias", alias), slog.String("method", r.Method))
+
+			w.Header().Set("Allow", "GET, HEAD")
+			render.Status(r, http.StatusMethodNotAllowed)
+			render.JSON(w, r, resp.Error(resp.CodeMethodNotAllowed, "method not allowed"))
+
+			return
+		}
+
+		// Disabled links keep their data but are not served until enabled again.
+		if rec.Disabled {
+			log.Info("url disabled", slog.String("namespace", namespace), slog.String("alias", alias))
+
+			responseDisabled(log, w, r, rec)
+
+			return
+		}
+
+		now := time.Now()
+
+		if rec.Expired(now) {
+			log.Info("url expired", slog.String("alias", alias), slog.Time("expires_at", *rec.ExpiresAt))
+
+			responseGone(log, w, r, rec, expiredTemplate, resp.CodeLinkExpired, "link expired")
+
+			return
+		}
+
+		// From here on rec.URL is the destination actually served, including the forwarded path and query.
+		// Path-forwarding links stand in for a whole subtree of the destination, so they always forward the query.
+		if rec.ForwardPath {
+			rec.URL = forwardPath(rec.URL, suffix)
+		}
+		if opts.ForwardQuery || rec.ForwardPath {
+			rec.URL = forwardQuery(rec.URL, r.URL.RawQuery)
+		}
+
+		// Links saved before destinations were limited to http and https may point
+		// elsewhere, e.g. to javascript: URLs that the HTML page would run.
+		if !api.IsHTTPURL(rec.URL) {
+			log.Warn("unsafe destination scheme", slog.String("namespace", rec.Namespace), slog.String("alias", rec.Alias))
+
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, resp.Error(resp.CodeInvalidURL, "link destination is not an http or https URL"))
+
+			return
+		}
+
+		// Untrusted destinations are confirmed first; the click is counted on the confirmed request.
+		if needsInterstitial(r, rec, opts) && (wantsHTML(r) || opts.InterstitialJSON) {
+			log.Info("untrusted destination, confirmation required", slog.String("namespace", rec.Namespace), slog.String("alias", rec.Alias))
+
+			responseInterstitial(log, w, r, rec, opts)
+
+			return
+		}
+
+		if rec.MaxClicks > 0 {
+			// HEAD requests only check the link, so they do not consume clicks.
+			if !rec.Exhausted() && r.Method != http.MethodHead && opts.ClickCounter != nil {
+				err = opts.ClickCounter.ConsumeClick(rec.ID)
+			}
+			if rec.Exhausted() || errors.Is(err, storage.ErrClicksExhausted) {
+				log.Info("url clicks exhausted", slog.String("alias", alias), slog.Int64("max_clicks", rec.MaxClicks))
+
+				responseGone(log, w, r, rec, expiredTemplate, resp.CodeLinkExhausted, "link exhausted")
+
+				return
+			}
+			if err != nil {
+				log.Error("failed to consume click", sl.Err(err))
+
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, resp.Error(resp.CodeInternal, "internal error"))
+
+				return
+			}
+		} else if r.Method != http.MethodHead && opts.AccessRecorder == nil && opts.ClickCounter != nil {
+			// Clicks of unlimited links only feed statistics, so a failed update does not fail the redirect.
+			// With an AccessRecorder they are counted in batches below instead.
+			if err := opts.ClickCounter.ConsumeClick(rec.ID); err != nil {
+				log.Error("failed to count click", sl.Err(err))
+			}
+		}
+
+		permanent := isPermanent(rec, opts)
+
+		// The HTML page is always followed with GET, so links that keep the method are redirected with a status.
+		htmlPage := opts.Mode == ModeHTML && !preserveMethod
+
+		status := statusCode(permanent, preserveMethod)
+		if htmlPage {
+			status = http.StatusOK
+		}
+
+		attrs := []any{
+			slog.String("namespace", rec.Namespace),
+			slog.String("alias", rec.Alias),
+			slog.Int("status", status),
+		}
+		if opts.LogTarget {
+			attrs = append(attrs, slog.String("url", rec.URL))
+		}
+		log.Info("redirect served", attrs...)
+
+		w.Header().Set("Cache-Control", cacheControl(permanent, rec, opts, now))
+		if modified := lastModified(rec); !modified.IsZero() {
+			w.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+		}
+		redirectheaders.Apply(w.Header(), opts.Headers, rec.Headers)
+
+		// HEAD requests only check the link, so they are not recorded as accesses.
+		if opts.AccessRecorder != nil && r.Method != http.MethodHead {
+			if rec.MaxClicks > 0 {
+				opts.AccessRecorder.Record(rec.ID, now)
+			} else {
+				opts.AccessRecorder.RecordClick(rec.ID, now)
+			}
+		}
+		if opts.Notifier != nil && r.Method != http.MethodHead {
+			opts.Notifier.LinkRedirected(rec.Namespace, rec.Alias, rec.URL)
+		}
+
+		if htmlPage {
+			responseHTML(log, w, r, rec, redirectTemplate)
+			return
+		}
 
 		// redirect to found url
-		http.Redirect(w, r, resURL, http.StatusFound)
+		http.Redirect(w, r, rec.URL, status)
+	}
+}
+
+// responseHTML answers 200 with the redirect page instead of a Location header.
+func responseHTML(log *slog.Logger, w http.ResponseWriter, r *http.Request, rec storage.URLRecord, tmpl *template.Template) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	if err := tmpl.Execute(w, RedirectPage{Alias: rec.Alias, URL: rec.URL}); err != nil {
+		log.Error("failed to render redirect page", sl.Err(err))
+	}
+}
+
+// preservedMethods are the methods besides GET and HEAD that links with
+// preserve_method redirect.
+var preservedMethods = []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// Register mounts handler on the redirect routes: /{alias} for the default
+// namespace, /{namespace}/{alias} for the others and /{alias}/* for
+// path-forwarding links, for GET, HEAD and preservedMethods.
+// With stripTrailingSlash a single trailing slash is accepted as well, so a
+// copied /myalias/ resolves like /myalias. Static routes such as /url keep
+// precedence over these patterns in chi, so they are not shadowed.
+func Register(r chi.Router, handler http.Handler, stripTrailingSlash bool) {
+	patterns := []string{"/{alias}", "/{namespace}/{alias}"}
+	if stripTrailingSlash {
+		patterns = append(patterns, "/{alias}/", "/{namespace}/{alias}/")
+	}
+
+	register := func(pattern string, h http.Handler) {
+		r.Method(http.MethodGet, pattern, h)
+		r.Method(http.MethodHead, pattern, h)
+		for _, method := range preservedMethods {
+			r.Method(method, pattern, h)
+		}
+	}
+
+	for _, pattern := range patterns {
+		register(pattern, handler)
+	}
+	// Paths of two segments match /{namespace}/{alias} first; see lookup.
+	register("/{alias}/*", forwardRoute(handler))
+}
+
+// responseGone answers 410 Gone for expired and exhausted links: an HTML
+// page for browsers and JSON with code and msg for API clients.
+func responseGone(
+	log *slog.Logger,
+	w http.ResponseWriter,
+	r *http.Request,
+	rec storage.URLRecord,
+	tmpl *template.Template,
+	code, msg string,
+) {
+	if !wantsHTML(r) {
+		render.Status(r, http.StatusGone)
+		render.JSON(w, r, ExpiredResponse{
+			Response:  resp.Error(code, msg),
+			ExpiresAt: rec.ExpiresAt,
+		})
+
+		return
 	}
-}
\ No newline at end of file
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusGone)
+
+	if err := tmpl.Execute(w, ExpiredPage{Alias: rec.Alias, ExpiresAt: rec.ExpiresAt}); err != nil {
+		log.Error("failed to render expired page", sl.Err(err))
+	}
+}
+
+// responseDisabled answers 404 Not Found for disabled links: a "temporarily
+// disabled" page for browsers and a JSON error for API clients.
+func responseDisabled(log *slog.Logger, w http.ResponseWriter, r *http.Request, rec storage.URLRecord) {
+	if !wantsHTML(r) {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, resp.Error(resp.CodeLinkDisabled, "link disabled"))
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusNotFound)
+
+	if err := disabledTemplate.Execute(w, ExpiredPage{Alias: rec.Alias}); err != nil {
+		log.Error("failed to render disabled page", sl.Err(err))
+	}
+}
+
+// wantsHTML reports whether the client prefers an HTML page, i.e. is a browser.
+func wantsHTML(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/html")
+}
+
+// lastModified returns the link's creation time, or the zero time when it is
+// unknown. The destination and redirect options are fixed at creation, so the
+// last access time, which changes on every click, would only defeat caches.
+func lastModified(rec storage.URLRecord) time.Time {
+	if rec.CreatedAt == nil {
+		return time.Time{}
+	}
+
+	return *rec.CreatedAt
+}
+
+// isPermanent reports whether the link should be redirected permanently.
+func isPermanent(rec storage.URLRecord, opts Options) bool {
+	if rec.Permanent != nil {
+		return *rec.Permanent
+	}
+
+	return opts.Permanent
+}
+
+// isPreserveMethod reports whether the link should keep the request method across the redirect.
+func isPreserveMethod(rec storage.URLRecord, opts Options) bool {
+	if rec.PreserveMethod != nil {
+		return *rec.PreserveMethod
+	}
+
+	return opts.PreserveMethod
+}
+
+// statusCode returns 301 for permanent links and 302 otherwise, or 308 and
+// 307 for links that keep the request method.
+func statusCode(permanent, preserveMethod bool) int {
+	switch {
+	case permanent && preserveMethod:
+		return http.StatusPermanentRedirect
+	case preserveMethod:
+		return http.StatusTemporaryRedirect
+	case permanent:
+		return http.StatusMovedPermanently
+	default:
+		return http.StatusFound
+	}
+}
+
+// cacheControl forbids caching temporary redirects without revalidation
+// and lets permanent ones be cached for the configured TTL, but never
+// longer than the link has left to live. Links with a click limit are never
+// cached, since a cached redirect would bypass the click count.
+func cacheControl(permanent bool, rec storage.URLRecord, opts Options, now time.Time) string {
+	if !permanent || opts.CacheTTL <= 0 || rec.MaxClicks > 0 {
+		return "no-cache"
+	}
+
+	maxAge := opts.CacheTTL
+	if rec.ExpiresAt != nil {
+		maxAge = min(maxAge, rec.ExpiresAt.Sub(now))
+	}
+
+	return fmt.Sprintf("public, max-age=%d", int64(maxAge.Seconds()))
+}
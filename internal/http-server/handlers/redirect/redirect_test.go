@@ -1,17 +1,38 @@
 package redirect_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"log/slog"
+	"net/http"
 	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"gopkg.in/go-playground/assert.v1"
 
 	"url-shortener/internal/http-server/handlers/redirect"
 	"url-shortener/internal/http-server/handlers/redirect/mocks"
+	"url-shortener/internal/http-server/handlers/url/save"
+	"url-shortener/internal/http-server/middleware/timeout"
 	"url-shortener/internal/lib/api"
 	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/lib/random"
+	"url-shortener/internal/lib/trustedhosts"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/sqlite"
 )
 
 func TestSaveHandler(t *testing.T) {
@@ -31,15 +52,15 @@ func TestSaveHandler(t *testing.T) {
 
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
-			urlGetterMock := mocks.NewURLGetter(t)
+			urlGetterMock := mocks.NewURLRecordGetter(t)
 
 			if tc.respError == "" || tc.mockError != nil {
-				urlGetterMock.On("GetURL", tc.alias).
-					Return(tc.url, tc.mockError).Once()
+				urlGetterMock.On("GetNamespacedURLRecord", mock.Anything, "", tc.alias).
+					Return(storage.URLRecord{Alias: tc.alias, URL: tc.url}, tc.mockError).Once()
 			}
 
 			r := chi.NewRouter()
-			r.Get("/{alias}", redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock))
+			r.Get("/{alias}", redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock, redirect.Options{}))
 
 			ts := httptest.NewServer(r)
 			defer ts.Close()
@@ -51,4 +72,1148 @@ func TestSaveHandler(t *testing.T) {
 			urlGetterMock.AssertExpectations(t) // Проверка вызова мока
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestRedirectStatusCode(t *testing.T) {
+	yes, no := true, false
+
+	cases := []struct {
+		name                  string
+		method                string
+		mode                  string
+		permanent             *bool
+		defaultPermanent      bool
+		preserveMethod        *bool
+		defaultPreserveMethod bool
+		wantCode              int
+		wantCacheControl      string
+	}{
+		{
+			name:             "Default temporary",
+			wantCode:         http.StatusFound,
+			wantCacheControl: "no-cache",
+		},
+		{
+			name:             "Default permanent",
+			defaultPermanent: true,
+			wantCode:         http.StatusMovedPermanently,
+			wantCacheControl: "public, max-age=3600",
+		},
+		{
+			name:             "Permanent link",
+			permanent:        &yes,
+			wantCode:         http.StatusMovedPermanently,
+			wantCacheControl: "public, max-age=3600",
+		},
+		{
+			name:             "Temporary link overrides permanent default",
+			permanent:        &no,
+			defaultPermanent: true,
+			wantCode:         http.StatusFound,
+			wantCacheControl: "no-cache",
+		},
+		{
+			name:                  "Default preserve method",
+			defaultPreserveMethod: true,
+			wantCode:              http.StatusTemporaryRedirect,
+			wantCacheControl:      "no-cache",
+		},
+		{
+			name:                  "Default preserve method and permanent",
+			defaultPermanent:      true,
+			defaultPreserveMethod: true,
+			wantCode:              http.StatusPermanentRedirect,
+			wantCacheControl:      "public, max-age=3600",
+		},
+		{
+			name:             "Link preserving method",
+			permanent:        &yes,
+			preserveMethod:   &yes,
+			wantCode:         http.StatusPermanentRedirect,
+			wantCacheControl: "public, max-age=3600",
+		},
+		{
+			name:                  "Link overrides preserve method default",
+			preserveMethod:        &no,
+			defaultPreserveMethod: true,
+			wantCode:              http.StatusFound,
+			wantCacheControl:      "no-cache",
+		},
+		{
+			name:             "POST to link preserving method",
+			method:           http.MethodPost,
+			preserveMethod:   &yes,
+			wantCode:         http.StatusTemporaryRedirect,
+			wantCacheControl: "no-cache",
+		},
+		{
+			name:     "POST to link not preserving method",
+			method:   http.MethodPost,
+			wantCode: http.StatusMethodNotAllowed,
+		},
+		{
+			name:             "HTML mode does not apply to link preserving method",
+			mode:             redirect.ModeHTML,
+			preserveMethod:   &yes,
+			wantCode:         http.StatusTemporaryRedirect,
+			wantCacheControl: "no-cache",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			const alias, url = "testalias", "https://www.google.com/"
+
+			method := tc.method
+			if method == "" {
+				method = http.MethodGet
+			}
+
+			urlGetterMock := mocks.NewURLRecordGetter(t)
+			urlGetterMock.On("GetNamespacedURLRecord", mock.Anything, "", alias).
+				Return(storage.URLRecord{Alias: alias, URL: url, Permanent: tc.permanent, PreserveMethod: tc.preserveMethod}, nil).Once()
+
+			r := chi.NewRouter()
+			redirect.Register(r, redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock, redirect.Options{
+				Permanent:      tc.defaultPermanent,
+				PreserveMethod: tc.defaultPreserveMethod,
+				CacheTTL:       time.Hour,
+				Mode:           tc.mode,
+			}), false)
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(method, "/"+alias, strings.NewReader(`{"a":1}`)))
+
+			require.Equal(t, tc.wantCode, rr.Code)
+			if tc.wantCode == http.StatusMethodNotAllowed {
+				require.Empty(t, rr.Header().Get("Location"))
+				require.Equal(t, "GET, HEAD", rr.Header().Get("Allow"))
+				return
+			}
+			require.Equal(t, url, rr.Header().Get("Location"))
+			require.Equal(t, tc.wantCacheControl, rr.Header().Get("Cache-Control"))
+		})
+	}
+}
+
+// TestRedirectGeneratedAlias saves links with aliases generated from an
+// alphabet full of punctuation and follows them through a router that uses
+// middleware.URLFormat, as main does: every character an alphabet may hold
+// must survive routing.
+func TestRedirectGeneratedAlias(t *testing.T) {
+	log := slogdiscard.NewDiscardLogger()
+
+	st, err := sqlite.New(log, filepath.Join(t.TempDir(), "storage.db"), sqlite.Options{})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = st.Close() })
+
+	gen, err := random.NewGeneratorWithAlphabet("ab-_~")
+	require.NoError(t, err)
+
+	r := chi.NewRouter()
+	r.Use(middleware.URLFormat)
+	r.Post("/url", save.New(log, st, save.Options{AliasGenerator: gen}))
+	redirect.Register(r, redirect.New(log, st, redirect.Options{}), true)
+
+	for i := range 20 {
+		target := fmt.Sprintf("https://example.com/%d", i)
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/url", strings.NewReader(`{"url":"`+target+`"}`)))
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		var res save.Response
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+		require.NotEmpty(t, res.Alias)
+
+		rr = httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/"+res.Alias, nil))
+		require.Equal(t, http.StatusFound, rr.Code, res.Alias)
+		require.Equal(t, target, rr.Header().Get("Location"), res.Alias)
+	}
+}
+
+func TestRedirectExpired(t *testing.T) {
+	const alias, url = "testalias", "https://www.google.com/"
+
+	expiresAt := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name            string
+		accept          string
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			name:            "API client",
+			accept:          "application/json",
+			wantContentType: "application/json",
+			wantBody:        `"expires_at":"2024-03-01T12:00:00Z"`,
+		},
+		{
+			name:            "Browser",
+			accept:          "text/html,application/xhtml+xml,*/*;q=0.8",
+			wantContentType: "text/html",
+			wantBody:        "1 Mar 2024 12:00 UTC",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlGetterMock := mocks.NewURLRecordGetter(t)
+			urlGetterMock.On("GetNamespacedURLRecord", mock.Anything, "", alias).
+				Return(storage.URLRecord{Alias: alias, URL: url, ExpiresAt: &expiresAt}, nil).Once()
+
+			r := chi.NewRouter()
+			r.Get("/{alias}", redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock, redirect.Options{}))
+
+			req := httptest.NewRequest(http.MethodGet, "/"+alias, nil)
+			req.Header.Set("Accept", tc.accept)
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusGone, rr.Code)
+			require.Empty(t, rr.Header().Get("Location"))
+			require.Contains(t, rr.Header().Get("Content-Type"), tc.wantContentType)
+			require.Contains(t, rr.Body.String(), tc.wantBody)
+		})
+	}
+}
+
+func TestRedirectDisabled(t *testing.T) {
+	const alias, url = "testalias", "https://www.google.com/"
+
+	cases := []struct {
+		name            string
+		accept          string
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			name:            "API client",
+			accept:          "application/json",
+			wantContentType: "application/json",
+			wantBody:        `"error":"link disabled"`,
+		},
+		{
+			name:            "Browser",
+			accept:          "text/html,application/xhtml+xml,*/*;q=0.8",
+			wantContentType: "text/html",
+			wantBody:        "temporarily disabled",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlGetterMock := mocks.NewURLRecordGetter(t)
+			urlGetterMock.On("GetNamespacedURLRecord", mock.Anything, "", alias).
+				Return(storage.URLRecord{ID: 1, Alias: alias, URL: url, Disabled: true}, nil).Once()
+
+			r := chi.NewRouter()
+			r.Get("/{alias}", redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock, redirect.Options{
+				// A disabled link is not a click.
+				ClickCounter: clickCounterFunc(func(int64) error {
+					t.Fatal("click counted for disabled link")
+					return nil
+				}),
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/"+alias, nil)
+			req.Header.Set("Accept", tc.accept)
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusNotFound, rr.Code)
+			require.Empty(t, rr.Header().Get("Location"))
+			require.Contains(t, rr.Header().Get("Content-Type"), tc.wantContentType)
+			require.Contains(t, rr.Body.String(), tc.wantBody)
+			require.NotContains(t, rr.Body.String(), url)
+		})
+	}
+}
+
+func TestRegisterTrailingSlash(t *testing.T) {
+	const url = "https://www.google.com/"
+
+	cases := []struct {
+		name      string
+		method    string
+		path      string
+		strip     bool
+		namespace string
+		alias     string
+		wantCode  int
+	}{
+		{name: "Alias", method: http.MethodGet, path: "/myalias", strip: true, alias: "myalias", wantCode: http.StatusFound},
+		{name: "Alias with trailing slash", method: http.MethodGet, path: "/myalias/", strip: true, alias: "myalias", wantCode: http.StatusFound},
+		{name: "HEAD with trailing slash", method: http.MethodHead, path: "/myalias/", strip: true, alias: "myalias", wantCode: http.StatusFound},
+		{name: "Namespaced with trailing slash", method: http.MethodGet, path: "/team/home/", strip: true, namespace: "team", alias: "home", wantCode: http.StatusFound},
+		{name: "Trailing slash not stripped", method: http.MethodGet, path: "/myalias/", strip: false, wantCode: http.StatusNotFound},
+		{name: "Alias without slash when not stripping", method: http.MethodGet, path: "/myalias", strip: false, alias: "myalias", wantCode: http.StatusFound},
+		// Static routes keep precedence over the redirect patterns.
+		{name: "URL group root", method: http.MethodGet, path: "/url/", strip: true, wantCode: http.StatusOK},
+		{name: "URL group route", method: http.MethodGet, path: "/url/myalias", strip: true, wantCode: http.StatusOK},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlGetterMock := mocks.NewURLRecordGetter(t)
+			if tc.alias != "" {
+				urlGetterMock.On("GetNamespacedURLRecord", mock.Anything, tc.namespace, tc.alias).
+					Return(storage.URLRecord{Namespace: tc.namespace, Alias: tc.alias, URL: url}, nil).Once()
+			}
+
+			r := chi.NewRouter()
+			r.Route("/url", func(r chi.Router) {
+				r.Get("/", func(http.ResponseWriter, *http.Request) {})
+				r.Get("/{alias}", func(http.ResponseWriter, *http.Request) {})
+			})
+			redirect.Register(r, redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock, redirect.Options{}), tc.strip)
+
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			rr := httptest.NewRecorder()
+
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.wantCode, rr.Code)
+			if tc.wantCode == http.StatusFound {
+				require.Equal(t, url, rr.Header().Get("Location"))
+			}
+		})
+	}
+}
+
+func TestRegisterForwardPath(t *testing.T) {
+	docs := storage.URLRecord{Alias: "guide", URL: "https://example.com/guide?lang=en", ForwardPath: true}
+	plain := storage.URLRecord{Alias: "google", URL: "https://www.google.com/"}
+
+	type lookup struct {
+		namespace, alias string
+		rec              *storage.URLRecord
+	}
+
+	cases := []struct {
+		name     string
+		path     string
+		lookups  []lookup
+		wantCode int
+		want     string
+	}{
+		{
+			name:     "Prefix alone",
+			path:     "/guide",
+			lookups:  []lookup{{"", "guide", &docs}},
+			wantCode: http.StatusFound,
+			want:     "https://example.com/guide?lang=en",
+		},
+		{
+			name:     "One segment falls back from the namespace route",
+			path:     "/guide/intro",
+			lookups:  []lookup{{"guide", "intro", nil}, {"", "guide", &docs}},
+			wantCode: http.StatusFound,
+			want:     "https://example.com/guide/intro?lang=en",
+		},
+		{
+			name:     "One segment with trailing slash",
+			path:     "/guide/intro/",
+			lookups:  []lookup{{"guide", "intro", nil}, {"", "guide", &docs}},
+			wantCode: http.StatusFound,
+			want:     "https://example.com/guide/intro/?lang=en",
+		},
+		{
+			name:     "Several segments keep the trailing slash",
+			path:     "/guide/a/b/",
+			lookups:  []lookup{{"", "guide", &docs}},
+			wantCode: http.StatusFound,
+			want:     "https://example.com/guide/a/b/?lang=en",
+		},
+		{
+			name:     "Extension and query are forwarded",
+			path:     "/guide/a/page.html?x=1",
+			lookups:  []lookup{{"", "guide", &docs}},
+			wantCode: http.StatusFound,
+			want:     "https://example.com/guide/a/page.html?lang=en&x=1",
+		},
+		{
+			name:     "Escaping is kept",
+			path:     "/guide/a%20b/c%2Fd",
+			lookups:  []lookup{{"", "guide", &docs}},
+			wantCode: http.StatusFound,
+			want:     "https://example.com/guide/a%20b/c%2Fd?lang=en",
+		},
+		{
+			name:     "Dot segments stay below the destination path",
+			path:     "/guide/a/../../../etc",
+			lookups:  []lookup{{"", "guide", &docs}},
+			wantCode: http.StatusFound,
+			want:     "https://example.com/guide/etc?lang=en",
+		},
+		{
+			name:     "Namespaced link wins over the fallback",
+			path:     "/guide/intro",
+			lookups:  []lookup{{"guide", "intro", &plain}},
+			wantCode: http.StatusFound,
+			want:     "https://www.google.com/",
+		},
+		{
+			name:     "Plain link does not forward one segment",
+			path:     "/google/intro",
+			lookups:  []lookup{{"google", "intro", nil}, {"", "google", &plain}},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Plain link does not forward several segments",
+			path:     "/google/a/b",
+			lookups:  []lookup{{"", "google", &plain}},
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlGetterMock := mocks.NewURLRecordGetter(t)
+			for _, l := range tc.lookups {
+				if l.rec == nil {
+					urlGetterMock.On("GetNamespacedURLRecord", mock.Anything, l.namespace, l.alias).
+						Return(storage.URLRecord{}, storage.ErrURLNotFound).Once()
+					continue
+				}
+				urlGetterMock.On("GetNamespacedURLRecord", mock.Anything, l.namespace, l.alias).Return(*l.rec, nil).Once()
+			}
+
+			r := chi.NewRouter()
+			r.Use(middleware.URLFormat)
+			redirect.Register(r, redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock, redirect.Options{
+				NotFound: http.NotFoundHandler(),
+			}), true)
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			require.Equal(t, tc.wantCode, rr.Code)
+			require.Equal(t, tc.want, rr.Header().Get("Location"))
+		})
+	}
+}
+
+func TestRedirectHTMLMode(t *testing.T) {
+	const alias, url = "testalias", "https://www.google.com/?q=a&b=c"
+
+	custom := template.Must(template.New("custom").Parse(`go to {{.URL}} from {{.Alias}}`))
+
+	cases := []struct {
+		name     string
+		method   string
+		tmpl     *template.Template
+		wantBody []string
+	}{
+		{
+			name:   "Built-in page",
+			method: http.MethodGet,
+			wantBody: []string{
+				`<meta http-equiv="refresh" content="0; url=https://www.google.com/?q=a&amp;b=c">`,
+				`<a href="https://www.google.com/?q=a&amp;b=c">`,
+			},
+		},
+		{
+			name:     "Custom template",
+			method:   http.MethodGet,
+			tmpl:     custom,
+			wantBody: []string{"go to https://www.google.com/?q=a&amp;b=c from testalias"},
+		},
+		{
+			name:   "HEAD",
+			method: http.MethodHead,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlGetterMock := mocks.NewURLRecordGetter(t)
+			urlGetterMock.On("GetNamespacedURLRecord", mock.Anything, "", alias).
+				Return(storage.URLRecord{Alias: alias, URL: url}, nil).Once()
+
+			r := chi.NewRouter()
+			r.Method(tc.method, "/{alias}", redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock, redirect.Options{
+				Mode:             redirect.ModeHTML,
+				RedirectTemplate: tc.tmpl,
+			}))
+
+			req := httptest.NewRequest(tc.method, "/"+alias, nil)
+			rr := httptest.NewRecorder()
+
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+			require.Empty(t, rr.Header().Get("Location"))
+			require.Contains(t, rr.Header().Get("Content-Type"), "text/html")
+			for _, want := range tc.wantBody {
+				require.Contains(t, rr.Body.String(), want)
+			}
+			if tc.method == http.MethodHead {
+				require.Empty(t, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestRedirectUnsafeScheme(t *testing.T) {
+	const alias = "testalias"
+
+	for _, mode := range []string{redirect.ModeHTTP, redirect.ModeHTML} {
+		t.Run(mode, func(t *testing.T) {
+			urlGetterMock := mocks.NewURLRecordGetter(t)
+			urlGetterMock.On("GetNamespacedURLRecord", mock.Anything, "", alias).
+				Return(storage.URLRecord{Alias: alias, URL: "javascript:alert(document.domain)"}, nil).Once()
+
+			r := chi.NewRouter()
+			r.Get("/{alias}", redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock, redirect.Options{Mode: mode}))
+
+			req := httptest.NewRequest(http.MethodGet, "/"+alias, nil)
+			rr := httptest.NewRecorder()
+
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusForbidden, rr.Code)
+			require.Empty(t, rr.Header().Get("Location"))
+			require.NotContains(t, rr.Body.String(), "javascript:")
+		})
+	}
+}
+
+func TestRedirectInterstitial(t *testing.T) {
+	const browser = "text/html,application/xhtml+xml,*/*;q=0.8"
+
+	links := map[string]string{
+		"trusted":   "https://docs.example.com/",
+		"untrusted": "https://evil.org/?a=1",
+		"other":     "https://evil.org/other",
+	}
+
+	newRouter := func(t *testing.T, interstitialJSON bool) http.Handler {
+		urlGetterMock := mocks.NewURLRecordGetter(t)
+		for alias, url := range links {
+			urlGetterMock.On("GetNamespacedURLRecord", mock.Anything, "", alias).
+				Return(storage.URLRecord{Alias: alias, URL: url}, nil).Maybe()
+		}
+
+		r := chi.NewRouter()
+		r.Get("/{alias}", redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock, redirect.Options{
+			TrustedHosts:     trustedhosts.New([]string{"example.com"}),
+			ConfirmSecret:    []byte("secret"),
+			InterstitialJSON: interstitialJSON,
+		}))
+
+		return r
+	}
+
+	get := func(h http.Handler, target, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("Accept", accept)
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	continueURL := regexp.MustCompile(`href="(/[^"]+)"`)
+
+	t.Run("Trusted destination is redirected", func(t *testing.T) {
+		rr := get(newRouter(t, false), "/trusted", browser)
+
+		require.Equal(t, http.StatusFound, rr.Code)
+		require.Equal(t, links["trusted"], rr.Header().Get("Location"))
+	})
+
+	t.Run("Browser confirms untrusted destination", func(t *testing.T) {
+		h := newRouter(t, false)
+
+		rr := get(h, "/untrusted", browser)
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Empty(t, rr.Header().Get("Location"))
+		require.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+		require.Contains(t, rr.Body.String(), "You are leaving to evil.org")
+
+		m := continueURL.FindStringSubmatch(rr.Body.String())
+		require.NotNil(t, m)
+		next := strings.ReplaceAll(m[1], "&amp;", "&")
+		require.True(t, strings.HasPrefix(next, "/untrusted?confirm="), next)
+
+		rr = get(h, next, browser)
+
+		require.Equal(t, http.StatusFound, rr.Code)
+		require.Equal(t, links["untrusted"], rr.Header().Get("Location"))
+
+		// The token only confirms the link it was issued for.
+		rr = get(h, strings.Replace(next, "/untrusted", "/other", 1), browser)
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Empty(t, rr.Header().Get("Location"))
+	})
+
+	t.Run("Forged token", func(t *testing.T) {
+		rr := get(newRouter(t, false), "/untrusted?confirm=forged", browser)
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Contains(t, rr.Body.String(), "You are leaving to evil.org")
+	})
+
+	t.Run("API client is redirected", func(t *testing.T) {
+		rr := get(newRouter(t, false), "/untrusted", "application/json")
+
+		require.Equal(t, http.StatusFound, rr.Code)
+		require.Equal(t, links["untrusted"], rr.Header().Get("Location"))
+	})
+
+	t.Run("API client gets JSON", func(t *testing.T) {
+		rr := get(newRouter(t, true), "/untrusted", "application/json")
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Empty(t, rr.Header().Get("Location"))
+
+		var resp redirect.InterstitialResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		require.Equal(t, links["untrusted"], resp.URL)
+	})
+}
+
+func TestRedirectForwardQuery(t *testing.T) {
+	cases := []struct {
+		name         string
+		destination  string
+		path         string
+		forwardQuery bool
+		want         string
+	}{
+		{
+			name:        "Disabled by default",
+			destination: "https://example.com/landing",
+			path:        "/promo?ref=twitter",
+			want:        "https://example.com/landing",
+		},
+		{
+			name:         "Query added to destination",
+			destination:  "https://example.com/landing",
+			path:         "/promo?ref=twitter&lang=en",
+			forwardQuery: true,
+			want:         "https://example.com/landing?ref=twitter&lang=en",
+		},
+		{
+			name:         "Merged after destination query",
+			destination:  "https://example.com/landing?utm_source=mail#top",
+			path:         "/promo?ref=twitter",
+			forwardQuery: true,
+			want:         "https://example.com/landing?utm_source=mail&ref=twitter#top",
+		},
+		{
+			name:         "Destination wins on collision",
+			destination:  "https://example.com/landing?utm_source=mail",
+			path:         "/promo?utm_source=twitter&ref=x",
+			forwardQuery: true,
+			want:         "https://example.com/landing?utm_source=mail&ref=x",
+		},
+		{
+			name:         "Repeated keys and encoding kept",
+			destination:  "https://example.com/landing",
+			path:         "/promo?tag=a&tag=b&q=hello%20world",
+			forwardQuery: true,
+			want:         "https://example.com/landing?tag=a&tag=b&q=hello%20world",
+		},
+		{
+			name:         "Empty query leaves destination untouched",
+			destination:  "https://example.com/landing?b=2&a=1",
+			path:         "/promo?",
+			forwardQuery: true,
+			want:         "https://example.com/landing?b=2&a=1",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlGetterMock := mocks.NewURLRecordGetter(t)
+			urlGetterMock.On("GetNamespacedURLRecord", mock.Anything, "", "promo").
+				Return(storage.URLRecord{Alias: "promo", URL: tc.destination}, nil).Once()
+
+			r := chi.NewRouter()
+			r.Get("/{alias}", redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock, redirect.Options{
+				ForwardQuery: tc.forwardQuery,
+			}))
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			require.Equal(t, http.StatusFound, rr.Code)
+			require.Equal(t, tc.want, rr.Header().Get("Location"))
+		})
+	}
+}
+
+func TestRedirectHeaders(t *testing.T) {
+	global := map[string]string{"Referrer-Policy": "no-referrer", "X-Robots-Tag": "noindex"}
+
+	cases := []struct {
+		name        string
+		linkHeaders map[string]string
+		mode        string
+		want        map[string]string
+	}{
+		{
+			name: "Global headers",
+			want: map[string]string{"Referrer-Policy": "no-referrer", "X-Robots-Tag": "noindex"},
+		},
+		{
+			name:        "Link overrides and removes global headers",
+			linkHeaders: map[string]string{"referrer-policy": "origin", "X-Robots-Tag": "", "X-Partner": "acme"},
+			want:        map[string]string{"Referrer-Policy": "origin", "X-Robots-Tag": "", "X-Partner": "acme"},
+		},
+		{
+			name: "HTML mode",
+			mode: redirect.ModeHTML,
+			want: map[string]string{"Referrer-Policy": "no-referrer", "X-Robots-Tag": "noindex"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlGetterMock := mocks.NewURLRecordGetter(t)
+			urlGetterMock.On("GetNamespacedURLRecord", mock.Anything, "", "partner").
+				Return(storage.URLRecord{Alias: "partner", URL: "https://partner.example.com", Headers: tc.linkHeaders}, nil).Once()
+
+			r := chi.NewRouter()
+			r.Get("/{alias}", redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock, redirect.Options{
+				Headers: global,
+				Mode:    tc.mode,
+			}))
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/partner", nil))
+
+			for name, want := range tc.want {
+				require.Equal(t, want, rr.Header().Get(name), name)
+			}
+			// The headers are set alongside the redirect, not instead of it.
+			if tc.mode == "" {
+				require.Equal(t, http.StatusFound, rr.Code)
+				require.Equal(t, "https://partner.example.com", rr.Header().Get("Location"))
+			}
+		})
+	}
+}
+
+func TestRedirectForwardQueryInterstitial(t *testing.T) {
+	const browser = "text/html,application/xhtml+xml,*/*;q=0.8"
+
+	urlGetterMock := mocks.NewURLRecordGetter(t)
+	urlGetterMock.On("GetNamespacedURLRecord", mock.Anything, "", "promo").
+		Return(storage.URLRecord{Alias: "promo", URL: "https://evil.org/"}, nil)
+
+	r := chi.NewRouter()
+	r.Get("/{alias}", redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock, redirect.Options{
+		TrustedHosts:  trustedhosts.New(nil),
+		ConfirmSecret: []byte("secret"),
+		ForwardQuery:  true,
+	}))
+
+	get := func(target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("Accept", browser)
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	rr := get("/promo?ref=twitter")
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	m := regexp.MustCompile(`href="(/[^"]+)"`).FindStringSubmatch(rr.Body.String())
+	require.NotNil(t, m)
+	next := strings.ReplaceAll(m[1], "&amp;", "&")
+	require.True(t, strings.HasPrefix(next, "/promo?ref=twitter&confirm="), next)
+
+	// The confirmed request keeps the forwarded query, but not the token.
+	rr = get(next)
+	require.Equal(t, http.StatusFound, rr.Code)
+	require.Equal(t, "https://evil.org/?ref=twitter", rr.Header().Get("Location"))
+}
+
+func TestRedirectNamespace(t *testing.T) {
+	cases := []struct {
+		name      string
+		path      string
+		namespace string
+		alias     string
+	}{
+		{
+			name:  "Default namespace",
+			path:  "/home",
+			alias: "home",
+		},
+		{
+			name:      "Named namespace",
+			path:      "/docs/home",
+			namespace: "docs",
+			alias:     "home",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			const url = "https://www.google.com/"
+
+			urlGetterMock := mocks.NewURLRecordGetter(t)
+			urlGetterMock.On("GetNamespacedURLRecord", mock.Anything, tc.namespace, tc.alias).
+				Return(storage.URLRecord{Namespace: tc.namespace, Alias: tc.alias, URL: url}, nil).Once()
+
+			handler := redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock, redirect.Options{})
+
+			r := chi.NewRouter()
+			r.Get("/{alias}", handler)
+			r.Get("/{namespace}/{alias}", handler)
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			require.Equal(t, http.StatusFound, rr.Code)
+			require.Equal(t, url, rr.Header().Get("Location"))
+		})
+	}
+}
+
+func TestRedirectLogTarget(t *testing.T) {
+	const alias, url = "testalias", "https://www.google.com/?user=alice"
+
+	for _, logTarget := range []bool{false, true} {
+		urlGetterMock := mocks.NewURLRecordGetter(t)
+		urlGetterMock.On("GetNamespacedURLRecord", mock.Anything, "", alias).
+			Return(storage.URLRecord{Alias: alias, URL: url}, nil).Once()
+
+		var logs bytes.Buffer
+		log := slog.New(slog.NewJSONHandler(&logs, nil))
+
+		r := chi.NewRouter()
+		r.Get("/{alias}", redirect.New(log, urlGetterMock, redirect.Options{LogTarget: logTarget}))
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/"+alias, nil))
+
+		require.Equal(t, http.StatusFound, rr.Code)
+		require.Contains(t, logs.String(), `"alias":"testalias"`)
+
+		if logTarget {
+			require.Contains(t, logs.String(), "user=alice")
+		} else {
+			require.NotContains(t, logs.String(), "user=alice")
+		}
+	}
+}
+
+func TestRedirectSlowThreshold(t *testing.T) {
+	const alias, url = "testalias", "https://www.google.com"
+
+	cases := []struct {
+		name      string
+		threshold time.Duration
+		delay     time.Duration
+		wantLog   bool
+	}{
+		{name: "Disabled", delay: 20 * time.Millisecond},
+		{name: "Fast redirect", threshold: time.Second},
+		{name: "Slow storage", threshold: 10 * time.Millisecond, delay: 20 * time.Millisecond, wantLog: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlGetterMock := mocks.NewURLRecordGetter(t)
+			urlGetterMock.On("GetNamespacedURLRecord", mock.Anything, "", alias).
+				After(tc.delay).
+				Return(storage.URLRecord{Alias: alias, URL: url}, nil).Once()
+
+			var logs bytes.Buffer
+			log := slog.New(slog.NewJSONHandler(&logs, nil))
+
+			r := chi.NewRouter()
+			r.Get("/{alias}", redirect.New(log, urlGetterMock, redirect.Options{SlowThreshold: tc.threshold}))
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/"+alias, nil))
+
+			require.Equal(t, http.StatusFound, rr.Code)
+
+			if tc.wantLog {
+				require.Contains(t, logs.String(), `"msg":"slow redirect"`)
+				require.Contains(t, logs.String(), `"storage_duration"`)
+			} else {
+				require.NotContains(t, logs.String(), "slow redirect")
+			}
+		})
+	}
+}
+
+func TestRedirectNotFound(t *testing.T) {
+	urlGetterMock := mocks.NewURLRecordGetter(t)
+	urlGetterMock.On("GetNamespacedURLRecord", mock.Anything, "", "missing").
+		Return(storage.URLRecord{}, storage.ErrURLNotFound).Once()
+
+	notFound := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "custom not found", http.StatusNotFound)
+	})
+
+	r := chi.NewRouter()
+	r.Get("/{alias}", redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock, redirect.Options{NotFound: notFound}))
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	require.Equal(t, http.StatusNotFound, rr.Code)
+	require.Contains(t, rr.Body.String(), "custom not found")
+}
+
+// accessRecorderFunc adapts a function to redirect.AccessRecorder.
+// Clicks are recorded as plain accesses.
+type accessRecorderFunc func(id int64, at time.Time)
+
+func (f accessRecorderFunc) Record(id int64, at time.Time) { f(id, at) }
+
+func (f accessRecorderFunc) RecordClick(id int64, at time.Time) { f(id, at) }
+
+// clickRecorder is a redirect.AccessRecorder that remembers which calls were clicks.
+type clickRecorder struct {
+	accesses, clicks int
+}
+
+func (c *clickRecorder) Record(int64, time.Time) { c.accesses++ }
+
+func (c *clickRecorder) RecordClick(int64, time.Time) { c.clicks++ }
+
+func TestRedirectHead(t *testing.T) {
+	const alias, url = "testalias", "https://www.google.com/"
+
+	createdAt := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	accessedAt := time.Date(2024, time.April, 2, 8, 30, 0, 0, time.UTC)
+
+	cases := []struct {
+		name             string
+		method           string
+		rec              storage.URLRecord
+		wantLastModified string
+		wantRecorded     bool
+	}{
+		{
+			name:             "GET",
+			method:           http.MethodGet,
+			rec:              storage.URLRecord{ID: 1, Alias: alias, URL: url, CreatedAt: &createdAt, LastAccessedAt: &accessedAt},
+			wantLastModified: "Fri, 01 Mar 2024 12:00:00 GMT",
+			wantRecorded:     true,
+		},
+		{
+			name:             "HEAD",
+			method:           http.MethodHead,
+			rec:              storage.URLRecord{ID: 1, Alias: alias, URL: url, CreatedAt: &createdAt, LastAccessedAt: &accessedAt},
+			wantLastModified: "Fri, 01 Mar 2024 12:00:00 GMT",
+		},
+		{
+			name:             "HEAD never accessed",
+			method:           http.MethodHead,
+			rec:              storage.URLRecord{ID: 1, Alias: alias, URL: url, CreatedAt: &createdAt},
+			wantLastModified: "Fri, 01 Mar 2024 12:00:00 GMT",
+		},
+		{
+			name:   "HEAD without timestamps",
+			method: http.MethodHead,
+			rec:    storage.URLRecord{ID: 1, Alias: alias, URL: url},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlGetterMock := mocks.NewURLRecordGetter(t)
+			urlGetterMock.On("GetNamespacedURLRecord", mock.Anything, "", alias).Return(tc.rec, nil).Once()
+
+			recorded := false
+			handler := redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock, redirect.Options{
+				AccessRecorder: accessRecorderFunc(func(int64, time.Time) { recorded = true }),
+			})
+
+			r := chi.NewRouter()
+			r.Get("/{alias}", handler)
+			r.Head("/{alias}", handler)
+
+			ts := httptest.NewServer(r)
+			defer ts.Close()
+
+			client := &http.Client{
+				CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+			}
+
+			req, err := http.NewRequest(tc.method, ts.URL+"/"+alias, nil)
+			require.NoError(t, err)
+
+			res, err := client.Do(req)
+			require.NoError(t, err)
+			defer func() { _ = res.Body.Close() }()
+
+			body, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+
+			require.Equal(t, http.StatusFound, res.StatusCode)
+			require.Equal(t, url, res.Header.Get("Location"))
+			require.Equal(t, tc.wantLastModified, res.Header.Get("Last-Modified"))
+			require.Equal(t, tc.wantRecorded, recorded)
+			if tc.method == http.MethodHead {
+				require.Empty(t, body)
+			}
+		})
+	}
+}
+
+// clickCounterFunc adapts a function to redirect.ClickCounter.
+type clickCounterFunc func(id int64) error
+
+func (f clickCounterFunc) ConsumeClick(id int64) error { return f(id) }
+
+func TestRedirectMaxClicks(t *testing.T) {
+	const alias, url = "promo", "https://www.google.com/"
+
+	cases := []struct {
+		name        string
+		method      string
+		rec         storage.URLRecord
+		consumeErr  error
+		recorder    bool
+		wantCode    int
+		wantConsume bool
+		wantClicks  int
+	}{
+		{
+			name:        "Clicks left",
+			method:      http.MethodGet,
+			rec:         storage.URLRecord{ID: 1, Alias: alias, URL: url, MaxClicks: 3, Clicks: 2},
+			wantCode:    http.StatusFound,
+			wantConsume: true,
+		},
+		{
+			name:     "Already exhausted",
+			method:   http.MethodGet,
+			rec:      storage.URLRecord{ID: 1, Alias: alias, URL: url, MaxClicks: 3, Clicks: 3},
+			wantCode: http.StatusGone,
+		},
+		{
+			name:        "Last click taken concurrently",
+			method:      http.MethodGet,
+			rec:         storage.URLRecord{ID: 1, Alias: alias, URL: url, MaxClicks: 3, Clicks: 2},
+			consumeErr:  storage.ErrClicksExhausted,
+			wantCode:    http.StatusGone,
+			wantConsume: true,
+		},
+		{
+			name:        "Unlimited link is counted",
+			method:      http.MethodGet,
+			rec:         storage.URLRecord{ID: 1, Alias: alias, URL: url},
+			wantCode:    http.StatusFound,
+			wantConsume: true,
+		},
+		{
+			name:        "Unlimited link counting error is ignored",
+			method:      http.MethodGet,
+			rec:         storage.URLRecord{ID: 1, Alias: alias, URL: url},
+			consumeErr:  errors.New("unexpected error"),
+			wantCode:    http.StatusFound,
+			wantConsume: true,
+		},
+		{
+			name:       "Unlimited link is counted in batches",
+			method:     http.MethodGet,
+			rec:        storage.URLRecord{ID: 1, Alias: alias, URL: url},
+			recorder:   true,
+			wantCode:   http.StatusFound,
+			wantClicks: 1,
+		},
+		{
+			name:        "Limited link is counted synchronously",
+			method:      http.MethodGet,
+			rec:         storage.URLRecord{ID: 1, Alias: alias, URL: url, MaxClicks: 3, Clicks: 2},
+			recorder:    true,
+			wantCode:    http.StatusFound,
+			wantConsume: true,
+		},
+		{
+			name:     "HEAD does not consume",
+			method:   http.MethodHead,
+			rec:      storage.URLRecord{ID: 1, Alias: alias, URL: url, MaxClicks: 3, Clicks: 2},
+			wantCode: http.StatusFound,
+		},
+		{
+			name:        "Storage error",
+			method:      http.MethodGet,
+			rec:         storage.URLRecord{ID: 1, Alias: alias, URL: url, MaxClicks: 3},
+			consumeErr:  errors.New("unexpected error"),
+			wantCode:    http.StatusInternalServerError,
+			wantConsume: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlGetterMock := mocks.NewURLRecordGetter(t)
+			urlGetterMock.On("GetNamespacedURLRecord", mock.Anything, "", alias).Return(tc.rec, nil).Once()
+
+			consumed := false
+			opts := redirect.Options{
+				ClickCounter: clickCounterFunc(func(id int64) error {
+					require.Equal(t, tc.rec.ID, id)
+					consumed = true
+					return tc.consumeErr
+				}),
+			}
+			recorder := &clickRecorder{}
+			if tc.recorder {
+				opts.AccessRecorder = recorder
+			}
+			handler := redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock, opts)
+
+			r := chi.NewRouter()
+			r.Get("/{alias}", handler)
+			r.Head("/{alias}", handler)
+
+			req := httptest.NewRequest(tc.method, "/"+alias, nil)
+			rr := httptest.NewRecorder()
+
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.wantCode, rr.Code)
+			require.Equal(t, tc.wantConsume, consumed)
+			require.Equal(t, tc.wantClicks, recorder.clicks)
+			if tc.wantCode == http.StatusGone && tc.method == http.MethodGet {
+				require.Contains(t, rr.Body.String(), "link exhausted")
+			}
+		})
+	}
+}
+
+func TestRedirectTimeout(t *testing.T) {
+	const alias = "slow"
+
+	urlGetterMock := mocks.NewURLRecordGetter(t)
+	urlGetterMock.On("GetNamespacedURLRecord", mock.Anything, "", alias).
+		Return(func(ctx context.Context, _, _ string) (storage.URLRecord, error) {
+			// A slow store gives up once the request deadline passes.
+			<-ctx.Done()
+			return storage.URLRecord{}, ctx.Err()
+		}).Once()
+
+	r := chi.NewRouter()
+	r.Use(timeout.New(slogdiscard.NewDiscardLogger(), 20*time.Millisecond))
+	r.Get("/{alias}", redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock, redirect.Options{}))
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/"+alias, nil))
+
+	require.Equal(t, http.StatusGatewayTimeout, rr.Code)
+	require.Contains(t, rr.Body.String(), `"error":"request timed out"`)
+	require.Empty(t, rr.Header().Get("Location"))
+}
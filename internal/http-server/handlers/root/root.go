@@ -0,0 +1,23 @@
+package root
+
+import (
+	"net/http"
+
+	"github.com/go-chi/render"
+
+	resp "url-shortener/internal/lib/api/response"
+)
+
+// New handles GET on the service root. When redirectURL is set, the client
+// is sent there with 302 Found; otherwise it gets a JSON 404.
+func New(redirectURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if redirectURL == "" {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, resp.Error(resp.CodeNotFound, "not found"))
+			return
+		}
+
+		http.Redirect(w, r, redirectURL, http.StatusFound)
+	}
+}
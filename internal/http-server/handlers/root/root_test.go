@@ -0,0 +1,58 @@
+package root_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/root"
+)
+
+func TestRoot(t *testing.T) {
+	cases := []struct {
+		name         string
+		redirectURL  string
+		path         string
+		wantCode     int
+		wantLocation string
+	}{
+		{
+			name:         "Configured",
+			redirectURL:  "https://example.org/",
+			path:         "/",
+			wantCode:     http.StatusFound,
+			wantLocation: "https://example.org/",
+		},
+		{
+			name:     "Not configured",
+			path:     "/",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:         "Alias is not shadowed",
+			redirectURL:  "https://example.org/",
+			path:         "/abc",
+			wantCode:     http.StatusOK,
+			wantLocation: "",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := chi.NewRouter()
+			r.Get("/", root.New(tc.redirectURL))
+			r.Get("/{alias}", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			require.Equal(t, tc.wantCode, rr.Code)
+			require.Equal(t, tc.wantLocation, rr.Header().Get("Location"))
+		})
+	}
+}
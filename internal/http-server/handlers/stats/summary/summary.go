@@ -0,0 +1,98 @@
+package summary
+
+import (
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+// topLinks is the number of most clicked links in the summary.
+const topLinks = 10
+
+type Response struct {
+	resp.Response
+	TotalLinks   int64               `json:"total_links"`
+	TotalClicks  int64               `json:"total_clicks"`
+	CreatedToday int64               `json:"created_today"`
+	Top          []storage.URLRecord `json:"top"`
+	// GeneratedAt is when the numbers were computed; with caching they may be up to CacheTTL old.
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// Options configures the summary handler.
+type Options struct {
+	// CacheTTL is how long a computed summary is served before it is
+	// recomputed. Zero disables caching.
+	CacheTTL time.Duration
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.53.7 --name=SummaryGetter
+
+// SummaryGetter is an interface for computing link statistics.
+type SummaryGetter interface {
+	Summary(since time.Time, top int) (storage.Summary, error)
+}
+
+// New returns dashboard statistics: total links and clicks, links created
+// since the start of the current UTC day and the 10 most clicked links.
+// The queries scan the whole table, so the result is cached for
+// Options.CacheTTL; concurrent requests on an expired cache wait for a
+// single recomputation.
+func New(log *slog.Logger, getter SummaryGetter, opts Options) http.HandlerFunc {
+	var (
+		mu      sync.Mutex
+		cached  Response
+		expires time.Time
+	)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.stats.summary.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		now := time.Now().UTC()
+		if now.Before(expires) {
+			render.JSON(w, r, cached)
+
+			return
+		}
+
+		today := now.Truncate(24 * time.Hour)
+
+		summary, err := getter.Summary(today, topLinks)
+		if err != nil {
+			log.Error("failed to get summary", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		cached = Response{
+			Response:     resp.OK(),
+			TotalLinks:   summary.TotalLinks,
+			TotalClicks:  summary.TotalClicks,
+			CreatedToday: summary.CreatedSince,
+			Top:          summary.Top,
+			GeneratedAt:  now,
+		}
+		expires = now.Add(opts.CacheTTL)
+
+		render.JSON(w, r, cached)
+	}
+}
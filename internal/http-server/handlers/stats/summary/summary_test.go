@@ -0,0 +1,85 @@
+package summary_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/stats/summary"
+	"url-shortener/internal/http-server/handlers/stats/summary/mocks"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestSummaryHandler(t *testing.T) {
+	stats := storage.Summary{
+		TotalLinks:   3,
+		TotalClicks:  42,
+		CreatedSince: 1,
+		Top:          []storage.URLRecord{{ID: 2, Alias: "google", URL: "https://google.com", Clicks: 40}},
+	}
+
+	getterMock := mocks.NewSummaryGetter(t)
+	getterMock.On("Summary", mock.MatchedBy(func(since time.Time) bool {
+		// Links are counted from the start of the current UTC day.
+		return since.Equal(time.Now().UTC().Truncate(24 * time.Hour))
+	}), 10).Return(stats, nil).Once()
+
+	handler := summary.New(slogdiscard.NewDiscardLogger(), getterMock, summary.Options{CacheTTL: time.Minute})
+
+	// The second request is served from the cache, so the mock is called once.
+	for range 2 {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/stats/summary", nil))
+
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var resp summary.Response
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+		require.Equal(t, int64(3), resp.TotalLinks)
+		require.Equal(t, int64(42), resp.TotalClicks)
+		require.Equal(t, int64(1), resp.CreatedToday)
+		require.Len(t, resp.Top, 1)
+		require.Equal(t, "google", resp.Top[0].Alias)
+		require.False(t, resp.GeneratedAt.IsZero())
+	}
+}
+
+func TestSummaryHandler_NoCache(t *testing.T) {
+	getterMock := mocks.NewSummaryGetter(t)
+	getterMock.On("Summary", mock.Anything, 10).Return(storage.Summary{}, nil).Twice()
+
+	handler := summary.New(slogdiscard.NewDiscardLogger(), getterMock, summary.Options{})
+
+	for range 2 {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/stats/summary", nil))
+		require.Equal(t, http.StatusOK, rr.Code)
+	}
+}
+
+func TestSummaryHandler_StorageError(t *testing.T) {
+	getterMock := mocks.NewSummaryGetter(t)
+	// Errors are not cached: every request retries the storage.
+	getterMock.On("Summary", mock.Anything, 10).Return(storage.Summary{}, errors.New("unexpected error")).Twice()
+
+	handler := summary.New(slogdiscard.NewDiscardLogger(), getterMock, summary.Options{CacheTTL: time.Minute})
+
+	for range 2 {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/stats/summary", nil))
+
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+
+		var resp summary.Response
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		require.Equal(t, "internal error", resp.Error)
+	}
+}
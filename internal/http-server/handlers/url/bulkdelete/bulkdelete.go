@@ -0,0 +1,129 @@
+package bulkdelete
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+// maxAliases limits how many aliases one request may delete.
+const maxAliases = 1000
+
+type Response struct {
+	resp.Response
+	CountDeleted int64    `json:"countDeleted"`
+	NotFound     []string `json:"notFound,omitempty"`
+}
+
+// TxRunner runs a function in a single storage transaction.
+type TxRunner interface {
+	WithTx(ctx context.Context, fn func(tx storage.Tx) error) error
+}
+
+// New deletes the links whose aliases are listed in the JSON array request
+// body and reports the aliases that did not exist. The lookup and the delete
+// run in one transaction. An empty array deletes nothing.
+func New(log *slog.Logger, txRunner TxRunner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.bulkdelete.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var aliases []string
+		if err := render.DecodeJSON(r.Body, &aliases); err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(resp.CodeInvalidRequest, "failed to decode request"))
+
+			return
+		}
+
+		aliases = unique(aliases)
+
+		if len(aliases) > maxAliases {
+			log.Info("too many aliases", slog.Int("count", len(aliases)))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(resp.CodeTooManyAliases, fmt.Sprintf("too many aliases: max is %d", maxAliases)))
+
+			return
+		}
+
+		if len(aliases) == 0 {
+			render.JSON(w, r, Response{Response: resp.OK()})
+
+			return
+		}
+
+		var countDeleted int64
+		var notFound []string
+
+		err := txRunner.WithTx(r.Context(), func(tx storage.Tx) error {
+			found := make([]string, 0, len(aliases))
+			for _, alias := range aliases {
+				_, err := tx.GetURL(alias)
+				if errors.Is(err, storage.ErrURLNotFound) {
+					notFound = append(notFound, alias)
+					continue
+				}
+				if err != nil {
+					return err
+				}
+
+				found = append(found, alias)
+			}
+
+			var err error
+			countDeleted, err = tx.BulkDeleteURL(found)
+
+			return err
+		})
+		if err != nil {
+			log.Error("failed to delete urls", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		log.Info("deleted urls", slog.Int64("count_deleted", countDeleted), slog.Int("not_found", len(notFound)))
+
+		render.JSON(w, r, Response{
+			Response:     resp.OK(),
+			CountDeleted: countDeleted,
+			NotFound:     notFound,
+		})
+	}
+}
+
+// unique drops empty and repeated aliases, comparing them in normalized form.
+func unique(aliases []string) []string {
+	seen := make(map[string]struct{}, len(aliases))
+	res := make([]string, 0, len(aliases))
+
+	for _, alias := range aliases {
+		key := storage.NormalizeAlias(alias)
+		if _, ok := seen[key]; ok || alias == "" {
+			continue
+		}
+
+		seen[key] = struct{}{}
+		res = append(res, alias)
+	}
+
+	return res
+}
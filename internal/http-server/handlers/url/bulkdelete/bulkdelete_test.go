@@ -0,0 +1,136 @@
+package bulkdelete_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/bulkdelete"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+// fakeTx keeps links in a map; only the methods the handler uses are implemented.
+type fakeTx struct {
+	storage.Tx
+	urls      map[string]string
+	deleteErr error
+}
+
+func (tx *fakeTx) GetURL(alias string) (string, error) {
+	url, ok := tx.urls[storage.NormalizeAlias(alias)]
+	if !ok {
+		return "", storage.ErrURLNotFound
+	}
+
+	return url, nil
+}
+
+func (tx *fakeTx) BulkDeleteURL(aliases []string) (int64, error) {
+	if tx.deleteErr != nil {
+		return 0, tx.deleteErr
+	}
+
+	var deleted int64
+	for _, alias := range aliases {
+		if _, ok := tx.urls[storage.NormalizeAlias(alias)]; ok {
+			delete(tx.urls, storage.NormalizeAlias(alias))
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+type fakeTxRunner struct {
+	tx    *fakeTx
+	calls int
+}
+
+func (r *fakeTxRunner) WithTx(_ context.Context, fn func(tx storage.Tx) error) error {
+	r.calls++
+	return fn(r.tx)
+}
+
+func TestBulkDeleteHandler(t *testing.T) {
+	cases := []struct {
+		name         string
+		body         string
+		deleteErr    error
+		respCode     int
+		respError    string
+		countDeleted int64
+		notFound     []string
+		wantTx       bool
+	}{
+		{
+			name:         "Success",
+			body:         `["google", "Yandex"]`,
+			respCode:     http.StatusOK,
+			countDeleted: 2,
+			wantTx:       true,
+		},
+		{
+			name:         "Some aliases not found",
+			body:         `["google", "missing", "GOOGLE", "other"]`,
+			respCode:     http.StatusOK,
+			countDeleted: 1,
+			notFound:     []string{"missing", "other"},
+			wantTx:       true,
+		},
+		{
+			name:     "Empty list",
+			body:     `[]`,
+			respCode: http.StatusOK,
+		},
+		{
+			name:      "Not an array",
+			body:      `{"aliases": ["google"]}`,
+			respCode:  http.StatusBadRequest,
+			respError: "failed to decode request",
+		},
+		{
+			name:      "Storage error",
+			body:      `["google"]`,
+			deleteErr: errors.New("unexpected error"),
+			respCode:  http.StatusInternalServerError,
+			respError: "internal error",
+			wantTx:    true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			txRunner := &fakeTxRunner{tx: &fakeTx{
+				urls: map[string]string{
+					"google": "https://google.com",
+					"yandex": "https://ya.ru",
+				},
+				deleteErr: tc.deleteErr,
+			}}
+
+			handler := bulkdelete.New(slogdiscard.NewDiscardLogger(), txRunner)
+
+			req := httptest.NewRequest(http.MethodDelete, "/url/batch", strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var resp bulkdelete.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+			require.Equal(t, tc.countDeleted, resp.CountDeleted)
+			require.Equal(t, tc.notFound, resp.NotFound)
+			require.Equal(t, tc.wantTx, txRunner.calls == 1)
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package bytarget
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+)
+
+type Response struct {
+	resp.Response
+	URL string `json:"url,omitempty"`
+	// Aliases are the links to URL in creation order. Links in a namespace
+	// are given with it, as in their path: "team/home".
+	Aliases []string `json:"aliases"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.53.7 --name=AliasLister
+
+// AliasLister is an interface for finding the aliases of a destination URL.
+type AliasLister interface {
+	ListAliasesByURL(urlToSave string) ([]string, error)
+}
+
+// New lists the aliases of all links to the destination in the "url" query
+// parameter. The URL must match exactly. No matches give an empty list,
+// not 404.
+func New(log *slog.Logger, lister AliasLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.bytarget.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		target := r.URL.Query().Get("url")
+		if target == "" {
+			log.Info("empty url parameter")
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(resp.CodeInvalidQuery, "query parameter url is required"))
+
+			return
+		}
+
+		aliases, err := lister.ListAliasesByURL(target)
+		if err != nil {
+			log.Error("failed to list aliases by url", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		if aliases == nil {
+			aliases = []string{}
+		}
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			URL:      target,
+			Aliases:  aliases,
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package bytarget_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/bytarget"
+	"url-shortener/internal/http-server/handlers/url/bytarget/mocks"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+func TestByTargetHandler(t *testing.T) {
+	const target = "https://google.com/?q=go&lang=en"
+
+	cases := []struct {
+		name        string
+		target      string
+		mockAliases []string
+		mockError   error
+		respCode    int
+		respError   string
+		wantAliases []string
+	}{
+		{
+			name:        "Aliases found",
+			target:      target,
+			mockAliases: []string{"google", "team/home"},
+			respCode:    http.StatusOK,
+			wantAliases: []string{"google", "team/home"},
+		},
+		{
+			name:        "No aliases",
+			target:      target,
+			respCode:    http.StatusOK,
+			wantAliases: []string{},
+		},
+		{
+			name:      "Missing url",
+			respCode:  http.StatusBadRequest,
+			respError: "query parameter url is required",
+		},
+		{
+			name:      "Storage error",
+			target:    target,
+			mockError: errors.New("unexpected error"),
+			respCode:  http.StatusInternalServerError,
+			respError: "internal error",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			listerMock := mocks.NewAliasLister(t)
+			if tc.target != "" {
+				listerMock.On("ListAliasesByURL", tc.target).Return(tc.mockAliases, tc.mockError).Once()
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/url/by-target?url="+url.QueryEscape(tc.target), nil)
+			rr := httptest.NewRecorder()
+
+			bytarget.New(slogdiscard.NewDiscardLogger(), listerMock).ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var resp bytarget.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+
+			if tc.respError == "" {
+				// An empty result is an empty array, not null.
+				require.Contains(t, rr.Body.String(), `"aliases":[`)
+				require.Equal(t, tc.wantAliases, resp.Aliases)
+				require.Equal(t, tc.target, resp.URL)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package confirm
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+
+	"url-shortener/internal/lib/api"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/clientip"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+// maxURLLengthHardCap limits URL length even when no limit is configured.
+const maxURLLengthHardCap = 8192
+
+type Request struct {
+	// Token is the token returned by POST /url/reservations.
+	Token string `json:"token" validate:"required"`
+	URL   string `json:"url" validate:"required,http_url"`
+}
+
+type Response struct {
+	resp.Response
+	Alias    string `json:"alias,omitempty"`
+	ShortURL string `json:"short_url,omitempty"`
+}
+
+// Options configures the confirm handler.
+type Options struct {
+	// MaxURLLength is the maximum accepted URL length in bytes.
+	// Zero or values above maxURLLengthHardCap fall back to the hard cap.
+	MaxURLLength int
+	// BasePath is the prefix the service is mounted under; it is part of short_url.
+	BasePath string
+	// TrustedProxies are the proxies whose forwarding headers are honored
+	// when resolving the client IP and the scheme of short_url.
+	TrustedProxies []*net.IPNet
+	// Notifier is told about every created link. Nil disables notifications.
+	Notifier CreationNotifier
+}
+
+// CreationNotifier is an interface for announcing created links.
+// LinkCreated must not block.
+type CreationNotifier interface {
+	LinkCreated(namespace, alias, target string)
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.53.7 --name=ReservationConfirmer
+
+// ReservationConfirmer is an interface for turning an alias reservation into a link.
+type ReservationConfirmer interface {
+	ConfirmReservation(token, urlToSave string, opts storage.URLOptions) (string, error)
+}
+
+// New creates the link for a reservation made with POST /url/reservations.
+// The link gets the reserved alias in the default namespace; an unknown
+// token and a reservation whose TTL has passed both answer 404.
+func New(log *slog.Logger, confirmer ReservationConfirmer, opts Options) http.HandlerFunc {
+	maxURLLength := opts.MaxURLLength
+	if maxURLLength <= 0 || maxURLLength > maxURLLengthHardCap {
+		maxURLLength = maxURLLengthHardCap
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.confirm.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req Request
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(resp.CodeInvalidRequest, "failed to decode request"))
+			return
+		}
+
+		if err := validator.New().Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+			log.Info("invalid request", sl.Err(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.ValidationError(validateErr))
+			return
+		}
+
+		if len(req.URL) > maxURLLength {
+			log.Info("url is too long", slog.Int("length", len(req.URL)), slog.Int("max_length", maxURLLength))
+			render.Status(r, http.StatusUnprocessableEntity)
+			render.JSON(w, r, resp.Error(resp.CodeURLTooLong, fmt.Sprintf("url is too long: max length is %d", maxURLLength)))
+			return
+		}
+
+		urlOpts := storage.URLOptions{}
+		if ip := clientip.ClientIP(r, opts.TrustedProxies); ip != nil {
+			urlOpts.CreatorIP = ip.String()
+		}
+
+		alias, err := confirmer.ConfirmReservation(req.Token, req.URL, urlOpts)
+		if errors.Is(err, storage.ErrReservationNotFound) {
+			log.Info("reservation not found")
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, resp.Error(resp.CodeNotFound, "reservation not found or expired"))
+			return
+		}
+		if errors.Is(err, storage.ErrAliasConfusable) {
+			log.Info("alias looks like an existing one")
+			render.Status(r, http.StatusConflict)
+			render.JSON(w, r, resp.Error(resp.CodeAliasConfusable, "alias is too similar to an existing alias"))
+			return
+		}
+		if errors.Is(err, storage.ErrURLDuplicate) {
+			log.Info("url already shortened")
+			render.Status(r, http.StatusConflict)
+			render.JSON(w, r, resp.Error(resp.CodeURLAlreadyShortened, "url already shortened"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to confirm reservation", sl.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(resp.CodeInternal, "failed to add url"))
+			return
+		}
+
+		log.Info("reservation confirmed, url added", slog.String("alias", alias))
+
+		if opts.Notifier != nil {
+			opts.Notifier.LinkCreated("", alias, req.URL)
+		}
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Alias:    alias,
+			ShortURL: api.ShortURL(r, opts.TrustedProxies, opts.BasePath, alias),
+		})
+	}
+}
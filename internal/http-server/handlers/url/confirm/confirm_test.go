@@ -0,0 +1,112 @@
+package confirm_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/confirm"
+	"url-shortener/internal/http-server/handlers/url/confirm/mocks"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestConfirmHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		body      string
+		mockAlias string
+		mockError error
+		respCode  int
+		errCode   string
+	}{
+		{
+			name:      "Success",
+			body:      `{"token":"token","url":"https://example.com"}`,
+			mockAlias: "vanity",
+			respCode:  http.StatusOK,
+		},
+		{
+			name:     "Missing token",
+			body:     `{"url":"https://example.com"}`,
+			respCode: http.StatusBadRequest,
+			errCode:  "VALIDATION_FAILED",
+		},
+		{
+			name:     "Invalid url",
+			body:     `{"token":"token","url":"example"}`,
+			respCode: http.StatusBadRequest,
+			errCode:  "INVALID_URL",
+		},
+		{
+			name:     "URL too long",
+			body:     `{"token":"token","url":"https://example.com/` + strings.Repeat("a", 100) + `"}`,
+			respCode: http.StatusUnprocessableEntity,
+			errCode:  "URL_TOO_LONG",
+		},
+		{
+			name:      "Unknown or expired reservation",
+			body:      `{"token":"token","url":"https://example.com"}`,
+			mockError: storage.ErrReservationNotFound,
+			respCode:  http.StatusNotFound,
+			errCode:   "NOT_FOUND",
+		},
+		{
+			name:      "Confusable alias",
+			body:      `{"token":"token","url":"https://example.com"}`,
+			mockError: errors.Join(storage.ErrURLExists, storage.ErrAliasConfusable),
+			respCode:  http.StatusConflict,
+			errCode:   "ALIAS_CONFUSABLE",
+		},
+		{
+			name:      "URL already shortened",
+			body:      `{"token":"token","url":"https://example.com"}`,
+			mockError: storage.ErrURLDuplicate,
+			respCode:  http.StatusConflict,
+			errCode:   "URL_ALREADY_SHORTENED",
+		},
+		{
+			name:      "Storage error",
+			body:      `{"token":"token","url":"https://example.com"}`,
+			mockError: errors.New("unexpected error"),
+			respCode:  http.StatusInternalServerError,
+			errCode:   "INTERNAL_ERROR",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			confirmerMock := mocks.NewReservationConfirmer(t)
+
+			if tc.mockAlias != "" || tc.mockError != nil {
+				confirmerMock.On("ConfirmReservation", "token", "https://example.com", mock.AnythingOfType("storage.URLOptions")).
+					Return(tc.mockAlias, tc.mockError).Once()
+			}
+
+			handler := confirm.New(slogdiscard.NewDiscardLogger(), confirmerMock, confirm.Options{MaxURLLength: 64})
+
+			req := httptest.NewRequest(http.MethodPost, "/url/reservations/confirm", bytes.NewReader([]byte(tc.body)))
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var resp confirm.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.errCode, resp.Code)
+			require.Equal(t, tc.mockAlias, resp.Alias)
+			if tc.mockAlias != "" {
+				require.Equal(t, "http://example.com/"+tc.mockAlias, resp.ShortURL)
+			}
+		})
+	}
+}
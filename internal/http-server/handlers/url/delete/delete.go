@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
 	"url-shortener/internal/storage"
 
 	"github.com/go-chi/chi/v5"
@@ -16,18 +17,23 @@ import (
 
 type Response struct {
 	resp.Response
-	CountDeleted int64 `json:"countDeleted"`
+	CountDeleted int64  `json:"countDeleted"`
+	Alias        string `json:"alias,omitempty"`
+	URL          string `json:"url,omitempty"`
 }
 
-type DeleteURL interface {
-	DeleteURL(alias string) (int64, error)
+// URLRecordDeleter is an interface for deleting a link and getting back the deleted record.
+type URLRecordDeleter interface {
+	DeleteURLRecord(alias string) (storage.URLRecord, error)
 }
 
-func New(log *slog.Logger, deleteURL DeleteURL) http.HandlerFunc {
+// New deletes a link and responds with the alias and URL that were removed,
+// so audit tooling can confirm exactly what was deleted.
+func New(log *slog.Logger, deleter URLRecordDeleter) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const fn = "handlers.url.delete.New"
 
-		log = log.With(
+		log := log.With(
 			slog.String("fn", fn),
 			slog.String("request_id", middleware.GetReqID(r.Context())),
 		)
@@ -35,31 +41,36 @@ func New(log *slog.Logger, deleteURL DeleteURL) http.HandlerFunc {
 		alias := chi.URLParam(r, "alias")
 		if alias == "" {
 			log.Error("empty alias")
-			render.JSON(w, r, resp.Error("invalid request"))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(resp.CodeInvalidRequest, "invalid request"))
 			return
 		}
 
-		countDeleted, err := deleteURL.DeleteURL(alias)
+		rec, err := deleter.DeleteURLRecord(alias)
 		if errors.Is(err, storage.ErrURLNotFound) {
 			log.Info("url not found", "alias", alias)
-			render.JSON(w, r, resp.Error("internal error"))
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, resp.Error(resp.CodeNotFound, "not found"))
 			return
 		}
 		if err != nil {
-			log.Error("failed to get url", "alias", alias)
-			render.JSON(w, r, resp.Error("failed to get url"))
+			log.Error("failed to delete url", slog.String("alias", alias), sl.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(resp.CodeInternal, "internal error"))
 			return
 		}
 
-		log.Info("deleted url", slog.String("alias", alias), slog.Int64("count_deleted", countDeleted))
+		log.Info("deleted url", slog.String("alias", rec.Alias), slog.String("url", rec.URL))
 
-		responseOK(w, r, countDeleted)
+		responseOK(w, r, rec)
 	}
 }
 
-func responseOK(w http.ResponseWriter, r *http.Request, countDeleted int64) {
+func responseOK(w http.ResponseWriter, r *http.Request, rec storage.URLRecord) {
 	render.JSON(w, r, Response{
 		Response:     resp.OK(),
-		CountDeleted: countDeleted,
+		CountDeleted: 1,
+		Alias:        rec.Alias,
+		URL:          rec.URL,
 	})
-}
\ No newline at end of file
+}
@@ -0,0 +1,64 @@
+package deleteall
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+)
+
+type Response struct {
+	resp.Response
+	CountDeleted int64 `json:"countDeleted"`
+}
+
+// AllURLsDeleter is an interface for wiping all links.
+type AllURLsDeleter interface {
+	DeleteAll(ctx context.Context) (int64, error)
+}
+
+// New removes every link. The operation is destructive, so besides
+// authentication it requires an explicit ?confirm=true query parameter.
+func New(log *slog.Logger, deleter AllURLsDeleter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.deleteall.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		if r.URL.Query().Get("confirm") != "true" {
+			log.Info("delete all rejected: not confirmed")
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(resp.CodeConfirmationRequired, "confirmation required: pass confirm=true"))
+
+			return
+		}
+
+		log.Warn("deleting all urls", slog.String("remote_addr", r.RemoteAddr))
+
+		countDeleted, err := deleter.DeleteAll(r.Context())
+		if err != nil {
+			log.Error("failed to delete all urls", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		log.Warn("deleted all urls", slog.Int64("count_deleted", countDeleted))
+
+		render.JSON(w, r, Response{
+			Response:     resp.OK(),
+			CountDeleted: countDeleted,
+		})
+	}
+}
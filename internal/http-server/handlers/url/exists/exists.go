@@ -0,0 +1,66 @@
+package exists
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+)
+
+type Response struct {
+	resp.Response
+	Alias  string `json:"alias"`
+	Exists bool   `json:"exists"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.53.7 --name=AliasChecker
+
+// AliasChecker is an interface for checking whether an alias is taken.
+type AliasChecker interface {
+	AliasExists(ctx context.Context, alias string) (bool, error)
+}
+
+func New(log *slog.Logger, aliasChecker AliasChecker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.exists.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		alias := chi.URLParam(r, "alias")
+		if alias == "" {
+			log.Info("alias is empty")
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(resp.CodeInvalidRequest, "invalid request"))
+
+			return
+		}
+
+		exists, err := aliasChecker.AliasExists(r.Context(), alias)
+		if err != nil {
+			log.Error("failed to check alias", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		log.Info("alias checked", slog.String("alias", alias), slog.Bool("exists", exists))
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Alias:    alias,
+			Exists:   exists,
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package exists_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/exists"
+	"url-shortener/internal/http-server/handlers/url/exists/mocks"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+func TestExistsHandler(t *testing.T) {
+	cases := []struct {
+		name       string
+		alias      string
+		mockExists bool
+		mockError  error
+		respCode   int
+		respError  string
+		wantExists bool
+	}{
+		{
+			name:       "Found",
+			alias:      "google",
+			mockExists: true,
+			respCode:   http.StatusOK,
+			wantExists: true,
+		},
+		{
+			name:     "Not found",
+			alias:    "missing",
+			respCode: http.StatusOK,
+		},
+		{
+			name:      "Storage error",
+			alias:     "google",
+			mockError: errors.New("unexpected error"),
+			respCode:  http.StatusInternalServerError,
+			respError: "internal error",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			checkerMock := mocks.NewAliasChecker(t)
+			checkerMock.On("AliasExists", mock.Anything, tc.alias).Return(tc.mockExists, tc.mockError).Once()
+
+			r := chi.NewRouter()
+			r.Get("/url/{alias}/exists", exists.New(slogdiscard.NewDiscardLogger(), checkerMock))
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/url/"+tc.alias+"/exists", nil))
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var resp exists.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+
+			if tc.respError == "" {
+				// A missing alias is answered with exists: false, not 404.
+				require.Equal(t, tc.alias, resp.Alias)
+				require.Equal(t, tc.wantExists, resp.Exists)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package expand
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+type Response struct {
+	resp.Response
+	Namespace string `json:"namespace,omitempty"`
+	Alias     string `json:"alias,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+// Options configures the expand handler.
+type Options struct {
+	// BasePath is the prefix the service is mounted under; it is stripped
+	// from the path of a short_url before the alias is read from it.
+	BasePath string
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.53.7 --name=URLRecordGetter
+
+// URLRecordGetter is an interface for getting url record by namespace and alias.
+type URLRecordGetter interface {
+	GetNamespacedURLRecord(ctx context.Context, namespace, alias string) (storage.URLRecord, error)
+}
+
+// New returns the destination of a short link without redirecting, for
+// tools that expand short links. The link is given either as ?alias=
+// (with an optional ?namespace=) or as ?short_url=, a full short link
+// whose path is /{alias} or /{namespace}/{alias} under the base path.
+// Links that the redirect would not follow are reported the same way:
+// unknown and disabled links get 404, expired and exhausted ones 410.
+func New(log *slog.Logger, urlGetter URLRecordGetter, opts Options) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.expand.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		namespace, alias, err := linkFromQuery(r.URL.Query(), opts.BasePath)
+		if err != nil {
+			log.Info("invalid link", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(resp.CodeInvalidQuery, err.Error()))
+
+			return
+		}
+
+		rec, err := urlGetter.GetNamespacedURLRecord(r.Context(), namespace, alias)
+		if errors.Is(err, storage.ErrURLNotFound) || (err == nil && rec.Disabled) {
+			log.Info("url not found", slog.String("namespace", namespace), slog.String("alias", alias))
+
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, resp.Error(resp.CodeNotFound, "not found"))
+
+			return
+		}
+		if err != nil && errors.Is(r.Context().Err(), context.DeadlineExceeded) {
+			// The timeout middleware answers with 504 when the handler returns without a response.
+			log.Warn("url lookup timed out", sl.Err(err))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to get url", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		if rec.Expired(time.Now()) {
+			render.Status(r, http.StatusGone)
+			render.JSON(w, r, resp.Error(resp.CodeLinkExpired, "link expired"))
+
+			return
+		}
+		if rec.Exhausted() {
+			render.Status(r, http.StatusGone)
+			render.JSON(w, r, resp.Error(resp.CodeLinkExhausted, "link exhausted"))
+
+			return
+		}
+
+		render.JSON(w, r, Response{
+			Response:  resp.OK(),
+			Namespace: rec.Namespace,
+			Alias:     rec.Alias,
+			URL:       rec.URL,
+		})
+	}
+}
+
+// linkFromQuery reads the namespace and alias of the link from the
+// query parameters. Exactly one of alias and short_url must be set.
+func linkFromQuery(query url.Values, basePath string) (namespace, alias string, err error) {
+	alias = strings.TrimSpace(query.Get("alias"))
+	shortURL := strings.TrimSpace(query.Get("short_url"))
+
+	switch {
+	case alias != "" && shortURL != "":
+		return "", "", errors.New("query parameters alias and short_url cannot be combined")
+	case alias != "":
+		return strings.TrimSpace(query.Get("namespace")), alias, nil
+	case shortURL != "":
+		return parseShortURL(shortURL, basePath)
+	default:
+		return "", "", errors.New("query parameter alias or short_url is required")
+	}
+}
+
+// parseShortURL extracts the namespace and alias from a short link such as
+// https://sho.rt/s/docs/home, where /s is the base path. The scheme and
+// host are optional, so sho.rt/home and /home work too; a single trailing
+// slash is ignored, as the redirect routes do.
+func parseShortURL(shortURL, basePath string) (namespace, alias string, err error) {
+	errInvalid := errors.New("query parameter short_url is not a short link")
+
+	u, err := url.Parse(shortURL)
+	if err != nil {
+		return "", "", errInvalid
+	}
+
+	p := u.Path
+	if u.Scheme == "" && u.Host == "" && !strings.HasPrefix(p, "/") {
+		// sho.rt/home parses as a relative path; drop the host part.
+		_, p, _ = strings.Cut(p, "/")
+		p = "/" + p
+	}
+
+	if base := path.Join("/", basePath); base != "/" {
+		rest, ok := strings.CutPrefix(p, base+"/")
+		if !ok {
+			return "", "", errInvalid
+		}
+		p = "/" + rest
+	}
+
+	segments := strings.Split(strings.Trim(strings.TrimSuffix(p, "/"), "/"), "/")
+	switch {
+	case len(segments) == 1 && segments[0] != "":
+		return "", segments[0], nil
+	case len(segments) == 2 && segments[0] != "" && segments[1] != "":
+		return segments[0], segments[1], nil
+	default:
+		return "", "", errInvalid
+	}
+}
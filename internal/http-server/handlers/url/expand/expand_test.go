@@ -0,0 +1,209 @@
+package expand_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/expand"
+	"url-shortener/internal/http-server/handlers/url/expand/mocks"
+	"url-shortener/internal/http-server/middleware/timeout"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestExpandHandler(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+
+	cases := []struct {
+		name      string
+		query     url.Values
+		basePath  string
+		namespace string
+		alias     string
+		rec       storage.URLRecord
+		mockError error
+		respCode  int
+		errCode   string
+		wantURL   string
+	}{
+		{
+			name:     "Alias",
+			query:    url.Values{"alias": {"google"}},
+			alias:    "google",
+			rec:      storage.URLRecord{Alias: "google", URL: "https://google.com"},
+			respCode: http.StatusOK,
+			wantURL:  "https://google.com",
+		},
+		{
+			name:      "Namespaced alias",
+			query:     url.Values{"alias": {"home"}, "namespace": {"docs"}},
+			namespace: "docs",
+			alias:     "home",
+			rec:       storage.URLRecord{Namespace: "docs", Alias: "home", URL: "https://docs.example.com"},
+			respCode:  http.StatusOK,
+			wantURL:   "https://docs.example.com",
+		},
+		{
+			name:     "Short URL",
+			query:    url.Values{"short_url": {"https://sho.rt/google"}},
+			alias:    "google",
+			rec:      storage.URLRecord{Alias: "google", URL: "https://google.com"},
+			respCode: http.StatusOK,
+			wantURL:  "https://google.com",
+		},
+		{
+			name:      "Short URL with namespace and trailing slash",
+			query:     url.Values{"short_url": {"https://sho.rt/docs/home/"}},
+			namespace: "docs",
+			alias:     "home",
+			rec:       storage.URLRecord{Namespace: "docs", Alias: "home", URL: "https://docs.example.com"},
+			respCode:  http.StatusOK,
+			wantURL:   "https://docs.example.com",
+		},
+		{
+			name:     "Short URL without scheme under base path",
+			query:    url.Values{"short_url": {"sho.rt/s/google?utm_source=x"}},
+			basePath: "/s",
+			alias:    "google",
+			rec:      storage.URLRecord{Alias: "google", URL: "https://google.com"},
+			respCode: http.StatusOK,
+			wantURL:  "https://google.com",
+		},
+		{
+			name:     "Short URL outside base path",
+			query:    url.Values{"short_url": {"https://sho.rt/google"}},
+			basePath: "/s",
+			respCode: http.StatusBadRequest,
+			errCode:  resp.CodeInvalidQuery,
+		},
+		{
+			name:     "Short URL with too many segments",
+			query:    url.Values{"short_url": {"https://sho.rt/a/b/c"}},
+			respCode: http.StatusBadRequest,
+			errCode:  resp.CodeInvalidQuery,
+		},
+		{
+			name:     "Short URL without alias",
+			query:    url.Values{"short_url": {"https://sho.rt/"}},
+			respCode: http.StatusBadRequest,
+			errCode:  resp.CodeInvalidQuery,
+		},
+		{
+			name:     "No parameters",
+			query:    url.Values{},
+			respCode: http.StatusBadRequest,
+			errCode:  resp.CodeInvalidQuery,
+		},
+		{
+			name:     "Both parameters",
+			query:    url.Values{"alias": {"google"}, "short_url": {"https://sho.rt/google"}},
+			respCode: http.StatusBadRequest,
+			errCode:  resp.CodeInvalidQuery,
+		},
+		{
+			name:      "Not found",
+			query:     url.Values{"alias": {"missing"}},
+			alias:     "missing",
+			mockError: storage.ErrURLNotFound,
+			respCode:  http.StatusNotFound,
+			errCode:   resp.CodeNotFound,
+		},
+		{
+			name:     "Disabled",
+			query:    url.Values{"alias": {"google"}},
+			alias:    "google",
+			rec:      storage.URLRecord{Alias: "google", URL: "https://google.com", Disabled: true},
+			respCode: http.StatusNotFound,
+			errCode:  resp.CodeNotFound,
+		},
+		{
+			name:     "Expired",
+			query:    url.Values{"alias": {"google"}},
+			alias:    "google",
+			rec:      storage.URLRecord{Alias: "google", URL: "https://google.com", ExpiresAt: &past},
+			respCode: http.StatusGone,
+			errCode:  resp.CodeLinkExpired,
+		},
+		{
+			name:     "Exhausted",
+			query:    url.Values{"alias": {"google"}},
+			alias:    "google",
+			rec:      storage.URLRecord{Alias: "google", URL: "https://google.com", MaxClicks: 3, Clicks: 3},
+			respCode: http.StatusGone,
+			errCode:  resp.CodeLinkExhausted,
+		},
+		{
+			name:      "Storage error",
+			query:     url.Values{"alias": {"google"}},
+			alias:     "google",
+			mockError: errors.New("unexpected error"),
+			respCode:  http.StatusInternalServerError,
+			errCode:   resp.CodeInternal,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			getterMock := mocks.NewURLRecordGetter(t)
+
+			if tc.alias != "" {
+				getterMock.On("GetNamespacedURLRecord", mock.Anything, tc.namespace, tc.alias).
+					Return(tc.rec, tc.mockError).Once()
+			}
+
+			handler := expand.New(slogdiscard.NewDiscardLogger(), getterMock, expand.Options{BasePath: tc.basePath})
+
+			req := httptest.NewRequest(http.MethodGet, "/api/expand?"+tc.query.Encode(), nil)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var body expand.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+
+			require.Equal(t, tc.errCode, body.Code)
+			require.Equal(t, tc.wantURL, body.URL)
+			if tc.wantURL != "" {
+				require.Equal(t, tc.alias, body.Alias)
+				require.Equal(t, tc.namespace, body.Namespace)
+			}
+		})
+	}
+}
+
+func TestExpandHandler_Timeout(t *testing.T) {
+	getterMock := mocks.NewURLRecordGetter(t)
+	getterMock.On("GetNamespacedURLRecord", mock.Anything, "", "google").
+		Return(func(ctx context.Context, _, _ string) (storage.URLRecord, error) {
+			<-ctx.Done()
+			return storage.URLRecord{}, ctx.Err()
+		}).Once()
+
+	handler := timeout.New(slogdiscard.NewDiscardLogger(), 20*time.Millisecond)(
+		expand.New(slogdiscard.NewDiscardLogger(), getterMock, expand.Options{}),
+	)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/expand?alias=google", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	// A lookup cut off by the deadline is answered by the timeout middleware, not as an internal error.
+	require.Equal(t, http.StatusGatewayTimeout, rr.Code)
+
+	var body expand.Response
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	require.Equal(t, resp.CodeTimeout, body.Code)
+}
@@ -0,0 +1,153 @@
+package expiry
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+// Request sets the new expiration either as an absolute time or as a
+// lifetime counted from now; exactly one of the fields must be present.
+type Request struct {
+	// ExpiresAt is the new expiration time in RFC 3339. An explicit null
+	// clears the expiration, so the link never expires.
+	ExpiresAt json.RawMessage `json:"expires_at,omitempty"`
+	// TTL is the new lifetime from now as a Go duration, e.g. "72h".
+	TTL *string `json:"ttl,omitempty"`
+}
+
+type Response struct {
+	resp.Response
+	Alias string `json:"alias,omitempty"`
+	// ExpiresAt is the new expiration time, null when the link never expires.
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.53.7 --name=ExpirySetter
+
+// ExpirySetter is an interface for changing a link's expiration.
+type ExpirySetter interface {
+	SetExpiry(alias string, expiresAt *time.Time) error
+}
+
+// New extends or shortens the lifetime of the link without touching its
+// destination or other settings, so that a campaign can run longer without
+// recreating its link. The new expiration must be in the future; a link
+// that already expired is served again once it is extended. A shortened
+// link may still be followed from browser caches for up to the max-age its
+// permanent redirects were sent with.
+func New(log *slog.Logger, setter ExpirySetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.expiry.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		alias := chi.URLParam(r, "alias")
+		if alias == "" {
+			log.Info("alias is empty")
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(resp.CodeInvalidRequest, "invalid request"))
+
+			return
+		}
+
+		var req Request
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(resp.CodeInvalidRequest, "failed to decode request"))
+
+			return
+		}
+
+		expiresAt, msg := newExpiry(req, time.Now())
+		if msg != "" {
+			log.Info("invalid expiry", slog.String("alias", alias), slog.String("reason", msg))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(resp.CodeValidationFailed, msg))
+
+			return
+		}
+
+		err := setter.SetExpiry(alias, expiresAt)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("url not found", slog.String("alias", alias))
+
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, resp.Error(resp.CodeNotFound, "not found"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to set url expiry", slog.String("alias", alias), sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		attrs := []any{slog.String("alias", alias)}
+		if expiresAt != nil {
+			attrs = append(attrs, slog.Time("expires_at", *expiresAt))
+		}
+		log.Info("url expiry changed", attrs...)
+
+		render.JSON(w, r, Response{
+			Response:  resp.OK(),
+			Alias:     alias,
+			ExpiresAt: expiresAt,
+		})
+	}
+}
+
+// newExpiry returns the expiration requested by req, nil for none, or a
+// message for the client when the request is invalid.
+func newExpiry(req Request, now time.Time) (*time.Time, string) {
+	switch {
+	case req.ExpiresAt != nil && req.TTL != nil:
+		return nil, "set either expires_at or ttl, not both"
+	case req.TTL != nil:
+		ttl, err := time.ParseDuration(*req.TTL)
+		if err != nil || ttl <= 0 {
+			return nil, "field ttl must be a positive duration, e.g. 72h"
+		}
+
+		expiresAt := now.Add(ttl)
+
+		return &expiresAt, ""
+	case req.ExpiresAt != nil:
+		if bytes.Equal(req.ExpiresAt, []byte("null")) {
+			return nil, ""
+		}
+
+		var expiresAt time.Time
+		if err := json.Unmarshal(req.ExpiresAt, &expiresAt); err != nil {
+			return nil, "field expires_at must be an RFC 3339 time or null"
+		}
+		if !expiresAt.After(now) {
+			return nil, "field expires_at must be in the future"
+		}
+
+		return &expiresAt, ""
+	default:
+		return nil, "expires_at or ttl is required"
+	}
+}
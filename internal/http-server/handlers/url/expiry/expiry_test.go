@@ -0,0 +1,140 @@
+package expiry_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/expiry"
+	"url-shortener/internal/http-server/handlers/url/expiry/mocks"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestExpiryHandler(t *testing.T) {
+	future := time.Now().Add(30 * 24 * time.Hour).UTC().Truncate(time.Second)
+
+	cases := []struct {
+		name      string
+		body      string
+		mockCall  bool
+		mockError error
+		// wantExpiry checks the expiration passed to the storage.
+		wantExpiry func(expiresAt *time.Time) bool
+		respCode   int
+		respError  string
+	}{
+		{
+			name:     "Absolute time",
+			body:     `{"expires_at": "` + future.Format(time.RFC3339) + `"}`,
+			mockCall: true,
+			wantExpiry: func(expiresAt *time.Time) bool {
+				return expiresAt != nil && expiresAt.Equal(future)
+			},
+			respCode: http.StatusOK,
+		},
+		{
+			name:     "TTL",
+			body:     `{"ttl": "72h"}`,
+			mockCall: true,
+			wantExpiry: func(expiresAt *time.Time) bool {
+				return expiresAt != nil && time.Until(*expiresAt) > 71*time.Hour && time.Until(*expiresAt) <= 72*time.Hour
+			},
+			respCode: http.StatusOK,
+		},
+		{
+			name:     "Null clears the expiration",
+			body:     `{"expires_at": null}`,
+			mockCall: true,
+			wantExpiry: func(expiresAt *time.Time) bool {
+				return expiresAt == nil
+			},
+			respCode: http.StatusOK,
+		},
+		{
+			name:      "Not found",
+			body:      `{"ttl": "72h"}`,
+			mockCall:  true,
+			mockError: storage.ErrURLNotFound,
+			respCode:  http.StatusNotFound,
+			respError: "not found",
+		},
+		{
+			name:      "Storage error",
+			body:      `{"ttl": "72h"}`,
+			mockCall:  true,
+			mockError: errors.New("unexpected error"),
+			respCode:  http.StatusInternalServerError,
+			respError: "internal error",
+		},
+		{
+			name:      "Empty body",
+			body:      `{}`,
+			respCode:  http.StatusBadRequest,
+			respError: "expires_at or ttl is required",
+		},
+		{
+			name:      "Both fields",
+			body:      `{"expires_at": "` + future.Format(time.RFC3339) + `", "ttl": "72h"}`,
+			respCode:  http.StatusBadRequest,
+			respError: "set either expires_at or ttl, not both",
+		},
+		{
+			name:      "Past time",
+			body:      `{"expires_at": "2020-01-01T00:00:00Z"}`,
+			respCode:  http.StatusBadRequest,
+			respError: "field expires_at must be in the future",
+		},
+		{
+			name:      "Invalid time",
+			body:      `{"expires_at": "next week"}`,
+			respCode:  http.StatusBadRequest,
+			respError: "field expires_at must be an RFC 3339 time or null",
+		},
+		{
+			name:      "Invalid TTL",
+			body:      `{"ttl": "-1h"}`,
+			respCode:  http.StatusBadRequest,
+			respError: "field ttl must be a positive duration, e.g. 72h",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			setterMock := mocks.NewExpirySetter(t)
+			if tc.mockCall {
+				var match any = mock.Anything
+				if tc.wantExpiry != nil {
+					match = mock.MatchedBy(tc.wantExpiry)
+				}
+				setterMock.On("SetExpiry", "google", match).Return(tc.mockError).Once()
+			}
+
+			r := chi.NewRouter()
+			r.Patch("/url/{alias}/expiry", expiry.New(slogdiscard.NewDiscardLogger(), setterMock))
+
+			req := httptest.NewRequest(http.MethodPatch, "/url/google/expiry", strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var resp expiry.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+			if tc.respError == "" {
+				require.Equal(t, "google", resp.Alias)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package list
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+const (
+	// defaultLimit is the page size when the limit parameter is omitted.
+	defaultLimit = 100
+	// maxLimit caps the page size.
+	maxLimit = 1000
+)
+
+type Response struct {
+	resp.Response
+	URLs []storage.URLRecord `json:"urls"`
+	// NextCursor is the after value for the next page; zero when this is the last page.
+	NextCursor int64 `json:"next_cursor,omitempty"`
+	// NextOffset is the offset value for the next page of a date range query;
+	// zero when this is the last page.
+	NextOffset int64 `json:"next_offset,omitempty"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.53.7 --name=URLLister
+
+// URLLister is an interface for listing links page by page.
+type URLLister interface {
+	ListURLsAfter(id int64, limit int) ([]storage.URLRecord, error)
+	ListURLsByTag(tag string, id int64, limit int) ([]storage.URLRecord, error)
+	ListByDateRange(from, to time.Time, limit, offset int) ([]storage.URLRecord, error)
+}
+
+// New lists links of all namespaces ordered by id using keyset pagination:
+// the "after" query parameter is the next_cursor of the previous page
+// (omitted for the first page) and "limit" is the page size. Unlike offset
+// pagination, fetching a page costs the same however deep it is.
+//
+// When "from" or "to" (RFC 3339 times) is given, New instead lists links
+// created within that inclusive range ordered by creation time, paged with
+// "offset" and "limit". Either bound may be omitted for an open-ended range.
+//
+// "tag" limits the keyset listing to links with that exact tag. It cannot be
+// combined with a date range.
+func New(log *slog.Logger, lister URLLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.list.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		after, err := intParam(r, "after", 0)
+		if err != nil || after < 0 {
+			log.Info("invalid after parameter", slog.String("after", r.URL.Query().Get("after")))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(resp.CodeInvalidQuery, "query parameter after must be a non-negative integer"))
+
+			return
+		}
+
+		limit, err := intParam(r, "limit", defaultLimit)
+		if err != nil || limit < 1 || limit > maxLimit {
+			log.Info("invalid limit parameter", slog.String("limit", r.URL.Query().Get("limit")))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(resp.CodeInvalidQuery, "query parameter limit must be between 1 and 1000"))
+
+			return
+		}
+
+		query := r.URL.Query()
+		tag := query.Get("tag")
+
+		if query.Has("from") || query.Has("to") {
+			if tag != "" {
+				log.Info("tag combined with date range", slog.String("tag", tag))
+
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, resp.Error(resp.CodeInvalidQuery, "query parameter tag cannot be combined with from and to"))
+
+				return
+			}
+
+			listByDateRange(log, lister, w, r, int(limit))
+
+			return
+		}
+
+		var records []storage.URLRecord
+		if tag != "" {
+			records, err = lister.ListURLsByTag(tag, after, int(limit))
+		} else {
+			records, err = lister.ListURLsAfter(after, int(limit))
+		}
+		if err != nil {
+			log.Error("failed to list urls", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		// A full page may be followed by more links; a short one is the last.
+		var nextCursor int64
+		if len(records) == int(limit) {
+			nextCursor = records[len(records)-1].ID
+		}
+
+		render.JSON(w, r, Response{
+			Response:   resp.OK(),
+			URLs:       records,
+			NextCursor: nextCursor,
+		})
+	}
+}
+
+// listByDateRange serves the date range variant of New.
+func listByDateRange(log *slog.Logger, lister URLLister, w http.ResponseWriter, r *http.Request, limit int) {
+	from, err := timeParam(r, "from")
+	if err != nil {
+		log.Info("invalid from parameter", sl.Err(err))
+
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Error(resp.CodeInvalidQuery, "query parameter from must be an RFC 3339 time"))
+
+		return
+	}
+
+	to, err := timeParam(r, "to")
+	if err != nil {
+		log.Info("invalid to parameter", sl.Err(err))
+
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Error(resp.CodeInvalidQuery, "query parameter to must be an RFC 3339 time"))
+
+		return
+	}
+
+	if !from.IsZero() && !to.IsZero() && from.After(to) {
+		log.Info("from is after to", slog.Time("from", from), slog.Time("to", to))
+
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Error(resp.CodeInvalidQuery, "query parameter from must not be after to"))
+
+		return
+	}
+
+	offset, err := intParam(r, "offset", 0)
+	if err != nil || offset < 0 {
+		log.Info("invalid offset parameter", slog.String("offset", r.URL.Query().Get("offset")))
+
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Error(resp.CodeInvalidQuery, "query parameter offset must be a non-negative integer"))
+
+		return
+	}
+
+	records, err := lister.ListByDateRange(from, to, limit, int(offset))
+	if err != nil {
+		log.Error("failed to list urls by date range", sl.Err(err))
+
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, resp.Error(resp.CodeInternal, "internal error"))
+
+		return
+	}
+
+	var nextOffset int64
+	if len(records) == limit {
+		nextOffset = offset + int64(limit)
+	}
+
+	render.JSON(w, r, Response{
+		Response:   resp.OK(),
+		URLs:       records,
+		NextOffset: nextOffset,
+	})
+}
+
+// timeParam parses an RFC 3339 query parameter, returning the zero time when it is absent.
+func timeParam(r *http.Request, name string) (time.Time, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return time.Time{}, nil
+	}
+
+	return time.Parse(time.RFC3339, v)
+}
+
+// intParam parses an integer query parameter, returning def when it is absent.
+func intParam(r *http.Request, name string, def int64) (int64, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, nil
+	}
+
+	return strconv.ParseInt(v, 10, 64)
+}
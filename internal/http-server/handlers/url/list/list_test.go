@@ -0,0 +1,256 @@
+package list_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/list"
+	"url-shortener/internal/http-server/handlers/url/list/mocks"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestListHandler(t *testing.T) {
+	records := func(ids ...int64) []storage.URLRecord {
+		res := make([]storage.URLRecord, 0, len(ids))
+		for _, id := range ids {
+			res = append(res, storage.URLRecord{ID: id, Alias: "a", URL: "https://google.com"})
+		}
+		return res
+	}
+
+	cases := []struct {
+		name       string
+		query      string
+		wantAfter  int64
+		wantLimit  int
+		records    []storage.URLRecord
+		mockError  error
+		respCode   int
+		respError  string
+		nextCursor int64
+	}{
+		{
+			name:      "First page with defaults",
+			query:     "",
+			wantAfter: 0,
+			wantLimit: 100,
+			records:   records(1, 2),
+			respCode:  http.StatusOK,
+		},
+		{
+			name:       "Full page has a next cursor",
+			query:      "?after=10&limit=2",
+			wantAfter:  10,
+			wantLimit:  2,
+			records:    records(11, 15),
+			respCode:   http.StatusOK,
+			nextCursor: 15,
+		},
+		{
+			name:      "Short page is the last",
+			query:     "?after=15&limit=2",
+			wantAfter: 15,
+			wantLimit: 2,
+			records:   records(20),
+			respCode:  http.StatusOK,
+		},
+		{
+			name:      "Invalid after",
+			query:     "?after=abc",
+			respCode:  http.StatusBadRequest,
+			respError: "query parameter after must be a non-negative integer",
+		},
+		{
+			name:      "Limit too large",
+			query:     "?limit=1001",
+			respCode:  http.StatusBadRequest,
+			respError: "query parameter limit must be between 1 and 1000",
+		},
+		{
+			name:      "Storage error",
+			query:     "?after=1",
+			wantAfter: 1,
+			wantLimit: 100,
+			mockError: errors.New("unexpected error"),
+			respCode:  http.StatusInternalServerError,
+			respError: "internal error",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			listerMock := mocks.NewURLLister(t)
+
+			if tc.wantLimit != 0 {
+				listerMock.On("ListURLsAfter", tc.wantAfter, tc.wantLimit).
+					Return(tc.records, tc.mockError).Once()
+			}
+
+			handler := list.New(slogdiscard.NewDiscardLogger(), listerMock)
+
+			req := httptest.NewRequest(http.MethodGet, "/url"+tc.query, nil)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var resp list.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+			require.Equal(t, tc.nextCursor, resp.NextCursor)
+			if tc.respError == "" {
+				require.Len(t, resp.URLs, len(tc.records))
+			}
+		})
+	}
+}
+
+func TestListHandler_Tag(t *testing.T) {
+	listerMock := mocks.NewURLLister(t)
+	listerMock.On("ListURLsByTag", "campaign", int64(10), 2).
+		Return([]storage.URLRecord{
+			{ID: 11, Alias: "a", URL: "https://google.com", Tags: []string{"campaign"}},
+			{ID: 14, Alias: "b", URL: "https://google.com", Tags: []string{"campaign", "docs"}},
+		}, nil).Once()
+
+	handler := list.New(slogdiscard.NewDiscardLogger(), listerMock)
+
+	req := httptest.NewRequest(http.MethodGet, "/url?tag=campaign&after=10&limit=2", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var resp list.Response
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+	require.Empty(t, resp.Error)
+	require.Len(t, resp.URLs, 2)
+	require.Equal(t, []string{"campaign", "docs"}, resp.URLs[1].Tags)
+	// Tagged listing pages with the same cursor as the plain one.
+	require.Equal(t, int64(14), resp.NextCursor)
+}
+
+func TestListHandler_DateRange(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name       string
+		query      string
+		wantFrom   time.Time
+		wantTo     time.Time
+		wantLimit  int
+		wantOffset int
+		records    int
+		mockError  error
+		respCode   int
+		respError  string
+		nextOffset int64
+	}{
+		{
+			name:      "Closed range",
+			query:     "?from=2024-01-01T00:00:00Z&to=2024-02-01T00:00:00Z",
+			wantFrom:  from,
+			wantTo:    to,
+			wantLimit: 100,
+			records:   2,
+			respCode:  http.StatusOK,
+		},
+		{
+			name:      "Only from",
+			query:     "?from=2024-01-01T00:00:00Z",
+			wantFrom:  from,
+			wantLimit: 100,
+			respCode:  http.StatusOK,
+		},
+		{
+			name:      "Only to",
+			query:     "?to=2024-02-01T00:00:00Z",
+			wantTo:    to,
+			wantLimit: 100,
+			respCode:  http.StatusOK,
+		},
+		{
+			name:       "Full page has a next offset",
+			query:      "?from=2024-01-01T00:00:00Z&limit=2&offset=4",
+			wantFrom:   from,
+			wantLimit:  2,
+			wantOffset: 4,
+			records:    2,
+			respCode:   http.StatusOK,
+			nextOffset: 6,
+		},
+		{
+			name:      "From after to",
+			query:     "?from=2024-02-01T00:00:00Z&to=2024-01-01T00:00:00Z",
+			respCode:  http.StatusBadRequest,
+			respError: "query parameter from must not be after to",
+		},
+		{
+			name:      "Invalid from",
+			query:     "?from=yesterday",
+			respCode:  http.StatusBadRequest,
+			respError: "query parameter from must be an RFC 3339 time",
+		},
+		{
+			name:      "Tag with date range",
+			query:     "?from=2024-01-01T00:00:00Z&tag=campaign",
+			respCode:  http.StatusBadRequest,
+			respError: "query parameter tag cannot be combined with from and to",
+		},
+		{
+			name:      "Invalid offset",
+			query:     "?to=2024-02-01T00:00:00Z&offset=-1",
+			respCode:  http.StatusBadRequest,
+			respError: "query parameter offset must be a non-negative integer",
+		},
+		{
+			name:      "Storage error",
+			query:     "?from=2024-01-01T00:00:00Z",
+			wantFrom:  from,
+			wantLimit: 100,
+			mockError: errors.New("unexpected error"),
+			respCode:  http.StatusInternalServerError,
+			respError: "internal error",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			listerMock := mocks.NewURLLister(t)
+
+			if tc.wantLimit != 0 {
+				records := make([]storage.URLRecord, tc.records)
+				listerMock.On("ListByDateRange", tc.wantFrom, tc.wantTo, tc.wantLimit, tc.wantOffset).
+					Return(records, tc.mockError).Once()
+			}
+
+			handler := list.New(slogdiscard.NewDiscardLogger(), listerMock)
+
+			req := httptest.NewRequest(http.MethodGet, "/url"+tc.query, nil)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var resp list.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+			require.Equal(t, tc.nextOffset, resp.NextOffset)
+			require.Zero(t, resp.NextCursor)
+		})
+	}
+}
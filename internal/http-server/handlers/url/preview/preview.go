@@ -0,0 +1,212 @@
+package preview
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"html"
+	"html/template"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/safehttp"
+	"url-shortener/internal/storage"
+)
+
+//go:embed templates/preview.html
+var previewHTML string
+
+var previewTemplate = template.Must(template.New("preview").Parse(previewHTML))
+
+// titleTimeout bounds the title fetch when Options.TitleTimeout is zero.
+const titleTimeout = 2 * time.Second
+
+// maxTitleBody is how much of the target page is read while looking for its title.
+const maxTitleBody = 64 << 10
+
+var titleRe = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+type Response struct {
+	resp.Response
+	Alias     string     `json:"alias,omitempty"`
+	URL       string     `json:"url,omitempty"`
+	Title     string     `json:"title,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// Page is the data passed to the preview template.
+type Page struct {
+	Alias string
+	URL   string
+	Title string
+}
+
+// Options configures the preview handler.
+type Options struct {
+	// FetchTitle enables fetching the title of the target page.
+	FetchTitle bool
+	// TitleTimeout bounds the title fetch. Zero means titleTimeout.
+	TitleTimeout time.Duration
+	// Client fetches the target page. Nil means a safehttp client, which
+	// refuses loopback, private and link-local addresses on every redirect hop:
+	// link destinations are user input and must not reach internal services.
+	Client *http.Client
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.53.7 --name=URLRecordGetter
+
+// URLRecordGetter is an interface for getting url record by alias.
+type URLRecordGetter interface {
+	GetURLRecord(alias string) (storage.URLRecord, error)
+}
+
+// New shows where a short link points without redirecting: JSON for API
+// clients and a simple HTML page for browsers. The target page title is
+// fetched only when enabled, and a failed fetch just leaves it out.
+func New(log *slog.Logger, urlGetter URLRecordGetter, opts Options) http.HandlerFunc {
+	timeout := opts.TitleTimeout
+	if timeout <= 0 {
+		timeout = titleTimeout
+	}
+
+	client := opts.Client
+	if client == nil {
+		client = safehttp.NewClient()
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.preview.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		alias := chi.URLParam(r, "alias")
+		if alias == "" {
+			log.Info("alias is empty")
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(resp.CodeInvalidRequest, "invalid request"))
+
+			return
+		}
+
+		rec, err := urlGetter.GetURLRecord(alias)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("url not found", slog.String("alias", alias))
+
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, resp.Error(resp.CodeNotFound, "not found"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to get url", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		// Disabled links are not served, as with the redirect.
+		if rec.Disabled {
+			log.Info("url disabled", slog.String("alias", alias))
+
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, resp.Error(resp.CodeNotFound, "not found"))
+
+			return
+		}
+
+		// Expired links no longer reveal their destination, as with the redirect.
+		if rec.Expired(time.Now()) {
+			log.Info("url expired", slog.String("alias", alias))
+
+			render.Status(r, http.StatusGone)
+			render.JSON(w, r, Response{
+				Response:  resp.Error(resp.CodeLinkExpired, "link expired"),
+				ExpiresAt: rec.ExpiresAt,
+			})
+
+			return
+		}
+
+		var title string
+		if opts.FetchTitle {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			title, err = fetchTitle(ctx, client, rec.URL)
+			cancel()
+			if err != nil {
+				log.Info("failed to fetch title", slog.String("alias", alias), sl.Err(err))
+			}
+		}
+
+		if strings.Contains(r.Header.Get("Accept"), "text/html") {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+			if err := previewTemplate.Execute(w, Page{Alias: rec.Alias, URL: rec.URL, Title: title}); err != nil {
+				log.Error("failed to render preview page", sl.Err(err))
+			}
+
+			return
+		}
+
+		render.JSON(w, r, Response{
+			Response:  resp.OK(),
+			Alias:     rec.Alias,
+			URL:       rec.URL,
+			Title:     title,
+			ExpiresAt: rec.ExpiresAt,
+		})
+	}
+}
+
+// fetchTitle returns the <title> of the HTML page at target.
+// Only the beginning of the page is read.
+func fetchTitle(ctx context.Context, client *http.Client, target string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return "", fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Accept", "text/html")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fetch page: %w", err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("fetch page: unexpected status %d", res.StatusCode)
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type")); mediaType != "text/html" {
+		return "", fmt.Errorf("fetch page: not an html page: %q", mediaType)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxTitleBody))
+	if err != nil {
+		return "", fmt.Errorf("read page: %w", err)
+	}
+
+	m := titleRe.FindSubmatch(body)
+	if m == nil {
+		return "", nil
+	}
+
+	return strings.Join(strings.Fields(html.UnescapeString(string(m[1]))), " "), nil
+}
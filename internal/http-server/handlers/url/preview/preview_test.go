@@ -0,0 +1,159 @@
+package preview_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/preview"
+	"url-shortener/internal/http-server/handlers/url/preview/mocks"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestPreviewHandler(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow":
+			select {
+			case <-time.After(time.Second):
+			case <-r.Context().Done():
+			}
+		case "/plain":
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte("<title>not html</title>"))
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte("<html><head><TITLE>\n  Go &amp; Friends\n</TITLE></head></html>"))
+	}))
+	defer target.Close()
+
+	expiredAt := time.Now().Add(-time.Hour)
+
+	cases := []struct {
+		name       string
+		alias      string
+		rec        storage.URLRecord
+		mockError  error
+		fetchTitle bool
+		// safeClient leaves Options.Client unset, so the title is fetched with
+		// the default client, which refuses the loopback test server.
+		safeClient bool
+		respCode   int
+		respError  string
+		wantTitle  string
+	}{
+		{
+			name:     "Success",
+			alias:    "go",
+			rec:      storage.URLRecord{Alias: "go", URL: target.URL + "/page"},
+			respCode: http.StatusOK,
+		},
+		{
+			name:       "Success with title",
+			alias:      "go",
+			rec:        storage.URLRecord{Alias: "go", URL: target.URL + "/page"},
+			fetchTitle: true,
+			respCode:   http.StatusOK,
+			wantTitle:  "Go & Friends",
+		},
+		{
+			name:       "Title fetch times out",
+			alias:      "go",
+			rec:        storage.URLRecord{Alias: "go", URL: target.URL + "/slow"},
+			fetchTitle: true,
+			respCode:   http.StatusOK,
+		},
+		{
+			name:       "Target is not html",
+			alias:      "go",
+			rec:        storage.URLRecord{Alias: "go", URL: target.URL + "/plain"},
+			fetchTitle: true,
+			respCode:   http.StatusOK,
+		},
+		{
+			name:       "Loopback target is not fetched",
+			alias:      "go",
+			rec:        storage.URLRecord{Alias: "go", URL: target.URL + "/page"},
+			fetchTitle: true,
+			safeClient: true,
+			respCode:   http.StatusOK,
+		},
+		{
+			name:      "Not found",
+			alias:     "missing",
+			mockError: storage.ErrURLNotFound,
+			respCode:  http.StatusNotFound,
+			respError: "not found",
+		},
+		{
+			name:      "Expired",
+			alias:     "old",
+			rec:       storage.URLRecord{Alias: "old", URL: target.URL, ExpiresAt: &expiredAt},
+			respCode:  http.StatusGone,
+			respError: "link expired",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlGetterMock := mocks.NewURLRecordGetter(t)
+			urlGetterMock.On("GetURLRecord", tc.alias).
+				Return(tc.rec, tc.mockError).Once()
+
+			opts := preview.Options{
+				FetchTitle:   tc.fetchTitle,
+				TitleTimeout: 100 * time.Millisecond,
+			}
+			if !tc.safeClient {
+				opts.Client = target.Client()
+			}
+
+			r := chi.NewRouter()
+			r.Get("/url/{alias}/preview", preview.New(slogdiscard.NewDiscardLogger(), urlGetterMock, opts))
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/url/"+tc.alias+"/preview", nil))
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var resp preview.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+			require.Equal(t, tc.wantTitle, resp.Title)
+			if tc.respError == "" {
+				require.Equal(t, tc.rec.URL, resp.URL)
+			} else {
+				require.Empty(t, resp.URL)
+			}
+		})
+	}
+}
+
+func TestPreviewHandler_HTML(t *testing.T) {
+	urlGetterMock := mocks.NewURLRecordGetter(t)
+	urlGetterMock.On("GetURLRecord", "go").
+		Return(storage.URLRecord{Alias: "go", URL: "https://go.dev/?a=1&b=2"}, nil).Once()
+
+	r := chi.NewRouter()
+	r.Get("/url/{alias}/preview", preview.New(slogdiscard.NewDiscardLogger(), urlGetterMock, preview.Options{}))
+
+	req := httptest.NewRequest(http.MethodGet, "/url/go/preview", nil)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,*/*;q=0.8")
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Empty(t, rr.Header().Get("Location"))
+	require.Contains(t, rr.Header().Get("Content-Type"), "text/html")
+	require.Contains(t, rr.Body.String(), `href="https://go.dev/?a=1&amp;b=2"`)
+}
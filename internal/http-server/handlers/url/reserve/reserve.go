@@ -0,0 +1,143 @@
+package reserve
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/reserved"
+	"url-shortener/internal/storage"
+)
+
+// defaultTTL is how long an alias is held when the request sets no TTL.
+const defaultTTL = 10 * time.Minute
+
+// maxTTL caps the TTL a client may ask for, so that abandoned forms
+// cannot keep vanity aliases away from everyone else for long.
+const maxTTL = time.Hour
+
+type Request struct {
+	Alias string `json:"alias" validate:"required,alphanum"`
+	// TTL is how long the alias is held as a Go duration, e.g. "15m".
+	// Empty means defaultTTL.
+	TTL string `json:"ttl,omitempty"`
+}
+
+type Response struct {
+	resp.Response
+	Alias string `json:"alias,omitempty"`
+	// Token confirms the reservation with POST /url/reservations/confirm.
+	// It is returned only here.
+	Token         string     `json:"token,omitempty"`
+	ReservedUntil *time.Time `json:"reserved_until,omitempty"`
+}
+
+// Options configures the reserve handler.
+type Options struct {
+	// Reserved lists aliases that cannot be used for links.
+	// Nil means the built-in reserved aliases only.
+	Reserved reserved.Set
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.53.7 --name=AliasReserver
+
+// AliasReserver is an interface for holding an alias for a while.
+type AliasReserver interface {
+	ReserveAlias(alias string, ttl time.Duration) (string, error)
+}
+
+// New holds a free alias in the default namespace for the requested TTL,
+// so that a client can finish filling out a form without someone else
+// taking the alias meanwhile. The returned token turns the hold into a
+// link; once the TTL passes the alias is free again.
+func New(log *slog.Logger, reserver AliasReserver, opts Options) http.HandlerFunc {
+	reservedAliases := opts.Reserved
+	if reservedAliases == nil {
+		reservedAliases = reserved.New(nil)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.reserve.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req Request
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(resp.CodeInvalidRequest, "failed to decode request"))
+			return
+		}
+
+		if err := validator.New().Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+			log.Info("invalid request", sl.Err(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.ValidationError(validateErr))
+			return
+		}
+
+		ttl := defaultTTL
+		if req.TTL != "" {
+			parsed, err := time.ParseDuration(req.TTL)
+			if err != nil || parsed <= 0 || parsed > maxTTL {
+				log.Info("invalid ttl", slog.String("ttl", req.TTL))
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, resp.Error(resp.CodeValidationFailed, fmt.Sprintf("field TTL must be a positive duration up to %s, e.g. 15m", maxTTL)))
+				return
+			}
+			ttl = parsed
+		}
+
+		if reservedAliases.Contains(req.Alias) {
+			log.Info("alias is reserved", slog.String("alias", req.Alias))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(resp.CodeAliasReserved, "alias is reserved"))
+			return
+		}
+
+		reservedUntil := time.Now().Add(ttl).UTC()
+
+		token, err := reserver.ReserveAlias(req.Alias, ttl)
+		if errors.Is(err, storage.ErrAliasHeld) {
+			log.Info("alias is already held", slog.String("alias", req.Alias))
+			render.Status(r, http.StatusConflict)
+			render.JSON(w, r, resp.Error(resp.CodeAliasHeld, "alias is reserved by another client"))
+			return
+		}
+		if errors.Is(err, storage.ErrURLExists) {
+			log.Info("alias already exists", slog.String("alias", req.Alias))
+			render.Status(r, http.StatusConflict)
+			render.JSON(w, r, resp.Error(resp.CodeAliasTaken, "url already exists"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to reserve alias", sl.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(resp.CodeInternal, "internal error"))
+			return
+		}
+
+		alias := storage.NormalizeAlias(req.Alias)
+
+		log.Info("alias reserved", slog.String("alias", alias), slog.Duration("ttl", ttl))
+
+		render.JSON(w, r, Response{
+			Response:      resp.OK(),
+			Alias:         alias,
+			Token:         token,
+			ReservedUntil: &reservedUntil,
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package reserve_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/reserve"
+	"url-shortener/internal/http-server/handlers/url/reserve/mocks"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestReserveHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		body      string
+		wantTTL   time.Duration
+		mockError error
+		respCode  int
+		errCode   string
+		wantAlias string
+	}{
+		{
+			name:      "Success with default TTL",
+			body:      `{"alias":"Vanity"}`,
+			wantTTL:   10 * time.Minute,
+			respCode:  http.StatusOK,
+			wantAlias: "vanity",
+		},
+		{
+			name:      "Success with TTL",
+			body:      `{"alias":"vanity","ttl":"15m"}`,
+			wantTTL:   15 * time.Minute,
+			respCode:  http.StatusOK,
+			wantAlias: "vanity",
+		},
+		{
+			name:     "TTL above the cap",
+			body:     `{"alias":"vanity","ttl":"2h"}`,
+			respCode: http.StatusBadRequest,
+			errCode:  "VALIDATION_FAILED",
+		},
+		{
+			name:     "Invalid TTL",
+			body:     `{"alias":"vanity","ttl":"soon"}`,
+			respCode: http.StatusBadRequest,
+			errCode:  "VALIDATION_FAILED",
+		},
+		{
+			name:     "Missing alias",
+			body:     `{}`,
+			respCode: http.StatusBadRequest,
+			errCode:  "VALIDATION_FAILED",
+		},
+		{
+			name:     "Reserved alias",
+			body:     `{"alias":"health"}`,
+			respCode: http.StatusBadRequest,
+			errCode:  "ALIAS_RESERVED",
+		},
+		{
+			name:     "Invalid body",
+			body:     `{`,
+			respCode: http.StatusBadRequest,
+			errCode:  "INVALID_REQUEST",
+		},
+		{
+			name:      "Alias taken",
+			body:      `{"alias":"google"}`,
+			wantTTL:   10 * time.Minute,
+			mockError: storage.ErrURLExists,
+			respCode:  http.StatusConflict,
+			errCode:   "ALIAS_TAKEN",
+		},
+		{
+			name:      "Alias held",
+			body:      `{"alias":"vanity"}`,
+			wantTTL:   10 * time.Minute,
+			mockError: errors.Join(storage.ErrURLExists, storage.ErrAliasHeld),
+			respCode:  http.StatusConflict,
+			errCode:   "ALIAS_HELD",
+		},
+		{
+			name:      "Storage error",
+			body:      `{"alias":"vanity"}`,
+			wantTTL:   10 * time.Minute,
+			mockError: errors.New("unexpected error"),
+			respCode:  http.StatusInternalServerError,
+			errCode:   "INTERNAL_ERROR",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			reserverMock := mocks.NewAliasReserver(t)
+
+			if tc.wantTTL != 0 {
+				reserverMock.On("ReserveAlias", mock.AnythingOfType("string"), tc.wantTTL).
+					Return("token", tc.mockError).Once()
+			}
+
+			handler := reserve.New(slogdiscard.NewDiscardLogger(), reserverMock, reserve.Options{})
+
+			req := httptest.NewRequest(http.MethodPost, "/url/reservations", bytes.NewReader([]byte(tc.body)))
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var resp reserve.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.errCode, resp.Code)
+			require.Equal(t, tc.wantAlias, resp.Alias)
+			if tc.wantAlias != "" {
+				require.Equal(t, "token", resp.Token)
+				require.NotNil(t, resp.ReservedUntil)
+				require.WithinDuration(t, time.Now().Add(tc.wantTTL), *resp.ReservedUntil, time.Minute)
+			}
+		})
+	}
+}
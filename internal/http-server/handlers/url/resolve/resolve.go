@@ -0,0 +1,99 @@
+package resolve
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+// maxAliases limits how many aliases one request may resolve.
+const maxAliases = 1000
+
+type Response struct {
+	resp.Response
+	URLs map[string]string `json:"urls"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.53.7 --name=URLsGetter
+
+// URLsGetter is an interface for getting urls by several aliases at once.
+type URLsGetter interface {
+	GetURLs(aliases []string) (map[string]string, error)
+}
+
+// New resolves the aliases listed in the JSON array request body with a
+// single storage query. The response maps normalized aliases to their urls;
+// aliases that do not exist are absent from the map.
+func New(log *slog.Logger, urlsGetter URLsGetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.resolve.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var aliases []string
+		if err := render.DecodeJSON(r.Body, &aliases); err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(resp.CodeInvalidRequest, "failed to decode request"))
+
+			return
+		}
+
+		aliases = unique(aliases)
+
+		if len(aliases) > maxAliases {
+			log.Info("too many aliases", slog.Int("count", len(aliases)))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(resp.CodeTooManyAliases, fmt.Sprintf("too many aliases: max is %d", maxAliases)))
+
+			return
+		}
+
+		urls, err := urlsGetter.GetURLs(aliases)
+		if err != nil {
+			log.Error("failed to get urls", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		log.Info("resolved urls", slog.Int("requested", len(aliases)), slog.Int("found", len(urls)))
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			URLs:     urls,
+		})
+	}
+}
+
+// unique drops empty and repeated aliases, comparing them in normalized form.
+func unique(aliases []string) []string {
+	seen := make(map[string]struct{}, len(aliases))
+	res := make([]string, 0, len(aliases))
+
+	for _, alias := range aliases {
+		key := storage.NormalizeAlias(alias)
+		if _, ok := seen[key]; ok || alias == "" {
+			continue
+		}
+
+		seen[key] = struct{}{}
+		res = append(res, alias)
+	}
+
+	return res
+}
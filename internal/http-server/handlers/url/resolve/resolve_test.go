@@ -0,0 +1,112 @@
+package resolve_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/resolve"
+	"url-shortener/internal/http-server/handlers/url/resolve/mocks"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+func TestResolveHandler(t *testing.T) {
+	cases := []struct {
+		name        string
+		body        string
+		wantAliases []string
+		urls        map[string]string
+		mockError   error
+		respCode    int
+		respError   string
+	}{
+		{
+			name:        "Success",
+			body:        `["google", "ya", "missing"]`,
+			wantAliases: []string{"google", "ya", "missing"},
+			urls:        map[string]string{"google": "https://google.com", "ya": "https://ya.ru"},
+			respCode:    http.StatusOK,
+		},
+		{
+			name:        "Duplicates and empty aliases are dropped",
+			body:        `["google", "Google", ""]`,
+			wantAliases: []string{"google"},
+			urls:        map[string]string{"google": "https://google.com"},
+			respCode:    http.StatusOK,
+		},
+		{
+			name:        "Empty list",
+			body:        `[]`,
+			wantAliases: []string{},
+			urls:        map[string]string{},
+			respCode:    http.StatusOK,
+		},
+		{
+			name:      "Invalid body",
+			body:      `{"alias": "google"}`,
+			respCode:  http.StatusBadRequest,
+			respError: "failed to decode request",
+		},
+		{
+			name:      "Too many aliases",
+			body:      tooManyAliases(),
+			respCode:  http.StatusBadRequest,
+			respError: "too many aliases: max is 1000",
+		},
+		{
+			name:        "Storage error",
+			body:        `["google"]`,
+			wantAliases: []string{"google"},
+			mockError:   errors.New("unexpected error"),
+			respCode:    http.StatusInternalServerError,
+			respError:   "internal error",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			getterMock := mocks.NewURLsGetter(t)
+
+			if tc.wantAliases != nil {
+				getterMock.On("GetURLs", mock.MatchedBy(func(aliases []string) bool {
+					return strings.Join(aliases, ",") == strings.Join(tc.wantAliases, ",")
+				})).Return(tc.urls, tc.mockError).Once()
+			}
+
+			handler := resolve.New(slogdiscard.NewDiscardLogger(), getterMock)
+
+			req := httptest.NewRequest(http.MethodPost, "/url/resolve", strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var resp resolve.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+			if tc.respError == "" {
+				require.Equal(t, tc.urls, resp.URLs)
+			}
+		})
+	}
+}
+
+func tooManyAliases() string {
+	aliases := make([]string, 1001)
+	for i := range aliases {
+		aliases[i] = "alias" + strconv.Itoa(i)
+	}
+
+	body, _ := json.Marshal(aliases)
+
+	return string(body)
+}
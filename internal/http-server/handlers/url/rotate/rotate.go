@@ -0,0 +1,182 @@
+package rotate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	"url-shortener/internal/lib/aliaslen"
+	"url-shortener/internal/lib/api"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/random"
+	"url-shortener/internal/lib/reserved"
+	"url-shortener/internal/storage"
+)
+
+type Response struct {
+	resp.Response
+	Alias    string `json:"alias,omitempty"`
+	ShortURL string `json:"short_url,omitempty"`
+}
+
+// rotateAttempts is how many generated aliases are tried before giving up.
+const rotateAttempts = 5
+
+// Options configures the rotate handler.
+type Options struct {
+	// BasePath is the prefix the service is mounted under; it is part of short_url.
+	BasePath string
+	// AliasGenerator generates the replacement alias.
+	// Nil means the crypto/rand-backed random.Generator.
+	AliasGenerator random.AliasGenerator
+	// AliasLength decides the length of generated aliases and grows it on collisions.
+	// Nil means a fixed length of random.DefaultLength.
+	AliasLength random.AliasLength
+	// Reserved lists aliases that cannot be used for links.
+	// Nil means the built-in reserved aliases only.
+	Reserved reserved.Set
+	// TrustedProxies are the proxies whose X-Forwarded-Proto is honored
+	// when building short_url.
+	TrustedProxies []*net.IPNet
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.53.7 --name=AliasRotator
+
+// AliasRotator is an interface for moving a link to a new alias.
+type AliasRotator interface {
+	RotateAlias(oldAlias, newAlias string) error
+}
+
+// TxRunner runs a function in a single storage transaction.
+type TxRunner interface {
+	WithTx(ctx context.Context, fn func(tx storage.Tx) error) error
+}
+
+// New moves the link to a freshly generated alias, keeping its destination.
+// The old alias stops resolving immediately. All attempts run in one storage
+// transaction, so a failed rotation leaves the link untouched.
+func New(log *slog.Logger, txRunner TxRunner, opts Options) http.HandlerFunc {
+	aliasGenerator := opts.AliasGenerator
+	if aliasGenerator == nil {
+		aliasGenerator = random.NewGenerator()
+	}
+
+	length := opts.AliasLength
+	if length == nil {
+		length = aliaslen.New(log, random.DefaultLength, random.DefaultLength)
+	}
+
+	reservedAliases := opts.Reserved
+	if reservedAliases == nil {
+		reservedAliases = reserved.New(nil)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.rotate.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		alias := chi.URLParam(r, "alias")
+		if alias == "" {
+			log.Info("alias is empty")
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(resp.CodeInvalidRequest, "invalid request"))
+
+			return
+		}
+
+		var newAlias string
+		err := txRunner.WithTx(r.Context(), func(tx storage.Tx) error {
+			var err error
+			newAlias, err = rotateWithGeneratedAlias(log, tx, aliasGenerator, length, reservedAliases, alias)
+			return err
+		})
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("url not found", slog.String("alias", alias))
+
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, resp.Error(resp.CodeNotFound, "not found"))
+
+			return
+		}
+		if errors.Is(err, storage.ErrURLExists) {
+			log.Error("failed to generate unique alias", slog.Int("attempts", rotateAttempts))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(resp.CodeAliasGenerationFailed, "failed to generate unique alias"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to rotate alias", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		log.Info("alias rotated", slog.String("old_alias", alias), slog.String("alias", newAlias))
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Alias:    newAlias,
+			ShortURL: api.ShortURL(r, opts.TrustedProxies, opts.BasePath, newAlias),
+		})
+	}
+}
+
+// rotateWithGeneratedAlias moves the link to a generated alias, retrying
+// with a fresh one when the generated alias is taken or reserved.
+func rotateWithGeneratedAlias(
+	log *slog.Logger,
+	rotator AliasRotator,
+	aliasGenerator random.AliasGenerator,
+	length random.AliasLength,
+	reservedAliases reserved.Set,
+	oldAlias string,
+) (string, error) {
+	var err error
+
+	for attempt := 1; attempt <= rotateAttempts; attempt++ {
+		n := length.Length()
+
+		var newAlias string
+		newAlias, err = aliasGenerator.Generate(n)
+		if err != nil {
+			return "", fmt.Errorf("generate alias: %w", err)
+		}
+
+		if reservedAliases.Contains(newAlias) {
+			log.Warn("generated alias is reserved, retrying", slog.String("alias", newAlias))
+			err = storage.ErrURLExists
+			continue
+		}
+
+		err = rotator.RotateAlias(oldAlias, newAlias)
+		if !errors.Is(err, storage.ErrURLExists) {
+			return newAlias, err
+		}
+
+		length.Collision(n)
+
+		log.Warn("generated alias already exists, retrying",
+			slog.String("alias", newAlias),
+			slog.Int("attempt", attempt),
+		)
+	}
+
+	return "", err
+}
@@ -0,0 +1,145 @@
+package rotate_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/rotate"
+	"url-shortener/internal/http-server/handlers/url/rotate/mocks"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+// fakeAliasGenerator returns predictable aliases: alias1, alias2, ...
+type fakeAliasGenerator struct {
+	n int
+}
+
+func (g *fakeAliasGenerator) Generate(_ int) (string, error) {
+	g.n++
+	return fmt.Sprintf("alias%d", g.n), nil
+}
+
+// fakeTx is a storage transaction whose RotateAlias is served by the mock.
+type fakeTx struct {
+	storage.Tx
+	rotator *mocks.AliasRotator
+}
+
+func (tx fakeTx) RotateAlias(oldAlias, newAlias string) error {
+	return tx.rotator.RotateAlias(oldAlias, newAlias)
+}
+
+// fakeTxRunner runs the function against fakeTx and records the outcome.
+type fakeTxRunner struct {
+	rotator    *mocks.AliasRotator
+	calls      int
+	rolledBack bool
+}
+
+func (r *fakeTxRunner) WithTx(_ context.Context, fn func(tx storage.Tx) error) error {
+	r.calls++
+
+	err := fn(fakeTx{rotator: r.rotator})
+	r.rolledBack = err != nil
+
+	return err
+}
+
+func TestRotateHandler(t *testing.T) {
+	cases := []struct {
+		name       string
+		alias      string
+		collisions []string
+		mockError  error
+		wantAlias  string
+		respCode   int
+		respError  string
+	}{
+		{
+			name:      "Success",
+			alias:     "google",
+			wantAlias: "alias1",
+			respCode:  http.StatusOK,
+		},
+		{
+			name:       "Generated alias collides once",
+			alias:      "google",
+			collisions: []string{"alias1"},
+			wantAlias:  "alias2",
+			respCode:   http.StatusOK,
+		},
+		{
+			name:       "Generated aliases always collide",
+			alias:      "google",
+			collisions: []string{"alias1", "alias2", "alias3", "alias4", "alias5"},
+			respCode:   http.StatusInternalServerError,
+			respError:  "failed to generate unique alias",
+		},
+		{
+			name:      "Not found",
+			alias:     "missing",
+			mockError: storage.ErrURLNotFound,
+			respCode:  http.StatusNotFound,
+			respError: "not found",
+		},
+		{
+			name:      "Storage error",
+			alias:     "google",
+			mockError: errors.New("unexpected error"),
+			respCode:  http.StatusInternalServerError,
+			respError: "internal error",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rotatorMock := mocks.NewAliasRotator(t)
+
+			for _, collision := range tc.collisions {
+				rotatorMock.On("RotateAlias", tc.alias, collision).
+					Return(storage.ErrURLExists).Once()
+			}
+			if len(tc.collisions) < 5 {
+				next := fmt.Sprintf("alias%d", len(tc.collisions)+1)
+				rotatorMock.On("RotateAlias", tc.alias, next).
+					Return(tc.mockError).Once()
+			}
+
+			txRunner := &fakeTxRunner{rotator: rotatorMock}
+
+			r := chi.NewRouter()
+			r.Post("/url/{alias}/rotate", rotate.New(slogdiscard.NewDiscardLogger(), txRunner, rotate.Options{
+				AliasGenerator: &fakeAliasGenerator{},
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/url/"+tc.alias+"/rotate", nil)
+			rr := httptest.NewRecorder()
+
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			// All attempts share one transaction, which is rolled back on failure.
+			require.Equal(t, 1, txRunner.calls)
+			require.Equal(t, tc.respError != "", txRunner.rolledBack)
+
+			var resp rotate.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+			require.Equal(t, tc.wantAlias, resp.Alias)
+			if tc.wantAlias != "" {
+				require.Equal(t, "http://example.com/"+tc.wantAlias, resp.ShortURL)
+			}
+		})
+	}
+}
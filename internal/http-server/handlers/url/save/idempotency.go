@@ -0,0 +1,165 @@
+package save
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+
+	"github.com/go-chi/render"
+)
+
+// idempotencyKeyHeader carries the client-chosen key that makes retries of
+// a save return the link created by the first attempt.
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// defaultIdempotencyTTL is how long a key is remembered when
+// Options.IdempotencyTTL is zero.
+const defaultIdempotencyTTL = 24 * time.Hour
+
+// maxIdempotencyKeyLength limits the stored key; UUIDs and similar tokens
+// fit comfortably.
+const maxIdempotencyKeyLength = 255
+
+// IdempotencyStore is an interface for remembering which link a request
+// with an Idempotency-Key created.
+type IdempotencyStore interface {
+	ClaimIdempotencyKey(key, fingerprint string, ttl time.Duration) (storage.IdempotencyRecord, error)
+	CompleteIdempotencyKey(key, namespace, alias string) error
+	ReleaseIdempotencyKey(key string) error
+}
+
+// idempotencyClaim is a key taken by the current request. Its methods are
+// safe to call on a nil claim, which stands for a request without a key.
+type idempotencyClaim struct {
+	store     IdempotencyStore
+	key       string
+	completed bool
+}
+
+// claimIdempotencyKey takes the request's Idempotency-Key before the link is
+// created. A key that is already taken is answered here: with the original
+// link for a retry of the same request, 409 while the first request is still
+// running and 422 for a different request. ok is false when the response has
+// been written. Requests without a key, dry runs and a nil store get a nil claim.
+func claimIdempotencyKey(
+	log *slog.Logger,
+	w http.ResponseWriter,
+	r *http.Request,
+	opts Options,
+	req Request,
+	dryRun bool,
+) (claim *idempotencyClaim, ok bool) {
+	key := r.Header.Get(idempotencyKeyHeader)
+	if key == "" || opts.Idempotency == nil || dryRun {
+		return nil, true
+	}
+
+	if len(key) > maxIdempotencyKeyLength {
+		log.Info("idempotency key is too long", slog.Int("length", len(key)))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Error(resp.CodeInvalidRequest,
+			fmt.Sprintf("Idempotency-Key must be at most %d characters", maxIdempotencyKeyLength)))
+		return nil, false
+	}
+
+	ttl := opts.IdempotencyTTL
+	if ttl <= 0 {
+		ttl = defaultIdempotencyTTL
+	}
+
+	fingerprint := requestFingerprint(req)
+
+	rec, err := opts.Idempotency.ClaimIdempotencyKey(key, fingerprint, ttl)
+	if errors.Is(err, storage.ErrIdempotencyKeyExists) {
+		replayIdempotent(log, w, r, opts, rec, fingerprint)
+		return nil, false
+	}
+	if err != nil {
+		log.Error("failed to claim idempotency key", sl.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, resp.Error(resp.CodeInternal, "failed to add url"))
+		return nil, false
+	}
+
+	return &idempotencyClaim{store: opts.Idempotency, key: key}, true
+}
+
+// complete records the created link under the key. The link exists either
+// way, so a failure is only logged; retries then get 409 until the key expires.
+func (c *idempotencyClaim) complete(log *slog.Logger, namespace, alias string) {
+	if c == nil {
+		return
+	}
+
+	if err := c.store.CompleteIdempotencyKey(c.key, namespace, alias); err != nil {
+		log.Error("failed to complete idempotency key", sl.Err(err))
+		return
+	}
+
+	c.completed = true
+}
+
+// release frees the key of a request that did not create a link, so that a
+// retry is processed again.
+func (c *idempotencyClaim) release(log *slog.Logger) {
+	if c == nil || c.completed {
+		return
+	}
+
+	if err := c.store.ReleaseIdempotencyKey(c.key); err != nil {
+		log.Error("failed to release idempotency key", sl.Err(err))
+	}
+}
+
+// replayIdempotent answers a request whose Idempotency-Key is already taken.
+func replayIdempotent(
+	log *slog.Logger,
+	w http.ResponseWriter,
+	r *http.Request,
+	opts Options,
+	rec storage.IdempotencyRecord,
+	fingerprint string,
+) {
+	switch {
+	case rec.Fingerprint != fingerprint:
+		log.Info("idempotency key reused for a different request")
+		render.Status(r, http.StatusUnprocessableEntity)
+		render.JSON(w, r, resp.Error(resp.CodeIdempotencyKeyMismatch, "Idempotency-Key was already used for a different request"))
+	case rec.Alias == "":
+		log.Info("request with the same idempotency key is in progress")
+		w.Header().Set("Retry-After", "1")
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, resp.Error(resp.CodeIdempotencyKeyInProgress, "a request with this Idempotency-Key is still in progress"))
+	default:
+		log.Info("idempotent retry, returning the original link", slog.String("alias", rec.Alias))
+		w.Header().Set("Idempotent-Replayed", "true")
+
+		shortURL := shortURLFor(r, opts, rec.Namespace, rec.Alias)
+		if opts.FormResultURL != "" && isForm(r) {
+			redirectToResult(w, r, opts.FormResultURL, rec.Alias, shortURL)
+			return
+		}
+
+		responseOK(w, r, rec.Alias, shortURL)
+	}
+}
+
+// requestFingerprint identifies the decoded request, so that a key reused
+// with different fields is told apart from a retry. JSON and form bodies
+// with the same fields have the same fingerprint.
+func requestFingerprint(req Request) string {
+	// Request has only JSON-safe fields, so Marshal cannot fail.
+	b, _ := json.Marshal(req)
+	sum := sha256.Sum256(b)
+
+	return hex.EncodeToString(sum[:])
+}
@@ -1,90 +1,779 @@
 package save
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+	"url-shortener/internal/lib/aliaslen"
+	"url-shortener/internal/lib/api"
 	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/clientip"
+	"url-shortener/internal/lib/linkcap"
 	"url-shortener/internal/lib/logger/sl"
 	"url-shortener/internal/lib/random"
+	"url-shortener/internal/lib/redirectheaders"
+	"url-shortener/internal/lib/reserved"
+	"url-shortener/internal/lib/utm"
 	"url-shortener/internal/storage"
 
 	"log/slog"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 	"github.com/go-playground/validator/v10"
 )
 
 type Request struct {
-	URL   string `json:"url" validate:"required,url"`
-	Alias string `json:"alias,omitempty"`
+	URL       string `json:"url" validate:"required,http_url"`
+	Alias     string `json:"alias,omitempty" validate:"omitempty,alphanum"`
+	Permanent *bool  `json:"permanent,omitempty"`
+	// PreserveMethod redirects with 307/308 instead of 302/301, so that
+	// clients resend the same method and body to the destination. Nil means
+	// the configured default.
+	PreserveMethod *bool `json:"preserve_method,omitempty"`
+	// TTL is the link lifetime as a Go duration, e.g. "72h". Empty means no expiration.
+	TTL string `json:"ttl,omitempty"`
+	// Namespace scopes the alias to a project; the link is served at /{namespace}/{alias}.
+	// Empty means the default namespace and the flat /{alias} link.
+	// A {namespace} path parameter takes precedence over the body.
+	Namespace string `json:"namespace,omitempty" validate:"omitempty,alphanum"`
+	// MaxClicks is how many redirects the link serves before answering 410 Gone.
+	// Zero means unlimited.
+	MaxClicks int64 `json:"max_clicks,omitempty" validate:"gte=0"`
+	// UTM tracking parameters are merged into the query string of the stored
+	// URL, replacing the URL's own values for the same parameters.
+	UTM *utm.Params `json:"utm,omitempty"`
+	// Tags label the link for filtering with GET /url?tag=. Tags are matched
+	// exactly, are stored without duplicates and cannot contain commas.
+	Tags []string `json:"tags,omitempty" validate:"max=20,dive,required,max=50,excludesall=0x2C"`
+	// Headers are set on the link's redirects on top of the configured
+	// redirect headers; an empty value removes a configured header. JSON
+	// only: forms cannot set them.
+	Headers map[string]string `json:"headers,omitempty" validate:"max=20,dive,keys,max=100,endkeys,max=1024"`
+	// ForwardPath makes the link forward the rest of the path: with the URL
+	// https://example.com/guide, /{alias}/intro redirects to
+	// https://example.com/guide/intro. Only for the default namespace.
+	ForwardPath bool `json:"forward_path,omitempty"`
 }
 
 type Response struct {
 	resp.Response
-	Alias string `json:"alias,omitempty"`
+	Alias    string `json:"alias,omitempty"`
+	ShortURL string `json:"short_url,omitempty"`
+	// DryRun is set when the request was only validated and no link was created.
+	DryRun bool `json:"dry_run,omitempty"`
+	// Existing is set when the URL was already shortened and the existing link is returned.
+	Existing bool `json:"existing,omitempty"`
 }
 
-// TODO: move to config if needed
-const aliasLength = 6
+// generateAliasAttempts is how many random aliases are tried before giving up.
+const generateAliasAttempts = 5
+
+// maxURLLengthHardCap limits URL length even when no limit is configured.
+const maxURLLengthHardCap = 8192
+
+// Options configures the save handler.
+type Options struct {
+	// MaxURLLength is the maximum accepted URL length in bytes.
+	// Zero or values above maxURLLengthHardCap fall back to the hard cap.
+	MaxURLLength int
+	// BasePath is the prefix the service is mounted under; it is part of short_url.
+	BasePath string
+	// AliasGenerator generates aliases when the client does not supply one.
+	// Nil means the crypto/rand-backed random.Generator.
+	AliasGenerator random.AliasGenerator
+	// AliasLength decides the length of generated aliases and grows it on collisions.
+	// Nil means a fixed length of random.DefaultLength.
+	AliasLength random.AliasLength
+	// Reserved lists aliases that cannot be used for links.
+	// Nil means the built-in reserved aliases only.
+	Reserved reserved.Set
+	// MaxLinksPerIP caps how many links a single client IP may own.
+	// Zero disables the cap. LinkCounter must be set when the cap is enabled.
+	MaxLinksPerIP int
+	// LinkCounter counts links by the IP that created them.
+	LinkCounter LinkCounter
+	// MaxTotalLinks caps how many links the storage may hold; saves beyond it
+	// get 507 Insufficient Storage. Zero disables the cap. TotalLinkCounter
+	// must be set when the cap is enabled.
+	MaxTotalLinks int64
+	// TotalLinkCounter counts all stored links for MaxTotalLinks. The count
+	// is cached, see linkcap.Cap.
+	TotalLinkCounter TotalLinkCounter
+	// TrustedProxies are the proxies whose forwarding headers are honored
+	// when resolving the client IP and the scheme of short_url.
+	TrustedProxies []*net.IPNet
+	// FormResultURL is where browsers are sent (303 See Other) after a
+	// successful form submission, with alias and short_url in the query.
+	// Empty means form submissions get the same JSON response as API clients.
+	FormResultURL string
+	// AliasChecker looks up existing links for ?dry_run=true requests.
+	// Nil means dry runs skip the alias availability check.
+	AliasChecker AliasChecker
+	// Notifier is told about every created link. Nil disables notifications.
+	Notifier CreationNotifier
+	// Sequential saves links without a client alias under an alias encoded
+	// from their ID by AliasEncoder instead of a generated one. Nil, or a
+	// nil AliasEncoder, means aliases are generated by AliasGenerator.
+	Sequential SequentialSaver
+	// AliasEncoder encodes link IDs into aliases for Sequential.
+	AliasEncoder AliasEncoder
+	// Duplicates looks up the existing link when the storage rejects a URL
+	// that is already shortened (storage.ErrURLDuplicate, dedupe mode), and
+	// its alias is returned instead of creating a link if it is live and has
+	// the requested options (see storage.URLRecord.Reusable). Otherwise, or
+	// when Duplicates is nil, such saves get 409 Conflict.
+	Duplicates DuplicateFinder
+	// Idempotency remembers the link created for each Idempotency-Key, so
+	// that a retried request gets the original link instead of a new one.
+	// Nil means the header is ignored.
+	Idempotency IdempotencyStore
+	// IdempotencyTTL is how long an Idempotency-Key is remembered.
+	// Zero means defaultIdempotencyTTL.
+	IdempotencyTTL time.Duration
+}
+
+// DuplicateFinder is an interface for looking up the link of an already shortened URL.
+type DuplicateFinder interface {
+	GetURLRecordByURL(urlToSave string) (storage.URLRecord, error)
+}
+
+// CreationNotifier is an interface for announcing created links.
+// LinkCreated must not block.
+type CreationNotifier interface {
+	LinkCreated(namespace, alias, target string)
+}
+
+// SequentialSaver is an interface for saving links under aliases derived
+// from their ID. encode returns "" for an ID whose alias cannot be used.
+type SequentialSaver interface {
+	SaveURLSequential(urlToSave string, opts storage.URLOptions, encode func(id int64) string) (string, int64, error)
+}
+
+// AliasEncoder is an interface for turning link IDs into aliases.
+type AliasEncoder interface {
+	Encode(id int64) string
+}
+
+// AliasChecker is an interface for looking up a link by namespace and alias.
+type AliasChecker interface {
+	GetNamespacedURLRecord(ctx context.Context, namespace, alias string) (storage.URLRecord, error)
+}
+
+// LinkCounter is an interface for counting links created from an IP.
+type LinkCounter interface {
+	CountByCreator(ip string) (int, error)
+}
+
+// TotalLinkCounter is an interface for counting all stored links.
+type TotalLinkCounter interface {
+	CountURLs(ctx context.Context) (int64, error)
+}
 
 //go:generate go run github.com/vektra/mockery/v2 --name=URLSaver 
 
 type URLSaver interface {
-	SaveURL(urlToSave string, alias string) (int64, error)
+	SaveURL(urlToSave string, alias string, opts storage.URLOptions) (int64, error)
 }
 
-func New(log *slog.Logger, urlSaver URLSaver) http.HandlerFunc {
+func New(log *slog.Logger, urlSaver URLSaver, opts Options) http.HandlerFunc {
+	maxURLLength := opts.MaxURLLength
+	if maxURLLength <= 0 || maxURLLength > maxURLLengthHardCap {
+		maxURLLength = maxURLLengthHardCap
+	}
+
+	aliasGenerator := opts.AliasGenerator
+	if aliasGenerator == nil {
+		aliasGenerator = random.NewGenerator()
+	}
+
+	length := opts.AliasLength
+	if length == nil {
+		length = aliaslen.New(log, random.DefaultLength, random.DefaultLength)
+	}
+
+	reservedAliases := opts.Reserved
+	if reservedAliases == nil {
+		reservedAliases = reserved.New(nil)
+	}
+
+	var linkCap *linkcap.Cap
+	if opts.MaxTotalLinks > 0 && opts.TotalLinkCounter != nil {
+		linkCap = linkcap.New(opts.TotalLinkCounter, opts.MaxTotalLinks)
+	}
+
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.url.save.New"
 
-		log = log.With(
+		log := log.With(
 			slog.String("op", op),
 			slog.String("request_id", middleware.GetReqID(r.Context())),
 		)
 
 		var req Request
 
-		err := render.DecodeJSON(r.Body, &req)
+		err := decodeRequest(r, &req)
 		if err != nil {
 			log.Error("failed to decode request body", sl.Err(err))
 
-			render.JSON(w, r, resp.Error("failed to decode request"))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(resp.CodeInvalidRequest, "failed to decode request"))
 			return
 		}
+		if namespace := chi.URLParam(r, "namespace"); namespace != "" {
+			req.Namespace = namespace
+		}
+
 		log.Info("request body decoded", slog.Any("request", req))
 
+		// dry_run=true validates the request and checks the alias without creating the link.
+		dryRun := false
+		if v := r.URL.Query().Get("dry_run"); v != "" {
+			dryRun, err = strconv.ParseBool(v)
+			if err != nil {
+				log.Info("invalid dry_run", slog.String("dry_run", v))
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, resp.Error(resp.CodeInvalidQuery, "query parameter dry_run must be a boolean"))
+				return
+			}
+		}
+
 		if err := validator.New().Struct(req); err != nil {
 			validateErr := err.(validator.ValidationErrors)
 			log.Error("invalid request", sl.Err(err))
+			render.Status(r, http.StatusBadRequest)
 			render.JSON(w, r, resp.ValidationError(validateErr))
 			return
 		}
+
+		// /{namespace}/{alias}/rest could not be told from /{alias}/rest of a link in the default namespace.
+		if req.ForwardPath && req.Namespace != "" {
+			log.Info("forward_path with a namespace", slog.String("namespace", req.Namespace))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(resp.CodeValidationFailed, "forward_path links cannot have a namespace"))
+			return
+		}
+
+		if err := redirectheaders.Validate(req.Headers); err != nil {
+			log.Info("invalid redirect headers", sl.Err(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(resp.CodeValidationFailed, "invalid headers: "+err.Error()))
+			return
+		}
+
+		if req.UTM != nil {
+			merged, err := utm.Merge(req.URL, *req.UTM)
+			if err != nil {
+				log.Info("failed to merge utm parameters", sl.Err(err))
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, resp.Error(resp.CodeInvalidURL, "failed to merge utm parameters into url"))
+				return
+			}
+			req.URL = merged
+		}
+
+		if len(req.URL) > maxURLLength {
+			log.Info("url is too long", slog.Int("length", len(req.URL)), slog.Int("max_length", maxURLLength))
+			render.Status(r, http.StatusUnprocessableEntity)
+			render.JSON(w, r, resp.Error(resp.CodeURLTooLong, fmt.Sprintf("url is too long: max length is %d", maxURLLength)))
+			return
+		}
+
+		// The key is claimed before the link caps, so that a retry of a save
+		// that already succeeded gets its link even when the cap is reached.
+		claim, ok := claimIdempotencyKey(log, w, r, opts, req, dryRun)
+		if !ok {
+			return
+		}
+		defer claim.release(log)
+
+		creatorIP := ""
+		if ip := clientip.ClientIP(r, opts.TrustedProxies); ip != nil {
+			creatorIP = ip.String()
+		}
+
+		if opts.MaxLinksPerIP > 0 && opts.LinkCounter != nil {
+			count, err := opts.LinkCounter.CountByCreator(creatorIP)
+			if err != nil {
+				log.Error("failed to count links by creator", sl.Err(err))
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, resp.Error(resp.CodeInternal, "failed to add url"))
+				return
+			}
+			if count >= opts.MaxLinksPerIP {
+				log.Info("links per ip limit reached",
+					slog.String("ip", creatorIP),
+					slog.Int("count", count),
+					slog.Int("limit", opts.MaxLinksPerIP),
+				)
+				render.Status(r, http.StatusTooManyRequests)
+				render.JSON(w, r, resp.Error(resp.CodeLinkLimitReached, fmt.Sprintf("link limit reached: max %d links per client", opts.MaxLinksPerIP)))
+				return
+			}
+		}
+
+		if linkCap != nil {
+			full, count, err := linkCap.Full(r.Context())
+			if err != nil {
+				log.Error("failed to count links", sl.Err(err))
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, resp.Error(resp.CodeInternal, "failed to add url"))
+				return
+			}
+			if full {
+				log.Warn("total links limit reached",
+					slog.Int64("count", count),
+					slog.Int64("limit", opts.MaxTotalLinks),
+				)
+				render.Status(r, http.StatusInsufficientStorage)
+				render.JSON(w, r, resp.Error(resp.CodeStorageFull, fmt.Sprintf("link storage is full: max %d links", opts.MaxTotalLinks)))
+				return
+			}
+		}
+
+		urlOpts := storage.URLOptions{
+			Permanent:      req.Permanent,
+			PreserveMethod: req.PreserveMethod,
+			CreatorIP:      creatorIP,
+			Namespace:      req.Namespace,
+			MaxClicks:      req.MaxClicks,
+			Tags:           req.Tags,
+			Headers:        req.Headers,
+			ForwardPath:    req.ForwardPath,
+		}
+
+		if req.TTL != "" {
+			ttl, err := time.ParseDuration(req.TTL)
+			if err != nil || ttl <= 0 {
+				log.Info("invalid ttl", slog.String("ttl", req.TTL))
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, resp.Error(resp.CodeValidationFailed, "field TTL must be a positive duration, e.g. 72h"))
+				return
+			}
+
+			expiresAt := time.Now().Add(ttl)
+			urlOpts.ExpiresAt = &expiresAt
+		}
+
+		// A namespace is the first path segment of its links, so it must not shadow the service's own routes.
+		if req.Namespace != "" && reservedAliases.Contains(req.Namespace) {
+			log.Info("namespace is reserved", slog.String("namespace", req.Namespace))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(resp.CodeNamespaceReserved, "namespace is reserved"))
+			return
+		}
+
 		alias := req.Alias
-		if alias == "" {
-			alias = random.NewRandomString(aliasLength)
-			
+
+		if alias != "" && reservedAliases.Contains(alias) {
+			log.Info("alias is reserved", slog.String("alias", alias))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(resp.CodeAliasReserved, "alias is reserved"))
+			return
 		}
 
-		id, err := urlSaver.SaveURL(req.URL, alias)
-		if errors.Is(err, storage.ErrURLExists) {
-			log.Info("url already exists", slog.String("url", req.URL))
-			render.JSON(w, r, resp.Error("url already exists"))
+		if dryRun {
+			dryRunSave(log, w, r, opts, aliasGenerator, length, reservedAliases, alias, req.Namespace)
+			return
+		}
+
+		var id int64
+		if alias != "" {
+			id, err = urlSaver.SaveURL(req.URL, alias, urlOpts)
+			if errors.Is(err, storage.ErrAliasConfusable) {
+				log.Info("alias looks like an existing one", slog.String("alias", alias))
+				render.Status(r, http.StatusConflict)
+				render.JSON(w, r, resp.Error(resp.CodeAliasConfusable, "alias is too similar to an existing alias"))
+				return
+			}
+			if errors.Is(err, storage.ErrAliasHeld) {
+				log.Info("alias is held by a reservation", slog.String("alias", alias))
+				render.Status(r, http.StatusConflict)
+				render.JSON(w, r, resp.Error(resp.CodeAliasHeld, "alias is reserved by another client"))
+				return
+			}
+			if errors.Is(err, storage.ErrURLExists) {
+				log.Info("alias already exists", slog.String("alias", alias))
+				render.Status(r, http.StatusConflict)
+				render.JSON(w, r, resp.Error(resp.CodeAliasTaken, "url already exists"))
+				return
+			}
+		} else {
+			if opts.Sequential != nil && opts.AliasEncoder != nil {
+				alias, id, err = saveWithSequentialAlias(opts.Sequential, opts.AliasEncoder, reservedAliases, req.URL, urlOpts)
+			} else {
+				alias, id, err = saveWithGeneratedAlias(log, urlSaver, aliasGenerator, length, reservedAliases, req.URL, urlOpts)
+			}
+			if errors.Is(err, storage.ErrURLExists) {
+				log.Error("failed to generate unique alias", slog.Int("attempts", generateAliasAttempts))
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, resp.Error(resp.CodeAliasGenerationFailed, "failed to generate unique alias"))
+				return
+			}
+		}
+		if errors.Is(err, storage.ErrURLDuplicate) {
+			respondExisting(log, w, r, opts, req.URL, urlOpts)
 			return
 		}
 		if err != nil {
 			log.Error("failed to add url", sl.Err(err))
-			render.JSON(w, r, resp.Error("failed to add url"))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(resp.CodeInternal, "failed to add url"))
 			return
 		}
 		log.Info("url added", slog.Int64("id", id))
-		responseOK(w, r, alias)
+		// The storage keeps aliases in lower case: answer with the alias as stored,
+		// not as typed, so that alias and short_url match what GET /url/{alias} returns.
+		alias = storage.NormalizeAlias(alias)
+		claim.complete(log, req.Namespace, alias)
+		if linkCap != nil {
+			linkCap.Added()
+		}
+		if opts.Notifier != nil {
+			opts.Notifier.LinkCreated(storage.NormalizeAlias(req.Namespace), alias, req.URL)
+		}
+		shortURL := shortURLFor(r, opts, req.Namespace, alias)
+
+		if opts.FormResultURL != "" && isForm(r) {
+			redirectToResult(w, r, opts.FormResultURL, alias, shortURL)
+			return
+		}
+
+		responseOK(w, r, alias, shortURL)
+	}
+}
+
+// respondExisting answers a save of an already shortened URL with its
+// existing link, which the storage guarantees to be unique in dedupe mode.
+// A link that no longer redirects or was created with other options is not
+// handed out: the client would silently get a different link than it asked
+// for. It gets 409 Conflict with the alias of the existing link instead.
+func respondExisting(log *slog.Logger, w http.ResponseWriter, r *http.Request, opts Options, urlToSave string, urlOpts storage.URLOptions) {
+	if opts.Duplicates == nil {
+		log.Info("url already shortened")
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, resp.Error(resp.CodeURLAlreadyShortened, "url already shortened"))
+		return
+	}
+
+	rec, err := opts.Duplicates.GetURLRecordByURL(urlToSave)
+	if err != nil {
+		log.Error("failed to get existing link", sl.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, resp.Error(resp.CodeInternal, "failed to add url"))
+		return
+	}
+
+	if !rec.Reusable(urlOpts, time.Now()) {
+		log.Info("url already shortened by a link that cannot be reused", slog.Int64("id", rec.ID), slog.String("alias", rec.Alias))
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, Response{
+			Response: resp.Error(resp.CodeURLAlreadyShortened, "url already shortened by an inactive link or with different options"),
+			Alias:    rec.Alias,
+		})
+		return
 	}
+
+	log.Info("url already shortened, returning existing link", slog.Int64("id", rec.ID), slog.String("alias", rec.Alias))
+
+	render.JSON(w, r, Response{
+		Response: resp.OK(),
+		Alias:    rec.Alias,
+		ShortURL: shortURLFor(r, opts, rec.Namespace, rec.Alias),
+		Existing: true,
+	})
+}
+
+// dryRunSave answers a ?dry_run=true request after validation: it reports the
+// alias and short_url the link would get without inserting it. A custom alias
+// is checked for availability; for a generated alias a free candidate is
+// returned, but the alias length is not adjusted on collisions and a real
+// save will generate a different alias.
+func dryRunSave(
+	log *slog.Logger,
+	w http.ResponseWriter,
+	r *http.Request,
+	opts Options,
+	aliasGenerator random.AliasGenerator,
+	length random.AliasLength,
+	reservedAliases reserved.Set,
+	alias, namespace string,
+) {
+	var err error
+	if alias != "" {
+		err = checkAliasFree(r.Context(), opts.AliasChecker, namespace, alias)
+		if errors.Is(err, storage.ErrURLExists) {
+			log.Info("dry run: alias already exists", slog.String("alias", alias))
+			render.Status(r, http.StatusConflict)
+			render.JSON(w, r, resp.Error(resp.CodeAliasTaken, "url already exists"))
+			return
+		}
+	} else if opts.Sequential != nil && opts.AliasEncoder != nil {
+		// The alias is derived from the ID, which is only known after the insert.
+		log.Info("dry run: url not added, alias is assigned on save")
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			DryRun:   true,
+		})
+
+		return
+	} else {
+		alias, err = freeGeneratedAlias(r.Context(), log, opts.AliasChecker, aliasGenerator, length, reservedAliases, namespace)
+		if errors.Is(err, storage.ErrURLExists) {
+			log.Error("dry run: failed to generate unique alias", slog.Int("attempts", generateAliasAttempts))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(resp.CodeAliasGenerationFailed, "failed to generate unique alias"))
+			return
+		}
+	}
+	if err != nil {
+		log.Error("dry run: failed to check alias", sl.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, resp.Error(resp.CodeInternal, "failed to add url"))
+		return
+	}
+
+	log.Info("dry run: url not added", slog.String("alias", alias))
+
+	alias = storage.NormalizeAlias(alias)
+	render.JSON(w, r, Response{
+		Response: resp.OK(),
+		Alias:    alias,
+		ShortURL: shortURLFor(r, opts, namespace, alias),
+		DryRun:   true,
+	})
+}
+
+// checkAliasFree returns storage.ErrURLExists if the alias is taken in the namespace.
+// A nil checker treats every alias as free.
+func checkAliasFree(ctx context.Context, checker AliasChecker, namespace, alias string) error {
+	if checker == nil {
+		return nil
+	}
+
+	_, err := checker.GetNamespacedURLRecord(ctx, namespace, alias)
+	switch {
+	case errors.Is(err, storage.ErrURLNotFound):
+		return nil
+	case err != nil:
+		return err
+	default:
+		return storage.ErrURLExists
+	}
+}
+
+// freeGeneratedAlias generates aliases like saveWithGeneratedAlias, but only
+// checks them for availability instead of inserting.
+func freeGeneratedAlias(
+	ctx context.Context,
+	log *slog.Logger,
+	checker AliasChecker,
+	aliasGenerator random.AliasGenerator,
+	length random.AliasLength,
+	reservedAliases reserved.Set,
+	namespace string,
+) (string, error) {
+	err := storage.ErrURLExists
+
+	for attempt := 1; attempt <= generateAliasAttempts; attempt++ {
+		alias, genErr := aliasGenerator.Generate(length.Length())
+		if genErr != nil {
+			return "", fmt.Errorf("generate alias: %w", genErr)
+		}
+
+		if reservedAliases.Contains(alias) {
+			continue
+		}
+
+		err = checkAliasFree(ctx, checker, namespace, alias)
+		if !errors.Is(err, storage.ErrURLExists) {
+			return alias, err
+		}
+
+		log.Debug("dry run: generated alias already exists", slog.String("alias", alias), slog.Int("attempt", attempt))
+	}
+
+	return "", err
+}
+
+// shortURLFor builds the short link for an alias in a namespace.
+func shortURLFor(r *http.Request, opts Options, namespace, alias string) string {
+	linkPath := alias
+	if namespace != "" {
+		linkPath = storage.NormalizeAlias(namespace) + "/" + alias
+	}
+
+	return api.ShortURL(r, opts.TrustedProxies, opts.BasePath, linkPath)
+}
+
+// decodeRequest decodes the body as JSON or as an HTML form
+// (application/x-www-form-urlencoded) depending on Content-Type.
+func decodeRequest(r *http.Request, req *Request) error {
+	if !isForm(r) {
+		return render.DecodeJSON(r.Body, req)
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+
+	req.URL = r.PostForm.Get("url")
+	req.Alias = r.PostForm.Get("alias")
+	req.TTL = r.PostForm.Get("ttl")
+	req.Namespace = r.PostForm.Get("namespace")
+	req.Tags = r.PostForm["tags"]
+
+	params := utm.Params{
+		Source:   r.PostForm.Get("utm_source"),
+		Medium:   r.PostForm.Get("utm_medium"),
+		Campaign: r.PostForm.Get("utm_campaign"),
+	}
+	if params != (utm.Params{}) {
+		req.UTM = &params
+	}
+
+	if v := r.PostForm.Get("max_clicks"); v != "" {
+		maxClicks, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid max_clicks value %q: %w", v, err)
+		}
+		req.MaxClicks = maxClicks
+	}
+
+	var err error
+	if req.Permanent, err = formBool(r, "permanent"); err != nil {
+		return err
+	}
+	if req.PreserveMethod, err = formBool(r, "preserve_method"); err != nil {
+		return err
+	}
+	forwardPath, err := formBool(r, "forward_path")
+	if err != nil {
+		return err
+	}
+	req.ForwardPath = forwardPath != nil && *forwardPath
+
+	return nil
+}
+
+// formBool parses an optional boolean form field. Nil means the field is absent.
+func formBool(r *http.Request, field string) (*bool, error) {
+	v := r.PostForm.Get(field)
+	if v == "" {
+		return nil, nil
+	}
+
+	// Checkboxes are submitted as "on".
+	value := v == "on"
+	if !value {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s value %q: %w", field, v, err)
+		}
+		value = parsed
+	}
+
+	return &value, nil
+}
+
+func isForm(r *http.Request) bool {
+	return render.GetRequestContentType(r) == render.ContentTypeForm
+}
+
+// redirectToResult sends a browser to the results page after a form submission.
+func redirectToResult(w http.ResponseWriter, r *http.Request, resultURL, alias, shortURL string) {
+	u, err := url.Parse(resultURL)
+	if err != nil {
+		responseOK(w, r, alias, shortURL)
+		return
+	}
+
+	q := u.Query()
+	q.Set("alias", alias)
+	q.Set("short_url", shortURL)
+	u.RawQuery = q.Encode()
+
+	http.Redirect(w, r, u.String(), http.StatusSeeOther)
+}
+
+// saveWithGeneratedAlias saves the url under a generated alias. A collision on a
+// generated alias is not the client's fault, so a fresh alias is generated
+// and the insert retried up to generateAliasAttempts times. Reserved aliases
+// are skipped the same way as collisions.
+func saveWithGeneratedAlias(
+	log *slog.Logger,
+	urlSaver URLSaver,
+	aliasGenerator random.AliasGenerator,
+	length random.AliasLength,
+	reservedAliases reserved.Set,
+	urlToSave string,
+	urlOpts storage.URLOptions,
+) (string, int64, error) {
+	var (
+		alias string
+		id    int64
+		err   error
+	)
+
+	for attempt := 1; attempt <= generateAliasAttempts; attempt++ {
+		n := length.Length()
+
+		alias, err = aliasGenerator.Generate(n)
+		if err != nil {
+			return "", 0, fmt.Errorf("generate alias: %w", err)
+		}
+
+		if reservedAliases.Contains(alias) {
+			log.Warn("generated alias is reserved, retrying", slog.String("alias", alias))
+			err = storage.ErrURLExists
+			continue
+		}
+
+		id, err = urlSaver.SaveURL(urlToSave, alias, urlOpts)
+		if !errors.Is(err, storage.ErrURLExists) {
+			return alias, id, err
+		}
+
+		length.Collision(n)
+
+		log.Warn("generated alias already exists, retrying",
+			slog.String("alias", alias),
+			slog.Int("attempt", attempt),
+		)
+	}
+
+	return "", 0, err
+}
+
+// saveWithSequentialAlias saves the link under the alias encoded from its
+// ID. IDs whose alias is reserved are skipped by the storage like taken ones.
+func saveWithSequentialAlias(
+	sequential SequentialSaver,
+	encoder AliasEncoder,
+	reservedAliases reserved.Set,
+	urlToSave string,
+	urlOpts storage.URLOptions,
+) (string, int64, error) {
+	return sequential.SaveURLSequential(urlToSave, urlOpts, func(id int64) string {
+		alias := encoder.Encode(id)
+		if reservedAliases.Contains(alias) {
+			return ""
+		}
+		return alias
+	})
 }
 
-func responseOK (w http.ResponseWriter, r *http.Request, alias string) {
+func responseOK (w http.ResponseWriter, r *http.Request, alias, shortURL string) {
 	render.JSON(w, r, Response{
 		Response: resp.OK(),
 		Alias:    alias,
+		ShortURL: shortURL,
 	})
 }
\ No newline at end of file
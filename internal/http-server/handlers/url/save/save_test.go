@@ -2,19 +2,29 @@ package save_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"net/http"
 	"net/http/httptest"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
 	"url-shortener/internal/http-server/handlers/url/save"
 	"url-shortener/internal/http-server/handlers/url/save/mocks"
+	"url-shortener/internal/lib/clientip"
 	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/lib/random"
+	"url-shortener/internal/lib/reserved"
+	"url-shortener/internal/storage"
 )
 
 func TestSaveHandler(t *testing.T) {
@@ -23,35 +33,96 @@ func TestSaveHandler(t *testing.T) {
 		alias     string
 		url       string
 		respError string
+		respCode  int
+		errCode   string
 		mockError error
 	}{
 		{
-			name:  "Success",
-			alias: "test_alias",
-			url:   "https://google.com",
+			name:     "Success",
+			alias:    "testalias",
+			url:      "https://google.com",
+			respCode: http.StatusOK,
 		},
 		{
-			name:  "Empty alias",
-			alias: "",
-			url:   "https://google.com",
+			name:     "Empty alias",
+			alias:    "",
+			url:      "https://google.com",
+			respCode: http.StatusOK,
 		},
 		{
 			name:      "Empty URL",
 			url:       "",
-			alias:     "some_alias",
+			alias:     "somealias",
 			respError: "field URL is a required field",
+			errCode:   "VALIDATION_FAILED",
+			respCode:  http.StatusBadRequest,
 		},
 		{
 			name:      "Invalid URL",
 			url:       "some invalid URL",
+			alias:     "somealias",
+			respError: "field URL is not a valid http or https URL",
+			errCode:   "INVALID_URL",
+			respCode:  http.StatusBadRequest,
+		},
+		{
+			// Served by the HTML redirect page, it would run in the service's origin.
+			name:      "JavaScript URL",
+			url:       "javascript:alert(document.domain)",
+			alias:     "somealias",
+			respError: "field URL is not a valid http or https URL",
+			errCode:   "INVALID_URL",
+			respCode:  http.StatusBadRequest,
+		},
+		{
+			name:      "Invalid alias characters",
+			url:       "https://google.com",
 			alias:     "some_alias",
-			respError: "field URL is not a valid URL",
+			respError: "field Alias must contain only letters and digits",
+			errCode:   "VALIDATION_FAILED",
+			respCode:  http.StatusBadRequest,
+		},
+		{
+			name:      "Reserved alias",
+			url:       "https://google.com",
+			alias:     "Health",
+			respError: "alias is reserved",
+			errCode:   "ALIAS_RESERVED",
+			respCode:  http.StatusBadRequest,
+		},
+		{
+			name:      "Alias of an admin route",
+			url:       "https://google.com",
+			alias:     "search",
+			respError: "alias is reserved",
+			errCode:   "ALIAS_RESERVED",
+			respCode:  http.StatusBadRequest,
+		},
+		{
+			name:      "Confusable alias",
+			alias:     "rnail",
+			url:       "https://google.com",
+			respError: "alias is too similar to an existing alias",
+			respCode:  http.StatusConflict,
+			errCode:   "ALIAS_CONFUSABLE",
+			mockError: errors.Join(storage.ErrURLExists, storage.ErrAliasConfusable),
+		},
+		{
+			name:      "Held alias",
+			alias:     "vanity",
+			url:       "https://google.com",
+			respError: "alias is reserved by another client",
+			respCode:  http.StatusConflict,
+			errCode:   "ALIAS_HELD",
+			mockError: errors.Join(storage.ErrURLExists, storage.ErrAliasHeld),
 		},
 		{
 			name:      "SaveURL Error",
-			alias:     "test_alias",
+			alias:     "testalias",
 			url:       "https://google.com",
 			respError: "failed to add url",
+			errCode:   "INTERNAL_ERROR",
+			respCode:  http.StatusInternalServerError,
 			mockError: errors.New("unexpected error"),
 		},
 	}
@@ -65,12 +136,14 @@ func TestSaveHandler(t *testing.T) {
 			urlSaverMock := mocks.NewURLSaver(t)
 
 			if tc.respError == "" || tc.mockError != nil {
-				urlSaverMock.On("SaveURL", tc.url, mock.AnythingOfType("string")).
+				urlSaverMock.On("SaveURL", tc.url, mock.AnythingOfType("string"), mock.Anything).
 					Return(int64(1), tc.mockError).
 					Once()
 			}
 
-			handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock)
+			handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock, save.Options{
+				BasePath: "/s",
+			})
 
 			input := fmt.Sprintf(`{"url": "%s", "alias": "%s"}`, tc.url, tc.alias)
 
@@ -80,7 +153,7 @@ func TestSaveHandler(t *testing.T) {
 			rr := httptest.NewRecorder()
 			handler.ServeHTTP(rr, req)
 
-			require.Equal(t, rr.Code, http.StatusOK)
+			require.Equal(t, tc.respCode, rr.Code)
 
 			body := rr.Body.String()
 
@@ -89,8 +162,1362 @@ func TestSaveHandler(t *testing.T) {
 			require.NoError(t, json.Unmarshal([]byte(body), &resp))
 
 			require.Equal(t, tc.respError, resp.Error)
+			require.Equal(t, tc.errCode, resp.Code)
+
+			if tc.respError == "" && tc.alias != "" {
+				require.Equal(t, "http://"+req.Host+"/s/"+tc.alias, resp.ShortURL)
+			}
 
 			// TODO: add more checks
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestSaveHandler_MaxURLLength(t *testing.T) {
+	const maxURLLength = 30
+
+	cases := []struct {
+		name     string
+		url      string
+		respCode int
+	}{
+		{
+			name:     "Under limit",
+			url:      "https://google.com/" + strings.Repeat("a", maxURLLength-len("https://google.com/")),
+			respCode: http.StatusOK,
+		},
+		{
+			name:     "Over limit",
+			url:      "https://google.com/" + strings.Repeat("a", maxURLLength-len("https://google.com/")+1),
+			respCode: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlSaverMock := mocks.NewURLSaver(t)
+
+			if tc.respCode == http.StatusOK {
+				urlSaverMock.On("SaveURL", tc.url, mock.AnythingOfType("string"), mock.Anything).
+					Return(int64(1), nil).
+					Once()
+			}
+
+			handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock, save.Options{
+				MaxURLLength: maxURLLength,
+			})
+
+			input := fmt.Sprintf(`{"url": "%s"}`, tc.url)
+
+			req, err := http.NewRequest(http.MethodPost, "/save", strings.NewReader(input))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+		})
+	}
+}
+
+func TestSaveHandler_UTM(t *testing.T) {
+	cases := []struct {
+		name        string
+		contentType string
+		body        string
+		wantURL     string
+	}{
+		{
+			name:        "JSON utm object is merged into the stored URL",
+			contentType: "application/json",
+			body:        `{"url": "https://google.com/?q=go&utm_source=old", "utm": {"source": "newsletter", "campaign": "launch"}}`,
+			wantURL:     "https://google.com/?q=go&utm_source=newsletter&utm_campaign=launch",
+		},
+		{
+			name:        "Form fields",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "url=https%3A%2F%2Fgoogle.com%2F&utm_medium=email",
+			wantURL:     "https://google.com/?utm_medium=email",
+		},
+		{
+			name:        "Without utm the URL is stored as is",
+			contentType: "application/json",
+			body:        `{"url": "https://google.com/?utm_source=own"}`,
+			wantURL:     "https://google.com/?utm_source=own",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlSaverMock := mocks.NewURLSaver(t)
+			urlSaverMock.On("SaveURL", tc.wantURL, mock.AnythingOfType("string"), mock.Anything).
+				Return(int64(1), nil).
+				Once()
+
+			handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock, save.Options{})
+
+			req, err := http.NewRequest(http.MethodPost, "/save", strings.NewReader(tc.body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", tc.contentType)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+		})
+	}
+}
+
+func TestSaveHandler_Tags(t *testing.T) {
+	cases := []struct {
+		name        string
+		contentType string
+		body        string
+		wantTags    []string
+		respCode    int
+		respError   string
+	}{
+		{
+			name:        "JSON tags",
+			contentType: "application/json",
+			body:        `{"url": "https://google.com", "alias": "testalias", "tags": ["campaign", "docs"]}`,
+			wantTags:    []string{"campaign", "docs"},
+			respCode:    http.StatusOK,
+		},
+		{
+			name:        "Form tags",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "url=https%3A%2F%2Fgoogle.com&alias=testalias&tags=campaign&tags=docs",
+			wantTags:    []string{"campaign", "docs"},
+			respCode:    http.StatusOK,
+		},
+		{
+			name:        "Tag with a comma",
+			contentType: "application/json",
+			body:        `{"url": "https://google.com", "alias": "testalias", "tags": ["a,b"]}`,
+			respCode:    http.StatusBadRequest,
+			respError:   "field Tags[0] is not valid",
+		},
+		{
+			name:        "Empty tag",
+			contentType: "application/json",
+			body:        `{"url": "https://google.com", "alias": "testalias", "tags": [""]}`,
+			respCode:    http.StatusBadRequest,
+			respError:   "field Tags[0] is a required field",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlSaverMock := mocks.NewURLSaver(t)
+			if tc.respError == "" {
+				urlSaverMock.On("SaveURL", "https://google.com", "testalias",
+					mock.MatchedBy(func(opts storage.URLOptions) bool { return slices.Equal(opts.Tags, tc.wantTags) })).
+					Return(int64(1), nil).
+					Once()
+			}
+
+			handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock, save.Options{})
+
+			req := httptest.NewRequest(http.MethodPost, "/url", strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", tc.contentType)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var resp save.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+		})
+	}
+}
+
+// fakeAliasGenerator returns predictable aliases: alias1, alias2, ...
+type fakeAliasGenerator struct {
+	n int
+}
+
+func (g *fakeAliasGenerator) Generate(_ int) (string, error) {
+	g.n++
+	return fmt.Sprintf("alias%d", g.n), nil
+}
+
+func TestSaveHandler_AliasCollision(t *testing.T) {
+	const url = "https://google.com"
+
+	cases := []struct {
+		name       string
+		alias      string
+		collisions []string
+		wantAlias  string
+		respCode   int
+		respError  string
+	}{
+		{
+			name:       "Generated alias collides once",
+			collisions: []string{"alias1"},
+			wantAlias:  "alias2",
+			respCode:   http.StatusOK,
+		},
+		{
+			name:       "Generated alias collides on every attempt",
+			collisions: []string{"alias1", "alias2", "alias3", "alias4", "alias5"},
+			respCode:   http.StatusInternalServerError,
+			respError:  "failed to generate unique alias",
+		},
+		{
+			name:       "Custom alias collides",
+			alias:      "taken",
+			collisions: []string{"taken"},
+			respCode:   http.StatusConflict,
+			respError:  "url already exists",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlSaverMock := mocks.NewURLSaver(t)
+
+			for _, alias := range tc.collisions {
+				urlSaverMock.On("SaveURL", url, alias, mock.Anything).
+					Return(int64(0), storage.ErrURLExists).
+					Once()
+			}
+
+			if tc.wantAlias != "" {
+				urlSaverMock.On("SaveURL", url, tc.wantAlias, mock.Anything).
+					Return(int64(1), nil).
+					Once()
+			}
+
+			handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock, save.Options{
+				AliasGenerator: &fakeAliasGenerator{},
+			})
+
+			input := fmt.Sprintf(`{"url": "%s", "alias": "%s"}`, url, tc.alias)
+
+			req, err := http.NewRequest(http.MethodPost, "/save", strings.NewReader(input))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var resp save.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+			require.Equal(t, tc.wantAlias, resp.Alias)
+		})
+	}
+}
+
+func TestSaveHandler_AliasAffixes(t *testing.T) {
+	const url = "https://google.com"
+
+	cases := []struct {
+		name      string
+		alias     string
+		wantAlias string
+	}{
+		{
+			// go-alias1 is reserved, so the check must see the prefixed alias and move on.
+			name:      "Generated alias gets the prefix and suffix",
+			wantAlias: "go-alias2-x",
+		},
+		{
+			name:      "Custom alias is stored as is",
+			alias:     "mine",
+			wantAlias: "mine",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlSaverMock := mocks.NewURLSaver(t)
+			urlSaverMock.On("SaveURL", url, tc.wantAlias, mock.Anything).
+				Return(int64(1), nil).
+				Once()
+
+			generator, err := random.NewAffixed(&fakeAliasGenerator{}, "go-", "-x")
+			require.NoError(t, err)
+
+			handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock, save.Options{
+				AliasGenerator: generator,
+				Reserved:       reserved.New([]string{"go-alias1-x"}),
+			})
+
+			input := fmt.Sprintf(`{"url": "%s", "alias": "%s"}`, url, tc.alias)
+
+			req, err := http.NewRequest(http.MethodPost, "/save", strings.NewReader(input))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var resp save.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.wantAlias, resp.Alias)
+			require.True(t, strings.HasSuffix(resp.ShortURL, "/"+tc.wantAlias))
+		})
+	}
+}
+
+// fakeSequentialSaver assigns IDs from 1 and skips those encode rejects, like the storage does.
+type fakeSequentialSaver struct {
+	saved map[string]string
+}
+
+func (f *fakeSequentialSaver) SaveURLSequential(urlToSave string, _ storage.URLOptions, encode func(id int64) string) (string, int64, error) {
+	for id := int64(1); ; id++ {
+		if alias := encode(id); alias != "" {
+			f.saved[alias] = urlToSave
+			return alias, id, nil
+		}
+	}
+}
+
+// aliasEncoderFunc adapts a function to save.AliasEncoder.
+type aliasEncoderFunc func(id int64) string
+
+func (f aliasEncoderFunc) Encode(id int64) string { return f(id) }
+
+func TestSaveHandler_Sequential(t *testing.T) {
+	const url = "https://google.com"
+
+	cases := []struct {
+		name      string
+		dryRun    bool
+		wantAlias string
+		wantSaved bool
+	}{
+		{
+			// ID 1 encodes to the reserved "url", so the link moves on to ID 2.
+			name:      "Alias is encoded from the id",
+			wantAlias: "seq2",
+			wantSaved: true,
+		},
+		{
+			name:   "Dry run does not know the alias yet",
+			dryRun: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sequential := &fakeSequentialSaver{saved: map[string]string{}}
+
+			handler := save.New(slogdiscard.NewDiscardLogger(), mocks.NewURLSaver(t), save.Options{
+				Sequential: sequential,
+				AliasEncoder: aliasEncoderFunc(func(id int64) string {
+					if id == 1 {
+						return "url"
+					}
+					return fmt.Sprintf("seq%d", id)
+				}),
+			})
+
+			target := "/save"
+			if tc.dryRun {
+				target += "?dry_run=true"
+			}
+
+			req, err := http.NewRequest(http.MethodPost, target, strings.NewReader(fmt.Sprintf(`{"url": "%s"}`, url)))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var resp save.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.wantAlias, resp.Alias)
+			require.Equal(t, tc.dryRun, resp.DryRun)
+
+			if tc.wantSaved {
+				require.Equal(t, map[string]string{tc.wantAlias: url}, sequential.saved)
+			} else {
+				require.Empty(t, sequential.saved)
+			}
+		})
+	}
+}
+
+func TestSaveHandler_ContentTypes(t *testing.T) {
+	cases := []struct {
+		name          string
+		contentType   string
+		body          string
+		formResultURL string
+		respCode      int
+		wantLocation  string
+	}{
+		{
+			name:        "JSON",
+			contentType: "application/json",
+			body:        `{"url": "https://google.com", "alias": "testalias", "ttl": "1h"}`,
+			respCode:    http.StatusOK,
+		},
+		{
+			name:        "Form",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "url=https%3A%2F%2Fgoogle.com&alias=testalias&ttl=1h",
+			respCode:    http.StatusOK,
+		},
+		{
+			name:          "Form with results page",
+			contentType:   "application/x-www-form-urlencoded",
+			body:          "url=https%3A%2F%2Fgoogle.com&alias=testalias&ttl=1h",
+			formResultURL: "/result",
+			respCode:      http.StatusSeeOther,
+			wantLocation:  "/result?alias=testalias&short_url=http%3A%2F%2Fexample.com%2Ftestalias",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlSaverMock := mocks.NewURLSaver(t)
+			urlSaverMock.On("SaveURL", "https://google.com", "testalias",
+				mock.MatchedBy(func(opts storage.URLOptions) bool { return opts.ExpiresAt != nil })).
+				Return(int64(1), nil).
+				Once()
+
+			handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock, save.Options{
+				FormResultURL: tc.formResultURL,
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/url", strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", tc.contentType)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			if tc.wantLocation != "" {
+				require.Equal(t, tc.wantLocation, rr.Header().Get("Location"))
+				return
+			}
+
+			var resp save.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, "testalias", resp.Alias)
+		})
+	}
+}
+
+func TestSaveHandler_PreserveMethod(t *testing.T) {
+	cases := []struct {
+		name        string
+		contentType string
+		body        string
+		set         bool
+		want        bool
+	}{
+		{
+			name:        "JSON",
+			contentType: "application/json",
+			body:        `{"url": "https://google.com", "alias": "testalias", "preserve_method": true}`,
+			set:         true,
+			want:        true,
+		},
+		{
+			name:        "Form",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "url=https%3A%2F%2Fgoogle.com&alias=testalias&preserve_method=false",
+			set:         true,
+		},
+		{
+			name:        "Omitted",
+			contentType: "application/json",
+			body:        `{"url": "https://google.com", "alias": "testalias"}`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlSaverMock := mocks.NewURLSaver(t)
+			urlSaverMock.On("SaveURL", "https://google.com", "testalias",
+				mock.MatchedBy(func(opts storage.URLOptions) bool {
+					if !tc.set {
+						return opts.PreserveMethod == nil
+					}
+					return opts.PreserveMethod != nil && *opts.PreserveMethod == tc.want
+				})).
+				Return(int64(1), nil).
+				Once()
+
+			handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock, save.Options{})
+
+			req := httptest.NewRequest(http.MethodPost, "/url", strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", tc.contentType)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+		})
+	}
+}
+
+func TestSaveHandler_Headers(t *testing.T) {
+	cases := []struct {
+		name     string
+		body     string
+		wantCode int
+		want     map[string]string
+	}{
+		{
+			name:     "Valid headers",
+			body:     `{"url": "https://google.com", "alias": "testalias", "headers": {"Referrer-Policy": "no-referrer"}}`,
+			wantCode: http.StatusOK,
+			want:     map[string]string{"Referrer-Policy": "no-referrer"},
+		},
+		{
+			name:     "Forbidden header",
+			body:     `{"url": "https://google.com", "alias": "testalias", "headers": {"Location": "https://evil.org"}}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Injected header",
+			body:     `{"url": "https://google.com", "alias": "testalias", "headers": {"X-Partner": "a\r\nSet-Cookie: session=1"}}`,
+			wantCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlSaverMock := mocks.NewURLSaver(t)
+			if tc.want != nil {
+				urlSaverMock.On("SaveURL", "https://google.com", "testalias",
+					mock.MatchedBy(func(opts storage.URLOptions) bool {
+						return maps.Equal(opts.Headers, tc.want)
+					})).
+					Return(int64(1), nil).
+					Once()
+			}
+
+			handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock, save.Options{})
+
+			req := httptest.NewRequest(http.MethodPost, "/url", strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.wantCode, rr.Code)
+		})
+	}
+}
+
+func TestSaveHandler_ForwardPath(t *testing.T) {
+	cases := []struct {
+		name        string
+		contentType string
+		path        string
+		body        string
+		wantCode    int
+	}{
+		{
+			name:        "JSON",
+			contentType: "application/json",
+			path:        "/url",
+			body:        `{"url": "https://example.com/guide", "alias": "testalias", "forward_path": true}`,
+			wantCode:    http.StatusOK,
+		},
+		{
+			name:        "Form",
+			contentType: "application/x-www-form-urlencoded",
+			path:        "/url",
+			body:        "url=https%3A%2F%2Fexample.com%2Fguide&alias=testalias&forward_path=on",
+			wantCode:    http.StatusOK,
+		},
+		{
+			name:        "Namespace in the body",
+			contentType: "application/json",
+			path:        "/url",
+			body:        `{"url": "https://example.com/guide", "alias": "testalias", "namespace": "team", "forward_path": true}`,
+			wantCode:    http.StatusBadRequest,
+		},
+		{
+			name:        "Namespace in the path",
+			contentType: "application/json",
+			path:        "/url/ns/team",
+			body:        `{"url": "https://example.com/guide", "alias": "testalias", "forward_path": true}`,
+			wantCode:    http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlSaverMock := mocks.NewURLSaver(t)
+			if tc.wantCode == http.StatusOK {
+				urlSaverMock.On("SaveURL", "https://example.com/guide", "testalias",
+					mock.MatchedBy(func(opts storage.URLOptions) bool { return opts.ForwardPath })).
+					Return(int64(1), nil).
+					Once()
+			}
+
+			handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock, save.Options{})
+
+			r := chi.NewRouter()
+			r.Post("/url", handler)
+			r.Post("/url/ns/{namespace}", handler)
+
+			req := httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", tc.contentType)
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.wantCode, rr.Code)
+		})
+	}
+}
+
+// fakeLinkCounter returns a fixed number of links for every IP and records the IPs asked for.
+type fakeLinkCounter struct {
+	count int
+	ips   []string
+}
+
+func (c *fakeLinkCounter) CountByCreator(ip string) (int, error) {
+	c.ips = append(c.ips, ip)
+	return c.count, nil
+}
+
+func TestSaveHandler_MaxLinksPerIP(t *testing.T) {
+	const url = "https://google.com"
+
+	cases := []struct {
+		name      string
+		limit     int
+		count     int
+		respCode  int
+		respError string
+	}{
+		{
+			name:     "Under limit",
+			limit:    3,
+			count:    2,
+			respCode: http.StatusOK,
+		},
+		{
+			name:      "Limit reached",
+			limit:     3,
+			count:     3,
+			respCode:  http.StatusTooManyRequests,
+			respError: "link limit reached: max 3 links per client",
+		},
+		{
+			name:     "Limit disabled",
+			limit:    0,
+			count:    100,
+			respCode: http.StatusOK,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlSaverMock := mocks.NewURLSaver(t)
+
+			if tc.respCode == http.StatusOK {
+				urlSaverMock.On("SaveURL", url, mock.AnythingOfType("string"),
+					mock.MatchedBy(func(opts storage.URLOptions) bool { return opts.CreatorIP == "192.0.2.1" }),
+				).Return(int64(1), nil).Once()
+			}
+
+			counter := &fakeLinkCounter{count: tc.count}
+
+			handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock, save.Options{
+				MaxLinksPerIP: tc.limit,
+				LinkCounter:   counter,
+			})
+
+			input := fmt.Sprintf(`{"url": "%s"}`, url)
+
+			req := httptest.NewRequest(http.MethodPost, "/save", strings.NewReader(input))
+			req.RemoteAddr = "192.0.2.1:1234"
+			// Forwarded headers from an untrusted peer must not change the counted IP.
+			req.Header.Set("X-Forwarded-For", "203.0.113.9")
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var resp save.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+
+			if tc.limit > 0 {
+				require.Equal(t, []string{"192.0.2.1"}, counter.ips)
+			}
+		})
+	}
+}
+
+// fakeTotalCounter returns a fixed total number of links and counts how often it is asked.
+type fakeTotalCounter struct {
+	count int64
+	calls int
+}
+
+func (c *fakeTotalCounter) CountURLs(_ context.Context) (int64, error) {
+	c.calls++
+	return c.count, nil
+}
+
+func TestSaveHandler_MaxTotalLinks(t *testing.T) {
+	const url = "https://google.com"
+
+	urlSaverMock := mocks.NewURLSaver(t)
+	urlSaverMock.On("SaveURL", url, mock.AnythingOfType("string"), mock.Anything).
+		Return(int64(1), nil).Twice()
+
+	counter := &fakeTotalCounter{count: 1}
+
+	handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock, save.Options{
+		MaxTotalLinks:    3,
+		TotalLinkCounter: counter,
+	})
+
+	post := func() (int, save.Response) {
+		req := httptest.NewRequest(http.MethodPost, "/save", strings.NewReader(fmt.Sprintf(`{"url": "%s"}`, url)))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		var resp save.Response
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+		return rr.Code, resp
+	}
+
+	// Two saves fill the storage up to the cap, the third is refused.
+	code, _ := post()
+	require.Equal(t, http.StatusOK, code)
+	code, _ = post()
+	require.Equal(t, http.StatusOK, code)
+
+	code, resp := post()
+	require.Equal(t, http.StatusInsufficientStorage, code)
+	require.Equal(t, "link storage is full: max 3 links", resp.Error)
+	require.Equal(t, "STORAGE_FULL", resp.Code)
+
+	// The count is read once and then kept current by the handler's own saves.
+	require.Equal(t, 1, counter.calls)
+}
+
+func TestSaveHandler_MaxTotalLinksDisabled(t *testing.T) {
+	urlSaverMock := mocks.NewURLSaver(t)
+	urlSaverMock.On("SaveURL", "https://google.com", mock.AnythingOfType("string"), mock.Anything).
+		Return(int64(1), nil).Once()
+
+	counter := &fakeTotalCounter{count: 100}
+
+	handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock, save.Options{
+		TotalLinkCounter: counter,
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/save", strings.NewReader(`{"url": "https://google.com"}`))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Zero(t, counter.calls)
+}
+
+func TestSaveHandler_Namespace(t *testing.T) {
+	const url = "https://google.com"
+
+	cases := []struct {
+		name          string
+		path          string
+		body          string
+		wantNamespace string
+		wantShortURL  string
+		respCode      int
+		respError     string
+	}{
+		{
+			name:          "Namespace from body",
+			path:          "/url",
+			body:          `{"url": "https://google.com", "alias": "home", "namespace": "Team"}`,
+			wantNamespace: "Team",
+			wantShortURL:  "http://example.com/team/home",
+			respCode:      http.StatusOK,
+		},
+		{
+			name:          "Namespace from path wins over body",
+			path:          "/url/ns/blog",
+			body:          `{"url": "https://google.com", "alias": "home", "namespace": "docs"}`,
+			wantNamespace: "blog",
+			wantShortURL:  "http://example.com/blog/home",
+			respCode:      http.StatusOK,
+		},
+		{
+			name:         "Default namespace",
+			path:         "/url",
+			body:         `{"url": "https://google.com", "alias": "home"}`,
+			wantShortURL: "http://example.com/home",
+			respCode:     http.StatusOK,
+		},
+		{
+			name:      "Reserved namespace",
+			path:      "/url",
+			body:      `{"url": "https://google.com", "alias": "home", "namespace": "url"}`,
+			respCode:  http.StatusBadRequest,
+			respError: "namespace is reserved",
+		},
+		{
+			name:      "Invalid namespace",
+			path:      "/url",
+			body:      `{"url": "https://google.com", "alias": "home", "namespace": "my docs"}`,
+			respCode:  http.StatusBadRequest,
+			respError: "field Namespace must contain only letters and digits",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlSaverMock := mocks.NewURLSaver(t)
+
+			if tc.respCode == http.StatusOK {
+				urlSaverMock.On("SaveURL", url, "home",
+					mock.MatchedBy(func(opts storage.URLOptions) bool { return opts.Namespace == tc.wantNamespace }),
+				).Return(int64(1), nil).Once()
+			}
+
+			handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock, save.Options{})
+
+			r := chi.NewRouter()
+			r.Post("/url", handler)
+			r.Post("/url/ns/{namespace}", handler)
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.body)))
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var resp save.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+			require.Equal(t, tc.wantShortURL, resp.ShortURL)
+		})
+	}
+}
+
+func TestSaveHandler_MixedCaseAlias(t *testing.T) {
+	for _, path := range []string{"/url", "/url?dry_run=true"} {
+		t.Run(path, func(t *testing.T) {
+			urlSaverMock := mocks.NewURLSaver(t)
+			if path == "/url" {
+				urlSaverMock.On("SaveURL", "https://google.com", "MyLink", mock.Anything).
+					Return(int64(1), nil).Once()
+			}
+
+			r := chi.NewRouter()
+			r.Post("/url", save.New(slogdiscard.NewDiscardLogger(), urlSaverMock, save.Options{
+				AliasChecker: fakeAliasChecker{},
+			}))
+
+			rr := httptest.NewRecorder()
+			body := `{"url": "https://google.com", "alias": "MyLink", "namespace": "Team"}`
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			// The storage lower-cases aliases, so the response names the link as stored.
+			var resp save.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, "mylink", resp.Alias)
+			require.Equal(t, "http://example.com/team/mylink", resp.ShortURL)
+		})
+	}
+}
+
+// fakeAliasChecker reports the aliases in taken as existing links.
+type fakeAliasChecker struct {
+	taken map[string]bool
+	err   error
+}
+
+func (c fakeAliasChecker) GetNamespacedURLRecord(_ context.Context, namespace, alias string) (storage.URLRecord, error) {
+	if c.err != nil {
+		return storage.URLRecord{}, c.err
+	}
+	if !c.taken[namespace+"/"+alias] {
+		return storage.URLRecord{}, storage.ErrURLNotFound
+	}
+
+	return storage.URLRecord{Namespace: namespace, Alias: alias}, nil
+}
+
+func TestSaveHandler_DryRun(t *testing.T) {
+	cases := []struct {
+		name         string
+		query        string
+		body         string
+		taken        []string
+		checkErr     error
+		wantAlias    string
+		wantShortURL string
+		respCode     int
+		respError    string
+	}{
+		{
+			name:         "Custom alias is free",
+			query:        "?dry_run=true",
+			body:         `{"url": "https://google.com", "alias": "home"}`,
+			wantAlias:    "home",
+			wantShortURL: "http://example.com/home",
+			respCode:     http.StatusOK,
+		},
+		{
+			name:      "Custom alias is taken",
+			query:     "?dry_run=true",
+			body:      `{"url": "https://google.com", "alias": "home"}`,
+			taken:     []string{"/home"},
+			respCode:  http.StatusConflict,
+			respError: "url already exists",
+		},
+		{
+			name:         "Alias is taken in another namespace only",
+			query:        "?dry_run=1",
+			body:         `{"url": "https://google.com", "alias": "home", "namespace": "team"}`,
+			taken:        []string{"/home"},
+			wantAlias:    "home",
+			wantShortURL: "http://example.com/team/home",
+			respCode:     http.StatusOK,
+		},
+		{
+			name:         "Generated alias skips taken candidates",
+			query:        "?dry_run=true",
+			body:         `{"url": "https://google.com"}`,
+			taken:        []string{"/alias1"},
+			wantAlias:    "alias2",
+			wantShortURL: "http://example.com/alias2",
+			respCode:     http.StatusOK,
+		},
+		{
+			name:      "Validation still applies",
+			query:     "?dry_run=true",
+			body:      `{"url": "not a url", "alias": "home"}`,
+			respCode:  http.StatusBadRequest,
+			respError: "field URL is not a valid http or https URL",
+		},
+		{
+			name:      "Reserved alias",
+			query:     "?dry_run=true",
+			body:      `{"url": "https://google.com", "alias": "health"}`,
+			respCode:  http.StatusBadRequest,
+			respError: "alias is reserved",
+		},
+		{
+			name:      "Invalid dry_run",
+			query:     "?dry_run=maybe",
+			body:      `{"url": "https://google.com", "alias": "home"}`,
+			respCode:  http.StatusBadRequest,
+			respError: "query parameter dry_run must be a boolean",
+		},
+		{
+			name:      "Storage error",
+			query:     "?dry_run=true",
+			body:      `{"url": "https://google.com", "alias": "home"}`,
+			checkErr:  errors.New("unexpected error"),
+			respCode:  http.StatusInternalServerError,
+			respError: "failed to add url",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// No SaveURL expectations: a dry run must never insert.
+			urlSaverMock := mocks.NewURLSaver(t)
+
+			checker := fakeAliasChecker{taken: map[string]bool{}, err: tc.checkErr}
+			for _, key := range tc.taken {
+				checker.taken[key] = true
+			}
+
+			handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock, save.Options{
+				AliasGenerator: &fakeAliasGenerator{},
+				AliasChecker:   checker,
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/url"+tc.query, strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var resp save.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+			require.Equal(t, tc.wantAlias, resp.Alias)
+			require.Equal(t, tc.wantShortURL, resp.ShortURL)
+			require.Equal(t, tc.respError == "", resp.DryRun)
+		})
+	}
+}
+
+// creationNotifierFunc adapts a function to save.CreationNotifier.
+type creationNotifierFunc func(namespace, alias, target string)
+
+func (f creationNotifierFunc) LinkCreated(namespace, alias, target string) {
+	f(namespace, alias, target)
+}
+
+func TestSaveHandler_Notifier(t *testing.T) {
+	urlSaverMock := mocks.NewURLSaver(t)
+	urlSaverMock.On("SaveURL", "https://google.com", "Home", mock.Anything).Return(int64(1), nil).Once()
+
+	var got []string
+	handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock, save.Options{
+		Notifier: creationNotifierFunc(func(namespace, alias, target string) {
+			got = append(got, namespace, alias, target)
+		}),
+	})
+
+	body := `{"url": "https://google.com", "alias": "Home", "namespace": "Team"}`
+
+	// A dry run creates nothing, so nothing is announced.
+	req := httptest.NewRequest(http.MethodPost, "/url?dry_run=true", strings.NewReader(body))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	require.Empty(t, got)
+
+	req = httptest.NewRequest(http.MethodPost, "/url", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, []string{"team", "home", "https://google.com"}, got)
+}
+
+// duplicateFinderFunc adapts a function to save.DuplicateFinder.
+type duplicateFinderFunc func(urlToSave string) (storage.URLRecord, error)
+
+func (f duplicateFinderFunc) GetURLRecordByURL(urlToSave string) (storage.URLRecord, error) {
+	return f(urlToSave)
+}
+
+func TestSaveHandler_Dedupe(t *testing.T) {
+	const url = "https://google.com"
+
+	past := time.Now().Add(-time.Hour)
+
+	// existing returns rec, filled in with the ID, alias and URL of the existing link.
+	existing := func(rec storage.URLRecord) save.DuplicateFinder {
+		return duplicateFinderFunc(func(urlToSave string) (storage.URLRecord, error) {
+			rec.ID, rec.Alias, rec.URL = 7, "home", urlToSave
+			return rec, nil
+		})
+	}
+
+	cases := []struct {
+		name         string
+		body         string
+		duplicates   save.DuplicateFinder
+		respCode     int
+		respError    string
+		wantAlias    string
+		wantShortURL string
+		wantExisting bool
+	}{
+		{
+			name:         "Existing link is returned",
+			body:         `{"url": "https://google.com", "namespace": "Team"}`,
+			duplicates:   existing(storage.URLRecord{Namespace: "team"}),
+			respCode:     http.StatusOK,
+			wantAlias:    "home",
+			wantShortURL: "http://example.com/team/home",
+			wantExisting: true,
+		},
+		{
+			name:         "Existing link is returned for a custom alias",
+			body:         `{"url": "https://google.com", "alias": "mine"}`,
+			duplicates:   existing(storage.URLRecord{}),
+			respCode:     http.StatusOK,
+			wantAlias:    "home",
+			wantShortURL: "http://example.com/home",
+			wantExisting: true,
+		},
+		{
+			name:         "Existing link with the same tags",
+			body:         `{"url": "https://google.com", "tags": ["b", "a", "b"]}`,
+			duplicates:   existing(storage.URLRecord{Tags: []string{"a", "b"}}),
+			respCode:     http.StatusOK,
+			wantAlias:    "home",
+			wantShortURL: "http://example.com/home",
+			wantExisting: true,
+		},
+		{
+			name:       "Existing link is expired",
+			body:       `{"url": "https://google.com"}`,
+			duplicates: existing(storage.URLRecord{ExpiresAt: &past}),
+			respCode:   http.StatusConflict,
+			respError:  "url already shortened by an inactive link or with different options",
+			wantAlias:  "home",
+		},
+		{
+			name:       "Existing link is disabled",
+			body:       `{"url": "https://google.com"}`,
+			duplicates: existing(storage.URLRecord{Disabled: true}),
+			respCode:   http.StatusConflict,
+			respError:  "url already shortened by an inactive link or with different options",
+			wantAlias:  "home",
+		},
+		{
+			name:       "Existing link is exhausted",
+			body:       `{"url": "https://google.com"}`,
+			duplicates: existing(storage.URLRecord{MaxClicks: 1, Clicks: 1}),
+			respCode:   http.StatusConflict,
+			respError:  "url already shortened by an inactive link or with different options",
+			wantAlias:  "home",
+		},
+		{
+			name:       "Different namespace",
+			body:       `{"url": "https://google.com", "namespace": "blog"}`,
+			duplicates: existing(storage.URLRecord{Namespace: "team"}),
+			respCode:   http.StatusConflict,
+			respError:  "url already shortened by an inactive link or with different options",
+			wantAlias:  "home",
+		},
+		{
+			name:       "Different redirect type",
+			body:       `{"url": "https://google.com", "permanent": true}`,
+			duplicates: existing(storage.URLRecord{}),
+			respCode:   http.StatusConflict,
+			respError:  "url already shortened by an inactive link or with different options",
+			wantAlias:  "home",
+		},
+		{
+			name:       "Requested TTL",
+			body:       `{"url": "https://google.com", "ttl": "1h"}`,
+			duplicates: existing(storage.URLRecord{}),
+			respCode:   http.StatusConflict,
+			respError:  "url already shortened by an inactive link or with different options",
+			wantAlias:  "home",
+		},
+		{
+			name:       "Requested max clicks",
+			body:       `{"url": "https://google.com", "max_clicks": 5}`,
+			duplicates: existing(storage.URLRecord{}),
+			respCode:   http.StatusConflict,
+			respError:  "url already shortened by an inactive link or with different options",
+			wantAlias:  "home",
+		},
+		{
+			name:      "Without a finder",
+			body:      `{"url": "https://google.com"}`,
+			respCode:  http.StatusConflict,
+			respError: "url already shortened",
+		},
+		{
+			name: "Finder error",
+			body: `{"url": "https://google.com"}`,
+			duplicates: duplicateFinderFunc(func(string) (storage.URLRecord, error) {
+				return storage.URLRecord{}, errors.New("unexpected error")
+			}),
+			respCode:  http.StatusInternalServerError,
+			respError: "failed to add url",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlSaverMock := mocks.NewURLSaver(t)
+			urlSaverMock.On("SaveURL", url, mock.AnythingOfType("string"), mock.Anything).
+				Return(int64(0), storage.ErrURLDuplicate).
+				Once()
+
+			handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock, save.Options{
+				Duplicates: tc.duplicates,
+				Notifier: creationNotifierFunc(func(_, _, _ string) {
+					t.Error("notifier must not be called for an existing link")
+				}),
+			})
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/url", strings.NewReader(tc.body)))
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var resp save.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+			require.Equal(t, tc.wantAlias, resp.Alias)
+			require.Equal(t, tc.wantShortURL, resp.ShortURL)
+			require.Equal(t, tc.wantExisting, resp.Existing)
+		})
+	}
+}
+
+// fakeIdempotencyStore keeps idempotency keys in memory, without expiry.
+type fakeIdempotencyStore struct {
+	keys     map[string]storage.IdempotencyRecord
+	released []string
+}
+
+func (s *fakeIdempotencyStore) ClaimIdempotencyKey(key, fingerprint string, _ time.Duration) (storage.IdempotencyRecord, error) {
+	if rec, ok := s.keys[key]; ok {
+		return rec, storage.ErrIdempotencyKeyExists
+	}
+
+	rec := storage.IdempotencyRecord{Fingerprint: fingerprint}
+	s.keys[key] = rec
+
+	return rec, nil
+}
+
+func (s *fakeIdempotencyStore) CompleteIdempotencyKey(key, namespace, alias string) error {
+	rec := s.keys[key]
+	rec.Namespace, rec.Alias = namespace, alias
+	s.keys[key] = rec
+
+	return nil
+}
+
+func (s *fakeIdempotencyStore) ReleaseIdempotencyKey(key string) error {
+	if s.keys[key].Alias == "" {
+		delete(s.keys, key)
+	}
+	s.released = append(s.released, key)
+
+	return nil
+}
+
+func TestSaveHandler_IdempotencyKey(t *testing.T) {
+	const body = `{"url": "https://google.com", "namespace": "team"}`
+
+	urlSaverMock := mocks.NewURLSaver(t)
+	urlSaverMock.On("SaveURL", "https://google.com", mock.AnythingOfType("string"), mock.Anything).
+		Return(int64(1), nil).
+		Once()
+
+	store := &fakeIdempotencyStore{keys: map[string]storage.IdempotencyRecord{}}
+	handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock, save.Options{
+		AliasGenerator: &fakeAliasGenerator{},
+		Idempotency:    store,
+	})
+
+	send := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/url", strings.NewReader(body))
+		req.Header.Set("Idempotency-Key", key)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	first := send("retry-1", body)
+	require.Equal(t, http.StatusOK, first.Code)
+	require.Empty(t, first.Header().Get("Idempotent-Replayed"))
+
+	var created save.Response
+	require.NoError(t, json.Unmarshal(first.Body.Bytes(), &created))
+	require.Equal(t, "alias1", created.Alias)
+	require.Equal(t, "http://example.com/team/alias1", created.ShortURL)
+
+	// A retry gets the original link without another insert.
+	retry := send("retry-1", body)
+	require.Equal(t, http.StatusOK, retry.Code)
+	require.Equal(t, "true", retry.Header().Get("Idempotent-Replayed"))
+
+	var replayed save.Response
+	require.NoError(t, json.Unmarshal(retry.Body.Bytes(), &replayed))
+	require.Equal(t, created, replayed)
+
+	// The same key with a different request is rejected.
+	other := send("retry-1", `{"url": "https://example.com"}`)
+	require.Equal(t, http.StatusUnprocessableEntity, other.Code)
+
+	var otherResp save.Response
+	require.NoError(t, json.Unmarshal(other.Body.Bytes(), &otherResp))
+	require.Equal(t, "IDEMPOTENCY_KEY_MISMATCH", otherResp.Code)
+
+	// While the first request with a key runs, duplicates are told to retry.
+	store.keys["pending"] = storage.IdempotencyRecord{Fingerprint: store.keys["retry-1"].Fingerprint}
+	pending := send("pending", body)
+	require.Equal(t, http.StatusConflict, pending.Code)
+	require.Equal(t, "1", pending.Header().Get("Retry-After"))
+
+	var pendingResp save.Response
+	require.NoError(t, json.Unmarshal(pending.Body.Bytes(), &pendingResp))
+	require.Equal(t, "IDEMPOTENCY_KEY_IN_PROGRESS", pendingResp.Code)
+}
+
+func TestSaveHandler_IdempotencyKeyReleasedOnFailure(t *testing.T) {
+	urlSaverMock := mocks.NewURLSaver(t)
+	urlSaverMock.On("SaveURL", "https://google.com", "taken", mock.Anything).
+		Return(int64(0), storage.ErrURLExists).
+		Twice()
+
+	store := &fakeIdempotencyStore{keys: map[string]storage.IdempotencyRecord{}}
+	handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock, save.Options{Idempotency: store})
+
+	// A failed save frees its key, so a retry is processed again rather than replayed.
+	for range 2 {
+		req := httptest.NewRequest(http.MethodPost, "/url", strings.NewReader(`{"url": "https://google.com", "alias": "taken"}`))
+		req.Header.Set("Idempotency-Key", "retry-2")
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusConflict, rr.Code)
+	}
+
+	require.Equal(t, []string{"retry-2", "retry-2"}, store.released)
+	require.Empty(t, store.keys)
+}
+
+func TestSaveHandler_ForwardedProto(t *testing.T) {
+	trusted, err := clientip.ParseNetworks([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+
+	cases := []struct {
+		name         string
+		remoteAddr   string
+		wantShortURL string
+	}{
+		{
+			name:         "Trusted proxy terminates TLS",
+			remoteAddr:   "10.0.0.1:1234",
+			wantShortURL: "https://example.com/home",
+		},
+		{
+			name:         "Header from untrusted client is ignored",
+			remoteAddr:   "203.0.113.7:1234",
+			wantShortURL: "http://example.com/home",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlSaverMock := mocks.NewURLSaver(t)
+			urlSaverMock.On("SaveURL", "https://google.com", "home", mock.Anything).Return(int64(1), nil).Once()
+
+			handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock, save.Options{TrustedProxies: trusted})
+
+			req := httptest.NewRequest(http.MethodPost, "/url", strings.NewReader(`{"url": "https://google.com", "alias": "home"}`))
+			req.RemoteAddr = tc.remoteAddr
+			req.Header.Set("X-Forwarded-Proto", "https")
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var resp save.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.wantShortURL, resp.ShortURL)
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package search
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+const (
+	// defaultLimit is how many links are returned when the limit parameter is omitted.
+	defaultLimit = 50
+	// maxLimit caps the number of links returned.
+	maxLimit = 200
+)
+
+type Response struct {
+	resp.Response
+	// URLs are the matching links ordered by id.
+	URLs []storage.URLRecord `json:"urls"`
+	// Truncated is set when more links match than were returned; a more
+	// specific term narrows the result.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.53.7 --name=URLSearcher
+
+// URLSearcher is an interface for finding links by part of their URL or alias.
+type URLSearcher interface {
+	SearchURLs(term string, limit int) ([]storage.URLRecord, error)
+}
+
+// New lists the links whose destination URL or alias contains the "q" query
+// parameter, case-insensitively, for support staff who only remember part
+// of a destination. "limit" caps the result. No matches give an empty
+// list, not 404.
+//
+// Substring matching cannot use an index, so every request scans the whole
+// table: keep the route admin-only and expect it to slow down as the
+// number of links grows.
+func New(log *slog.Logger, searcher URLSearcher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.search.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		term := r.URL.Query().Get("q")
+		if term == "" {
+			log.Info("empty q parameter")
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(resp.CodeInvalidQuery, "query parameter q is required"))
+
+			return
+		}
+
+		limit := defaultLimit
+		if v := r.URL.Query().Get("limit"); v != "" {
+			parsed, err := strconv.Atoi(v)
+			if err != nil || parsed < 1 || parsed > maxLimit {
+				log.Info("invalid limit parameter", slog.String("limit", v))
+
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, resp.Error(resp.CodeInvalidQuery, fmt.Sprintf("query parameter limit must be between 1 and %d", maxLimit)))
+
+				return
+			}
+			limit = parsed
+		}
+
+		// One extra link tells whether the result was cut.
+		records, err := searcher.SearchURLs(term, limit+1)
+		if err != nil {
+			log.Error("failed to search urls", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		truncated := len(records) > limit
+		if truncated {
+			records = records[:limit]
+		}
+		if records == nil {
+			records = []storage.URLRecord{}
+		}
+
+		log.Info("urls searched", slog.Int("found", len(records)), slog.Bool("truncated", truncated))
+
+		render.JSON(w, r, Response{
+			Response:  resp.OK(),
+			URLs:      records,
+			Truncated: truncated,
+		})
+	}
+}
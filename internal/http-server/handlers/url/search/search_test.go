@@ -0,0 +1,115 @@
+package search_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/search"
+	"url-shortener/internal/http-server/handlers/url/search/mocks"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestSearchHandler(t *testing.T) {
+	records := func(aliases ...string) []storage.URLRecord {
+		res := make([]storage.URLRecord, 0, len(aliases))
+		for i, alias := range aliases {
+			res = append(res, storage.URLRecord{ID: int64(i + 1), Alias: alias, URL: "https://shop.example.com/" + alias})
+		}
+		return res
+	}
+
+	cases := []struct {
+		name          string
+		query         string
+		mockLimit     int
+		mockRecords   []storage.URLRecord
+		mockError     error
+		respCode      int
+		respError     string
+		wantAliases   []string
+		wantTruncated bool
+	}{
+		{
+			name:        "Matches",
+			query:       "?q=shop",
+			mockLimit:   51,
+			mockRecords: records("spring", "summer"),
+			respCode:    http.StatusOK,
+			wantAliases: []string{"spring", "summer"},
+		},
+		{
+			name:          "Result is capped",
+			query:         "?q=shop&limit=2",
+			mockLimit:     3,
+			mockRecords:   records("spring", "summer", "autumn"),
+			respCode:      http.StatusOK,
+			wantAliases:   []string{"spring", "summer"},
+			wantTruncated: true,
+		},
+		{
+			name:        "No matches",
+			query:       "?q=missing",
+			mockLimit:   51,
+			respCode:    http.StatusOK,
+			wantAliases: []string{},
+		},
+		{
+			name:      "Empty q",
+			query:     "?q=",
+			respCode:  http.StatusBadRequest,
+			respError: "query parameter q is required",
+		},
+		{
+			name:      "Limit too large",
+			query:     "?q=shop&limit=1000",
+			respCode:  http.StatusBadRequest,
+			respError: "query parameter limit must be between 1 and 200",
+		},
+		{
+			name:      "Storage error",
+			query:     "?q=shop",
+			mockLimit: 51,
+			mockError: errors.New("unexpected error"),
+			respCode:  http.StatusInternalServerError,
+			respError: "internal error",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			searcherMock := mocks.NewURLSearcher(t)
+			if tc.mockLimit > 0 {
+				searcherMock.On("SearchURLs", "shop", tc.mockLimit).Maybe().Return(tc.mockRecords, tc.mockError)
+				searcherMock.On("SearchURLs", "missing", tc.mockLimit).Maybe().Return(tc.mockRecords, tc.mockError)
+			}
+
+			handler := search.New(slogdiscard.NewDiscardLogger(), searcherMock)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/url/search"+tc.query, nil))
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var resp search.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+			if tc.respError != "" {
+				return
+			}
+
+			aliases := []string{}
+			for _, rec := range resp.URLs {
+				aliases = append(aliases, rec.Alias)
+			}
+			require.Equal(t, tc.wantAliases, aliases)
+			require.Equal(t, tc.wantTruncated, resp.Truncated)
+		})
+	}
+}
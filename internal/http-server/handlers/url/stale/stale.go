@@ -0,0 +1,61 @@
+package stale
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+type Response struct {
+	resp.Response
+	URLs []storage.URLRecord `json:"urls"`
+}
+
+// StaleURLLister is an interface for listing links unused since a cutoff.
+type StaleURLLister interface {
+	ListStale(olderThan time.Time) ([]storage.URLRecord, error)
+}
+
+// New lists links not accessed since the RFC 3339 time in the "before" query parameter.
+func New(log *slog.Logger, lister StaleURLLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.stale.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		before, err := time.Parse(time.RFC3339, r.URL.Query().Get("before"))
+		if err != nil {
+			log.Info("invalid before parameter", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(resp.CodeInvalidQuery, "query parameter before must be an RFC 3339 time"))
+
+			return
+		}
+
+		records, err := lister.ListStale(before)
+		if err != nil {
+			log.Error("failed to list stale urls", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			URLs:     records,
+		})
+	}
+}
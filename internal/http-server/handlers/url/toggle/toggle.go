@@ -0,0 +1,79 @@
+package toggle
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+type Response struct {
+	resp.Response
+	Alias   string `json:"alias,omitempty"`
+	Enabled bool   `json:"enabled"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.53.7 --name=EnabledSetter
+
+// EnabledSetter is an interface for enabling and disabling a link.
+type EnabledSetter interface {
+	SetEnabled(alias string, enabled bool) error
+}
+
+// New enables or disables the link, depending on enabled. A disabled link
+// keeps its data and click history but is not redirected until it is
+// enabled again. Browsers may still hold permanent redirects cached before
+// the link was disabled, for up to redirect.cache_ttl.
+func New(log *slog.Logger, setter EnabledSetter, enabled bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.toggle.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		alias := chi.URLParam(r, "alias")
+		if alias == "" {
+			log.Info("alias is empty")
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(resp.CodeInvalidRequest, "invalid request"))
+
+			return
+		}
+
+		err := setter.SetEnabled(alias, enabled)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("url not found", slog.String("alias", alias))
+
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, resp.Error(resp.CodeNotFound, "not found"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to set url enabled", slog.String("alias", alias), sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(resp.CodeInternal, "internal error"))
+
+			return
+		}
+
+		log.Info("url enabled changed", slog.String("alias", alias), slog.Bool("enabled", enabled))
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Alias:    alias,
+			Enabled:  enabled,
+		})
+	}
+}
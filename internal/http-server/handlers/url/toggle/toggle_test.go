@@ -0,0 +1,86 @@
+package toggle_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/toggle"
+	"url-shortener/internal/http-server/handlers/url/toggle/mocks"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestToggleHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		action    string
+		enabled   bool
+		mockError error
+		respCode  int
+		respError string
+	}{
+		{
+			name:     "Disable",
+			action:   "disable",
+			enabled:  false,
+			respCode: http.StatusOK,
+		},
+		{
+			name:     "Enable",
+			action:   "enable",
+			enabled:  true,
+			respCode: http.StatusOK,
+		},
+		{
+			name:      "Not found",
+			action:    "disable",
+			enabled:   false,
+			mockError: storage.ErrURLNotFound,
+			respCode:  http.StatusNotFound,
+			respError: "not found",
+		},
+		{
+			name:      "Storage error",
+			action:    "enable",
+			enabled:   true,
+			mockError: errors.New("unexpected error"),
+			respCode:  http.StatusInternalServerError,
+			respError: "internal error",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			setterMock := mocks.NewEnabledSetter(t)
+			setterMock.On("SetEnabled", "google", tc.enabled).Return(tc.mockError).Once()
+
+			log := slogdiscard.NewDiscardLogger()
+
+			r := chi.NewRouter()
+			r.Post("/url/{alias}/disable", toggle.New(log, setterMock, false))
+			r.Post("/url/{alias}/enable", toggle.New(log, setterMock, true))
+
+			req := httptest.NewRequest(http.MethodPost, "/url/google/"+tc.action, nil)
+			rr := httptest.NewRecorder()
+
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respCode, rr.Code)
+
+			var resp toggle.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+			if tc.respError == "" {
+				require.Equal(t, "google", resp.Alias)
+				require.Equal(t, tc.enabled, resp.Enabled)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package version
+
+import (
+	"net/http"
+
+	"github.com/go-chi/render"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/buildinfo"
+)
+
+type Response struct {
+	resp.Response
+	buildinfo.Info
+}
+
+func New(info buildinfo.Info) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Info:     info,
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package allowlist
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/clientip"
+)
+
+// New returns middleware that lets through only clients whose IP belongs
+// to one of the given CIDRs and answers 403 to everyone else.
+// A bare IP is treated as a single-host network. An empty list disables the check.
+// The client IP is resolved with clientip.ClientIP, so forwarding headers
+// are honored only from trusted proxies.
+func New(log *slog.Logger, cidrs []string, trustedProxies []*net.IPNet) (func(next http.Handler) http.Handler, error) {
+	const op = "middleware.allowlist.New"
+
+	nets, err := clientip.ParseNetworks(cidrs)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return func(next http.Handler) http.Handler {
+		if len(nets) == 0 {
+			return next
+		}
+
+		log := log.With(
+			slog.String("component", "middleware/allowlist"),
+		)
+
+		log.Info("ip allowlist enabled", slog.Any("networks", cidrs))
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			ip := clientip.ClientIP(r, trustedProxies)
+
+			if !clientip.Contains(nets, ip) {
+				log.Warn("client ip is not allowed",
+					slog.String("ip", ip.String()),
+					slog.String("request_id", middleware.GetReqID(r.Context())),
+				)
+
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, resp.Error(resp.CodeForbidden, "forbidden"))
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}, nil
+}
@@ -0,0 +1,97 @@
+package allowlist_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/middleware/allowlist"
+	"url-shortener/internal/lib/clientip"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+func TestAllowlist(t *testing.T) {
+	cases := []struct {
+		name          string
+		cidrs         []string
+		remoteAddr    string
+		xForwardedFor string
+		wantCode      int
+	}{
+		{
+			name:       "Empty list allows everyone",
+			remoteAddr: "203.0.113.7:1234",
+			wantCode:   http.StatusOK,
+		},
+		{
+			name:       "IPv4 inside network",
+			cidrs:      []string{"10.0.0.0/8"},
+			remoteAddr: "10.1.2.3:1234",
+			wantCode:   http.StatusOK,
+		},
+		{
+			name:       "IPv4 outside network",
+			cidrs:      []string{"10.0.0.0/8"},
+			remoteAddr: "203.0.113.7:1234",
+			wantCode:   http.StatusForbidden,
+		},
+		{
+			name:       "IPv6 inside network",
+			cidrs:      []string{"2001:db8::/32"},
+			remoteAddr: "[2001:db8::1]:1234",
+			wantCode:   http.StatusOK,
+		},
+		{
+			name:       "Single IP",
+			cidrs:      []string{"192.0.2.1"},
+			remoteAddr: "192.0.2.1:1234",
+			wantCode:   http.StatusOK,
+		},
+		{
+			name:          "Forwarded client inside network via trusted proxy",
+			cidrs:         []string{"10.0.0.0/8"},
+			remoteAddr:    "127.0.0.1:1234",
+			xForwardedFor: "10.1.2.3",
+			wantCode:      http.StatusOK,
+		},
+		{
+			name:          "Spoofed forwarded header from untrusted peer",
+			cidrs:         []string{"10.0.0.0/8"},
+			remoteAddr:    "203.0.113.7:1234",
+			xForwardedFor: "10.1.2.3",
+			wantCode:      http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			trusted, err := clientip.ParseNetworks([]string{"127.0.0.1"})
+			require.NoError(t, err)
+
+			mw, err := allowlist.New(slogdiscard.NewDiscardLogger(), tc.cidrs, trusted)
+			require.NoError(t, err)
+
+			handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/url", nil)
+			req.RemoteAddr = tc.remoteAddr
+			if tc.xForwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tc.xForwardedFor)
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.wantCode, rr.Code)
+		})
+	}
+}
+
+func TestAllowlistInvalidCIDR(t *testing.T) {
+	_, err := allowlist.New(slogdiscard.NewDiscardLogger(), []string{"10.0.0.0/99"}, nil)
+	require.Error(t, err)
+}
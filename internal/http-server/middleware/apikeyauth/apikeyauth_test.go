@@ -0,0 +1,115 @@
+package apikeyauth_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/middleware/apikeyauth"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/apikey"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+// keyGetterFunc adapts a function to apikeyauth.KeyGetter.
+type keyGetterFunc func(prefix string) (storage.APIKey, error)
+
+func (f keyGetterFunc) GetAPIKey(prefix string) (storage.APIKey, error) {
+	return f(prefix)
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	key, prefix, hash, err := apikey.Generate()
+	require.NoError(t, err)
+
+	// A well-formed key that is not in the storage, e.g. a revoked one.
+	other, _, _, err := apikey.Generate()
+	require.NoError(t, err)
+
+	keys := keyGetterFunc(func(p string) (storage.APIKey, error) {
+		if p != prefix {
+			return storage.APIKey{}, storage.ErrAPIKeyNotFound
+		}
+		return storage.APIKey{ID: 1, Prefix: prefix, Hash: hash}, nil
+	})
+
+	cases := []struct {
+		name      string
+		key       string
+		keys      apikeyauth.KeyGetter
+		wantCode  int
+		wantError string
+	}{
+		{
+			name:     "Valid key",
+			key:      key,
+			keys:     keys,
+			wantCode: http.StatusOK,
+		},
+		{
+			name:      "Missing key",
+			keys:      keys,
+			wantCode:  http.StatusUnauthorized,
+			wantError: "missing api key",
+		},
+		{
+			name:      "Malformed key",
+			key:       "secret",
+			keys:      keys,
+			wantCode:  http.StatusUnauthorized,
+			wantError: "invalid api key",
+		},
+		{
+			name:      "Wrong secret",
+			key:       "usk_" + prefix + "_wrong",
+			keys:      keys,
+			wantCode:  http.StatusUnauthorized,
+			wantError: "invalid api key",
+		},
+		{
+			name:      "Unknown or revoked key",
+			key:       other,
+			keys:      keys,
+			wantCode:  http.StatusUnauthorized,
+			wantError: "invalid api key",
+		},
+		{
+			name: "Storage error",
+			key:  key,
+			keys: keyGetterFunc(func(string) (storage.APIKey, error) {
+				return storage.APIKey{}, errors.New("unexpected error")
+			}),
+			wantCode:  http.StatusInternalServerError,
+			wantError: "internal error",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := apikeyauth.New(slogdiscard.NewDiscardLogger(), tc.keys)(
+				http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) }),
+			)
+
+			req := httptest.NewRequest(http.MethodPost, "/url", nil)
+			if tc.key != "" {
+				req.Header.Set(apikeyauth.Header, tc.key)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.wantCode, rr.Code)
+
+			if tc.wantError != "" {
+				var body resp.Response
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+				require.Equal(t, tc.wantError, body.Error)
+			}
+		})
+	}
+}
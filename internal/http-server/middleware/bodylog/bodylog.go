@@ -0,0 +1,163 @@
+package bodylog
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// defaultMaxBytes is how much of each body is logged when Options.MaxBytes is zero.
+const defaultMaxBytes = 2048
+
+// redacted replaces the values of secret fields.
+const redacted = "[REDACTED]"
+
+// Options configures body logging.
+type Options struct {
+	// MaxBytes is how much of the request and of the response body is
+	// logged; longer bodies are cut. Zero means defaultMaxBytes.
+	MaxBytes int
+}
+
+// New returns DEBUG-ONLY middleware that logs the request and response
+// bodies of the routes it wraps, to debug a client integration. Bodies
+// are cut to opts.MaxBytes, and the values of fields that look like
+// secrets (passwords, tokens, keys) are replaced with [REDACTED] in JSON
+// and form bodies. Redaction goes by field name only, so a body can still
+// carry personal data such as URLs: do not enable it in production.
+// Entries are written at debug level. A false enabled disables the middleware.
+func New(log *slog.Logger, enabled bool, opts Options) func(next http.Handler) http.Handler {
+	maxBytes := opts.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = defaultMaxBytes
+	}
+
+	log = log.With(
+		slog.String("component", "middleware/bodylog"),
+	)
+
+	// Warned once, however many route groups use the middleware.
+	if enabled {
+		log.Warn("request and response bodies are logged: debug only, do not enable in production",
+			slog.Int("max_bytes", maxBytes),
+		)
+		if !log.Enabled(context.Background(), slog.LevelDebug) {
+			log.Warn("body logging has no effect: the logger does not write debug messages")
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		if !enabled {
+			return next
+		}
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			// Only the logged prefix is read ahead; the handler gets it back
+			// followed by the rest of the body.
+			reqBody, _ := io.ReadAll(io.LimitReader(r.Body, int64(maxBytes)+1))
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(reqBody), r.Body), r.Body}
+
+			respBody := &capped{max: maxBytes}
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			ww.Tee(respBody)
+
+			defer func() {
+				log.Debug("request bodies",
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+					slog.String("request_id", middleware.GetReqID(r.Context())),
+					slog.Int("status", ww.Status()),
+					slog.String("request_body", format(
+						reqBody[:min(len(reqBody), maxBytes)], len(reqBody) > maxBytes, r.Header.Get("Content-Type"))),
+					slog.String("response_body", format(
+						respBody.buf.Bytes(), respBody.seen > maxBytes, ww.Header().Get("Content-Type"))),
+				)
+			}()
+
+			next.ServeHTTP(ww, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// capped keeps the first max bytes written to it and counts the rest.
+// It never fails, so it cannot break the response it is teed from.
+type capped struct {
+	buf  bytes.Buffer
+	max  int
+	seen int
+}
+
+func (c *capped) Write(p []byte) (int, error) {
+	c.seen += len(p)
+	if room := c.max - c.buf.Len(); room > 0 {
+		c.buf.Write(p[:min(room, len(p))])
+	}
+
+	return len(p), nil
+}
+
+// format redacts the logged part of a body and marks a body that was cut.
+func format(body []byte, truncated bool, contentType string) string {
+	s := redact(string(body), contentType)
+	if truncated {
+		s += "...(truncated)"
+	}
+
+	return s
+}
+
+var (
+	// jsonString matches a "name": "value" pair; a value cut by truncation
+	// runs to the end of the body.
+	jsonString = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"(\s*:\s*)"(?:[^"\\]|\\.)*(?:"|$)`)
+	// secretName matches field names whose values are secrets.
+	secretName = regexp.MustCompile(`(?i)^(key|.*(password|passwd|secret|token|api_?key|authorization|credential).*)$`)
+)
+
+// redact replaces the values of secret fields in a form or JSON body.
+// Other bodies are treated as JSON, so text without "name": "value" pairs is unchanged.
+func redact(body, contentType string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == "application/x-www-form-urlencoded" {
+		return redactForm(body)
+	}
+
+	return jsonString.ReplaceAllStringFunc(body, func(pair string) string {
+		m := jsonString.FindStringSubmatch(pair)
+		if !secretName.MatchString(m[1]) {
+			return pair
+		}
+
+		return `"` + m[1] + `"` + m[2] + `"` + redacted + `"`
+	})
+}
+
+// redactForm replaces the values of secret fields in a URL-encoded form,
+// keeping the order and encoding of the other fields.
+func redactForm(body string) string {
+	fields := strings.Split(body, "&")
+	for i, field := range fields {
+		name, _, found := strings.Cut(field, "=")
+		if !found {
+			continue
+		}
+		if decoded, err := url.QueryUnescape(name); err == nil && secretName.MatchString(decoded) {
+			fields[i] = name + "=" + redacted
+		}
+	}
+
+	return strings.Join(fields, "&")
+}
@@ -0,0 +1,137 @@
+package bodylog_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/middleware/bodylog"
+)
+
+// bodyEntry is the logged "request bodies" entry.
+type bodyEntry struct {
+	Msg          string `json:"msg"`
+	Status       int    `json:"status"`
+	RequestBody  string `json:"request_body"`
+	ResponseBody string `json:"response_body"`
+}
+
+func TestBodyLog(t *testing.T) {
+	cases := []struct {
+		name         string
+		contentType  string
+		body         string
+		response     string
+		maxBytes     int
+		wantRequest  string
+		wantResponse string
+	}{
+		{
+			name:         "JSON bodies",
+			contentType:  "application/json",
+			body:         `{"url": "https://google.com", "alias": "google"}`,
+			response:     `{"status":"OK","alias":"google"}`,
+			wantRequest:  `{"url": "https://google.com", "alias": "google"}`,
+			wantResponse: `{"status":"OK","alias":"google"}`,
+		},
+		{
+			name:         "JSON secrets are redacted",
+			contentType:  "application/json",
+			body:         `{"token": "abc\"def", "url": "https://google.com", "Password":"hunter2"}`,
+			response:     `{"status":"OK","key":"sk_live_123","reserved_until":"2026-01-01T00:00:00Z"}`,
+			wantRequest:  `{"token": "[REDACTED]", "url": "https://google.com", "Password":"[REDACTED]"}`,
+			wantResponse: `{"status":"OK","key":"[REDACTED]","reserved_until":"2026-01-01T00:00:00Z"}`,
+		},
+		{
+			name:         "Form secrets are redacted",
+			contentType:  "application/x-www-form-urlencoded; charset=utf-8",
+			body:         "url=https%3A%2F%2Fgoogle.com&api_key=abc&alias=google",
+			wantRequest:  "url=https%3A%2F%2Fgoogle.com&api_key=[REDACTED]&alias=google",
+			wantResponse: "",
+		},
+		{
+			name:         "Long bodies are cut",
+			contentType:  "application/json",
+			body:         `{"url": "https://google.com/` + strings.Repeat("a", 100) + `"}`,
+			response:     strings.Repeat("b", 100),
+			maxBytes:     20,
+			wantRequest:  `{"url": "https://goo...(truncated)`,
+			wantResponse: strings.Repeat("b", 20) + "...(truncated)",
+		},
+		{
+			name:         "A secret cut in the middle is still redacted",
+			contentType:  "application/json",
+			body:         `{"secret": "0123456789abcdef"}`,
+			maxBytes:     20,
+			wantRequest:  `{"secret": "[REDACTED]"...(truncated)`,
+			wantResponse: "",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			log := slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+			var handlerGot []byte
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var err error
+				handlerGot, err = io.ReadAll(r.Body)
+				require.NoError(t, err)
+
+				w.WriteHeader(http.StatusCreated)
+				_, _ = w.Write([]byte(tc.response))
+			})
+
+			handler := bodylog.New(log, true, bodylog.Options{MaxBytes: tc.maxBytes})(next)
+
+			req := httptest.NewRequest(http.MethodPost, "/url", strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", tc.contentType)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			// The handler and the client see the bodies unchanged.
+			require.Equal(t, tc.body, string(handlerGot))
+			require.Equal(t, tc.response, rr.Body.String())
+
+			entry := lastEntry(t, &logs)
+			require.Equal(t, "request bodies", entry.Msg)
+			require.Equal(t, http.StatusCreated, entry.Status)
+			require.Equal(t, tc.wantRequest, entry.RequestBody)
+			require.Equal(t, tc.wantResponse, entry.ResponseBody)
+		})
+	}
+}
+
+func TestBodyLog_Disabled(t *testing.T) {
+	var logs bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	handler := bodylog.New(log, false, bodylog.Options{})(next)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/url", strings.NewReader(`{"url": "https://google.com"}`)))
+
+	require.Empty(t, logs.String())
+}
+
+// lastEntry decodes the last log line.
+func lastEntry(t *testing.T, logs *bytes.Buffer) bodyEntry {
+	t.Helper()
+
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+
+	var entry bodyEntry
+	require.NoError(t, json.Unmarshal([]byte(lines[len(lines)-1]), &entry))
+
+	return entry
+}
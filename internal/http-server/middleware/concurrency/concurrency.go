@@ -0,0 +1,71 @@
+package concurrency
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	resp "url-shortener/internal/lib/api/response"
+)
+
+// retryAfter is the Retry-After sent with 503: slots free up as soon as
+// running requests finish, so clients may retry almost immediately.
+const retryAfter = time.Second
+
+// New returns middleware that handles at most limit requests at a time.
+// Slots are held in a semaphore (a buffered channel); a request arriving
+// when all slots are taken is rejected with 503 and Retry-After instead of
+// waiting in a queue. Requests to the exempt paths, such as health checks,
+// are never limited. A non-positive limit disables the middleware.
+func New(log *slog.Logger, limit int, exempt ...string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if limit <= 0 {
+			return next
+		}
+
+		log := log.With(
+			slog.String("component", "middleware/concurrency"),
+		)
+
+		log.Info("concurrency limit enabled", slog.Int("limit", limit), slog.Any("exempt", exempt))
+
+		exemptPaths := make(map[string]struct{}, len(exempt))
+		for _, path := range exempt {
+			exemptPaths[path] = struct{}{}
+		}
+
+		sem := make(chan struct{}, limit)
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := exemptPaths[r.URL.Path]; ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			default:
+				log.Warn("too many concurrent requests",
+					slog.Int("limit", limit),
+					slog.String("request_id", middleware.GetReqID(r.Context())),
+				)
+
+				w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())))
+
+				render.Status(r, http.StatusServiceUnavailable)
+				render.JSON(w, r, resp.Error(resp.CodeServerBusy, "server is busy, retry later"))
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
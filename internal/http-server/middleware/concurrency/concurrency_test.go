@@ -0,0 +1,99 @@
+package concurrency_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/middleware/concurrency"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+func TestConcurrency(t *testing.T) {
+	const limit, requests = 3, 20
+
+	// Requests within the limit block until released, so all slots are taken at once.
+	entered := make(chan struct{}, requests+1)
+	release := make(chan struct{})
+
+	handler := concurrency.New(slogdiscard.NewDiscardLogger(), limit, "/health")(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			entered <- struct{}{}
+			<-release
+		}),
+	)
+
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	codes := make(chan int, requests+1)
+	get := func(wg *sync.WaitGroup, path string) {
+		defer wg.Done()
+
+		res, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer func() { _ = res.Body.Close() }()
+
+		if res.StatusCode == http.StatusServiceUnavailable {
+			require.Equal(t, "1", res.Header.Get("Retry-After"))
+		}
+		codes <- res.StatusCode
+	}
+
+	var held sync.WaitGroup
+	for range limit {
+		held.Add(1)
+		go get(&held, "/alias")
+	}
+	for range limit {
+		<-entered
+	}
+
+	// All slots are taken: the rest get 503 right away instead of queueing.
+	var rejected sync.WaitGroup
+	for range requests - limit {
+		rejected.Add(1)
+		go get(&rejected, "/alias")
+	}
+	rejected.Wait()
+
+	// /health is exempt and passes even when all slots are taken.
+	held.Add(1)
+	go get(&held, "/health")
+	<-entered
+
+	close(release)
+	held.Wait()
+	close(codes)
+
+	count := map[int]int{}
+	for code := range codes {
+		count[code]++
+	}
+
+	require.Equal(t, map[int]int{
+		http.StatusOK:                 limit + 1,
+		http.StatusServiceUnavailable: requests - limit,
+	}, count)
+
+	// Once the slots are free, requests are served again.
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/alias", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestConcurrency_Disabled(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	handler := concurrency.New(slogdiscard.NewDiscardLogger(), 0)(next)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/alias", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+}
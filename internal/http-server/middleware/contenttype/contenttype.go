@@ -0,0 +1,63 @@
+package contenttype
+
+import (
+	"log/slog"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	resp "url-shortener/internal/lib/api/response"
+)
+
+// New returns middleware that answers 415 Unsupported Media Type to
+// requests with a body whose Content-Type is not one of accepted, e.g.
+// "application/json". Media type parameters such as charset are ignored
+// and the comparison is case-insensitive. Requests without a body, like
+// DELETE /url/{alias}, pass through. An empty accepted disables the
+// middleware.
+func New(log *slog.Logger, accepted []string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if len(accepted) == 0 {
+			return next
+		}
+
+		log := log.With(
+			slog.String("component", "middleware/contenttype"),
+		)
+
+		allowed := make([]string, 0, len(accepted))
+		for _, contentType := range accepted {
+			allowed = append(allowed, strings.ToLower(strings.TrimSpace(contentType)))
+		}
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			header := r.Header.Get("Content-Type")
+			mediaType, _, err := mime.ParseMediaType(header)
+			if err == nil && slices.Contains(allowed, mediaType) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			log.Info("unsupported content type",
+				slog.String("content_type", header),
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.String("request_id", middleware.GetReqID(r.Context())),
+			)
+
+			render.Status(r, http.StatusUnsupportedMediaType)
+			render.JSON(w, r, resp.Error(resp.CodeUnsupportedMediaType, "unsupported content type, expected one of: "+strings.Join(allowed, ", ")))
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
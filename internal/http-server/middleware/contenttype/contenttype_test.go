@@ -0,0 +1,111 @@
+package contenttype_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/middleware/contenttype"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+func TestContentType(t *testing.T) {
+	accepted := []string{"application/json", "application/x-www-form-urlencoded"}
+
+	cases := []struct {
+		name        string
+		accepted    []string
+		method      string
+		contentType string
+		body        string
+		wantCode    int
+	}{
+		{
+			name:        "JSON",
+			accepted:    accepted,
+			method:      http.MethodPost,
+			contentType: "application/json",
+			body:        `{"url":"https://google.com"}`,
+			wantCode:    http.StatusOK,
+		},
+		{
+			name:        "JSON with charset",
+			accepted:    accepted,
+			method:      http.MethodPost,
+			contentType: "Application/JSON; charset=utf-8",
+			body:        `{"url":"https://google.com"}`,
+			wantCode:    http.StatusOK,
+		},
+		{
+			name:        "Form",
+			accepted:    accepted,
+			method:      http.MethodPost,
+			contentType: "application/x-www-form-urlencoded",
+			body:        "url=https://google.com",
+			wantCode:    http.StatusOK,
+		},
+		{
+			name:        "Plain text",
+			accepted:    accepted,
+			method:      http.MethodPost,
+			contentType: "text/plain",
+			body:        `{"url":"https://google.com"}`,
+			wantCode:    http.StatusUnsupportedMediaType,
+		},
+		{
+			name:     "Missing content type",
+			accepted: accepted,
+			method:   http.MethodPost,
+			body:     `{"url":"https://google.com"}`,
+			wantCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:     "No body",
+			accepted: accepted,
+			method:   http.MethodDelete,
+			wantCode: http.StatusOK,
+		},
+		{
+			name:        "Disabled",
+			method:      http.MethodPost,
+			contentType: "text/plain",
+			body:        "https://google.com",
+			wantCode:    http.StatusOK,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := contenttype.New(slogdiscard.NewDiscardLogger(), tc.accepted)(
+				http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) }),
+			)
+
+			var body io.Reader
+			if tc.body != "" {
+				body = strings.NewReader(tc.body)
+			}
+
+			req := httptest.NewRequest(tc.method, "/url", body)
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.wantCode, rr.Code)
+
+			if tc.wantCode == http.StatusUnsupportedMediaType {
+				var got resp.Response
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+				require.Equal(t, "unsupported content type, expected one of: application/json, application/x-www-form-urlencoded", got.Error)
+			}
+		})
+	}
+}
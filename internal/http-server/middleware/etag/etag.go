@@ -0,0 +1,97 @@
+package etag
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// New returns middleware that adds an ETag to successful GET and HEAD
+// responses and answers 304 Not Modified when the client's If-None-Match
+// matches it. The ETag is a hash of the response body, so the handler
+// still runs on every request; the savings are in bandwidth and client-side
+// caching.
+//
+// The response is buffered, so the middleware is meant for small JSON
+// read endpoints rather than streaming responses.
+func New() func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			bw := &bufferedWriter{header: w.Header(), status: http.StatusOK}
+			next.ServeHTTP(bw, r)
+
+			if bw.status != http.StatusOK {
+				w.WriteHeader(bw.status)
+				_, _ = w.Write(bw.body.Bytes())
+				return
+			}
+
+			sum := sha256.Sum256(bw.body.Bytes())
+			tag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+			w.Header().Set("ETag", tag)
+
+			if matches(r.Header.Get("If-None-Match"), tag) {
+				// A 304 carries no body and no body-describing headers.
+				w.Header().Del("Content-Type")
+				w.Header().Del("Content-Length")
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write(bw.body.Bytes())
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// matches reports whether an If-None-Match header value matches tag.
+// Weak comparison is used, as RFC 9110 requires for If-None-Match.
+func matches(header, tag string) bool {
+	if header == "" {
+		return false
+	}
+
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// bufferedWriter collects the status and body written by the handler.
+type bufferedWriter struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (w *bufferedWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *bufferedWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.status = status
+	w.wroteHeader = true
+}
+
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.body.Write(b)
+}
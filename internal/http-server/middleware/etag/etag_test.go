@@ -0,0 +1,75 @@
+package etag_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/middleware/etag"
+)
+
+func TestETag(t *testing.T) {
+	body := `{"status":"OK"}`
+
+	handler := etag.New()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	}))
+
+	// First request gets the body and an ETag.
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, body, rr.Body.String())
+
+	tag := rr.Header().Get("ETag")
+	require.NotEmpty(t, tag)
+
+	cases := []struct {
+		name        string
+		ifNoneMatch string
+		wantCode    int
+	}{
+		{name: "Matching tag", ifNoneMatch: tag, wantCode: http.StatusNotModified},
+		{name: "Weak matching tag", ifNoneMatch: "W/" + tag, wantCode: http.StatusNotModified},
+		{name: "One of several tags", ifNoneMatch: `"other", ` + tag, wantCode: http.StatusNotModified},
+		{name: "Wildcard", ifNoneMatch: "*", wantCode: http.StatusNotModified},
+		{name: "Stale tag", ifNoneMatch: `"stale"`, wantCode: http.StatusOK},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("If-None-Match", tc.ifNoneMatch)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.wantCode, rr.Code)
+			require.Equal(t, tag, rr.Header().Get("ETag"))
+
+			if tc.wantCode == http.StatusNotModified {
+				require.Empty(t, rr.Body.String())
+			} else {
+				require.Equal(t, body, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestETag_ErrorResponse(t *testing.T) {
+	handler := etag.New()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"status":"Error"}`))
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	require.Equal(t, http.StatusNotFound, rr.Code)
+	require.Empty(t, rr.Header().Get("ETag"))
+	require.Equal(t, `{"status":"Error"}`, rr.Body.String())
+}
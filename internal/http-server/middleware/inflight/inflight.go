@@ -0,0 +1,36 @@
+package inflight
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// Counter tracks the number of requests currently being handled.
+// It is used during graceful shutdown to report how many requests
+// were still in flight when the server stopped.
+type Counter struct {
+	n atomic.Int64
+}
+
+// New returns a Counter with no requests in flight.
+func New() *Counter {
+	return &Counter{}
+}
+
+// Middleware increments the counter before the request is handled and
+// decrements it once the handler returns, including on panic.
+func (c *Counter) Middleware(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		c.n.Add(1)
+		defer c.n.Add(-1)
+
+		next.ServeHTTP(w, r)
+	}
+
+	return http.HandlerFunc(fn)
+}
+
+// Count returns the number of requests currently in flight.
+func (c *Counter) Count() int64 {
+	return c.n.Load()
+}
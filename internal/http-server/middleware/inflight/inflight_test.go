@@ -0,0 +1,41 @@
+package inflight_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/middleware/inflight"
+)
+
+func TestCounter(t *testing.T) {
+	counter := inflight.New()
+
+	var during int64
+	handler := counter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		during = counter.Count()
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	require.Equal(t, int64(1), during)
+	require.Equal(t, int64(0), counter.Count())
+}
+
+func TestCounter_Panic(t *testing.T) {
+	counter := inflight.New()
+
+	handler := counter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	require.Panics(t, func() {
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	})
+
+	require.Equal(t, int64(0), counter.Count())
+}
@@ -0,0 +1,226 @@
+package jwtauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/golang-jwt/jwt/v5"
+
+	resp "url-shortener/internal/lib/api/response"
+)
+
+// Supported signing algorithms.
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+)
+
+// Config configures token validation.
+type Config struct {
+	// Algorithm is the only signing algorithm accepted: HS256 or RS256.
+	Algorithm string
+	// Secret is the HS256 shared secret.
+	Secret string
+	// PublicKeyPath is the PEM file with the RS256 public key.
+	PublicKeyPath string
+	// Scope must be present in the token's scope claim.
+	Scope string
+}
+
+// claims are the token claims the middleware reads. The scope claim is a
+// space-separated string, as in OAuth 2.0; a JSON array is accepted too.
+type claims struct {
+	jwt.RegisteredClaims
+	Scope any `json:"scope"`
+}
+
+type ctxKey struct{}
+
+// Subject returns the token subject stored in ctx by the middleware.
+func Subject(ctx context.Context) (string, bool) {
+	sub, ok := ctx.Value(ctxKey{}).(string)
+	return sub, ok
+}
+
+// Validation errors. Validate wraps the parser error in ErrInvalidToken.
+var (
+	ErrTokenExpired      = errors.New("token expired")
+	ErrInvalidToken      = errors.New("invalid token")
+	ErrInsufficientScope = errors.New("insufficient scope")
+)
+
+// Validator checks bearer tokens. It is shared by the HTTP middleware and
+// the gRPC interceptor, so both accept exactly the same tokens.
+type Validator struct {
+	parser  *jwt.Parser
+	keyFunc jwt.Keyfunc
+	scope   string
+}
+
+// NewValidator returns a validator for cfg. It fails when the key cannot be
+// loaded or the required scope is empty.
+func NewValidator(cfg Config) (*Validator, error) {
+	const op = "middleware.jwtauth.NewValidator"
+
+	key, err := verificationKey(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if cfg.Scope == "" {
+		return nil, fmt.Errorf("%s: required scope is empty", op)
+	}
+
+	return &Validator{
+		parser: jwt.NewParser(
+			jwt.WithValidMethods([]string{cfg.Algorithm}),
+			jwt.WithExpirationRequired(),
+		),
+		keyFunc: func(*jwt.Token) (any, error) { return key, nil },
+		scope:   cfg.Scope,
+	}, nil
+}
+
+// Validate checks that raw is signed with the configured algorithm and key,
+// is not expired and carries the required scope, and returns its subject.
+// The subject is returned with ErrInsufficientScope too, for logging.
+func (v *Validator) Validate(raw string) (string, error) {
+	var c claims
+	if _, err := v.parser.ParseWithClaims(raw, &c, v.keyFunc); err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return "", fmt.Errorf("%w: %w", ErrTokenExpired, err)
+		}
+		return "", fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	if !slices.Contains(scopes(c.Scope), v.scope) {
+		return c.Subject, ErrInsufficientScope
+	}
+
+	return c.Subject, nil
+}
+
+// New returns middleware that accepts only requests with a valid bearer
+// token: signed with the configured algorithm and key, not expired and
+// carrying the required scope. Missing, malformed and expired tokens get
+// 401, tokens without the scope get 403. The token subject is stored in
+// the request context, see Subject.
+func New(log *slog.Logger, cfg Config) (func(next http.Handler) http.Handler, error) {
+	const op = "middleware.jwtauth.New"
+
+	v, err := NewValidator(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return func(next http.Handler) http.Handler {
+		log := log.With(
+			slog.String("component", "middleware/jwtauth"),
+		)
+
+		log.Info("jwt auth enabled", slog.String("algorithm", cfg.Algorithm), slog.String("scope", cfg.Scope))
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			log := log.With(slog.String("request_id", middleware.GetReqID(r.Context())))
+
+			raw, ok := BearerToken(r.Header.Get("Authorization"))
+			if !ok {
+				log.Info("missing bearer token")
+				unauthorized(w, r, `Bearer realm="url-shortener"`, "unauthorized")
+				return
+			}
+
+			sub, err := v.Validate(raw)
+			switch {
+			case errors.Is(err, ErrInsufficientScope):
+				log.Warn("token lacks required scope", slog.String("sub", sub), slog.String("scope", cfg.Scope))
+
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="url-shortener", error="insufficient_scope", scope=%q`, cfg.Scope))
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, resp.Error(resp.CodeInsufficientScope, "insufficient scope"))
+
+				return
+			case err != nil:
+				log.Info("invalid token", slog.String("error", err.Error()))
+
+				msg := "invalid token"
+				if errors.Is(err, ErrTokenExpired) {
+					msg = "token expired"
+				}
+				unauthorized(w, r, `Bearer realm="url-shortener", error="invalid_token"`, msg)
+
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxKey{}, sub)))
+		}
+
+		return http.HandlerFunc(fn)
+	}, nil
+}
+
+// verificationKey returns the key tokens are verified with.
+func verificationKey(cfg Config) (any, error) {
+	switch cfg.Algorithm {
+	case AlgHS256:
+		if cfg.Secret == "" {
+			return nil, errors.New("HS256 requires a secret")
+		}
+		return []byte(cfg.Secret), nil
+	case AlgRS256:
+		pem, err := os.ReadFile(cfg.PublicKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("read public key: %w", err)
+		}
+
+		key, err := jwt.ParseRSAPublicKeyFromPEM(pem)
+		if err != nil {
+			return nil, fmt.Errorf("parse public key: %w", err)
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q: expected %q or %q", cfg.Algorithm, AlgHS256, AlgRS256)
+	}
+}
+
+// BearerToken extracts the token from an Authorization header value.
+func BearerToken(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+
+	return strings.TrimSpace(token), true
+}
+
+// scopes returns the scopes from a scope claim in either supported form.
+func scopes(claim any) []string {
+	switch v := claim.(type) {
+	case string:
+		return strings.Fields(v)
+	case []any:
+		res := make([]string, 0, len(v))
+		for _, s := range v {
+			if s, ok := s.(string); ok {
+				res = append(res, s)
+			}
+		}
+		return res
+	default:
+		return nil
+	}
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request, challenge, msg string) {
+	w.Header().Set("WWW-Authenticate", challenge)
+	render.Status(r, http.StatusUnauthorized)
+	render.JSON(w, r, resp.Error(resp.CodeUnauthorized, msg))
+}
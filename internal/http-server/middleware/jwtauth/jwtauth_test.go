@@ -0,0 +1,185 @@
+package jwtauth_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/middleware/jwtauth"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+const secret = "test-secret"
+
+func sign(t *testing.T, method jwt.SigningMethod, key any, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	require.NoError(t, err)
+
+	return token
+}
+
+func serve(t *testing.T, cfg jwtauth.Config, authorization string) (*httptest.ResponseRecorder, string) {
+	t.Helper()
+
+	mw, err := jwtauth.New(slogdiscard.NewDiscardLogger(), cfg)
+	require.NoError(t, err)
+
+	var subject string
+	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		subject, _ = jwtauth.Subject(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/url", nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	return rr, subject
+}
+
+func TestJWTAuth_HS256(t *testing.T) {
+	cfg := jwtauth.Config{Algorithm: jwtauth.AlgHS256, Secret: secret, Scope: "links:write"}
+
+	valid := jwt.MapClaims{
+		"sub":   "billing-service",
+		"scope": "links:read links:write",
+		"exp":   time.Now().Add(time.Minute).Unix(),
+	}
+
+	cases := []struct {
+		name          string
+		authorization string
+		wantCode      int
+		wantSubject   string
+	}{
+		{
+			name:          "Valid token",
+			authorization: "Bearer " + sign(t, jwt.SigningMethodHS256, []byte(secret), valid),
+			wantCode:      http.StatusOK,
+			wantSubject:   "billing-service",
+		},
+		{
+			name: "Scope as array",
+			authorization: "Bearer " + sign(t, jwt.SigningMethodHS256, []byte(secret), jwt.MapClaims{
+				"sub":   "billing-service",
+				"scope": []string{"links:write"},
+				"exp":   time.Now().Add(time.Minute).Unix(),
+			}),
+			wantCode:    http.StatusOK,
+			wantSubject: "billing-service",
+		},
+		{
+			name:     "Missing token",
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:          "Basic credentials",
+			authorization: "Basic dXNlcjpwYXNz",
+			wantCode:      http.StatusUnauthorized,
+		},
+		{
+			name: "Expired token",
+			authorization: "Bearer " + sign(t, jwt.SigningMethodHS256, []byte(secret), jwt.MapClaims{
+				"sub":   "billing-service",
+				"scope": "links:write",
+				"exp":   time.Now().Add(-time.Minute).Unix(),
+			}),
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name: "Token without expiry",
+			authorization: "Bearer " + sign(t, jwt.SigningMethodHS256, []byte(secret), jwt.MapClaims{
+				"sub":   "billing-service",
+				"scope": "links:write",
+			}),
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:          "Wrong secret",
+			authorization: "Bearer " + sign(t, jwt.SigningMethodHS256, []byte("other"), valid),
+			wantCode:      http.StatusUnauthorized,
+		},
+		{
+			name:          "Unsigned token",
+			authorization: "Bearer " + sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, valid),
+			wantCode:      http.StatusUnauthorized,
+		},
+		{
+			name: "Wrong scope",
+			authorization: "Bearer " + sign(t, jwt.SigningMethodHS256, []byte(secret), jwt.MapClaims{
+				"sub":   "billing-service",
+				"scope": "links:read",
+				"exp":   time.Now().Add(time.Minute).Unix(),
+			}),
+			wantCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rr, subject := serve(t, cfg, tc.authorization)
+
+			require.Equal(t, tc.wantCode, rr.Code)
+			require.Equal(t, tc.wantSubject, subject)
+			if tc.wantCode != http.StatusOK {
+				require.Contains(t, rr.Header().Get("WWW-Authenticate"), "Bearer")
+			}
+		})
+	}
+}
+
+func TestJWTAuth_RS256(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "jwt.pub")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600))
+
+	cfg := jwtauth.Config{Algorithm: jwtauth.AlgRS256, PublicKeyPath: path, Scope: "links:write"}
+
+	claims := jwt.MapClaims{
+		"sub":   "billing-service",
+		"scope": "links:write",
+		"exp":   time.Now().Add(time.Minute).Unix(),
+	}
+
+	rr, subject := serve(t, cfg, "Bearer "+sign(t, jwt.SigningMethodRS256, key, claims))
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "billing-service", subject)
+
+	// An HS256 token signed with the public key as the secret is rejected.
+	rr, _ = serve(t, cfg, "Bearer "+sign(t, jwt.SigningMethodHS256, der, claims))
+	require.Equal(t, http.StatusUnauthorized, rr.Code)
+}
+
+func TestNew_InvalidConfig(t *testing.T) {
+	log := slogdiscard.NewDiscardLogger()
+
+	for name, cfg := range map[string]jwtauth.Config{
+		"Unknown algorithm": {Algorithm: "ES256", Scope: "links:write"},
+		"HS256 no secret":   {Algorithm: jwtauth.AlgHS256, Scope: "links:write"},
+		"RS256 missing key": {Algorithm: jwtauth.AlgRS256, PublicKeyPath: "missing.pem", Scope: "links:write"},
+		"Empty scope":       {Algorithm: jwtauth.AlgHS256, Secret: secret},
+	} {
+		_, err := jwtauth.New(log, cfg)
+		require.Error(t, err, name)
+	}
+}
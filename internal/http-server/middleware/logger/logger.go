@@ -5,22 +5,44 @@ import (
 	// log/slog - стандартный пакет для логирования в Go, используется для создания и управления логами.
 	"log/slog"
 
+	// net - стандартный пакет для работы с сетевыми адресами (сети доверенных прокси).
+	"net"
+
 	// net/http - стандартный пакет для работы с HTTP-сервером и клиентом в Go.
 	// Содержит все основные типы и методы для реализации HTTP-сервера.
 	"net/http"
 
+	// sync/atomic - стандартный пакет атомарных операций, используется для счётчика выборочного логирования.
+	"sync/atomic"
+
 	// time - стандартный пакет для работы с временем, используется для работы с длительностью и временем в приложении.
 	"time"
 
 	// github.com/go-chi/chi/v5/middleware - сторонний пакет для промежуточного ПО в библиотеке chi.
 	// Включает в себя набор полезных middleware для обработки запросов в веб-приложениях на базе chi.
 	"github.com/go-chi/chi/v5/middleware"
+
+	// url-shortener/internal/lib/clientip - определение реального IP клиента с учётом доверенных прокси.
+	"url-shortener/internal/lib/clientip"
 )
 
+// Sampling - настройки выборочного логирования запросов при большом трафике.
+type Sampling struct {
+	// Rate - логируется один из Rate успешных запросов. 0 и 1 - логируются все запросы.
+	Rate int
+	// SlowThreshold - запросы, обработка которых заняла не меньше этого времени, логируются всегда.
+	// 0 - медленные запросы не выделяются и попадают в выборку наравне с остальными.
+	SlowThreshold time.Duration
+}
+
 // New - функция, которая возвращает middleware для логирования HTTP-запросов.
 // Входной параметр log - это уже настроенный логгер (slog.Logger).
+// trustedProxies - сети доверенных прокси: только от них принимаются заголовки X-Forwarded-For/X-Real-IP
+// при определении реального IP клиента.
+// sampling - выборочное логирование: из успешных быстрых запросов логируется только каждый sampling.Rate-й,
+// а ошибки (статус >= 400) и медленные запросы логируются всегда.
 // Функция создает новый обработчик запросов, который будет логировать информацию о запросах и их ответах.
-func New(log *slog.Logger) func(next http.Handler) http.Handler {
+func New(log *slog.Logger, trustedProxies []*net.IPNet, sampling Sampling) func(next http.Handler) http.Handler {
 	// Возвращаем функцию, которая принимает следующий обработчик HTTP-запросов (next) и возвращает новый обработчик.
 	return func(next http.Handler) http.Handler {
 		// Создаем новый логгер, добавляя к нему метку, что это компонент "middleware/logger".
@@ -30,17 +52,25 @@ func New(log *slog.Logger) func(next http.Handler) http.Handler {
 		)
 
 		// Логируем, что middleware для логирования включено.
-		log.Info("logger middleware enabled")
+		log.Info("logger middleware enabled",
+			slog.Int("sample_rate", max(sampling.Rate, 1)),
+			slog.String("slow_threshold", sampling.SlowThreshold.String()),
+		)
+
+		// sampled - счётчик запросов, которые могли быть пропущены выборкой. Логируется каждый Rate-й из них.
+		var sampled atomic.Uint64
 
 		// Создаем функцию-обработчик для HTTP-запросов.
 		// Эта функция будет логировать информацию о запросах и обрабатывать их.
 		fn := func(w http.ResponseWriter, r *http.Request) {
 			// Создаем лог-обработчик для каждого запроса, добавляя в лог информацию о запросе:
-			// метод запроса, путь, удаленный адрес, user-agent и ID запроса.
+			// метод запроса, путь, IP непосредственного собеседника (без порта), IP клиента, user-agent и ID запроса.
+			// За доверенным прокси remote_ip - адрес прокси, а client_ip - реальный клиент из заголовков.
 			entry := log.With(
 				slog.String("method", r.Method),
 				slog.String("path", r.URL.Path),
-				slog.String("remote_addr", r.RemoteAddr),
+				slog.String("remote_ip", ipString(clientip.RemoteIP(r), r.RemoteAddr)),
+				slog.String("client_ip", ipString(clientip.ClientIP(r, trustedProxies), r.RemoteAddr)),
 				slog.String("user_agent", r.UserAgent()),
 				slog.String("request_id", middleware.GetReqID(r.Context())),
 			)
@@ -54,11 +84,20 @@ func New(log *slog.Logger) func(next http.Handler) http.Handler {
 
 			// Отложенно логируем завершение обработки запроса (это будет выполнено после того, как запрос будет обработан).
 			defer func() {
+				duration := time.Since(t1)
+
+				// Успешные быстрые запросы логируются выборочно. Ответ без явного статуса (0) считается успешным.
+				failed := ww.Status() >= http.StatusBadRequest
+				slow := sampling.SlowThreshold > 0 && duration >= sampling.SlowThreshold
+				if sampling.Rate > 1 && !failed && !slow && sampled.Add(1)%uint64(sampling.Rate) != 0 {
+					return
+				}
+
 				// Логируем информацию о завершении запроса: статус, количество байтов и продолжительность.
 				entry.Info("request completed",
-					slog.Int("status", ww.Status()),                  // Статус ответа.
-					slog.Int("bytes", ww.BytesWritten()),             // Количество отправленных байтов.
-					slog.String("duration", time.Since(t1).String()), // Время выполнения запроса.
+					slog.Int("status", ww.Status()),            // Статус ответа.
+					slog.Int("bytes", ww.BytesWritten()),       // Количество отправленных байтов.
+					slog.String("duration", duration.String()), // Время выполнения запроса.
 				)
 			}()
 
@@ -72,3 +111,13 @@ func New(log *slog.Logger) func(next http.Handler) http.Handler {
 		return http.HandlerFunc(fn)
 	}
 }
+
+// ipString - текстовое представление ip для лога. Если адрес не удалось разобрать
+// (например, RemoteAddr задан в нестандартном виде), логируется исходная строка raw.
+func ipString(ip net.IP, raw string) string {
+	if ip == nil {
+		return raw
+	}
+
+	return ip.String()
+}
@@ -0,0 +1,190 @@
+package logger_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	mwLogger "url-shortener/internal/http-server/middleware/logger"
+	"url-shortener/internal/lib/clientip"
+)
+
+// countCompleted returns how many requests were logged.
+func countCompleted(logs *bytes.Buffer) int {
+	return strings.Count(logs.String(), `"msg":"request completed"`)
+}
+
+func TestLogger_Sampling(t *testing.T) {
+	cases := []struct {
+		name     string
+		sampling mwLogger.Sampling
+		status   int
+		delay    time.Duration
+		requests int
+		want     int
+	}{
+		{
+			name:     "No sampling logs everything",
+			status:   http.StatusOK,
+			requests: 100,
+			want:     100,
+		},
+		{
+			name:     "One in ten successful requests",
+			sampling: mwLogger.Sampling{Rate: 10},
+			status:   http.StatusOK,
+			requests: 1000,
+			want:     100,
+		},
+		{
+			name:     "Errors are never sampled out",
+			sampling: mwLogger.Sampling{Rate: 10},
+			status:   http.StatusNotFound,
+			requests: 100,
+			want:     100,
+		},
+		{
+			name:     "Slow requests are never sampled out",
+			sampling: mwLogger.Sampling{Rate: 10, SlowThreshold: time.Millisecond},
+			status:   http.StatusOK,
+			delay:    2 * time.Millisecond,
+			requests: 20,
+			want:     20,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			log := slog.New(slog.NewJSONHandler(&logs, nil))
+
+			handler := mwLogger.New(log, nil, tc.sampling)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				time.Sleep(tc.delay)
+				w.WriteHeader(tc.status)
+			}))
+
+			for range tc.requests {
+				handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/google", nil))
+			}
+
+			require.Equal(t, tc.want, countCompleted(&logs))
+		})
+	}
+}
+
+func TestLogger_SamplingMixedTraffic(t *testing.T) {
+	var logs bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&logs, nil))
+
+	handler := mwLogger.New(log, nil, mwLogger.Sampling{Rate: 5})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for i := range 500 {
+		path := "/google"
+		if i%10 == 0 {
+			path = "/missing"
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	// All 50 errors plus roughly one in five of the 450 successful requests.
+	require.Equal(t, 50, strings.Count(logs.String(), `"status":404`))
+	require.InDelta(t, 90, strings.Count(logs.String(), `"status":200`), 5)
+}
+
+func TestLogger_ClientIP(t *testing.T) {
+	trusted, err := clientip.ParseNetworks([]string{"10.0.0.0/8", "fd00::1"})
+	require.NoError(t, err)
+
+	cases := []struct {
+		name          string
+		remoteAddr    string
+		xForwardedFor string
+		wantRemoteIP  string
+		wantClientIP  string
+	}{
+		{
+			name:         "IPv4 without port in log",
+			remoteAddr:   "203.0.113.7:1234",
+			wantRemoteIP: "203.0.113.7",
+			wantClientIP: "203.0.113.7",
+		},
+		{
+			name:         "Bracketed IPv6",
+			remoteAddr:   "[2001:db8::7]:1234",
+			wantRemoteIP: "2001:db8::7",
+			wantClientIP: "2001:db8::7",
+		},
+		{
+			name:          "Forwarded by trusted proxy",
+			remoteAddr:    "10.0.0.1:1234",
+			xForwardedFor: "198.51.100.9",
+			wantRemoteIP:  "10.0.0.1",
+			wantClientIP:  "198.51.100.9",
+		},
+		{
+			name:          "Forwarded by trusted IPv6 proxy",
+			remoteAddr:    "[fd00::1]:1234",
+			xForwardedFor: "2001:db8::7",
+			wantRemoteIP:  "fd00::1",
+			wantClientIP:  "2001:db8::7",
+		},
+		{
+			name:          "Forwarded header from untrusted peer is ignored",
+			remoteAddr:    "203.0.113.7:1234",
+			xForwardedFor: "198.51.100.9",
+			wantRemoteIP:  "203.0.113.7",
+			wantClientIP:  "203.0.113.7",
+		},
+		{
+			name:         "Unparsable address is logged as is",
+			remoteAddr:   "pipe",
+			wantRemoteIP: "pipe",
+			wantClientIP: "pipe",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			log := slog.New(slog.NewJSONHandler(&logs, nil))
+
+			handler := mwLogger.New(log, trusted, mwLogger.Sampling{})(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/google", nil)
+			req.RemoteAddr = tc.remoteAddr
+			if tc.xForwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tc.xForwardedFor)
+			}
+
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			var entry struct {
+				Msg      string `json:"msg"`
+				RemoteIP string `json:"remote_ip"`
+				ClientIP string `json:"client_ip"`
+			}
+
+			lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+			require.NoError(t, json.Unmarshal([]byte(lines[len(lines)-1]), &entry))
+
+			require.Equal(t, "request completed", entry.Msg)
+			require.Equal(t, tc.wantRemoteIP, entry.RemoteIP)
+			require.Equal(t, tc.wantClientIP, entry.ClientIP)
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package ratelimit
+
+import (
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/clientip"
+)
+
+// Response is returned with 429 and tells the client when it may retry.
+type Response struct {
+	resp.Response
+	Limit     int   `json:"limit"`
+	Remaining int   `json:"remaining"`
+	Reset     int64 `json:"reset"`
+}
+
+// window is the request count of one client in the current window.
+type window struct {
+	count   int
+	resetAt time.Time
+}
+
+// limiter counts requests per client in fixed windows.
+type limiter struct {
+	mu        sync.Mutex
+	limit     int
+	period    time.Duration
+	clients   map[string]*window
+	nextSweep time.Time
+}
+
+// New returns middleware that allows each client IP at most limit requests
+// per period and answers 429 to the rest. Every response carries
+// X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset (Unix time
+// in seconds) so well-behaved clients can throttle themselves; 429 responses
+// also carry Retry-After and the same data in the JSON body.
+// A non-positive limit disables the middleware.
+// The client IP is resolved with clientip.ClientIP, so forwarding headers
+// are honored only from trusted proxies.
+func New(log *slog.Logger, limit int, period time.Duration, trustedProxies []*net.IPNet) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if limit <= 0 || period <= 0 {
+			return next
+		}
+
+		log := log.With(
+			slog.String("component", "middleware/ratelimit"),
+		)
+
+		log.Info("rate limit enabled", slog.Int("limit", limit), slog.Duration("period", period))
+
+		l := &limiter{
+			limit:   limit,
+			period:  period,
+			clients: make(map[string]*window),
+		}
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			ip := clientip.ClientIP(r, trustedProxies)
+
+			allowed, remaining, resetAt := l.allow(ip.String(), time.Now())
+
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(limit))
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(resetAt.Unix(), 10))
+
+			if !allowed {
+				log.Warn("rate limit exceeded",
+					slog.String("ip", ip.String()),
+					slog.String("request_id", middleware.GetReqID(r.Context())),
+				)
+
+				retryAfter := max(int64(time.Until(resetAt).Seconds()+0.5), 1)
+				w.Header().Set("Retry-After", strconv.FormatInt(retryAfter, 10))
+
+				render.Status(r, http.StatusTooManyRequests)
+				render.JSON(w, r, Response{
+					Response:  resp.Error(resp.CodeRateLimited, "rate limit exceeded"),
+					Limit:     limit,
+					Remaining: 0,
+					Reset:     resetAt.Unix(),
+				})
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// allow counts a request of client at now and reports whether it is within
+// the limit, how many requests are left and when the window resets.
+func (l *limiter) allow(client string, now time.Time) (bool, int, time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	win, ok := l.clients[client]
+	if !ok || !now.Before(win.resetAt) {
+		win = &window{resetAt: now.Add(l.period)}
+		l.clients[client] = win
+	}
+
+	if win.count >= l.limit {
+		return false, 0, win.resetAt
+	}
+
+	win.count++
+
+	return true, l.limit - win.count, win.resetAt
+}
+
+// sweep drops expired windows at most once per period, so clients that
+// stopped sending requests do not stay in memory.
+func (l *limiter) sweep(now time.Time) {
+	if now.Before(l.nextSweep) {
+		return
+	}
+
+	for client, win := range l.clients {
+		if !now.Before(win.resetAt) {
+			delete(l.clients, client)
+		}
+	}
+
+	l.nextSweep = now.Add(l.period)
+}
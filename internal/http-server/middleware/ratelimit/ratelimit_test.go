@@ -0,0 +1,108 @@
+package ratelimit_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/middleware/ratelimit"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+func TestRateLimit(t *testing.T) {
+	const limit = 3
+
+	handler := ratelimit.New(slogdiscard.NewDiscardLogger(), limit, time.Minute, nil)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+	)
+
+	request := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/alias", nil)
+		req.RemoteAddr = remoteAddr
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	var reset string
+	for i := 1; i <= limit; i++ {
+		rr := request("203.0.113.7:1234")
+
+		require.Equal(t, http.StatusOK, rr.Code, i)
+		require.Equal(t, "3", rr.Header().Get("X-RateLimit-Limit"))
+		require.Equal(t, strconv.Itoa(limit-i), rr.Header().Get("X-RateLimit-Remaining"))
+
+		// The reset time is fixed for the whole window.
+		if reset == "" {
+			reset = rr.Header().Get("X-RateLimit-Reset")
+		}
+		require.Equal(t, reset, rr.Header().Get("X-RateLimit-Reset"))
+	}
+
+	resetAt, err := strconv.ParseInt(reset, 10, 64)
+	require.NoError(t, err)
+	require.InDelta(t, time.Now().Add(time.Minute).Unix(), resetAt, 2)
+
+	for range 2 {
+		rr := request("203.0.113.7:1234")
+
+		require.Equal(t, http.StatusTooManyRequests, rr.Code)
+		require.Equal(t, "0", rr.Header().Get("X-RateLimit-Remaining"))
+		require.Equal(t, reset, rr.Header().Get("X-RateLimit-Reset"))
+		require.NotEmpty(t, rr.Header().Get("Retry-After"))
+
+		var resp ratelimit.Response
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+		require.Equal(t, "rate limit exceeded", resp.Error)
+		require.Equal(t, limit, resp.Limit)
+		require.Zero(t, resp.Remaining)
+		require.Equal(t, resetAt, resp.Reset)
+	}
+
+	// Other clients have their own limit.
+	rr := request("198.51.100.1:1234")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "2", rr.Header().Get("X-RateLimit-Remaining"))
+}
+
+func TestRateLimit_WindowResets(t *testing.T) {
+	handler := ratelimit.New(slogdiscard.NewDiscardLogger(), 1, 50*time.Millisecond, nil)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+	)
+
+	request := func() int {
+		req := httptest.NewRequest(http.MethodGet, "/alias", nil)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	require.Equal(t, http.StatusOK, request())
+	require.Equal(t, http.StatusTooManyRequests, request())
+
+	time.Sleep(60 * time.Millisecond)
+
+	require.Equal(t, http.StatusOK, request())
+}
+
+func TestRateLimit_Disabled(t *testing.T) {
+	handler := ratelimit.New(slogdiscard.NewDiscardLogger(), 0, time.Minute, nil)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+	)
+
+	for range 5 {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/alias", nil))
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Empty(t, rr.Header().Get("X-RateLimit-Limit"))
+	}
+}
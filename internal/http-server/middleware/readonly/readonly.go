@@ -0,0 +1,41 @@
+package readonly
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	resp "url-shortener/internal/lib/api/response"
+)
+
+// New returns middleware for the group of mutating routes that answers
+// 503 Service Unavailable to every request while the service is in
+// read-only mode, e.g. during a maintenance window. Routes outside the
+// group, such as redirects and reads, keep working. A false enabled
+// disables the middleware.
+func New(log *slog.Logger, enabled bool) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !enabled {
+			return next
+		}
+
+		log := log.With(
+			slog.String("component", "middleware/readonly"),
+		)
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			log.Info("write rejected in read-only mode",
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.String("request_id", middleware.GetReqID(r.Context())),
+			)
+
+			render.Status(r, http.StatusServiceUnavailable)
+			render.JSON(w, r, resp.Error(resp.CodeReadOnly, "service is in read-only mode, writes are temporarily disabled"))
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
@@ -0,0 +1,81 @@
+package readonly_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/middleware/readonly"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+func TestReadOnly(t *testing.T) {
+	cases := []struct {
+		name     string
+		enabled  bool
+		method   string
+		path     string
+		wantCode int
+	}{
+		{
+			name:     "Disabled lets writes through",
+			method:   http.MethodPost,
+			path:     "/url",
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "Enabled blocks writes",
+			enabled:  true,
+			method:   http.MethodPost,
+			path:     "/url",
+			wantCode: http.StatusServiceUnavailable,
+		},
+		{
+			name:     "Enabled blocks deletes",
+			enabled:  true,
+			method:   http.MethodDelete,
+			path:     "/url/google",
+			wantCode: http.StatusServiceUnavailable,
+		},
+		{
+			name:     "Enabled keeps routes outside the group",
+			enabled:  true,
+			method:   http.MethodGet,
+			path:     "/google",
+			wantCode: http.StatusOK,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ok := func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) }
+
+			r := chi.NewRouter()
+			r.Get("/{alias}", ok)
+			r.Group(func(r chi.Router) {
+				r.Use(readonly.New(slogdiscard.NewDiscardLogger(), tc.enabled))
+
+				r.Post("/url", ok)
+				r.Delete("/url/{alias}", ok)
+			})
+
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			rr := httptest.NewRecorder()
+
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.wantCode, rr.Code)
+
+			if tc.wantCode == http.StatusServiceUnavailable {
+				var body resp.Response
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+				require.Equal(t, "service is in read-only mode, writes are temporarily disabled", body.Error)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package recoverer
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	resp "url-shortener/internal/lib/api/response"
+)
+
+// Options configures what the client sees in the 500 response.
+type Options struct {
+	// IncludeRequestID adds the request ID to the response so users can quote it when reporting the error.
+	IncludeRequestID bool
+	// IncludeStack adds the stack trace to the response. Meant for local development only.
+	IncludeStack bool
+}
+
+type Response struct {
+	resp.Response
+	RequestID string `json:"request_id,omitempty"`
+	Stack     string `json:"stack,omitempty"`
+}
+
+// New returns middleware that recovers from panics in later handlers,
+// logs the panic value and stack trace with the request ID and answers
+// with a JSON 500 Internal Server Error. It replaces chi's Recoverer,
+// which prints to stderr and returns an empty body.
+//
+// http.ErrAbortHandler is re-panicked so that net/http aborts the
+// response as intended. The middleware must be placed after the
+// request ID middleware.
+func New(log *slog.Logger, opts Options) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		log := log.With(
+			slog.String("component", "middleware/recoverer"),
+		)
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				rvr := recover()
+				if rvr == nil {
+					return
+				}
+				if rvr == http.ErrAbortHandler {
+					panic(rvr)
+				}
+
+				requestID := middleware.GetReqID(r.Context())
+				stack := string(debug.Stack())
+
+				log.Error("panic recovered",
+					slog.String("panic", fmt.Sprint(rvr)),
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+					slog.String("request_id", requestID),
+					slog.String("stack", stack),
+				)
+
+				// A protocol upgrade has taken over the connection; there is nothing to write to.
+				if r.Header.Get("Connection") == "Upgrade" {
+					return
+				}
+
+				response := Response{Response: resp.Error(resp.CodeInternal, "internal error")}
+				if opts.IncludeRequestID {
+					response.RequestID = requestID
+				}
+				if opts.IncludeStack {
+					response.Stack = stack
+				}
+
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, response)
+			}()
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
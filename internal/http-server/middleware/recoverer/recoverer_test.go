@@ -0,0 +1,86 @@
+package recoverer_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/middleware/recoverer"
+	"url-shortener/internal/http-server/middleware/requestid"
+)
+
+func TestRecoverer(t *testing.T) {
+	cases := []struct {
+		name          string
+		opts          recoverer.Options
+		wantRequestID bool
+		wantStack     bool
+	}{
+		{
+			name: "Bare error",
+		},
+		{
+			name:          "With request ID",
+			opts:          recoverer.Options{IncludeRequestID: true},
+			wantRequestID: true,
+		},
+		{
+			name:          "With request ID and stack",
+			opts:          recoverer.Options{IncludeRequestID: true, IncludeStack: true},
+			wantRequestID: true,
+			wantStack:     true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			log := slog.New(slog.NewJSONHandler(&logs, nil))
+
+			panicking := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+				panic("boom")
+			})
+			handler := requestid.New()(recoverer.New(log, tc.opts)(panicking))
+
+			req := httptest.NewRequest(http.MethodGet, "/google", nil)
+			req.Header.Set(requestid.Header, "req-1")
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusInternalServerError, rr.Code)
+
+			var resp recoverer.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, "internal error", resp.Error)
+			require.Equal(t, tc.wantRequestID, resp.RequestID == "req-1")
+			require.Equal(t, tc.wantStack, resp.Stack != "")
+
+			// The panic is always logged in full, whatever the client sees.
+			require.Contains(t, logs.String(), `"panic":"boom"`)
+			require.Contains(t, logs.String(), `"request_id":"req-1"`)
+			require.Contains(t, logs.String(), `"stack":"goroutine`)
+		})
+	}
+}
+
+func TestRecoverer_AbortHandler(t *testing.T) {
+	var logs bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&logs, nil))
+
+	handler := recoverer.New(log, recoverer.Options{})(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	// net/http uses ErrAbortHandler to abort a response silently, so it must propagate.
+	require.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+	require.Empty(t, logs.String())
+}
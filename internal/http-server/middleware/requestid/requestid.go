@@ -0,0 +1,74 @@
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Header is the request and response header carrying the request ID.
+const Header = "X-Request-ID"
+
+// maxLength limits client-supplied request IDs so that they cannot bloat logs.
+const maxLength = 128
+
+// prefix makes generated IDs unique across hosts and restarts,
+// like chi's "host/random-counter" IDs.
+var prefix = newPrefix()
+
+// New returns middleware that assigns every request an ID and echoes it back
+// in the X-Request-ID response header. A valid X-Request-ID sent by the client
+// is reused; otherwise a new ID is generated the same way chi does.
+//
+// The ID is stored in the context under chi's key, so middleware.GetReqID
+// keeps working. It replaces chi's middleware.RequestID.
+func New() func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(Header)
+			if !valid(id) {
+				id = fmt.Sprintf("%s-%06d", prefix, middleware.NextRequestID())
+			}
+
+			w.Header().Set(Header, id)
+
+			ctx := context.WithValue(r.Context(), middleware.RequestIDKey, id)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// valid reports whether a client-supplied ID is non-empty, reasonably short
+// and made of printable ASCII, so it is safe to log and echo back.
+func valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
+
+func newPrefix() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "localhost"
+	}
+
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+
+	return host + "/" + hex.EncodeToString(b)
+}
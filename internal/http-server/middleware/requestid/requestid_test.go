@@ -0,0 +1,84 @@
+package requestid_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+
+	mwLogger "url-shortener/internal/http-server/middleware/logger"
+	"url-shortener/internal/http-server/middleware/requestid"
+)
+
+func TestRequestID(t *testing.T) {
+	cases := []struct {
+		name     string
+		clientID string
+		wantID   string
+	}{
+		{
+			name: "Generated",
+		},
+		{
+			name:     "Client ID honored",
+			clientID: "client-req-42",
+			wantID:   "client-req-42",
+		},
+		{
+			name:     "Client ID too long",
+			clientID: strings.Repeat("a", 129),
+		},
+		{
+			name:     "Client ID with spaces",
+			clientID: "bad id",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			log := slog.New(slog.NewJSONHandler(&logs, nil))
+
+			r := chi.NewRouter()
+			r.Use(requestid.New())
+			r.Use(mwLogger.New(log, nil, mwLogger.Sampling{}))
+			r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.clientID != "" {
+				req.Header.Set(requestid.Header, tc.clientID)
+			}
+			rr := httptest.NewRecorder()
+
+			r.ServeHTTP(rr, req)
+
+			got := rr.Header().Get(requestid.Header)
+			require.NotEmpty(t, got)
+			if tc.wantID != "" {
+				require.Equal(t, tc.wantID, got)
+			} else {
+				require.NotEqual(t, tc.clientID, got)
+			}
+
+			// The header must match the request_id in the "request completed" log entry.
+			var logged string
+			for _, line := range bytes.Split(bytes.TrimSpace(logs.Bytes()), []byte("\n")) {
+				var entry map[string]any
+				require.NoError(t, json.Unmarshal(line, &entry))
+
+				if entry["msg"] == "request completed" {
+					logged, _ = entry["request_id"].(string)
+				}
+			}
+			require.Equal(t, got, logged)
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package timeout
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	resp "url-shortener/internal/lib/api/response"
+)
+
+// New returns middleware that sets a deadline on the request context.
+// Handlers and context-aware storage calls see the deadline and abort;
+// if the handler returns after the deadline without writing a response,
+// the middleware answers with 504 Gateway Timeout.
+// Zero or negative d disables the middleware.
+//
+// The middleware must be placed after the logger middleware so that
+// timed-out requests are still logged with their final status.
+func New(log *slog.Logger, d time.Duration) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if d <= 0 {
+			return next
+		}
+
+		log := log.With(
+			slog.String("component", "middleware/timeout"),
+		)
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+			r = r.WithContext(ctx)
+			next.ServeHTTP(ww, r)
+
+			if !errors.Is(ctx.Err(), context.DeadlineExceeded) || ww.Status() != 0 {
+				return
+			}
+
+			log.Warn("request timed out",
+				slog.String("path", r.URL.Path),
+				slog.String("request_id", middleware.GetReqID(ctx)),
+				slog.String("timeout", d.String()),
+			)
+
+			render.Status(r, http.StatusGatewayTimeout)
+			render.JSON(ww, r, resp.Error(resp.CodeTimeout, "request timed out"))
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
@@ -0,0 +1,62 @@
+package timeout_test
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+
+	mwLogger "url-shortener/internal/http-server/middleware/logger"
+	"url-shortener/internal/http-server/middleware/timeout"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+func TestTimeout(t *testing.T) {
+	cases := []struct {
+		name     string
+		delay    time.Duration
+		wantCode int
+	}{
+		{
+			name:     "Fast handler",
+			delay:    0,
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "Slow handler",
+			delay:    time.Second,
+			wantCode: http.StatusGatewayTimeout,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			log := slog.New(slog.NewJSONHandler(&logs, nil))
+
+			r := chi.NewRouter()
+			r.Use(mwLogger.New(log, nil, mwLogger.Sampling{}))
+			r.Use(timeout.New(slogdiscard.NewDiscardLogger(), 50*time.Millisecond))
+			r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case <-time.After(tc.delay):
+					w.WriteHeader(http.StatusOK)
+				case <-r.Context().Done():
+				}
+			})
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			require.Equal(t, tc.wantCode, rr.Code)
+			require.Contains(t, logs.String(), `"msg":"request completed"`)
+			require.Contains(t, logs.String(), fmt.Sprintf(`"status":%d`, tc.wantCode))
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package access
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"url-shortener/internal/lib/logger/sl"
+)
+
+// queueSize bounds the number of pending access events. When the queue is
+// full new events are dropped: last-access time is a hint, and clicks of
+// unlimited links only feed statistics.
+const queueSize = 1024
+
+// LastAccessUpdater is an interface for persisting last-access times and
+// click counts of links without a click limit.
+type LastAccessUpdater interface {
+	UpdateLastAccessed(accessed map[int64]time.Time) error
+	AddClicks(clicks map[int64]int64) error
+}
+
+type event struct {
+	id    int64
+	at    time.Time
+	click bool
+}
+
+// Recorder collects link accesses and writes them to storage in batches
+// from a background goroutine, so recording never blocks a redirect.
+type Recorder struct {
+	log      *slog.Logger
+	updater  LastAccessUpdater
+	interval time.Duration
+
+	// events is never closed: Record may run concurrently with Close, and a
+	// send on a closed channel panics. Close signals through stop instead.
+	events chan event
+	stop   chan struct{}
+	done   chan struct{}
+	once   sync.Once
+}
+
+// NewRecorder starts a Recorder that flushes collected accesses every interval.
+func NewRecorder(log *slog.Logger, updater LastAccessUpdater, interval time.Duration) *Recorder {
+	r := &Recorder{
+		log: log.With(
+			slog.String("component", "access/recorder"),
+		),
+		updater:  updater,
+		interval: interval,
+		events:   make(chan event, queueSize),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	go r.run()
+
+	return r
+}
+
+// Record registers an access to the link with the given ID at the given time without blocking.
+// Links are identified by ID because aliases are only unique within a namespace.
+// After Close accesses are dropped.
+func (r *Recorder) Record(id int64, at time.Time) {
+	r.enqueue(event{id: id, at: at})
+}
+
+// RecordClick registers an access like Record and also counts a click.
+// It is meant for links without a click limit: their counters are written in
+// batches instead of one UPDATE per redirect.
+func (r *Recorder) RecordClick(id int64, at time.Time) {
+	r.enqueue(event{id: id, at: at, click: true})
+}
+
+func (r *Recorder) enqueue(e event) {
+	select {
+	case <-r.stop:
+		return
+	default:
+	}
+
+	select {
+	case r.events <- e:
+	default:
+		r.log.Debug("access queue is full, dropping event", slog.Int64("id", e.id))
+	}
+}
+
+// Close stops the background goroutine after flushing pending accesses.
+// It is safe to call concurrently with Record.
+func (r *Recorder) Close() {
+	r.once.Do(func() {
+		close(r.stop)
+		<-r.done
+	})
+}
+
+func (r *Recorder) run() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	pending := make(batch)
+
+	for {
+		select {
+		case e := <-r.events:
+			pending.add(e)
+		case <-ticker.C:
+			r.flush(pending)
+			clear(pending)
+		case <-r.stop:
+			// Drain what was queued before Close. A Record racing with Close
+			// may still land after this and is lost, as with a full queue.
+			for {
+				select {
+				case e := <-r.events:
+					pending.add(e)
+				default:
+					r.flush(pending)
+					return
+				}
+			}
+		}
+	}
+}
+
+// batch holds the latest access time and the number of counted clicks per link ID.
+type batch map[int64]access
+
+type access struct {
+	at     time.Time
+	clicks int64
+}
+
+func (b batch) add(e event) {
+	a := b[e.id]
+	if e.at.After(a.at) {
+		a.at = e.at
+	}
+	if e.click {
+		a.clicks++
+	}
+	b[e.id] = a
+}
+
+func (r *Recorder) flush(b batch) {
+	if len(b) == 0 {
+		return
+	}
+
+	accessed := make(map[int64]time.Time, len(b))
+	clicks := make(map[int64]int64)
+	for id, a := range b {
+		accessed[id] = a.at
+		if a.clicks > 0 {
+			clicks[id] = a.clicks
+		}
+	}
+
+	if err := r.updater.UpdateLastAccessed(accessed); err != nil {
+		r.log.Error("failed to update last access time", sl.Err(err), slog.Int("links", len(accessed)))
+	}
+
+	if len(clicks) == 0 {
+		return
+	}
+
+	if err := r.updater.AddClicks(clicks); err != nil {
+		r.log.Error("failed to count clicks", sl.Err(err), slog.Int("links", len(clicks)))
+	}
+}
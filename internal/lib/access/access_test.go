@@ -0,0 +1,98 @@
+package access_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/access"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+type updaterStub struct {
+	mu       sync.Mutex
+	accessed map[int64]time.Time
+	clicks   map[int64]int64
+}
+
+func (u *updaterStub) UpdateLastAccessed(accessed map[int64]time.Time) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	for id, at := range accessed {
+		u.accessed[id] = at
+	}
+
+	return nil
+}
+
+func (u *updaterStub) AddClicks(clicks map[int64]int64) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.clicks == nil {
+		u.clicks = make(map[int64]int64)
+	}
+	for id, n := range clicks {
+		u.clicks[id] += n
+	}
+
+	return nil
+}
+
+func TestRecorder_CloseFlushes(t *testing.T) {
+	updater := &updaterStub{accessed: make(map[int64]time.Time)}
+	r := access.NewRecorder(slogdiscard.NewDiscardLogger(), updater, time.Hour)
+
+	first, last := time.Unix(100, 0), time.Unix(200, 0)
+	r.Record(1, first)
+	r.Record(1, last)
+	r.Record(2, first)
+	r.Close()
+
+	// Close записывает накопленное, не дожидаясь тикера, и оставляет самое позднее время обращения.
+	require.Equal(t, map[int64]time.Time{1: last, 2: first}, updater.accessed)
+
+	// Обращения после Close отбрасываются, повторный Close ничего не делает.
+	r.Record(3, last)
+	r.Close()
+	require.NotContains(t, updater.accessed, int64(3))
+}
+
+func TestRecorder_RecordClick(t *testing.T) {
+	updater := &updaterStub{accessed: make(map[int64]time.Time)}
+	r := access.NewRecorder(slogdiscard.NewDiscardLogger(), updater, time.Hour)
+
+	at := time.Unix(100, 0)
+	r.RecordClick(1, at)
+	r.RecordClick(1, at)
+	r.RecordClick(2, at)
+	r.Record(3, at)
+	r.Close()
+
+	// Переходы копятся в пачке и записываются одним вызовом; Record обновляет только время обращения.
+	require.Equal(t, map[int64]int64{1: 2, 2: 1}, updater.clicks)
+	require.Equal(t, map[int64]time.Time{1: at, 2: at, 3: at}, updater.accessed)
+}
+
+func TestRecorder_RecordDuringClose(t *testing.T) {
+	updater := &updaterStub{accessed: make(map[int64]time.Time)}
+	r := access.NewRecorder(slogdiscard.NewDiscardLogger(), updater, time.Millisecond)
+
+	// Редиректы продолжают записывать обращения, пока сервер останавливается: Close не должен приводить к панике.
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 1000 {
+				r.Record(int64(i*1000+j), time.Now())
+			}
+		}()
+	}
+
+	r.Close()
+	wg.Wait()
+}
@@ -0,0 +1,104 @@
+package aliaslen
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"sync"
+)
+
+// minSpace is the smallest alias space Check accepts: fewer possible
+// aliases would start colliding after a handful of links.
+const minSpace = 100_000
+
+// loadFactor is the share of the alias space that may be taken before
+// a longer length is used: at 1/1000 a generated alias collides with
+// probability under 0.1%.
+const loadFactor = 1000
+
+// collisionThreshold is how many collisions at the current length make
+// the Scaler switch to a longer one.
+const collisionThreshold = 3
+
+// ForCount returns the shortest length in [minLength, maxLength] whose
+// alias space keeps count links under the load factor. symbols is the
+// number of characters that tell two aliases apart; aliases are stored
+// lowercase, so base62 gives 36.
+func ForCount(count int64, symbols, minLength, maxLength int) int {
+	length := minLength
+	for length < maxLength && float64(count)*loadFactor >= math.Pow(float64(symbols), float64(length)) {
+		length++
+	}
+
+	return length
+}
+
+// Check reports an error when aliases of length built from symbols
+// distinct characters leave too few possible aliases.
+func Check(symbols, length int) error {
+	if space := math.Pow(float64(symbols), float64(length)); space < minSpace {
+		return fmt.Errorf(
+			"%d characters give only %.0f aliases of length %d, need at least %d: use a larger alphabet or a longer length",
+			symbols, space, length, minSpace,
+		)
+	}
+
+	return nil
+}
+
+// Scaler holds the effective length of generated aliases. It starts short
+// and grows by one each time collisionThreshold collisions are reported
+// at the current length, up to a maximum. It is safe for concurrent use.
+type Scaler struct {
+	log *slog.Logger
+
+	mu         sync.Mutex
+	length     int
+	maxLength  int
+	collisions int
+}
+
+// New creates a Scaler starting at length and never exceeding maxLength.
+func New(log *slog.Logger, length, maxLength int) *Scaler {
+	if maxLength < length {
+		maxLength = length
+	}
+
+	return &Scaler{
+		log:       log.With(slog.String("component", "aliaslen")),
+		length:    length,
+		maxLength: maxLength,
+	}
+}
+
+// Length returns the length to generate aliases with.
+func (s *Scaler) Length() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.length
+}
+
+// Collision reports that a generated alias of the given length was taken.
+// Reports for a length other than the current one are ignored, so requests
+// that started before the length grew do not grow it again.
+func (s *Scaler) Collision(length int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if length != s.length || s.length >= s.maxLength {
+		return
+	}
+
+	s.collisions++
+	if s.collisions < collisionThreshold {
+		return
+	}
+
+	s.length++
+	s.collisions = 0
+
+	s.log.Warn("generated alias collisions are frequent, increasing alias length",
+		slog.Int("length", s.length),
+	)
+}
@@ -0,0 +1,54 @@
+package aliaslen_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/aliaslen"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+func TestForCount(t *testing.T) {
+	cases := []struct {
+		name  string
+		count int64
+		want  int
+	}{
+		{name: "Empty table", count: 0, want: 6},
+		{name: "Under load factor", count: 2_000_000, want: 6},
+		{name: "Over load factor", count: 3_000_000, want: 7},
+		{name: "Capped at max", count: 1 << 62, want: 10},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, aliaslen.ForCount(tc.count, 36, 6, 10))
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	require.NoError(t, aliaslen.Check(36, 6))
+	require.NoError(t, aliaslen.Check(31, 4))
+	require.ErrorContains(t, aliaslen.Check(36, 3), "46656 aliases of length 3")
+	require.Error(t, aliaslen.Check(2, 10))
+}
+
+func TestScaler(t *testing.T) {
+	s := aliaslen.New(slogdiscard.NewDiscardLogger(), 6, 7)
+
+	s.Collision(6)
+	s.Collision(6)
+	require.Equal(t, 6, s.Length())
+
+	s.Collision(6)
+	require.Equal(t, 7, s.Length())
+
+	// Late reports for the old length do not count against the new one.
+	s.Collision(6)
+	s.Collision(7)
+	s.Collision(7)
+	s.Collision(7)
+	require.Equal(t, 7, s.Length(), "length must not exceed the maximum")
+}
@@ -3,7 +3,13 @@ package api
 import (
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"url-shortener/internal/lib/clientip"
 )
 
 var (
@@ -31,4 +37,31 @@ func GetRedirect(url string) (string, error) {
 	}
 
 	return resp.Header.Get("Location"), nil
-}
\ No newline at end of file
+}
+
+// IsHTTPURL reports whether s is an absolute http or https URL with a host.
+// Links may only point to such URLs: other schemes, such as javascript:,
+// would run in the service's origin when served by an HTML redirect page.
+func IsHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return false
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+
+	return scheme == "http" || scheme == "https"
+}
+
+// ShortURL builds the public short link for alias as seen by the client
+// that sent r, including the base path the service is mounted under.
+// The scheme follows X-Forwarded-Proto from the trusted proxies (see clientip.Scheme).
+func ShortURL(r *http.Request, trusted []*net.IPNet, basePath, alias string) string {
+	u := url.URL{
+		Scheme: clientip.Scheme(r, trusted),
+		Host:   r.Host,
+		Path:   path.Join("/", basePath, alias),
+	}
+
+	return u.String()
+}
@@ -10,6 +10,9 @@ import (
 type Response struct {
 	Response string `json:"status"`
 	Error    string `json:"error,omitempty"`
+	// Code is a stable machine-readable identifier of the failure; Error
+	// is meant for humans and may change wording between releases.
+	Code string `json:"code,omitempty"`
 }
 
 const (
@@ -17,20 +20,64 @@ const (
 	StatusError = "Error"
 )
 
+// Error codes returned in Response.Code. Clients should branch on these
+// rather than on the message text. Keep in sync with openapi.json.
+const (
+	CodeInternal              = "INTERNAL_ERROR"
+	CodeNotFound              = "NOT_FOUND"
+	CodeMethodNotAllowed      = "METHOD_NOT_ALLOWED"
+	CodeInvalidRequest        = "INVALID_REQUEST"
+	CodeInvalidQuery          = "INVALID_QUERY"
+	CodeValidationFailed      = "VALIDATION_FAILED"
+	CodeInvalidURL            = "INVALID_URL"
+	CodeURLTooLong            = "URL_TOO_LONG"
+	CodeAliasTaken            = "ALIAS_TAKEN"
+	CodeAliasConfusable       = "ALIAS_CONFUSABLE"
+	CodeAliasHeld             = "ALIAS_HELD"
+	CodeAliasReserved         = "ALIAS_RESERVED"
+	CodeNamespaceReserved     = "NAMESPACE_RESERVED"
+	CodeAliasGenerationFailed = "ALIAS_GENERATION_FAILED"
+	CodeURLAlreadyShortened   = "URL_ALREADY_SHORTENED"
+	CodeLinkLimitReached      = "LINK_LIMIT_REACHED"
+	CodeStorageFull           = "STORAGE_FULL"
+	CodeTooManyAliases        = "TOO_MANY_ALIASES"
+	CodeConfirmationRequired  = "CONFIRMATION_REQUIRED"
+	CodeLinkExpired           = "LINK_EXPIRED"
+	CodeLinkExhausted         = "LINK_EXHAUSTED"
+	CodeLinkDisabled          = "LINK_DISABLED"
+	CodeUnauthorized          = "UNAUTHORIZED"
+	CodeForbidden             = "FORBIDDEN"
+	CodeInsufficientScope     = "INSUFFICIENT_SCOPE"
+	CodeUnsupportedMediaType  = "UNSUPPORTED_CONTENT_TYPE"
+	CodeRateLimited           = "RATE_LIMITED"
+	CodeServerBusy            = "SERVER_BUSY"
+	CodeTimeout               = "TIMEOUT"
+	CodeReadOnly              = "READ_ONLY"
+
+	// Idempotency-Key conflicts on POST /url.
+	CodeIdempotencyKeyMismatch   = "IDEMPOTENCY_KEY_MISMATCH"
+	CodeIdempotencyKeyInProgress = "IDEMPOTENCY_KEY_IN_PROGRESS"
+)
+
 func OK() Response {
 	return Response{
 		Response: StatusOK,
 	}
 }
 
-func Error(msg string) Response {
+func Error(code, msg string) Response {
 	return Response{
 		Response: StatusError,
 		Error:    msg,
+		Code:     code,
 	}
 }
 
+// ValidationError describes every failed field in the message. The code is
+// INVALID_URL when any URL field is malformed and VALIDATION_FAILED otherwise.
 func ValidationError(errs validator.ValidationErrors) Response {
+	code := CodeValidationFailed
+
 	var errMsgs []string
 	for _, err := range errs {
 		switch err.ActualTag() {
@@ -38,13 +85,16 @@ func ValidationError(errs validator.ValidationErrors) Response {
 			errMsgs = append(errMsgs, fmt.Sprintf("field %s is a required field", err.Field()))
 		case "url":
 			errMsgs = append(errMsgs, fmt.Sprintf("field %s is not a valid URL", err.Field()))
+			code = CodeInvalidURL
+		case "http_url":
+			errMsgs = append(errMsgs, fmt.Sprintf("field %s is not a valid http or https URL", err.Field()))
+			code = CodeInvalidURL
+		case "alphanum":
+			errMsgs = append(errMsgs, fmt.Sprintf("field %s must contain only letters and digits", err.Field()))
 		default:
 			errMsgs = append(errMsgs, fmt.Sprintf("field %s is not valid", err.Field()))
 		}
 	}
 
-	return Response{
-		Response: StatusError,
-		Error:    strings.Join(errMsgs, ", "),
-	}
+	return Error(code, strings.Join(errMsgs, ", "))
 }
@@ -0,0 +1,65 @@
+package response
+
+import (
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestError(t *testing.T) {
+	r := Error(CodeNotFound, "not found")
+
+	assert.Equal(t, StatusError, r.Response)
+	assert.Equal(t, "not found", r.Error)
+	assert.Equal(t, CodeNotFound, r.Code)
+}
+
+func TestValidationError(t *testing.T) {
+	type request struct {
+		URL   string `validate:"required,url"`
+		Alias string `validate:"omitempty,alphanum"`
+	}
+
+	cases := []struct {
+		name     string
+		req      request
+		wantMsg  string
+		wantCode string
+	}{
+		{
+			name:     "missing url",
+			req:      request{},
+			wantMsg:  "field URL is a required field",
+			wantCode: CodeValidationFailed,
+		},
+		{
+			name:     "invalid url",
+			req:      request{URL: "not a url"},
+			wantMsg:  "field URL is not a valid URL",
+			wantCode: CodeInvalidURL,
+		},
+		{
+			// Неверный URL задаёт код, даже если есть и другие ошибки.
+			name:     "invalid url and alias",
+			req:      request{URL: "not a url", Alias: "a_b"},
+			wantMsg:  "field URL is not a valid URL, field Alias must contain only letters and digits",
+			wantCode: CodeInvalidURL,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validator.New().Struct(tc.req)
+
+			var errs validator.ValidationErrors
+			require.ErrorAs(t, err, &errs)
+
+			r := ValidationError(errs)
+			assert.Equal(t, StatusError, r.Response)
+			assert.Equal(t, tc.wantMsg, r.Error)
+			assert.Equal(t, tc.wantCode, r.Code)
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// keyPrefix starts every key so that leaked keys are easy to recognise in
+// logs and by secret scanners.
+const keyPrefix = "usk_"
+
+// Key layout: keyPrefix, a public lookup prefix of prefixBytes random bytes
+// in hex, "_" and a secret of secretBytes random bytes in base64url. Only
+// the bcrypt hash of the whole key is stored; the lookup prefix finds the
+// hash to compare with, so a request costs one bcrypt comparison however
+// many keys exist. The key stays under bcrypt's 72-byte input limit.
+const (
+	prefixBytes = 6
+	secretBytes = 32
+)
+
+// Generate creates a new key and returns it together with its lookup
+// prefix and bcrypt hash. The key itself is shown to its owner once and
+// must not be stored.
+func Generate() (key, prefix string, hash []byte, err error) {
+	const op = "apikey.Generate"
+
+	buf := make([]byte, prefixBytes+secretBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	prefix = hex.EncodeToString(buf[:prefixBytes])
+	key = keyPrefix + prefix + "_" + base64.RawURLEncoding.EncodeToString(buf[prefixBytes:])
+
+	hash, err = bcrypt.GenerateFromPassword([]byte(key), bcrypt.DefaultCost)
+	if err != nil {
+		return "", "", nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return key, prefix, hash, nil
+}
+
+// Prefix returns the lookup prefix of key. ok is false if key does not
+// look like a key made by Generate.
+func Prefix(key string) (prefix string, ok bool) {
+	rest, ok := strings.CutPrefix(key, keyPrefix)
+	if !ok {
+		return "", false
+	}
+
+	prefix, secret, ok := strings.Cut(rest, "_")
+	if !ok || len(prefix) != 2*prefixBytes || secret == "" {
+		return "", false
+	}
+
+	return prefix, true
+}
+
+// dummyHash is compared against when no key has the requested prefix, so
+// that unknown and known prefixes take the same time to reject.
+var dummyHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte(keyPrefix), bcrypt.DefaultCost)
+	return hash
+})
+
+// Verify reports whether key matches hash. The comparison is constant-time.
+// A nil hash, for a prefix that has no key, still costs a full comparison
+// and reports false.
+func Verify(hash []byte, key string) bool {
+	if hash == nil {
+		_ = bcrypt.CompareHashAndPassword(dummyHash(), []byte(key))
+		return false
+	}
+
+	return bcrypt.CompareHashAndPassword(hash, []byte(key)) == nil
+}
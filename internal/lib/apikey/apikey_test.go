@@ -0,0 +1,38 @@
+package apikey
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerate(t *testing.T) {
+	key, prefix, hash, err := Generate()
+	assert.NoError(t, err)
+
+	got, ok := Prefix(key)
+	assert.True(t, ok)
+	assert.Equal(t, prefix, got)
+
+	// Ключ не хранится в хэше в открытом виде, но проходит проверку.
+	assert.NotContains(t, string(hash), key)
+	assert.True(t, Verify(hash, key))
+	assert.False(t, Verify(hash, key+"x"))
+	assert.False(t, Verify(nil, key))
+
+	// Каждый вызов даёт новый ключ.
+	other, _, _, err := Generate()
+	assert.NoError(t, err)
+	assert.NotEqual(t, key, other)
+}
+
+func TestPrefix(t *testing.T) {
+	for _, key := range []string{"", "usk_", "usk_abc_secret", "key_0123456789ab_secret", "usk_0123456789ab_", "usk_0123456789ab"} {
+		_, ok := Prefix(key)
+		assert.False(t, ok, key)
+	}
+
+	prefix, ok := Prefix("usk_0123456789ab_se_cret")
+	assert.True(t, ok)
+	assert.Equal(t, "0123456789ab", prefix)
+}
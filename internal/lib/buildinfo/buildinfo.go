@@ -0,0 +1,33 @@
+package buildinfo
+
+import (
+	"runtime"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Info describes the running build. Values are injected at build time via -ldflags.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+}
+
+// Collector returns the url_shortener_build_info gauge: always 1, with the
+// build in its labels, so other metrics can be joined on it and broken down
+// by version during a rolling deploy.
+func (i Info) Collector() prometheus.Collector {
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "url_shortener",
+		Name:      "build_info",
+		Help:      "Build of the running service; always 1.",
+		ConstLabels: prometheus.Labels{
+			"version":   i.Version,
+			"commit":    i.Commit,
+			"goversion": runtime.Version(),
+		},
+	})
+	gauge.Set(1)
+
+	return gauge
+}
@@ -0,0 +1,38 @@
+package buildinfo
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInfo_Collector(t *testing.T) {
+	info := Info{Version: "v1.2.0", Commit: "abc1234", BuildDate: "2026-01-02T03:04:05Z"}
+
+	reg := prometheus.NewRegistry()
+	require.NoError(t, reg.Register(info.Collector()))
+
+	families, err := reg.Gather()
+	require.NoError(t, err)
+	require.Len(t, families, 1)
+	require.Equal(t, "url_shortener_build_info", families[0].GetName())
+	require.Len(t, families[0].GetMetric(), 1)
+
+	metric := families[0].GetMetric()[0]
+	assert.Equal(t, 1.0, metric.GetGauge().GetValue())
+
+	labels := make(map[string]string)
+	for _, l := range metric.GetLabel() {
+		labels[l.GetName()] = l.GetValue()
+	}
+
+	// Дата сборки в метки не попадает: по ней не нужно разбивать метрики.
+	assert.Equal(t, map[string]string{
+		"version":   "v1.2.0",
+		"commit":    "abc1234",
+		"goversion": runtime.Version(),
+	}, labels)
+}
@@ -0,0 +1,141 @@
+package clientip
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ParseNetworks parses a list of CIDRs. A bare IP is treated as a single-host network.
+func ParseNetworks(cidrs []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid ip %q", cidr)
+			}
+
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				bits = 8 * net.IPv4len
+			}
+
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+
+			continue
+		}
+
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cidr %q: %w", cidr, err)
+		}
+
+		nets = append(nets, ipNet)
+	}
+
+	return nets, nil
+}
+
+// Contains reports whether ip belongs to any of nets.
+func Contains(nets []*net.IPNet, ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ClientIP returns the IP of the client that sent r.
+//
+// Forwarding headers are trivially spoofed, so they are honored only when
+// the immediate peer (RemoteAddr) is one of the trusted proxies. A peer on
+// a Unix domain socket is always trusted: it has no IP, and only local
+// processes allowed by the socket file permissions, in practice the reverse
+// proxy in front of the service, can connect. In that case X-Forwarded-For
+// is walked from right to left, skipping trusted proxies, and the first
+// untrusted hop is the client. X-Real-IP is used when X-Forwarded-For is
+// absent. Otherwise the peer address is returned, which is nil for a Unix
+// socket peer.
+func ClientIP(r *http.Request, trusted []*net.IPNet) net.IP {
+	peer := RemoteIP(r)
+
+	if !Contains(trusted, peer) && !FromUnixSocket(r) {
+		return peer
+	}
+
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		hops := strings.Split(strings.Join(xff, ","), ",")
+
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip := net.ParseIP(strings.TrimSpace(hops[i]))
+			if ip == nil {
+				// A malformed hop means the chain can't be trusted past this point.
+				return peer
+			}
+
+			if !Contains(trusted, ip) || i == 0 {
+				return ip
+			}
+		}
+	}
+
+	if ip := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ip != nil {
+		return ip
+	}
+
+	return peer
+}
+
+// Scheme returns the scheme, "http" or "https", the client used to reach the service.
+//
+// Behind a TLS-terminating proxy r.TLS is nil, so X-Forwarded-Proto is honored,
+// but only from the peers ClientIP trusts: trusted proxies and Unix socket peers.
+// The first value of the header is the one the outermost proxy saw; values other
+// than http and https are ignored.
+func Scheme(r *http.Request, trusted []*net.IPNet) string {
+	if r.TLS != nil {
+		return "https"
+	}
+
+	if !Contains(trusted, RemoteIP(r)) && !FromUnixSocket(r) {
+		return "http"
+	}
+
+	proto, _, _ := strings.Cut(r.Header.Get("X-Forwarded-Proto"), ",")
+	if proto := strings.ToLower(strings.TrimSpace(proto)); proto == "https" {
+		return proto
+	}
+
+	return "http"
+}
+
+// FromUnixSocket reports whether r was received on a Unix domain socket.
+func FromUnixSocket(r *http.Request) bool {
+	addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr)
+	return ok && addr.Network() == "unix"
+}
+
+// RemoteIP returns the IP part of r.RemoteAddr, handling bracketed IPv6 addresses.
+// It is nil for requests received on a Unix domain socket, which have no peer IP.
+func RemoteIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	return net.ParseIP(strings.Trim(host, "[]"))
+}
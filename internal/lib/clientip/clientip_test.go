@@ -0,0 +1,233 @@
+package clientip_test
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/clientip"
+	"url-shortener/internal/lib/listen"
+)
+
+func TestClientIP(t *testing.T) {
+	trusted, err := clientip.ParseNetworks([]string{"10.0.0.0/8", "fd00::1"})
+	require.NoError(t, err)
+
+	cases := []struct {
+		name          string
+		remoteAddr    string
+		xForwardedFor string
+		xRealIP       string
+		want          string
+	}{
+		{
+			name:       "Direct client",
+			remoteAddr: "203.0.113.7:1234",
+			want:       "203.0.113.7",
+		},
+		{
+			name:          "Spoofed header from untrusted peer",
+			remoteAddr:    "203.0.113.7:1234",
+			xForwardedFor: "10.1.2.3",
+			want:          "203.0.113.7",
+		},
+		{
+			name:       "Spoofed X-Real-IP from untrusted peer",
+			remoteAddr: "203.0.113.7:1234",
+			xRealIP:    "10.1.2.3",
+			want:       "203.0.113.7",
+		},
+		{
+			name:          "Trusted proxy",
+			remoteAddr:    "10.0.0.1:1234",
+			xForwardedFor: "198.51.100.9",
+			want:          "198.51.100.9",
+		},
+		{
+			name:          "Client prepends spoofed hop behind trusted proxy",
+			remoteAddr:    "10.0.0.1:1234",
+			xForwardedFor: "1.2.3.4, 198.51.100.9",
+			want:          "198.51.100.9",
+		},
+		{
+			name:          "Chain of trusted proxies",
+			remoteAddr:    "10.0.0.1:1234",
+			xForwardedFor: "198.51.100.9, 10.0.0.2",
+			want:          "198.51.100.9",
+		},
+		{
+			name:       "Trusted proxy with X-Real-IP",
+			remoteAddr: "10.0.0.1:1234",
+			xRealIP:    "198.51.100.9",
+			want:       "198.51.100.9",
+		},
+		{
+			name:          "Trusted IPv6 proxy",
+			remoteAddr:    "[fd00::1]:1234",
+			xForwardedFor: "2001:db8::7",
+			want:          "2001:db8::7",
+		},
+		{
+			name:       "IPv6 client",
+			remoteAddr: "[2001:db8::7]:1234",
+			want:       "2001:db8::7",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tc.remoteAddr
+			if tc.xForwardedFor != "" {
+				r.Header.Set("X-Forwarded-For", tc.xForwardedFor)
+			}
+			if tc.xRealIP != "" {
+				r.Header.Set("X-Real-IP", tc.xRealIP)
+			}
+
+			require.Equal(t, tc.want, clientip.ClientIP(r, trusted).String())
+		})
+	}
+}
+
+func TestClientIP_UnixSocket(t *testing.T) {
+	// Путь к сокету ограничен ~100 байтами, поэтому каталог создаётся покороче, чем t.TempDir().
+	dir, err := os.MkdirTemp("", "us")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+
+	ln, err := listen.New("unix:" + filepath.Join(dir, "s.sock"))
+	require.NoError(t, err)
+
+	got := make(chan string, 1)
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got <- clientip.ClientIP(r, nil).String()
+	})}
+	go func() { _ = srv.Serve(ln) }()
+	t.Cleanup(func() { _ = srv.Close() })
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", ln.Addr().String())
+		},
+	}}
+
+	cases := []struct {
+		name          string
+		xForwardedFor string
+		xRealIP       string
+		want          string
+	}{
+		{
+			// За сокетом стоит обратный прокси: его заголовки принимаются без trusted_proxies.
+			name:          "X-Forwarded-For",
+			xForwardedFor: "203.0.113.7",
+			want:          "203.0.113.7",
+		},
+		{
+			name:          "Chain ends with the client",
+			xForwardedFor: "198.51.100.9, 203.0.113.7",
+			want:          "203.0.113.7",
+		},
+		{
+			name:    "X-Real-IP",
+			xRealIP: "203.0.113.7",
+			want:    "203.0.113.7",
+		},
+		{
+			// Без заголовков IP клиента неизвестен.
+			name: "No headers",
+			want: "<nil>",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "http://unix/", nil)
+			require.NoError(t, err)
+			if tc.xForwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tc.xForwardedFor)
+			}
+			if tc.xRealIP != "" {
+				req.Header.Set("X-Real-IP", tc.xRealIP)
+			}
+
+			res, err := client.Do(req)
+			require.NoError(t, err)
+			_ = res.Body.Close()
+
+			require.Equal(t, tc.want, <-got)
+		})
+	}
+}
+
+func TestScheme(t *testing.T) {
+	trusted, err := clientip.ParseNetworks([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+
+	cases := []struct {
+		name       string
+		remoteAddr string
+		proto      string
+		tls        bool
+		want       string
+	}{
+		{
+			name:       "Plain request",
+			remoteAddr: "203.0.113.7:1234",
+			want:       "http",
+		},
+		{
+			name:       "TLS connection",
+			remoteAddr: "203.0.113.7:1234",
+			tls:        true,
+			want:       "https",
+		},
+		{
+			name:       "Trusted proxy terminates TLS",
+			remoteAddr: "10.0.0.1:1234",
+			proto:      "https",
+			want:       "https",
+		},
+		{
+			name:       "Outermost proxy wins",
+			remoteAddr: "10.0.0.1:1234",
+			proto:      "HTTPS, http",
+			want:       "https",
+		},
+		{
+			name:       "Spoofed header from untrusted peer",
+			remoteAddr: "203.0.113.7:1234",
+			proto:      "https",
+			want:       "http",
+		},
+		{
+			name:       "Unknown scheme",
+			remoteAddr: "10.0.0.1:1234",
+			proto:      "javascript",
+			want:       "http",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tc.remoteAddr
+			if tc.proto != "" {
+				r.Header.Set("X-Forwarded-Proto", tc.proto)
+			}
+			if tc.tls {
+				r.TLS = &tls.ConnectionState{}
+			}
+
+			require.Equal(t, tc.want, clientip.Scheme(r, trusted))
+		})
+	}
+}
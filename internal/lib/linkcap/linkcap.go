@@ -0,0 +1,63 @@
+package linkcap
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// cacheTTL is how long the total link count is trusted before it is read
+// from the storage again. Saves reported with Added keep the cached count
+// current in between; deletions are picked up on the next refresh.
+const cacheTTL = 5 * time.Second
+
+// Counter is an interface for counting all stored links.
+type Counter interface {
+	CountURLs(ctx context.Context) (int64, error)
+}
+
+// Cap enforces a cap on the total number of stored links without running
+// COUNT(*) on every save. The HTTP and gRPC APIs each keep their own Cap,
+// so saves through one are seen by the other on its next refresh. It is
+// safe for concurrent use.
+type Cap struct {
+	counter Counter
+	max     int64
+
+	mu      sync.Mutex
+	count   int64
+	expires time.Time
+}
+
+// New returns a Cap of max links counted by counter.
+func New(counter Counter, max int64) *Cap {
+	return &Cap{counter: counter, max: max}
+}
+
+// Full reports whether the cap is reached, along with the count it was
+// checked against.
+func (c *Cap) Full(ctx context.Context) (bool, int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if !now.Before(c.expires) {
+		count, err := c.counter.CountURLs(ctx)
+		if err != nil {
+			return false, 0, err
+		}
+
+		c.count = count
+		c.expires = now.Add(cacheTTL)
+	}
+
+	return c.count >= c.max, c.count, nil
+}
+
+// Added accounts for a link inserted since the last refresh.
+func (c *Cap) Added() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.count++
+}
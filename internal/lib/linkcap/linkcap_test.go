@@ -0,0 +1,47 @@
+package linkcap
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// counterFunc adapts a function to Counter.
+type counterFunc func(ctx context.Context) (int64, error)
+
+func (f counterFunc) CountURLs(ctx context.Context) (int64, error) {
+	return f(ctx)
+}
+
+func TestCap(t *testing.T) {
+	calls := 0
+	c := New(counterFunc(func(context.Context) (int64, error) {
+		calls++
+		return 1, nil
+	}), 2)
+
+	full, count, err := c.Full(context.Background())
+	require.NoError(t, err)
+	require.False(t, full)
+	require.Equal(t, int64(1), count)
+
+	// Сохранение учитывается без повторного подсчёта в хранилище.
+	c.Added()
+
+	full, count, err = c.Full(context.Background())
+	require.NoError(t, err)
+	require.True(t, full)
+	require.Equal(t, int64(2), count)
+	require.Equal(t, 1, calls)
+}
+
+func TestCap_CounterError(t *testing.T) {
+	c := New(counterFunc(func(context.Context) (int64, error) {
+		return 0, errors.New("unexpected error")
+	}), 2)
+
+	_, _, err := c.Full(context.Background())
+	require.Error(t, err)
+}
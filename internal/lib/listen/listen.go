@@ -0,0 +1,79 @@
+package listen
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// unixPrefix marks an address as a Unix domain socket path, e.g. "unix:/run/url-shortener.sock".
+const unixPrefix = "unix:"
+
+// IsUnix reports whether address names a Unix domain socket.
+func IsUnix(address string) bool {
+	return strings.HasPrefix(address, unixPrefix)
+}
+
+// New listens on address: a Unix domain socket for "unix:/path/to/sock",
+// TCP for a host:port. A socket file left behind by a previous run that
+// crashed is removed first; a socket another process still accepts
+// connections on, or a path that is not a socket, is an error. The socket
+// file is removed when the listener is closed.
+func New(address string) (net.Listener, error) {
+	const op = "listen.New"
+
+	if !IsUnix(address) {
+		ln, err := net.Listen("tcp", address)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		return ln, nil
+	}
+
+	path := strings.TrimPrefix(address, unixPrefix)
+	if path == "" {
+		return nil, fmt.Errorf("%s: empty unix socket path", op)
+	}
+
+	if err := removeStaleSocket(path); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return ln, nil
+}
+
+// removeStaleSocket removes the socket file at path if no one is listening on it.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("stat socket %s: %w", path, err)
+	}
+
+	if info.Mode().Type() != fs.ModeSocket {
+		return fmt.Errorf("%s exists and is not a socket", path)
+	}
+
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err == nil {
+		_ = conn.Close()
+		return fmt.Errorf("socket %s is in use by another process", path)
+	}
+
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("remove stale socket %s: %w", path, err)
+	}
+
+	return nil
+}
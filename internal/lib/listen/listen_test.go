@@ -0,0 +1,59 @@
+package listen
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew_TCP(t *testing.T) {
+	ln, err := New("127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	assert.Equal(t, "tcp", ln.Addr().Network())
+}
+
+func TestNew_Unix(t *testing.T) {
+	// Путь к сокету ограничен ~100 байтами, поэтому каталог создаётся покороче, чем t.TempDir().
+	dir, err := os.MkdirTemp("", "us")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "s.sock")
+
+	ln, err := New("unix:" + path)
+	require.NoError(t, err)
+	assert.Equal(t, "unix", ln.Addr().Network())
+
+	// Пока сокет слушается, второй сервер запуститься не может.
+	_, err = New("unix:" + path)
+	assert.ErrorContains(t, err, "in use")
+
+	// После закрытия файл сокета удаляется.
+	require.NoError(t, ln.Close())
+	assert.NoFileExists(t, path)
+
+	// Файл, оставшийся после аварийного завершения, удаляется при запуске.
+	stale, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, stale.Close())
+	assert.FileExists(t, path)
+
+	ln, err = New("unix:" + path)
+	require.NoError(t, err)
+	require.NoError(t, ln.Close())
+
+	// Обычный файл по этому пути не удаляется.
+	require.NoError(t, os.WriteFile(path, nil, 0o644))
+	_, err = New("unix:" + path)
+	assert.ErrorContains(t, err, "not a socket")
+
+	_, err = New("unix:")
+	assert.Error(t, err)
+}
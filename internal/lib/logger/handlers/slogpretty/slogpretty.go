@@ -104,14 +104,14 @@ func (h *PrettyHandler) Handle(_ context.Context, r slog.Record) error {
 	// Обрабатываем атрибуты из записи лога (slog.Record) и сохраняем их в карту.
 	// Каждый атрибут (ключ-значение) добавляется в поле "fields".
 	r.Attrs(func(a slog.Attr) bool {
-		fields[a.Key] = a.Value.Any() // Сохраняем атрибут в карту.
+		fields[a.Key] = attrValue(a.Value) // Сохраняем атрибут в карту.
 		return true
 	})
 
 	// Добавляем дополнительные атрибуты, определенные в h.attrs.
 	// Это позволяет добавлять дополнительные данные ко всем лог-сообщениям, например, метки или идентификаторы.
 	for _, a := range h.attrs {
-		fields[a.Key] = a.Value.Any()
+		fields[a.Key] = attrValue(a.Value)
 	}
 
 	// Массив для хранения JSON-строки с аттрибутами.
@@ -146,6 +146,23 @@ func (h *PrettyHandler) Handle(_ context.Context, r slog.Record) error {
 	return nil
 }
 
+// attrValue превращает значение атрибута в то, что можно сериализовать в JSON.
+// Значения, реализующие slog.LogValuer, сначала разрешаются через Resolve: иначе в лог попала бы
+// исходная структура целиком, вместе с секретами, которые LogValue скрывает (например, config.Config).
+// Группы раскрываются в вложенные карты, как это делает slog.JSONHandler.
+func attrValue(v slog.Value) interface{} {
+	v = v.Resolve()
+	if v.Kind() != slog.KindGroup {
+		return v.Any()
+	}
+
+	group := make(map[string]interface{}, len(v.Group()))
+	for _, a := range v.Group() {
+		group[a.Key] = attrValue(a.Value)
+	}
+	return group
+}
+
 // WithAttrs - метод, который позволяет добавить дополнительные атрибуты к обработчику логов.
 // Он возвращает новый экземпляр PrettyHandler с обновленным списком атрибутов, которые будут добавлены ко всем лог-сообщениям.
 // В этом методе:
@@ -0,0 +1,35 @@
+package slogpretty_test
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/config"
+	"url-shortener/internal/lib/logger/handlers/slogpretty"
+)
+
+func TestPrettyHandler_LogValuer(t *testing.T) {
+	cfg := &config.Config{Env: "local"}
+	cfg.Auth.Mode = "basic"
+	cfg.Auth.User = "admin"
+	cfg.Auth.Password = "s3cret-password"
+	cfg.Auth.JWT.Secret = "jwt-signing-key"
+
+	var logs bytes.Buffer
+	opts := slogpretty.PrettyHandlerOptions{SlogOpts: &slog.HandlerOptions{Level: slog.LevelDebug}}
+	log := slog.New(opts.NewPrettyHandler(&logs))
+
+	// Так main пишет сводку конфигурации при env=local.
+	log.Info("effective config", slog.Any("config", cfg))
+	// Атрибуты из With проходят через тот же путь.
+	log.With(slog.Any("config", cfg)).Info("with attrs")
+
+	require.Contains(t, logs.String(), `"password": "[REDACTED]"`)
+	require.Contains(t, logs.String(), `"user": "admin"`)
+	for _, secret := range []string{"s3cret-password", "jwt-signing-key"} {
+		require.NotContains(t, logs.String(), secret)
+	}
+}
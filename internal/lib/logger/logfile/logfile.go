@@ -0,0 +1,218 @@
+package logfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat is the timestamp put into backup file names. It sorts
+// lexically in time order and contains no characters that need quoting.
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// Options configures when a Writer rotates its file and which backups it keeps.
+type Options struct {
+	// MaxSize is the size in bytes at which the file is rotated. 0 disables rotation.
+	MaxSize int64
+
+	// MaxAge removes backups older than this. 0 keeps backups regardless of age.
+	MaxAge time.Duration
+
+	// MaxBackups is the number of backups to keep. 0 keeps all of them.
+	MaxBackups int
+}
+
+// Writer is an io.WriteCloser that appends to a file and, once the file
+// would exceed MaxSize, renames it to <name>-<timestamp><ext> and starts a new one.
+// Backups beyond MaxBackups or older than MaxAge are removed after each rotation.
+// It is safe for concurrent use.
+type Writer struct {
+	path string
+	opts Options
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+
+	// lastBackup is the time in the name of the latest backup, so that backups
+	// made within one millisecond still sort in the order they were made.
+	lastBackup time.Time
+}
+
+// New opens path for appending, creating it and its directory if needed.
+func New(path string, opts Options) (*Writer, error) {
+	w := &Writer{
+		path: path,
+		opts: opts,
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("create log directory: %w", err)
+	}
+
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// Write writes p to the file, rotating it first if p would not fit into MaxSize.
+// A single write larger than MaxSize goes into a fresh file as a whole.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	if w.opts.MaxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.opts.MaxSize {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+
+	return n, err
+}
+
+// Close closes the file. Writes after Close fail with os.ErrClosed.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+
+	err := w.file.Close()
+	w.file = nil
+
+	return err
+}
+
+func (w *Writer) open() error {
+	f, err := os.OpenFile(w.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("open log file: %w", err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("stat log file: %w", err)
+	}
+
+	w.file = f
+	w.size = info.Size()
+
+	return nil
+}
+
+func (w *Writer) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return fmt.Errorf("close log file: %w", err)
+	}
+	w.file = nil
+
+	// If the file cannot be renamed, it is reopened and keeps growing until
+	// a later rotation succeeds: losing log lines is worse than a large file.
+	renamed := os.Rename(w.path, w.backupName(time.Now())) == nil
+
+	if err := w.open(); err != nil {
+		return err
+	}
+
+	// A backup that cannot be removed is left for the next rotation.
+	if renamed {
+		w.prune()
+	}
+
+	return nil
+}
+
+// backupName returns a free name for the backup made at t: app.log becomes app-<timestamp>.log.
+// Rotations within the same millisecond get the following timestamps instead of overwriting each other.
+func (w *Writer) backupName(t time.Time) string {
+	ext := filepath.Ext(w.path)
+	base := strings.TrimSuffix(w.path, ext)
+
+	t = t.Truncate(time.Millisecond)
+	if !t.After(w.lastBackup) {
+		t = w.lastBackup.Add(time.Millisecond)
+	}
+
+	for {
+		name := base + "-" + t.UTC().Format(backupTimeFormat) + ext
+		if _, err := os.Lstat(name); os.IsNotExist(err) {
+			w.lastBackup = t
+			return name
+		}
+		t = t.Add(time.Millisecond)
+	}
+}
+
+// backups returns the existing backups, newest first, with the time each was made.
+func (w *Writer) backups() ([]backup, error) {
+	dir := filepath.Dir(w.path)
+	ext := filepath.Ext(w.path)
+	prefix := strings.TrimSuffix(filepath.Base(w.path), ext) + "-"
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var found []backup
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+			continue
+		}
+
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext)
+		t, err := time.Parse(backupTimeFormat, stamp)
+		if err != nil {
+			continue
+		}
+
+		found = append(found, backup{path: filepath.Join(dir, name), made: t})
+	}
+
+	sort.Slice(found, func(i, j int) bool { return found[i].made.After(found[j].made) })
+
+	return found, nil
+}
+
+func (w *Writer) prune() {
+	if w.opts.MaxBackups <= 0 && w.opts.MaxAge <= 0 {
+		return
+	}
+
+	found, err := w.backups()
+	if err != nil {
+		return
+	}
+
+	cutoff := time.Now().Add(-w.opts.MaxAge)
+	for i, b := range found {
+		tooMany := w.opts.MaxBackups > 0 && i >= w.opts.MaxBackups
+		tooOld := w.opts.MaxAge > 0 && b.made.Before(cutoff)
+
+		if tooMany || tooOld {
+			_ = os.Remove(b.path)
+		}
+	}
+}
+
+type backup struct {
+	path string
+	made time.Time
+}
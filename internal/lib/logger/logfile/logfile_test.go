@@ -0,0 +1,134 @@
+package logfile_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/logger/logfile"
+)
+
+// backupsOf возвращает содержимое резервных копий app.log в dir.
+func backupsOf(t *testing.T, dir string) []string {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+
+	var contents []string
+	for _, e := range entries {
+		if e.Name() == "app.log" {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		require.NoError(t, err)
+
+		contents = append(contents, string(data))
+	}
+
+	return contents
+}
+
+func TestWriter_Rotates(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "logs", "app.log")
+
+	w, err := logfile.New(path, logfile.Options{MaxSize: 10})
+	require.NoError(t, err)
+	defer w.Close()
+
+	// Первые две записи помещаются в 10 байт, третья уже нет - файл ротируется.
+	for _, line := range []string{"aaaa\n", "bbbb\n", "cccc\n"} {
+		_, err := w.Write([]byte(line))
+		require.NoError(t, err)
+	}
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "cccc\n", string(data))
+
+	require.Equal(t, []string{"aaaa\nbbbb\n"}, backupsOf(t, filepath.Dir(path)))
+}
+
+func TestWriter_AppendsToExistingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	require.NoError(t, os.WriteFile(path, []byte("old\n"), 0o644))
+
+	w, err := logfile.New(path, logfile.Options{MaxSize: 8})
+	require.NoError(t, err)
+	defer w.Close()
+
+	// Размер существующего файла учитывается: 4 + 5 байт больше 8.
+	_, err = w.Write([]byte("new1\n"))
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "new1\n", string(data))
+	require.Equal(t, []string{"old\n"}, backupsOf(t, filepath.Dir(path)))
+}
+
+func TestWriter_MaxBackups(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+
+	w, err := logfile.New(path, logfile.Options{MaxSize: 1, MaxBackups: 2})
+	require.NoError(t, err)
+	defer w.Close()
+
+	// Каждая запись после первой ротирует файл; ротации в одну миллисекунду не затирают друг друга.
+	for _, line := range []string{"1", "2", "3", "4", "5"} {
+		_, err := w.Write([]byte(line))
+		require.NoError(t, err)
+	}
+
+	// Остаются две самые новые копии.
+	require.ElementsMatch(t, []string{"3", "4"}, backupsOf(t, dir))
+}
+
+func TestWriter_MaxAge(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+
+	// Копия недельной давности удаляется при ближайшей ротации, копия другого файла не трогается.
+	old := time.Now().Add(-7 * 24 * time.Hour).UTC().Format("2006-01-02T15-04-05.000")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app-"+old+".log"), []byte("old"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "other-"+old+".log"), []byte("other"), 0o644))
+
+	w, err := logfile.New(path, logfile.Options{MaxSize: 1, MaxAge: 24 * time.Hour})
+	require.NoError(t, err)
+	defer w.Close()
+
+	for _, line := range []string{"1", "2"} {
+		_, err := w.Write([]byte(line))
+		require.NoError(t, err)
+	}
+
+	var names []string
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+
+	require.Len(t, names, 3)
+	require.Contains(t, names, "other-"+old+".log")
+	for _, name := range names {
+		require.False(t, strings.HasPrefix(name, "app-"+old))
+	}
+}
+
+func TestWriter_Close(t *testing.T) {
+	w, err := logfile.New(filepath.Join(t.TempDir(), "app.log"), logfile.Options{})
+	require.NoError(t, err)
+
+	require.NoError(t, w.Close())
+
+	_, err = w.Write([]byte("late"))
+	require.ErrorIs(t, err, os.ErrClosed)
+}
@@ -0,0 +1,16 @@
+package random
+
+// DefaultLength is the length of generated aliases when no AliasLength is configured.
+const DefaultLength = 6
+
+// AliasGenerator is an interface for generating aliases of a given length.
+type AliasGenerator interface {
+	Generate(length int) (string, error)
+}
+
+// AliasLength provides the length of generated aliases and learns from collisions.
+// aliaslen.Scaler implements it.
+type AliasLength interface {
+	Length() int
+	Collision(length int)
+}
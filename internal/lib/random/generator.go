@@ -0,0 +1,106 @@
+package random
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"unicode"
+
+	"url-shortener/internal/storage"
+)
+
+// DefaultAlphabet is the base62 character set used for aliases.
+const DefaultAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+// UnambiguousAlphabet is a lowercase set without characters that are easy
+// to confuse when typing a link by hand (0/o, 1/l/i, u/v).
+const UnambiguousAlphabet = "23456789abcdefghjkmnpqrstwxyz"
+
+// WithoutConfusable returns alphabet without the characters of
+// storage.ConfusableChars in either case, so generated aliases never
+// contain characters that look like others (0/o, 1/l/i, rn/m).
+func WithoutConfusable(alphabet string) string {
+	return strings.Map(func(c rune) rune {
+		if strings.ContainsRune(storage.ConfusableChars, unicode.ToLower(c)) {
+			return -1
+		}
+		return c
+	}, alphabet)
+}
+
+// urlSafe lists the characters allowed in an alphabet: the unreserved URL
+// characters, which never need escaping in a path, except ".". The router's
+// middleware.URLFormat cuts everything after the last "." off the path as a
+// format extension, so an alias containing one would never resolve.
+const urlSafe = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_~"
+
+// Generator generates random aliases from an alphabet using crypto/rand.
+// It is safe for concurrent use.
+type Generator struct {
+	alphabet []rune
+}
+
+// NewGenerator creates a Generator over DefaultAlphabet.
+func NewGenerator() *Generator {
+	return &Generator{alphabet: []rune(DefaultAlphabet)}
+}
+
+// NewGeneratorWithAlphabet creates a Generator over alphabet. The alphabet
+// must consist of unique URL-safe characters (letters, digits, "-_~")
+// and have at least two of them.
+func NewGeneratorWithAlphabet(alphabet string) (*Generator, error) {
+	const op = "random.NewGeneratorWithAlphabet"
+
+	chars := []rune(alphabet)
+	if len(chars) < 2 {
+		return nil, fmt.Errorf("%s: %w", op, errors.New("alphabet must have at least 2 characters"))
+	}
+
+	seen := make(map[rune]struct{}, len(chars))
+	for _, c := range chars {
+		if !strings.ContainsRune(urlSafe, c) {
+			return nil, fmt.Errorf("%s: character %q is not URL-safe", op, c)
+		}
+		if _, ok := seen[c]; ok {
+			return nil, fmt.Errorf("%s: character %q is repeated", op, c)
+		}
+		seen[c] = struct{}{}
+	}
+
+	return &Generator{alphabet: chars}, nil
+}
+
+// Symbols returns the number of alphabet characters that tell two aliases
+// apart. Aliases are case-insensitive, so "A" and "a" count once.
+func (g *Generator) Symbols() int {
+	distinct := make(map[string]struct{}, len(g.alphabet))
+	for _, c := range g.alphabet {
+		distinct[storage.NormalizeAlias(string(c))] = struct{}{}
+	}
+
+	return len(distinct)
+}
+
+// Generate returns a random string of the given length.
+func (g *Generator) Generate(length int) (string, error) {
+	const op = "random.Generator.Generate"
+
+	if length <= 0 {
+		return "", nil
+	}
+
+	limit := big.NewInt(int64(len(g.alphabet)))
+
+	b := make([]rune, length)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, limit)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", op, err)
+		}
+		b[i] = g.alphabet[n.Int64()]
+	}
+
+	return string(b), nil
+}
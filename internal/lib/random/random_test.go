@@ -1,9 +1,12 @@
 package random
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"url-shortener/internal/storage"
 )
 
 func TestNewRandomString(t *testing.T) {
@@ -63,4 +66,86 @@ func TestNewRandomString(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestGenerator_Generate(t *testing.T) {
+	g := NewGenerator()
+
+	for _, size := range []int{-1, 0, 1, 6, 30} {
+		str, err := g.Generate(size)
+		assert.NoError(t, err)
+
+		if size <= 0 {
+			assert.Empty(t, str)
+			continue
+		}
+
+		assert.Len(t, str, size)
+		for _, c := range str {
+			assert.Contains(t, DefaultAlphabet, string(c))
+		}
+	}
+}
+
+func TestNewGeneratorWithAlphabet(t *testing.T) {
+	g, err := NewGeneratorWithAlphabet(UnambiguousAlphabet)
+	assert.NoError(t, err)
+	assert.Equal(t, len(UnambiguousAlphabet), g.Symbols())
+
+	str, err := g.Generate(50)
+	assert.NoError(t, err)
+	for _, c := range str {
+		assert.Contains(t, UnambiguousAlphabet, string(c))
+	}
+
+	// Точку middleware.URLFormat отрезает вместе с концом пути, поэтому она недопустима.
+	for _, alphabet := range []string{"", "a", "abca", "ab/c", "abc d", "abc.", "ab.c~"} {
+		_, err := NewGeneratorWithAlphabet(alphabet)
+		assert.Error(t, err, alphabet)
+	}
+}
+
+func TestWithoutConfusable(t *testing.T) {
+	alphabet := WithoutConfusable(DefaultAlphabet)
+	assert.Equal(t, "ABCDEFGHJKMNPQTWXYabcdefghjkmnpqtwxy346789", alphabet)
+
+	// Псевдонимы из оставшихся символов совпадают со своим каноническим видом.
+	g, err := NewGeneratorWithAlphabet(alphabet)
+	assert.NoError(t, err)
+
+	str, err := g.Generate(200)
+	assert.NoError(t, err)
+	assert.Equal(t, storage.NormalizeAlias(str), storage.CanonicalAlias(str))
+}
+
+func TestGenerator_Symbols(t *testing.T) {
+	// Псевдонимы нечувствительны к регистру, поэтому base62 различает только 36 символов.
+	assert.Equal(t, 36, NewGenerator().Symbols())
+}
+
+func TestAffixed(t *testing.T) {
+	g, err := NewAffixed(NewGenerator(), "go-", "_x")
+	assert.NoError(t, err)
+	assert.Equal(t, 5, g.AffixLength())
+
+	str, err := g.Generate(6)
+	assert.NoError(t, err)
+	assert.Len(t, str, 11)
+	assert.True(t, strings.HasPrefix(str, "go-"))
+	assert.True(t, strings.HasSuffix(str, "_x"))
+
+	// Без префикса и суффикса псевдоним не меняется.
+	g, err = NewAffixed(NewGenerator(), "", "")
+	assert.NoError(t, err)
+	str, err = g.Generate(6)
+	assert.NoError(t, err)
+	assert.Len(t, str, 6)
+
+	// "." отрезается middleware.URLFormat вместе с остальной частью пути, и такие ссылки не открывались бы.
+	for _, affix := range []string{"go/", "a b", "?", ".go", "v1."} {
+		_, err := NewAffixed(NewGenerator(), affix, "")
+		assert.Error(t, err, affix)
+		_, err = NewAffixed(NewGenerator(), "", affix)
+		assert.Error(t, err, affix)
+	}
+}
@@ -0,0 +1,67 @@
+package words
+
+import (
+	"crypto/rand"
+	_ "embed"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+//go:embed adjectives.txt
+var adjectivesFile string
+
+//go:embed nouns.txt
+var nounsFile string
+
+// maxNumber bounds the numeric suffix. With the embedded lists this gives
+// len(adjectives) * len(nouns) * maxNumber ≈ 67 million distinct aliases,
+// so a collision on a single insert stays rare even with many stored links.
+const maxNumber = 10000
+
+// Generator builds human-friendly aliases such as "brave-otter-12"
+// from embedded adjective and noun lists. It is safe for concurrent use.
+type Generator struct {
+	adjectives []string
+	nouns      []string
+}
+
+// NewGenerator creates a Generator over the embedded word lists.
+func NewGenerator() *Generator {
+	return &Generator{
+		adjectives: strings.Fields(adjectivesFile),
+		nouns:      strings.Fields(nounsFile),
+	}
+}
+
+// Generate returns an alias of the form adjective-noun-number.
+// The length argument is ignored: word aliases are as long as their words.
+func (g *Generator) Generate(_ int) (string, error) {
+	const op = "random.words.Generator.Generate"
+
+	adjective, err := randomInt(len(g.adjectives))
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	noun, err := randomInt(len(g.nouns))
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	number, err := randomInt(maxNumber)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return fmt.Sprintf("%s-%s-%d", g.adjectives[adjective], g.nouns[noun], number), nil
+}
+
+func randomInt(n int) (int, error) {
+	v, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+
+	return int(v.Int64()), nil
+}
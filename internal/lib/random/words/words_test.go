@@ -0,0 +1,29 @@
+package words
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerator_Generate(t *testing.T) {
+	g := NewGenerator()
+
+	assert.NotEmpty(t, g.adjectives)
+	assert.NotEmpty(t, g.nouns)
+
+	pattern := regexp.MustCompile(`^[a-z]+-[a-z]+-\d{1,4}$`)
+
+	seen := make(map[string]struct{})
+	for i := 0; i < 100; i++ {
+		alias, err := g.Generate(6)
+		assert.NoError(t, err)
+		assert.Regexp(t, pattern, alias)
+
+		seen[alias] = struct{}{}
+	}
+
+	// 100 aliases out of ~67 million combinations should almost never repeat.
+	assert.Greater(t, len(seen), 95)
+}
@@ -0,0 +1,111 @@
+package redirectheaders
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// forbidden are the headers that redirect headers cannot set: the ones that
+// frame the response or the connection, the ones the redirect handler sets
+// itself, and the ones that affect the whole host rather than one link.
+var forbidden = map[string]bool{
+	"Alt-Svc":                   true,
+	"Allow":                     true,
+	"Cache-Control":             true,
+	"Clear-Site-Data":           true,
+	"Connection":                true,
+	"Content-Encoding":          true,
+	"Content-Length":            true,
+	"Content-Type":              true,
+	"Date":                      true,
+	"Keep-Alive":                true,
+	"Last-Modified":             true,
+	"Location":                  true,
+	"Proxy-Connection":          true,
+	"Set-Cookie":                true,
+	"Strict-Transport-Security": true,
+	"Te":                        true,
+	"Trailer":                   true,
+	"Transfer-Encoding":         true,
+	"Upgrade":                   true,
+}
+
+// Validate checks that every name in headers is a valid header name that
+// redirect headers may set, such as Referrer-Policy or X-Robots-Tag, and
+// that no value contains control characters that would let it inject
+// another header. Names are case-insensitive. The error names the first
+// invalid header and can be shown to the client that sent it.
+func Validate(headers map[string]string) error {
+	// Sorted, so that the same headers always report the same error.
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		if !validName(name) {
+			return fmt.Errorf("invalid header name %q", name)
+		}
+		if forbidden[http.CanonicalHeaderKey(name)] {
+			return fmt.Errorf("header %s cannot be set on redirects", http.CanonicalHeaderKey(name))
+		}
+		if !validValue(headers[name]) {
+			return fmt.Errorf("invalid value of header %s", http.CanonicalHeaderKey(name))
+		}
+	}
+
+	return nil
+}
+
+// Apply sets the global headers and then the link's own on h, so that a
+// link overrides a global header of the same name. An empty link value
+// removes the global header for that link. Both maps must have passed Validate.
+func Apply(h http.Header, global, link map[string]string) {
+	for name, value := range global {
+		if value != "" {
+			h.Set(name, value)
+		}
+	}
+
+	for name, value := range link {
+		if value == "" {
+			h.Del(name)
+			continue
+		}
+		h.Set(name, value)
+	}
+}
+
+// validName reports whether name is an RFC 9110 token.
+func validName(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for _, c := range []byte(name) {
+		if !isTokenChar(c) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// validValue reports whether value has no control characters other than tab.
+func validValue(value string) bool {
+	return !strings.ContainsFunc(value, func(r rune) bool {
+		return r != '\t' && (r < ' ' || r == 0x7f)
+	})
+}
+
+func isTokenChar(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	default:
+		return strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0
+	}
+}
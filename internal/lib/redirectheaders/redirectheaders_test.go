@@ -0,0 +1,53 @@
+package redirectheaders
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		headers map[string]string
+		wantErr bool
+	}{
+		{headers: nil},
+		{headers: map[string]string{"Referrer-Policy": "no-referrer", "x-robots-tag": "noindex, nofollow"}},
+		// Пустое значение убирает глобальный заголовок у ссылки.
+		{headers: map[string]string{"Referrer-Policy": ""}},
+		{headers: map[string]string{"X-Partner": "a\tb"}},
+		{headers: map[string]string{"": "x"}, wantErr: true},
+		{headers: map[string]string{"Bad Name": "x"}, wantErr: true},
+		{headers: map[string]string{"X-Partner:": "x"}, wantErr: true},
+		// Перевод строки в значении позволил бы дописать свой заголовок.
+		{headers: map[string]string{"X-Partner": "a\r\nSet-Cookie: session=1"}, wantErr: true},
+		{headers: map[string]string{"X-Partner": "a\x00"}, wantErr: true},
+		{headers: map[string]string{"location": "https://evil.org"}, wantErr: true},
+		{headers: map[string]string{"Set-Cookie": "session=1"}, wantErr: true},
+		{headers: map[string]string{"Cache-Control": "public"}, wantErr: true},
+		{headers: map[string]string{"Strict-Transport-Security": "max-age=1"}, wantErr: true},
+	}
+
+	for _, tc := range cases {
+		err := Validate(tc.headers)
+		if tc.wantErr {
+			assert.Error(t, err, tc.headers)
+		} else {
+			assert.NoError(t, err, tc.headers)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	h := http.Header{}
+	Apply(h,
+		map[string]string{"Referrer-Policy": "no-referrer", "X-Robots-Tag": "noindex"},
+		map[string]string{"referrer-policy": "origin", "X-Robots-Tag": "", "X-Partner": "acme"},
+	)
+
+	// Заголовок ссылки заменяет глобальный, пустое значение его убирает.
+	assert.Equal(t, "origin", h.Get("Referrer-Policy"))
+	assert.Empty(t, h.Values("X-Robots-Tag"))
+	assert.Equal(t, "acme", h.Get("X-Partner"))
+}
@@ -0,0 +1,51 @@
+package reserved
+
+import "url-shortener/internal/storage"
+
+// defaults are path segments taken by the service's own routes.
+// A link with such an alias would be shadowed by the route and never redirect.
+var defaults = []string{
+	"url",
+	"health",
+	"version",
+	"openapi",
+	"docs",
+	"metrics",
+	"stats",
+	"admin",
+	"api",
+
+	// Static routes under /url/ that share the path of /url/{alias}: a link
+	// with such an alias could not be read, deleted or rotated through /url.
+	"batch",
+	"stale",
+	"by-target",
+	"search",
+	"resolve",
+	"reservations",
+	"ns",
+}
+
+// Set is a set of aliases that cannot be used for links.
+// Aliases are compared in their normalized (case-insensitive) form.
+type Set map[string]struct{}
+
+// New creates a Set of the built-in reserved aliases plus extra ones.
+func New(extra []string) Set {
+	s := make(Set, len(defaults)+len(extra))
+
+	for _, alias := range defaults {
+		s[storage.NormalizeAlias(alias)] = struct{}{}
+	}
+	for _, alias := range extra {
+		s[storage.NormalizeAlias(alias)] = struct{}{}
+	}
+
+	return s
+}
+
+// Contains reports whether alias is reserved.
+func (s Set) Contains(alias string) bool {
+	_, ok := s[storage.NormalizeAlias(alias)]
+	return ok
+}
@@ -0,0 +1,106 @@
+// Package safehttp builds HTTP clients for fetching URLs supplied by users,
+// such as link destinations, without letting them reach internal services.
+package safehttp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress is returned when a request would connect to an address
+// that is not public: loopback, private, link-local and the like.
+var ErrForbiddenAddress = errors.New("address is not public")
+
+// MaxRedirects is how many redirects a client follows before giving up.
+const MaxRedirects = 3
+
+const (
+	dialTimeout         = 5 * time.Second
+	tlsHandshakeTimeout = 5 * time.Second
+)
+
+// nonPublic lists ranges that netip does not classify as private or
+// link-local but that must not be reachable from user-supplied URLs either.
+var nonPublic = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),      // "this network"
+	netip.MustParsePrefix("100.64.0.0/10"),  // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),   // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"),  // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),    // reserved
+	netip.MustParsePrefix("64:ff9b::/96"),   // NAT64, may embed a private IPv4
+	netip.MustParsePrefix("64:ff9b:1::/48"), // local-use NAT64
+	netip.MustParsePrefix("2001:db8::/32"),  // documentation
+	netip.MustParsePrefix("2002::/16"),      // 6to4, may embed a private IPv4
+}
+
+// NewClient returns a client that only connects to public unicast addresses.
+// The check runs in the dialer after DNS resolution, so a host name resolving
+// to a private address is refused too, and so is every redirect hop, since
+// each hop dials again. At most MaxRedirects redirects are followed, and only
+// to http and https URLs. Proxies from the environment are not used: the check
+// would then apply to the proxy instead of the destination.
+func NewClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: dialTimeout,
+		Control: control,
+	}
+
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: tlsHandshakeTimeout,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     30 * time.Second,
+		},
+		CheckRedirect: checkRedirect,
+	}
+}
+
+// IsPublic reports whether ip is a public unicast address.
+func IsPublic(ip netip.Addr) bool {
+	ip = ip.Unmap()
+
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return false
+	}
+
+	for _, p := range nonPublic {
+		if p.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// control refuses connections to non-public addresses. address is the
+// resolved "ip:port" the dialer is about to connect to.
+func control(_, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, address)
+	}
+
+	if !IsPublic(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, addrPort.Addr())
+	}
+
+	return nil
+}
+
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= MaxRedirects {
+		return fmt.Errorf("stopped after %d redirects", MaxRedirects)
+	}
+
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return fmt.Errorf("redirect to unsupported scheme %q", req.URL.Scheme)
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package safehttp_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/safehttp"
+)
+
+func TestIsPublic(t *testing.T) {
+	cases := []struct {
+		ip   string
+		want bool
+	}{
+		{ip: "93.184.216.34", want: true},
+		{ip: "2606:2800:220:1:248:1893:25c8:1946", want: true},
+		{ip: "127.0.0.1"},
+		{ip: "::1"},
+		{ip: "10.1.2.3"},
+		{ip: "172.16.0.1"},
+		{ip: "192.168.1.1"},
+		{ip: "169.254.169.254"}, // метаданные облака
+		{ip: "100.64.0.1"},
+		{ip: "0.0.0.0"},
+		{ip: "fd00::1"},
+		{ip: "fe80::1"},
+		{ip: "::ffff:127.0.0.1"}, // IPv4, отображённый в IPv6
+		{ip: "::ffff:169.254.169.254"},
+		{ip: "64:ff9b::a00:1"}, // NAT64 для 10.0.0.1
+		{ip: "224.0.0.1"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.ip, func(t *testing.T) {
+			require.Equal(t, tc.want, safehttp.IsPublic(netip.MustParseAddr(tc.ip)))
+		})
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("internal"))
+	}))
+	defer srv.Close()
+
+	client := safehttp.NewClient()
+
+	// Адрес проверяется после разрешения имени, поэтому имя, указывающее на loopback, тоже отклоняется.
+	for _, target := range []string{srv.URL, strings.Replace(srv.URL, "127.0.0.1", "localhost", 1)} {
+		_, err := client.Get(target)
+		require.ErrorIs(t, err, safehttp.ErrForbiddenAddress, target)
+	}
+}
@@ -0,0 +1,45 @@
+package sequence
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// Encoder turns link IDs into aliases. Distinct IDs always give distinct
+// aliases, so such aliases never collide with each other and are as short
+// as the number of links allows.
+//
+// The trade-off is that aliases are enumerable: anyone who sees a few
+// links can guess the neighbouring ones. The multiplier and offset make
+// the sequence less obvious, but they are obfuscation, not secrecy.
+//
+// Aliases are case-insensitive, so IDs are encoded in base36 (digits and
+// lowercase letters) rather than base62.
+type Encoder struct {
+	offset     int64
+	multiplier int64
+	prefix     string
+	suffix     string
+}
+
+// New creates an Encoder that encodes id*multiplier + offset between the
+// prefix and the suffix, which are added as is. The multiplier must be
+// positive and the offset non-negative.
+func New(offset, multiplier int64, prefix, suffix string) (*Encoder, error) {
+	const op = "sequence.New"
+
+	if multiplier < 1 {
+		return nil, fmt.Errorf("%s: %w", op, errors.New("multiplier must be positive"))
+	}
+	if offset < 0 {
+		return nil, fmt.Errorf("%s: %w", op, errors.New("offset must not be negative"))
+	}
+
+	return &Encoder{offset: offset, multiplier: multiplier, prefix: prefix, suffix: suffix}, nil
+}
+
+// Encode returns the alias for the link with the given ID.
+func (e *Encoder) Encode(id int64) string {
+	return e.prefix + strconv.FormatInt(id*e.multiplier+e.offset, 36) + e.suffix
+}
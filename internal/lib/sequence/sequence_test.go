@@ -0,0 +1,36 @@
+package sequence
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncoder_Encode(t *testing.T) {
+	e, err := New(0, 1, "", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "1", e.Encode(1))
+	assert.Equal(t, "z", e.Encode(35))
+	assert.Equal(t, "10", e.Encode(36))
+
+	e, err = New(0, 1, "go-", "-x")
+	assert.NoError(t, err)
+	assert.Equal(t, "go-10-x", e.Encode(36))
+
+	// Смещение и множитель скрывают порядок, но псевдонимы остаются уникальными.
+	e, err = New(1000, 7919, "", "")
+	assert.NoError(t, err)
+
+	seen := make(map[string]struct{})
+	for id := int64(1); id <= 1000; id++ {
+		alias := e.Encode(id)
+		_, dup := seen[alias]
+		assert.False(t, dup, alias)
+		seen[alias] = struct{}{}
+	}
+
+	for _, tc := range []struct{ offset, multiplier int64 }{{0, 0}, {0, -1}, {-1, 1}} {
+		_, err := New(tc.offset, tc.multiplier, "", "")
+		assert.Error(t, err)
+	}
+}
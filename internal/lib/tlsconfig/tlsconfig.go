@@ -0,0 +1,58 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+)
+
+// versions maps configuration names to TLS protocol versions.
+var versions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// New builds a server TLS configuration with the given minimum protocol
+// version ("1.2", "1.3", ...) and cipher suites named as in crypto/tls
+// (for example TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256). An empty version
+// means TLS 1.2; an empty cipher list keeps Go's secure defaults. Unknown
+// versions, unknown or insecure cipher suites are rejected.
+//
+// Cipher suites only apply to TLS 1.2 and below: TLS 1.3 suites are not
+// configurable in Go.
+func New(minVersion string, cipherSuites []string) (*tls.Config, error) {
+	const op = "tlsconfig.New"
+
+	if minVersion == "" {
+		minVersion = "1.2"
+	}
+
+	version, ok := versions[strings.TrimPrefix(minVersion, "TLS")]
+	if !ok {
+		return nil, fmt.Errorf("%s: unknown tls min_version %q: expected one of 1.0, 1.1, 1.2, 1.3", op, minVersion)
+	}
+
+	cfg := &tls.Config{MinVersion: version}
+
+	if len(cipherSuites) == 0 {
+		return cfg, nil
+	}
+
+	ids := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		ids[suite.Name] = suite.ID
+	}
+
+	for _, name := range cipherSuites {
+		id, ok := ids[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("%s: unknown or insecure cipher suite %q", op, name)
+		}
+
+		cfg.CipherSuites = append(cfg.CipherSuites, id)
+	}
+
+	return cfg, nil
+}
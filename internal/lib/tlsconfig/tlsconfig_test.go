@@ -0,0 +1,70 @@
+package tlsconfig_test
+
+import (
+	"crypto/tls"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/tlsconfig"
+)
+
+func TestNew(t *testing.T) {
+	cases := []struct {
+		name       string
+		minVersion string
+		ciphers    []string
+		wantMin    uint16
+		wantSuites []uint16
+		wantErr    string
+	}{
+		{
+			name:    "Defaults",
+			wantMin: tls.VersionTLS12,
+		},
+		{
+			name:       "TLS 1.3",
+			minVersion: "1.3",
+			wantMin:    tls.VersionTLS13,
+		},
+		{
+			name:       "Cipher suites",
+			minVersion: "1.2",
+			ciphers:    []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", " TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384"},
+			wantMin:    tls.VersionTLS12,
+			wantSuites: []uint16{
+				tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+				tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			},
+		},
+		{
+			name:       "Unknown version",
+			minVersion: "1.4",
+			wantErr:    `unknown tls min_version "1.4"`,
+		},
+		{
+			name:    "Unknown cipher suite",
+			ciphers: []string{"TLS_FANCY_CIPHER"},
+			wantErr: `unknown or insecure cipher suite "TLS_FANCY_CIPHER"`,
+		},
+		{
+			name:    "Insecure cipher suite",
+			ciphers: []string{"TLS_RSA_WITH_RC4_128_SHA"},
+			wantErr: "unknown or insecure cipher suite",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := tlsconfig.New(tc.minVersion, tc.ciphers)
+			if tc.wantErr != "" {
+				require.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.wantMin, cfg.MinVersion)
+			require.Equal(t, tc.wantSuites, cfg.CipherSuites)
+		})
+	}
+}
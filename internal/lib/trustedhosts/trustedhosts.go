@@ -0,0 +1,67 @@
+package trustedhosts
+
+import (
+	"net"
+	"net/url"
+	"strings"
+)
+
+// Set is a set of trusted destination hosts. A host is trusted when it is
+// in the set or is a subdomain of a host in the set: "example.com" trusts
+// "example.com" and "docs.example.com", but not "badexample.com".
+// Hosts are compared case-insensitively and without a port.
+type Set map[string]struct{}
+
+// New creates a Set of hosts. Empty entries are skipped.
+func New(hosts []string) Set {
+	s := make(Set, len(hosts))
+
+	for _, host := range hosts {
+		host = normalize(host)
+		if host != "" {
+			s[host] = struct{}{}
+		}
+	}
+
+	return s
+}
+
+// Trusted reports whether host, or one of its parent domains, is in the set.
+func (s Set) Trusted(host string) bool {
+	host = normalize(host)
+
+	for host != "" {
+		if _, ok := s[host]; ok {
+			return true
+		}
+
+		_, parent, found := strings.Cut(host, ".")
+		if !found {
+			return false
+		}
+		host = parent
+	}
+
+	return false
+}
+
+// TrustedURL reports whether the host of rawURL is trusted.
+// URLs that do not parse are never trusted.
+func (s Set) TrustedURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+
+	return s.Trusted(u.Hostname())
+}
+
+// normalize lowercases host and strips a port and a trailing dot.
+func normalize(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	return strings.TrimSuffix(host, ".")
+}
@@ -0,0 +1,43 @@
+package trustedhosts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSet_Trusted(t *testing.T) {
+	s := New([]string{"Example.com", "go.dev", ""})
+
+	cases := []struct {
+		host string
+		want bool
+	}{
+		{host: "example.com", want: true},
+		{host: "EXAMPLE.COM", want: true},
+		// Поддомены доверенного хоста тоже доверенные.
+		{host: "docs.example.com", want: true},
+		{host: "a.b.example.com", want: true},
+		{host: "example.com.", want: true},
+		{host: "example.com:8080", want: true},
+		// Совпадение только по границе метки домена.
+		{host: "badexample.com", want: false},
+		{host: "example.com.evil.org", want: false},
+		{host: "dev", want: false},
+		{host: "", want: false},
+	}
+
+	for _, tc := range cases {
+		assert.Equal(t, tc.want, s.Trusted(tc.host), tc.host)
+	}
+}
+
+func TestSet_TrustedURL(t *testing.T) {
+	s := New([]string{"example.com"})
+
+	assert.True(t, s.TrustedURL("https://docs.example.com/path?q=1"))
+	assert.False(t, s.TrustedURL("https://example.org/"))
+	// Имя пользователя в URL не делает хост доверенным.
+	assert.False(t, s.TrustedURL("https://example.com@evil.org/"))
+	assert.False(t, s.TrustedURL("://bad"))
+}
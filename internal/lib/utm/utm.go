@@ -0,0 +1,83 @@
+package utm
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Params are the UTM tracking parameters merged into a destination URL.
+// Empty fields are left out.
+type Params struct {
+	Source   string `json:"source,omitempty"`
+	Medium   string `json:"medium,omitempty"`
+	Campaign string `json:"campaign,omitempty"`
+}
+
+// Merge adds p to the query string of rawURL as utm_source, utm_medium
+// and utm_campaign. The rest of the URL, including the order and encoding
+// of the other query parameters and the fragment, is kept as is.
+//
+// A parameter set in p replaces any value the URL already has for it:
+// the request is the more specific intent. Parameters not set in p keep
+// their value from the URL.
+func Merge(rawURL string, p Params) (string, error) {
+	const op = "utm.Merge"
+
+	set := p.values()
+	if len(set) == 0 {
+		return rawURL, nil
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	var query []string
+	for _, part := range strings.Split(u.RawQuery, "&") {
+		if part == "" {
+			continue
+		}
+
+		key, _, _ := strings.Cut(part, "=")
+		if unescaped, err := url.QueryUnescape(key); err == nil {
+			key = unescaped
+		}
+		if _, replaced := set[key]; replaced {
+			continue
+		}
+
+		query = append(query, part)
+	}
+
+	for _, key := range keys {
+		if value, ok := set[key]; ok {
+			query = append(query, key+"="+url.QueryEscape(value))
+		}
+	}
+
+	u.RawQuery = strings.Join(query, "&")
+
+	return u.String(), nil
+}
+
+// keys lists the UTM parameters in the order they are appended.
+var keys = []string{"utm_source", "utm_medium", "utm_campaign"}
+
+// values returns the non-empty parameters keyed by their query name.
+func (p Params) values() map[string]string {
+	values := make(map[string]string, len(keys))
+
+	if p.Source != "" {
+		values["utm_source"] = p.Source
+	}
+	if p.Medium != "" {
+		values["utm_medium"] = p.Medium
+	}
+	if p.Campaign != "" {
+		values["utm_campaign"] = p.Campaign
+	}
+
+	return values
+}
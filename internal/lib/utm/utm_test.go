@@ -0,0 +1,62 @@
+package utm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMerge(t *testing.T) {
+	cases := []struct {
+		name   string
+		url    string
+		params Params
+		want   string
+	}{
+		{
+			name:   "No parameters leaves the URL untouched",
+			url:    "https://example.com/a?b=1",
+			params: Params{},
+			want:   "https://example.com/a?b=1",
+		},
+		{
+			name:   "URL without a query",
+			url:    "https://example.com/a",
+			params: Params{Source: "newsletter", Medium: "email", Campaign: "spring sale"},
+			want:   "https://example.com/a?utm_source=newsletter&utm_medium=email&utm_campaign=spring+sale",
+		},
+		{
+			name:   "Existing parameters keep their order and encoding",
+			url:    "https://example.com/a?z=1&q=a%20b&flag",
+			params: Params{Source: "x"},
+			want:   "https://example.com/a?z=1&q=a%20b&flag&utm_source=x",
+		},
+		{
+			name:   "Conflicting parameter is replaced",
+			url:    "https://example.com/?utm_source=old&id=7&utm_source=older",
+			params: Params{Source: "new"},
+			want:   "https://example.com/?id=7&utm_source=new",
+		},
+		{
+			name:   "Parameters not set in the request are kept",
+			url:    "https://example.com/?utm_medium=cpc",
+			params: Params{Campaign: "launch"},
+			want:   "https://example.com/?utm_medium=cpc&utm_campaign=launch",
+		},
+		{
+			name:   "Fragment stays at the end",
+			url:    "https://example.com/page?a=1#section",
+			params: Params{Medium: "social"},
+			want:   "https://example.com/page?a=1&utm_medium=social#section",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Merge(tc.url, tc.params)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
@@ -0,0 +1,298 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"url-shortener/internal/lib/logger/sl"
+)
+
+// Event types.
+const (
+	EventLinkCreated    = "link.created"
+	EventLinkRedirected = "link.redirected"
+)
+
+// Defaults used when the corresponding Options field is zero.
+const (
+	defaultWorkers   = 2
+	defaultQueueSize = 1024
+	defaultBackoff   = time.Second
+	defaultTimeout   = 5 * time.Second
+)
+
+// Event is the JSON body posted to a webhook endpoint.
+type Event struct {
+	Event     string    `json:"event"`
+	Namespace string    `json:"namespace,omitempty"`
+	Alias     string    `json:"alias"`
+	Target    string    `json:"target"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Options configures a Notifier.
+type Options struct {
+	// LinkCreatedURL receives link.created events. Empty disables them.
+	LinkCreatedURL string
+	// LinkRedirectedURL receives link.redirected events. Empty disables them.
+	LinkRedirectedURL string
+	// RedactTarget reduces the target to scheme and host, since paths and
+	// queries of destinations may carry personal data.
+	RedactTarget bool
+	// Workers is the number of goroutines delivering events.
+	Workers int
+	// QueueSize bounds the number of pending events. When the queue is full
+	// new events are dropped, so a slow endpoint never blocks requests.
+	QueueSize int
+	// MaxRetries is how many times a failed delivery is retried before the
+	// event is dropped. Retries wait Backoff, doubling after each attempt.
+	MaxRetries int
+	Backoff    time.Duration
+	// Timeout limits a single delivery attempt.
+	Timeout time.Duration
+	// Client posts events. Nil means a client with Timeout.
+	Client *http.Client
+}
+
+type delivery struct {
+	endpoint string
+	event    Event
+}
+
+// Notifier posts events to webhook endpoints from background workers.
+// A Notifier without endpoints accepts events and discards them.
+type Notifier struct {
+	log    *slog.Logger
+	opts   Options
+	client *http.Client
+
+	// queue stays open for the Notifier's lifetime, since redirects may
+	// still be queueing events while Close runs; stop ends the workers.
+	queue  chan delivery
+	stop   chan struct{}
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	once   sync.Once
+}
+
+// New starts a Notifier. Workers are started only if an endpoint is configured.
+func New(log *slog.Logger, opts Options) *Notifier {
+	if opts.Workers <= 0 {
+		opts.Workers = defaultWorkers
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = defaultQueueSize
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = defaultBackoff
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultTimeout
+	}
+
+	client := opts.Client
+	if client == nil {
+		client = &http.Client{Timeout: opts.Timeout}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	n := &Notifier{
+		log: log.With(
+			slog.String("component", "webhook/notifier"),
+		),
+		opts:   opts,
+		client: client,
+		queue:  make(chan delivery, opts.QueueSize),
+		stop:   make(chan struct{}),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+
+	if opts.LinkCreatedURL == "" && opts.LinkRedirectedURL == "" {
+		return n
+	}
+
+	n.log.Info("webhooks enabled",
+		slog.Bool("link_created", opts.LinkCreatedURL != ""),
+		slog.Bool("link_redirected", opts.LinkRedirectedURL != ""),
+	)
+
+	for range opts.Workers {
+		n.wg.Add(1)
+		go n.run()
+	}
+
+	return n
+}
+
+// LinkCreated queues a link.created event without blocking.
+func (n *Notifier) LinkCreated(namespace, alias, target string) {
+	n.notify(n.opts.LinkCreatedURL, EventLinkCreated, namespace, alias, target)
+}
+
+// LinkRedirected queues a link.redirected event without blocking.
+func (n *Notifier) LinkRedirected(namespace, alias, target string) {
+	n.notify(n.opts.LinkRedirectedURL, EventLinkRedirected, namespace, alias, target)
+}
+
+// Close stops accepting events and waits for queued ones to be delivered
+// until ctx is done; deliveries still pending then are abandoned.
+// Events queued concurrently with Close are delivered or dropped, never panic.
+func (n *Notifier) Close(ctx context.Context) {
+	n.once.Do(func() {
+		close(n.stop)
+
+		done := make(chan struct{})
+		go func() {
+			n.wg.Wait()
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-ctx.Done():
+			n.log.Warn("webhook queue not drained before shutdown", slog.Int("pending", len(n.queue)))
+			n.cancel()
+			<-done
+		}
+
+		n.cancel()
+	})
+}
+
+func (n *Notifier) notify(endpoint, eventType, namespace, alias, target string) {
+	if endpoint == "" {
+		return
+	}
+
+	select {
+	case <-n.stop:
+		n.log.Debug("notifier is closed, dropping event", slog.String("event", eventType), slog.String("alias", alias))
+		return
+	default:
+	}
+
+	if n.opts.RedactTarget {
+		target = redact(target)
+	}
+
+	e := Event{
+		Event:     eventType,
+		Namespace: namespace,
+		Alias:     alias,
+		Target:    target,
+		Timestamp: time.Now().UTC(),
+	}
+
+	select {
+	case n.queue <- delivery{endpoint: endpoint, event: e}:
+	default:
+		n.log.Warn("webhook queue is full, dropping event", slog.String("event", eventType), slog.String("alias", alias))
+	}
+}
+
+func (n *Notifier) run() {
+	defer n.wg.Done()
+
+	for {
+		select {
+		case d := <-n.queue:
+			n.deliver(d)
+		case <-n.stop:
+			// Deliver the backlog, then exit once the queue is empty.
+			for {
+				select {
+				case d := <-n.queue:
+					n.deliver(d)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// deliver posts the event, retrying with exponential backoff, and drops it
+// with a log entry after MaxRetries failed retries.
+func (n *Notifier) deliver(d delivery) {
+	body, err := json.Marshal(d.event)
+	if err != nil {
+		n.log.Error("failed to encode webhook event", sl.Err(err))
+		return
+	}
+
+	backoff := n.opts.Backoff
+
+	for attempt := 0; ; attempt++ {
+		err = n.post(d.endpoint, body)
+		if err == nil {
+			return
+		}
+
+		if attempt >= n.opts.MaxRetries {
+			break
+		}
+
+		n.log.Debug("webhook delivery failed, retrying",
+			slog.String("event", d.event.Event),
+			slog.Int("attempt", attempt+1),
+			sl.Err(err),
+		)
+
+		select {
+		case <-time.After(backoff):
+		case <-n.ctx.Done():
+			return
+		}
+		backoff *= 2
+	}
+
+	n.log.Error("webhook delivery failed, dropping event",
+		slog.String("event", d.event.Event),
+		slog.String("alias", d.event.Alias),
+		slog.Int("attempts", n.opts.MaxRetries+1),
+		sl.Err(err),
+	)
+}
+
+func (n *Notifier) post(endpoint string, body []byte) error {
+	ctx, cancel := context.WithTimeout(n.ctx, n.opts.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+
+	return nil
+}
+
+// redact keeps only the scheme and host of a URL.
+func redact(target string) string {
+	u, err := url.Parse(target)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+
+	return u.Scheme + "://" + u.Host
+}
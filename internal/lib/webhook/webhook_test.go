@@ -0,0 +1,172 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/lib/webhook"
+)
+
+// endpoint is a test webhook endpoint that fails the first failures requests.
+type endpoint struct {
+	failures int32
+	calls    atomic.Int32
+
+	mu     sync.Mutex
+	events []webhook.Event
+}
+
+func (e *endpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if e.calls.Add(1) <= e.failures {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	var event webhook.Event
+	if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	e.mu.Lock()
+	e.events = append(e.events, event)
+	e.mu.Unlock()
+}
+
+func TestNotifier(t *testing.T) {
+	cases := []struct {
+		name       string
+		failures   int32
+		maxRetries int
+		redact     bool
+		wantCalls  int32
+		wantTarget string
+	}{
+		{
+			name:       "Delivered",
+			maxRetries: 2,
+			wantCalls:  1,
+			wantTarget: "https://example.com/private?token=1",
+		},
+		{
+			name:       "Redacted target",
+			maxRetries: 2,
+			redact:     true,
+			wantCalls:  1,
+			wantTarget: "https://example.com",
+		},
+		{
+			name:       "Retried after failures",
+			failures:   2,
+			maxRetries: 2,
+			wantCalls:  3,
+			wantTarget: "https://example.com/private?token=1",
+		},
+		{
+			name:       "Dropped after repeated failures",
+			failures:   10,
+			maxRetries: 2,
+			wantCalls:  3,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ep := &endpoint{failures: tc.failures}
+			ts := httptest.NewServer(ep)
+			defer ts.Close()
+
+			n := webhook.New(slogdiscard.NewDiscardLogger(), webhook.Options{
+				LinkCreatedURL: ts.URL,
+				RedactTarget:   tc.redact,
+				MaxRetries:     tc.maxRetries,
+				Backoff:        time.Millisecond,
+			})
+
+			n.LinkCreated("team", "promo", "https://example.com/private?token=1")
+			// No endpoint for redirects: the event is discarded.
+			n.LinkRedirected("team", "promo", "https://example.com/private?token=1")
+
+			n.Close(context.Background())
+
+			require.Equal(t, tc.wantCalls, ep.calls.Load())
+
+			if tc.wantTarget == "" {
+				require.Empty(t, ep.events)
+				return
+			}
+
+			require.Len(t, ep.events, 1)
+			require.Equal(t, webhook.EventLinkCreated, ep.events[0].Event)
+			require.Equal(t, "team", ep.events[0].Namespace)
+			require.Equal(t, "promo", ep.events[0].Alias)
+			require.Equal(t, tc.wantTarget, ep.events[0].Target)
+			require.False(t, ep.events[0].Timestamp.IsZero())
+		})
+	}
+}
+
+func TestNotifier_SlowEndpointDoesNotBlock(t *testing.T) {
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		<-release
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	n := webhook.New(slogdiscard.NewDiscardLogger(), webhook.Options{
+		LinkRedirectedURL: ts.URL,
+		Workers:           1,
+		QueueSize:         2,
+	})
+
+	// Events beyond the queue are dropped instead of blocking the caller.
+	start := time.Now()
+	for range 100 {
+		n.LinkRedirected("", "promo", "https://example.com")
+	}
+	require.Less(t, time.Since(start), time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	n.Close(ctx)
+}
+
+func TestNotifier_EventsDuringClose(t *testing.T) {
+	ts := httptest.NewServer(&endpoint{})
+	defer ts.Close()
+
+	n := webhook.New(slogdiscard.NewDiscardLogger(), webhook.Options{
+		LinkRedirectedURL: ts.URL,
+		Workers:           2,
+	})
+
+	// Redirects keep firing events while the server shuts down; Close must not make them panic.
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 200 {
+				n.LinkRedirected("", "promo", "https://example.com")
+			}
+		}()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	n.Close(ctx)
+	wg.Wait()
+
+	// Events after Close are dropped.
+	n.LinkRedirected("", "promo", "https://example.com")
+}
@@ -0,0 +1,59 @@
+package factory
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/sqlite"
+)
+
+// Поддерживаемые значения storage_type.
+const (
+	TypeSQLite = "sqlite"
+)
+
+// Config - параметры создания хранилища.
+type Config struct {
+	// Type - бэкенд хранилища (storage_type). Пустое значение означает sqlite.
+	Type string
+	// Path - путь к файлу базы данных SQLite.
+	Path string
+	// SlowQueryThreshold - порог журнала медленных запросов (slow_query_threshold). 0 - журнал выключен.
+	SlowQueryThreshold time.Duration
+	// SQLiteParams - прагмы SQLite для строки подключения (sqlite_params).
+	SQLiteParams map[string]string
+	// UniqueURL - режим дедупликации (dedupe_urls): уникальный индекс по адресу ссылки.
+	UniqueURL bool
+	// UniqueCanonicalAlias - защита от похожих псевдонимов (reject_confusable_aliases).
+	UniqueCanonicalAlias bool
+	// VerifySchema - проверять при запуске обязательные индексы и создавать недостающие (выключается skip_schema_verify).
+	VerifySchema bool
+	// RecoverCorrupt - заменять повреждённый файл базы пустой базой (recover_corrupt).
+	RecoverCorrupt bool
+}
+
+// New создаёт хранилище бэкенда, выбранного в cfg.Type. Логгер log передаётся бэкенду.
+// Новый бэкенд подключается добавлением ветки в switch.
+func New(log *slog.Logger, cfg Config) (storage.URLStorage, error) {
+	const op = "storage.factory.New"
+
+	switch cfg.Type {
+	case "", TypeSQLite:
+		s, err := sqlite.New(log, cfg.Path, sqlite.Options{
+			SlowQueryThreshold:   cfg.SlowQueryThreshold,
+			Params:               cfg.SQLiteParams,
+			UniqueURL:            cfg.UniqueURL,
+			UniqueCanonicalAlias: cfg.UniqueCanonicalAlias,
+			VerifySchema:         cfg.VerifySchema,
+			RecoverCorrupt:       cfg.RecoverCorrupt,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		return s, nil
+	default:
+		return nil, fmt.Errorf("%s: unknown storage_type %q: supported types are %q", op, cfg.Type, []string{TypeSQLite})
+	}
+}
@@ -0,0 +1,25 @@
+package factory_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage/factory"
+)
+
+func TestNew(t *testing.T) {
+	s, err := factory.New(slogdiscard.NewDiscardLogger(), factory.Config{
+		Type: factory.TypeSQLite,
+		Path: filepath.Join(t.TempDir(), "storage.db"),
+	})
+	require.NoError(t, err)
+	require.NotNil(t, s)
+}
+
+func TestNew_UnknownType(t *testing.T) {
+	_, err := factory.New(slogdiscard.NewDiscardLogger(), factory.Config{Type: "mongo"})
+	require.ErrorContains(t, err, `unknown storage_type "mongo"`)
+}
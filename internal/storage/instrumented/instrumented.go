@@ -0,0 +1,101 @@
+package instrumented
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"url-shortener/internal/storage"
+)
+
+// Исходы операций - значения метки outcome.
+const (
+	OutcomeOK       = "ok"
+	OutcomeNotFound = "not_found"
+	OutcomeConflict = "conflict"
+	OutcomeError    = "error"
+)
+
+// Storage - обёртка над storage.URLStorage, которая измеряет длительность операций хранилища
+// и записывает её в гистограмму с метками operation и outcome.
+// Неизмеряемые методы передаются обёрнутому хранилищу как есть, поэтому код бэкенда не меняется.
+type Storage struct {
+	storage.URLStorage
+
+	duration *prometheus.HistogramVec
+}
+
+// New оборачивает s и регистрирует гистограмму длительности операций в reg.
+func New(s storage.URLStorage, reg prometheus.Registerer) (*Storage, error) {
+	const op = "storage.instrumented.New"
+
+	duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "url_shortener",
+		Subsystem: "storage",
+		Name:      "operation_duration_seconds",
+		Help:      "Duration of storage operations by operation and outcome.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"operation", "outcome"})
+
+	if err := reg.Register(duration); err != nil {
+		return nil, fmt.Errorf("%s: register histogram: %w", op, err)
+	}
+
+	return &Storage{URLStorage: s, duration: duration}, nil
+}
+
+// SaveURL - измеряемая обёртка над storage.URLStorage.SaveURL.
+func (s *Storage) SaveURL(urlToSave, alias string, opts storage.URLOptions) (int64, error) {
+	start := time.Now()
+
+	id, err := s.URLStorage.SaveURL(urlToSave, alias, opts)
+	s.observe("SaveURL", start, outcome(err))
+
+	return id, err
+}
+
+// GetURL - измеряемая обёртка над storage.URLStorage.GetURL.
+func (s *Storage) GetURL(alias string) (string, error) {
+	start := time.Now()
+
+	url, err := s.URLStorage.GetURL(alias)
+	s.observe("GetURL", start, outcome(err))
+
+	return url, err
+}
+
+// DeleteURL - измеряемая обёртка над storage.URLStorage.DeleteURL.
+// Удаление несуществующего псевдонима не является ошибкой и учитывается с исходом not_found.
+func (s *Storage) DeleteURL(alias string) (int64, error) {
+	start := time.Now()
+
+	deleted, err := s.URLStorage.DeleteURL(alias)
+
+	result := outcome(err)
+	if err == nil && deleted == 0 {
+		result = OutcomeNotFound
+	}
+	s.observe("DeleteURL", start, result)
+
+	return deleted, err
+}
+
+func (s *Storage) observe(operation string, start time.Time, outcome string) {
+	s.duration.WithLabelValues(operation, outcome).Observe(time.Since(start).Seconds())
+}
+
+// outcome сопоставляет ошибку операции значению метки outcome.
+func outcome(err error) string {
+	switch {
+	case err == nil:
+		return OutcomeOK
+	case errors.Is(err, storage.ErrURLNotFound):
+		return OutcomeNotFound
+	case errors.Is(err, storage.ErrURLExists), errors.Is(err, storage.ErrURLDuplicate):
+		return OutcomeConflict
+	default:
+		return OutcomeError
+	}
+}
@@ -0,0 +1,100 @@
+package instrumented_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/instrumented"
+	"url-shortener/internal/storage/sqlite"
+)
+
+// sampleCounts возвращает количество наблюдений гистограммы по паре operation/outcome.
+func sampleCounts(t *testing.T, reg *prometheus.Registry) map[[2]string]uint64 {
+	t.Helper()
+
+	families, err := reg.Gather()
+	require.NoError(t, err)
+
+	counts := make(map[[2]string]uint64)
+	for _, family := range families {
+		if family.GetName() != "url_shortener_storage_operation_duration_seconds" {
+			continue
+		}
+
+		for _, m := range family.GetMetric() {
+			var key [2]string
+			for _, label := range m.GetLabel() {
+				switch label.GetName() {
+				case "operation":
+					key[0] = label.GetValue()
+				case "outcome":
+					key[1] = label.GetValue()
+				}
+			}
+			counts[key] = m.GetHistogram().GetSampleCount()
+		}
+	}
+
+	return counts
+}
+
+func TestStorage(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	db.SetMaxOpenConns(1)
+
+	backend, err := sqlite.NewWithDB(slogdiscard.NewDiscardLogger(), db, sqlite.Options{})
+	require.NoError(t, err)
+
+	reg := prometheus.NewRegistry()
+	s, err := instrumented.New(backend, reg)
+	require.NoError(t, err)
+
+	_, err = s.SaveURL("https://google.com", "google", storage.URLOptions{})
+	require.NoError(t, err)
+	_, err = s.SaveURL("https://google.com", "google", storage.URLOptions{})
+	require.ErrorIs(t, err, storage.ErrURLExists)
+
+	_, err = s.GetURL("google")
+	require.NoError(t, err)
+	_, err = s.GetURL("google")
+	require.NoError(t, err)
+	_, err = s.GetURL("missing")
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+
+	_, err = s.DeleteURL("google")
+	require.NoError(t, err)
+	_, err = s.DeleteURL("google")
+	require.NoError(t, err)
+
+	// Неизмеряемые методы работают через обёртку без изменений.
+	count, err := s.CountURLs(context.Background())
+	require.NoError(t, err)
+	require.Zero(t, count)
+
+	require.Equal(t, map[[2]string]uint64{
+		{"SaveURL", "ok"}:          1,
+		{"SaveURL", "conflict"}:    1,
+		{"GetURL", "ok"}:           2,
+		{"GetURL", "not_found"}:    1,
+		{"DeleteURL", "ok"}:        1,
+		{"DeleteURL", "not_found"}: 1,
+	}, sampleCounts(t, reg))
+}
+
+func TestNew_RegistersOnce(t *testing.T) {
+	reg := prometheus.NewRegistry()
+
+	_, err := instrumented.New(nil, reg)
+	require.NoError(t, err)
+
+	_, err = instrumented.New(nil, reg)
+	require.Error(t, err)
+}
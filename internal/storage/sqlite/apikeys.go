@@ -0,0 +1,81 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"url-shortener/internal/storage"
+)
+
+// SaveAPIKey - метод, который сохраняет хэш нового API-ключа с открытым префиксом prefix.
+// Возвращает сохранённую запись с ID и временем создания.
+func (s *Storage) SaveAPIKey(name, prefix string, hash []byte) (storage.APIKey, error) {
+	const op = "storage.sqlite.SaveAPIKey"
+
+	defer s.slow.observe(op, time.Now(), slog.String("prefix", prefix))
+
+	key := storage.APIKey{
+		Name:      name,
+		Prefix:    prefix,
+		Hash:      hash,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	res, err := s.db.Exec("INSERT INTO api_keys(name, prefix, hash, created_at) VALUES(?, ?, ?, ?)", name, prefix, hash, key.CreatedAt)
+	if err != nil {
+		return storage.APIKey{}, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	key.ID, err = res.LastInsertId()
+	if err != nil {
+		return storage.APIKey{}, fmt.Errorf("%s: failed to get last insert id: %w", op, err)
+	}
+
+	return key, nil
+}
+
+// GetAPIKey - метод, который находит API-ключ по открытому префиксу.
+// Если ключа нет (в том числе отозванного), возвращает storage.ErrAPIKeyNotFound.
+func (s *Storage) GetAPIKey(prefix string) (storage.APIKey, error) {
+	const op = "storage.sqlite.GetAPIKey"
+
+	defer s.slow.observe(op, time.Now(), slog.String("prefix", prefix))
+
+	var key storage.APIKey
+	err := s.db.QueryRow("SELECT id, name, prefix, hash, created_at FROM api_keys WHERE prefix = ?", prefix).
+		Scan(&key.ID, &key.Name, &key.Prefix, &key.Hash, &key.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.APIKey{}, storage.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return storage.APIKey{}, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	return key, nil
+}
+
+// DeleteAPIKey - метод, который отзывает API-ключ: запись удаляется, и ключ сразу перестаёт проходить проверку.
+// Если ключа с таким ID нет, возвращает storage.ErrAPIKeyNotFound.
+func (s *Storage) DeleteAPIKey(id int64) error {
+	const op = "storage.sqlite.DeleteAPIKey"
+
+	defer s.slow.observe(op, time.Now(), slog.Int64("id", id))
+
+	res, err := s.db.Exec("DELETE FROM api_keys WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: get rows affected: %w", op, err)
+	}
+	if deleted == 0 {
+		return storage.ErrAPIKeyNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package sqlite
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// isCorrupt сообщает, что err - ошибка SQLite о повреждённом файле ("database disk image is malformed")
+// или о файле, который не является базой SQLite ("file is not a database").
+func isCorrupt(err error) bool {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+
+	return sqliteErr.Code == sqlite3.ErrCorrupt || sqliteErr.Code == sqlite3.ErrNotADB
+}
+
+// handleCorrupt обрабатывает повреждённый файл базы storagePath, который не удалось открыть с ошибкой cause.
+// Без recover возвращает ошибку с путём к файлу и подсказкой, что делать. С recover переносит файл и его
+// журналы -wal и -shm в сторону (журнал старой базы нельзя применять к новой) и возвращает nil:
+// следующее открытие создаст пустую базу. Базы в памяти и пути в виде URI не переносятся.
+func handleCorrupt(log *slog.Logger, storagePath string, recover bool, cause error) error {
+	log.Error("database file is corrupted or is not a sqlite database",
+		slog.String("path", storagePath),
+		slog.String("error", cause.Error()),
+		slog.Bool("recover_corrupt", recover),
+	)
+
+	if !recover || storagePath == ":memory:" || strings.HasPrefix(storagePath, "file:") {
+		return fmt.Errorf(
+			"database file %s is corrupted or is not a sqlite database: restore it from a backup, "+
+				"move it aside, or enable recover_corrupt to start with an empty database: %w",
+			storagePath, cause,
+		)
+	}
+
+	backup := fmt.Sprintf("%s.corrupt-%s", storagePath, time.Now().UTC().Format("20060102T150405Z"))
+
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		err := os.Rename(storagePath+suffix, backup+suffix)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("move corrupted database file %s aside: %w", storagePath+suffix, err)
+		}
+	}
+
+	log.Warn("corrupted database file moved aside, starting with an empty database",
+		slog.String("path", storagePath),
+		slog.String("moved_to", backup),
+	)
+
+	return nil
+}
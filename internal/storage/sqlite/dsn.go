@@ -0,0 +1,72 @@
+package sqlite
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// defaultParams - параметры соединения, которые применяются, если их не переопределили в Options.Params.
+// WAL позволяет читать базу во время записи, а busy_timeout заставляет SQLite подождать освобождения
+// блокировки вместо немедленной ошибки "database is locked" при одновременной записи.
+var defaultParams = map[string]string{
+	"journal_mode": "WAL",
+	"busy_timeout": "5000",
+}
+
+// knownParams - прагмы, которые драйвер go-sqlite3 принимает в строке подключения (с префиксом "_").
+// Список нужен, чтобы опечатка в конфигурации приводила к ошибке при старте, а не молча игнорировалась драйвером.
+var knownParams = []string{
+	"auto_vacuum",
+	"busy_timeout",
+	"cache_size",
+	"case_sensitive_like",
+	"defer_foreign_keys",
+	"foreign_keys",
+	"ignore_check_constraints",
+	"journal_mode",
+	"locking_mode",
+	"query_only",
+	"recursive_triggers",
+	"secure_delete",
+	"synchronous",
+	"txlock",
+	"writable_schema",
+}
+
+// dsn строит строку подключения драйвера go-sqlite3: к пути storagePath добавляются параметры по умолчанию
+// и params, которые их переопределяют. Ключи params - имена прагм без префикса, например "foreign_keys";
+// неизвестный ключ - ошибка.
+func dsn(storagePath string, params map[string]string) (string, error) {
+	merged := make(map[string]string, len(defaultParams)+len(params))
+	for key, value := range defaultParams {
+		merged[key] = value
+	}
+	for key, value := range params {
+		key = strings.ToLower(strings.TrimPrefix(key, "_"))
+		if !slices.Contains(knownParams, key) {
+			return "", fmt.Errorf("unknown sqlite parameter %q: supported parameters are %q", key, knownParams)
+		}
+		merged[key] = value
+	}
+
+	keys := make([]string, 0, len(merged))
+	for key := range merged {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	query := make([]string, 0, len(keys))
+	for _, key := range keys {
+		query = append(query, "_"+key+"="+url.QueryEscape(merged[key]))
+	}
+
+	// Путь вида "file:...?mode=ro" уже может содержать параметры.
+	sep := "?"
+	if strings.Contains(storagePath, "?") {
+		sep = "&"
+	}
+
+	return storagePath + sep + strings.Join(query, "&"), nil
+}
@@ -0,0 +1,35 @@
+package sqlite
+
+import (
+	"encoding/json"
+)
+
+// Собственные заголовки редиректа ссылки хранятся в колонке headers JSON-объектом {"Referrer-Policy": "no-referrer"}.
+// Пустая строка - у ссылки нет собственных заголовков.
+
+// encodeHeaders кодирует заголовки ссылки для колонки headers.
+func encodeHeaders(headers map[string]string) string {
+	if len(headers) == 0 {
+		return ""
+	}
+
+	// map[string]string всегда кодируется без ошибок.
+	b, _ := json.Marshal(headers)
+
+	return string(b)
+}
+
+// decodeHeaders возвращает заголовки из значения колонки headers. Для ссылки без заголовков - nil.
+// Значение пишет только encodeHeaders, поэтому испорченное значение считается отсутствием заголовков.
+func decodeHeaders(encoded string) map[string]string {
+	if encoded == "" {
+		return nil
+	}
+
+	var headers map[string]string
+	if err := json.Unmarshal([]byte(encoded), &headers); err != nil {
+		return nil
+	}
+
+	return headers
+}
@@ -0,0 +1,126 @@
+package sqlite
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"url-shortener/internal/storage"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// nowMillis - текущее Unix-время SQLite в миллисекундах, в тех же единицах, что alias_holds.reserved_until.
+const nowMillis = "CAST((julianday('now') - 2440587.5) * 86400000 AS INTEGER)"
+
+// aliasHeldMessage - текст ошибки, с которой триггеры url_alias_held_* отклоняют запись удерживаемого псевдонима.
+const aliasHeldMessage = "alias is held by a reservation"
+
+// holdTokenBytes - число случайных байт в токене резервации.
+const holdTokenBytes = 24
+
+// ReserveAlias - метод, который удерживает псевдоним в пространстве имён по умолчанию на время ttl.
+// Возвращает токен для ConfirmReservation; в базе хранится только его SHA-256. Истёкшие резервации удаляются
+// здесь же, поэтому псевдоним освобождается сам. Возвращает storage.ErrURLExists, если ссылка с псевдонимом
+// уже есть, и storage.ErrURLExists вместе с storage.ErrAliasHeld, если он уже зарезервирован.
+func (s *Storage) ReserveAlias(alias string, ttl time.Duration) (string, error) {
+	const op = "storage.sqlite.ReserveAlias"
+
+	defer s.slow.observe(op, time.Now(), slog.String("alias", alias))
+
+	buf := make([]byte, holdTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("%s: generate token: %w", op, err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+
+	alias = storage.NormalizeAlias(alias)
+	now := time.Now()
+
+	// Удаление и вставка - в одной транзакции: запись блокирует базу с первого запроса,
+	// поэтому между проверкой и вставкой никто не займёт псевдоним.
+	tx, err := s.db.Begin()
+	if err != nil {
+		return "", fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec("DELETE FROM alias_holds WHERE reserved_until <= ?", now.UnixMilli()); err != nil {
+		return "", fmt.Errorf("%s: delete expired holds: %w", op, err)
+	}
+
+	res, err := tx.Exec(
+		"INSERT INTO alias_holds(alias, token_hash, reserved_until) SELECT ?, ?, ? WHERE NOT EXISTS (SELECT 1 FROM url WHERE namespace = '' AND alias = ?)",
+		alias, hashToken(token), now.Add(ttl).UnixMilli(), alias,
+	)
+	if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey {
+		return "", fmt.Errorf("%s: %w", op, errors.Join(storage.ErrURLExists, storage.ErrAliasHeld))
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s: insert hold: %w", op, err)
+	}
+
+	inserted, err := res.RowsAffected()
+	if err != nil {
+		return "", fmt.Errorf("%s: get rows affected: %w", op, err)
+	}
+	if inserted == 0 {
+		return "", fmt.Errorf("%s: %w", op, storage.ErrURLExists)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("%s: commit: %w", op, err)
+	}
+
+	return token, nil
+}
+
+// ConfirmReservation - метод, который превращает действующую резервацию в ссылку на urlToSave.
+// Резервация снимается и ссылка сохраняется в одной транзакции: если сохранить не удалось,
+// резервация остаётся. opts.Namespace не учитывается - резервации есть только в пространстве имён по умолчанию.
+// Возвращает псевдоним ссылки или storage.ErrReservationNotFound, если токен неизвестен или резервация истекла.
+func (s *Storage) ConfirmReservation(token, urlToSave string, opts storage.URLOptions) (string, error) {
+	const op = "storage.sqlite.ConfirmReservation"
+
+	defer s.slow.observe(op, time.Now(), slog.String("host", urlHost(urlToSave)))
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return "", fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var alias string
+	err = tx.QueryRow("DELETE FROM alias_holds WHERE token_hash = ? AND reserved_until > ? RETURNING alias",
+		hashToken(token), time.Now().UnixMilli()).Scan(&alias)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", storage.ErrReservationNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s: delete hold: %w", op, err)
+	}
+
+	opts.Namespace = ""
+	if _, err := s.txQueries(tx).SaveURL(urlToSave, alias, opts); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("%s: commit: %w", op, err)
+	}
+
+	return alias, nil
+}
+
+// hashToken возвращает SHA-256 токена резервации в hex: по нему резервация находится в alias_holds.
+// Токен живёт минуты и случаен, поэтому медленный хэш, как у API-ключей, не нужен.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,106 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"url-shortener/internal/storage"
+)
+
+// ClaimIdempotencyKey - метод, который занимает ключ идемпотентности для запроса с отпечатком fingerprint
+// на время ttl. Истёкшие ключи удаляются здесь же. Если ключ уже занят, возвращает его запись вместе
+// с storage.ErrIdempotencyKeyExists: пустой Alias в ней означает, что первый запрос ещё выполняется.
+func (s *Storage) ClaimIdempotencyKey(key, fingerprint string, ttl time.Duration) (storage.IdempotencyRecord, error) {
+	const op = "storage.sqlite.ClaimIdempotencyKey"
+
+	defer s.slow.observe(op, time.Now())
+
+	now := time.Now()
+
+	// Удаление, вставка и чтение - в одной транзакции: запись блокирует базу с первого запроса,
+	// поэтому из одновременных запросов с одним ключом вставка удаётся только одному.
+	tx, err := s.db.Begin()
+	if err != nil {
+		return storage.IdempotencyRecord{}, fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec("DELETE FROM idempotency_keys WHERE expires_at <= ?", now.UnixMilli()); err != nil {
+		return storage.IdempotencyRecord{}, fmt.Errorf("%s: delete expired keys: %w", op, err)
+	}
+
+	res, err := tx.Exec(
+		"INSERT INTO idempotency_keys(key, fingerprint, expires_at) VALUES(?, ?, ?) ON CONFLICT(key) DO NOTHING",
+		key, fingerprint, now.Add(ttl).UnixMilli(),
+	)
+	if err != nil {
+		return storage.IdempotencyRecord{}, fmt.Errorf("%s: insert key: %w", op, err)
+	}
+
+	inserted, err := res.RowsAffected()
+	if err != nil {
+		return storage.IdempotencyRecord{}, fmt.Errorf("%s: get rows affected: %w", op, err)
+	}
+
+	rec := storage.IdempotencyRecord{Fingerprint: fingerprint}
+	if inserted == 0 {
+		var alias sql.NullString
+		err := tx.QueryRow("SELECT fingerprint, namespace, alias FROM idempotency_keys WHERE key = ?", key).
+			Scan(&rec.Fingerprint, &rec.Namespace, &alias)
+		if err != nil {
+			return storage.IdempotencyRecord{}, fmt.Errorf("%s: get key: %w", op, err)
+		}
+		rec.Alias = alias.String
+	}
+
+	if err := tx.Commit(); err != nil {
+		return storage.IdempotencyRecord{}, fmt.Errorf("%s: commit: %w", op, err)
+	}
+
+	if inserted == 0 {
+		return rec, storage.ErrIdempotencyKeyExists
+	}
+
+	return rec, nil
+}
+
+// CompleteIdempotencyKey - метод, который записывает в занятый ключ идемпотентности созданную ссылку.
+// Повтор запроса с этим ключом получит её вместо новой ссылки. Псевдоним хранится в том виде,
+// в каком его вернул первый ответ.
+func (s *Storage) CompleteIdempotencyKey(key, namespace, alias string) error {
+	const op = "storage.sqlite.CompleteIdempotencyKey"
+
+	defer s.slow.observe(op, time.Now(), slog.String("alias", alias))
+
+	res, err := s.db.Exec("UPDATE idempotency_keys SET namespace = ?, alias = ? WHERE key = ?",
+		namespace, alias, key)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: get rows affected: %w", op, err)
+	}
+	if updated == 0 {
+		return fmt.Errorf("%s: key not found", op)
+	}
+
+	return nil
+}
+
+// ReleaseIdempotencyKey - метод, который удаляет занятый ключ идемпотентности, пока в нём нет ссылки,
+// чтобы повтор неудавшегося запроса выполнялся заново. Завершённый ключ не удаляется.
+func (s *Storage) ReleaseIdempotencyKey(key string) error {
+	const op = "storage.sqlite.ReleaseIdempotencyKey"
+
+	defer s.slow.observe(op, time.Now())
+
+	if _, err := s.db.Exec("DELETE FROM idempotency_keys WHERE key = ? AND alias IS NULL", key); err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	return nil
+}
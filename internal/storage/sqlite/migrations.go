@@ -0,0 +1,480 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+	"url-shortener/internal/storage"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// migration - одна версия схемы базы данных.
+// Миграции применяются строго по возрастанию версии, каждая в своей транзакции.
+type migration struct {
+	version int
+	name    string
+	up      func(tx *sql.Tx) error
+}
+
+// migrations - список всех миграций схемы. Новые миграции добавляются только в конец
+// со следующим номером версии; уже выпущенные миграции менять нельзя.
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "create url table",
+		up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				CREATE TABLE IF NOT EXISTS url(
+					id INTEGER PRIMARY KEY,
+					alias TEXT NOT NULL UNIQUE,
+					url TEXT NOT NULL);
+				CREATE INDEX IF NOT EXISTS idx_alias ON url(alias);
+			`)
+			return err
+		},
+	},
+	{
+		version: 2,
+		name:    "add permanent column",
+		up: func(tx *sql.Tx) error {
+			// Колонка могла быть добавлена до появления миграций.
+			return addColumnIfMissing(tx, "url", "permanent", "BOOLEAN")
+		},
+	},
+	{
+		version: 3,
+		name:    "lowercase aliases",
+		up: func(tx *sql.Tx) error {
+			// Псевдонимы, различающиеся только регистром, после приведения совпали бы. Пропустить такую ссылку
+			// нельзя: в исходном регистре она больше не открылась бы. Поэтому миграция останавливается
+			// со списком конфликтов, и их нужно переименовать или удалить вручную до перезапуска.
+			conflicts, err := aliasCaseConflicts(tx)
+			if err != nil {
+				return err
+			}
+			if len(conflicts) > 0 {
+				return fmt.Errorf("aliases differ only in case, rename or delete all but one in each group and restart: %s",
+					strings.Join(conflicts, "; "))
+			}
+
+			_, err = tx.Exec("UPDATE url SET alias = lower(alias) WHERE alias <> lower(alias)")
+			return err
+		},
+	},
+	{
+		version: 4,
+		name:    "add expires_at column",
+		up: func(tx *sql.Tx) error {
+			return addColumnIfMissing(tx, "url", "expires_at", "TIMESTAMP")
+		},
+	},
+	{
+		version: 5,
+		name:    "add created_at column",
+		up: func(tx *sql.Tx) error {
+			// SQLite не разрешает добавлять колонку с DEFAULT CURRENT_TIMESTAMP,
+			// поэтому время создания проставляется при вставке, а у старых строк остаётся NULL.
+			return addColumnIfMissing(tx, "url", "created_at", "TIMESTAMP")
+		},
+	},
+	{
+		version: 6,
+		name:    "add last_accessed_at column",
+		up: func(tx *sql.Tx) error {
+			return addColumnIfMissing(tx, "url", "last_accessed_at", "TIMESTAMP")
+		},
+	},
+	{
+		version: 7,
+		name:    "add creator_ip column",
+		up: func(tx *sql.Tx) error {
+			if err := addColumnIfMissing(tx, "url", "creator_ip", "TEXT"); err != nil {
+				return err
+			}
+			_, err := tx.Exec(`CREATE INDEX IF NOT EXISTS idx_creator_ip ON url(creator_ip)`)
+			return err
+		},
+	},
+	{
+		version: 8,
+		name:    "add namespace column",
+		up: func(tx *sql.Tx) error {
+			// Уникальность псевдонима задана ограничением UNIQUE в определении колонки, а SQLite
+			// не умеет удалять ограничения, поэтому таблица пересоздаётся с уникальностью по (namespace, alias).
+			// Существующие ссылки попадают в пространство имён по умолчанию ('').
+			_, err := tx.Exec(`
+				CREATE TABLE url_new(
+					id INTEGER PRIMARY KEY,
+					namespace TEXT NOT NULL DEFAULT '',
+					alias TEXT NOT NULL,
+					url TEXT NOT NULL,
+					permanent BOOLEAN,
+					expires_at TIMESTAMP,
+					created_at TIMESTAMP,
+					last_accessed_at TIMESTAMP,
+					creator_ip TEXT);
+				INSERT INTO url_new(id, alias, url, permanent, expires_at, created_at, last_accessed_at, creator_ip)
+					SELECT id, alias, url, permanent, expires_at, created_at, last_accessed_at, creator_ip FROM url;
+				DROP TABLE url;
+				ALTER TABLE url_new RENAME TO url;
+				CREATE UNIQUE INDEX idx_namespace_alias ON url(namespace, alias);
+				CREATE INDEX idx_creator_ip ON url(creator_ip);
+			`)
+			return err
+		},
+	},
+	{
+		version: 9,
+		name:    "add clicks and max_clicks columns",
+		up: func(tx *sql.Tx) error {
+			// max_clicks = 0 - ограничения нет, поэтому существующие ссылки остаются бессрочными.
+			if err := addColumnIfMissing(tx, "url", "max_clicks", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+				return err
+			}
+			return addColumnIfMissing(tx, "url", "clicks", "INTEGER NOT NULL DEFAULT 0")
+		},
+	},
+	{
+		version: 10,
+		name:    "add created_at index",
+		up: func(tx *sql.Tx) error {
+			// Индекс нужен для выборки ссылок за период (ListByDateRange).
+			_, err := tx.Exec(`CREATE INDEX IF NOT EXISTS idx_created_at ON url(created_at)`)
+			return err
+		},
+	},
+	{
+		version: 11,
+		name:    "create api_keys table",
+		up: func(tx *sql.Tx) error {
+			// Сами ключи не хранятся: только bcrypt-хэш и открытый префикс, по которому хэш находится.
+			_, err := tx.Exec(`
+				CREATE TABLE IF NOT EXISTS api_keys(
+					id INTEGER PRIMARY KEY,
+					name TEXT NOT NULL DEFAULT '',
+					prefix TEXT NOT NULL UNIQUE,
+					hash BLOB NOT NULL,
+					created_at TIMESTAMP NOT NULL)
+			`)
+			return err
+		},
+	},
+	{
+		version: 12,
+		name:    "add url index",
+		up: func(tx *sql.Tx) error {
+			// Индекс нужен для поиска ссылок по адресу назначения (ListAliasesByURL).
+			_, err := tx.Exec(`CREATE INDEX IF NOT EXISTS idx_url ON url(url)`)
+			return err
+		},
+	},
+	{
+		version: 13,
+		name:    "add enabled column",
+		up: func(tx *sql.Tx) error {
+			// Существующие ссылки остаются включёнными.
+			return addColumnIfMissing(tx, "url", "enabled", "BOOLEAN NOT NULL DEFAULT 1")
+		},
+	},
+	{
+		version: 14,
+		name:    "add tags column",
+		up: func(tx *sql.Tx) error {
+			// Формат значения описан в tags.go. Существующие ссылки остаются без меток.
+			return addColumnIfMissing(tx, "url", "tags", "TEXT NOT NULL DEFAULT ''")
+		},
+	},
+	{
+		version: 15,
+		name:    "add canonical_alias column",
+		up: func(tx *sql.Tx) error {
+			// Канонический вид вычисляется в Go (storage.CanonicalAlias), поэтому у существующих ссылок
+			// колонка остаётся NULL до applyCanonicalAliasIndex, который заполняет её при включении режима.
+			return addColumnIfMissing(tx, "url", "canonical_alias", "TEXT")
+		},
+	},
+	{
+		version: 16,
+		name:    "create alias_holds table",
+		up: func(tx *sql.Tx) error {
+			// Резервации псевдонимов (см. holds.go). reserved_until - Unix-время в миллисекундах, чтобы триггеры
+			// сравнивали его с текущим временем SQLite числом. Триггеры не дают занять удерживаемый псевдоним
+			// ни вставкой, ни переименованием, через какой бы метод хранилища ни шла запись.
+			_, err := tx.Exec(`
+				CREATE TABLE IF NOT EXISTS alias_holds(
+					alias TEXT PRIMARY KEY,
+					token_hash TEXT NOT NULL UNIQUE,
+					reserved_until INTEGER NOT NULL);
+				CREATE TRIGGER IF NOT EXISTS url_alias_held_insert BEFORE INSERT ON url
+				WHEN NEW.namespace = '' AND EXISTS (
+					SELECT 1 FROM alias_holds WHERE alias = NEW.alias AND reserved_until > ` + nowMillis + `)
+				BEGIN
+					SELECT RAISE(ABORT, '` + aliasHeldMessage + `');
+				END;
+				CREATE TRIGGER IF NOT EXISTS url_alias_held_update BEFORE UPDATE OF alias, namespace ON url
+				WHEN NEW.namespace = '' AND EXISTS (
+					SELECT 1 FROM alias_holds WHERE alias = NEW.alias AND reserved_until > ` + nowMillis + `)
+				BEGIN
+					SELECT RAISE(ABORT, '` + aliasHeldMessage + `');
+				END;
+			`)
+			return err
+		},
+	},
+	{
+		version: 17,
+		name:    "add preserve_method column",
+		up: func(tx *sql.Tx) error {
+			// NULL - как у permanent: действует значение по умолчанию из конфигурации.
+			return addColumnIfMissing(tx, "url", "preserve_method", "BOOLEAN")
+		},
+	},
+	{
+		version: 18,
+		name:    "create idempotency_keys table",
+		up: func(tx *sql.Tx) error {
+			// Ключи идемпотентности POST /url (см. idempotency.go). alias остаётся NULL, пока запрос,
+			// занявший ключ, выполняется. expires_at - Unix-время в миллисекундах, как в alias_holds.
+			_, err := tx.Exec(`
+				CREATE TABLE IF NOT EXISTS idempotency_keys(
+					key TEXT PRIMARY KEY,
+					fingerprint TEXT NOT NULL,
+					namespace TEXT NOT NULL DEFAULT '',
+					alias TEXT,
+					expires_at INTEGER NOT NULL);
+			`)
+			return err
+		},
+	},
+	{
+		version: 19,
+		name:    "add headers column",
+		up: func(tx *sql.Tx) error {
+			// Формат значения описан в headers.go. Существующие ссылки остаются без собственных заголовков.
+			return addColumnIfMissing(tx, "url", "headers", "TEXT NOT NULL DEFAULT ''")
+		},
+	},
+	{
+		version: 20,
+		name:    "add forward_path column",
+		up: func(tx *sql.Tx) error {
+			// Существующие ссылки остаются обычными: путь после псевдонима не переносится.
+			return addColumnIfMissing(tx, "url", "forward_path", "BOOLEAN NOT NULL DEFAULT 0")
+		},
+	},
+}
+
+// migrate создаёт таблицу schema_migrations и применяет все миграции,
+// версии которых ещё не записаны в неё. Применённые миграции пишутся в log.
+func migrate(log *slog.Logger, db *sql.DB) error {
+	const op = "storage.sqlite.migrate"
+
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations(
+			version INTEGER PRIMARY KEY,
+			applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP)
+	`)
+	if err != nil {
+		return fmt.Errorf("%s: create schema_migrations: %w", op, err)
+	}
+
+	applied := make(map[int]bool)
+
+	rows, err := db.Query("SELECT version FROM schema_migrations")
+	if err != nil {
+		return fmt.Errorf("%s: read applied versions: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return fmt.Errorf("%s: scan version: %w", op, err)
+		}
+		applied[version] = true
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("%s: read applied versions: %w", op, err)
+	}
+
+	for _, m := range migrations {
+		if applied[m.version] {
+			continue
+		}
+
+		if err := applyMigration(db, m); err != nil {
+			return fmt.Errorf("%s: migration %d (%s): %w", op, m.version, m.name, err)
+		}
+
+		log.Info("applied migration",
+			slog.String("op", op),
+			slog.Int("version", m.version),
+			slog.String("name", m.name),
+		)
+	}
+
+	return nil
+}
+
+// uniqueURLIndex - индекс, который в режиме дедупликации запрещает сохранять один адрес дважды.
+// В отличие от миграций из списка migrations он необязательный: создаётся или удаляется при каждом
+// запуске в зависимости от Options.UniqueURL, поэтому версия в schema_migrations ему не нужна.
+const uniqueURLIndex = "idx_url_unique"
+
+// applyUniqueURLIndex создаёт уникальный индекс по url, если enabled, и удаляет его, если нет.
+// Создание не удастся, если в базе уже есть одинаковые адреса: их нужно удалить вручную.
+func applyUniqueURLIndex(db *sql.DB, enabled bool) error {
+	const op = "storage.sqlite.applyUniqueURLIndex"
+
+	if !enabled {
+		if _, err := db.Exec("DROP INDEX IF EXISTS " + uniqueURLIndex); err != nil {
+			return fmt.Errorf("%s: drop index: %w", op, err)
+		}
+		return nil
+	}
+
+	_, err := db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS " + uniqueURLIndex + " ON url(url)")
+	if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+		return fmt.Errorf("%s: the database already has duplicate urls, remove them or disable dedupe: %w", op, err)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: create index: %w", op, err)
+	}
+
+	return nil
+}
+
+// canonicalAliasIndex - индекс, который в режиме защиты от похожих псевдонимов запрещает псевдонимы,
+// совпадающие по каноническому виду (storage.CanonicalAlias) в одном пространстве имён.
+// Как и uniqueURLIndex, создаётся или удаляется при каждом запуске в зависимости от Options.UniqueCanonicalAlias.
+const canonicalAliasIndex = "idx_url_canonical_alias"
+
+// applyCanonicalAliasIndex заполняет canonical_alias у ссылок, сохранённых до миграции 15, и создаёт
+// уникальный индекс по (namespace, canonical_alias), если enabled, и удаляет его, если нет.
+// Создание не удастся, если в базе уже есть похожие псевдонимы: их нужно переименовать вручную.
+func applyCanonicalAliasIndex(db *sql.DB, enabled bool) error {
+	const op = "storage.sqlite.applyCanonicalAliasIndex"
+
+	if !enabled {
+		if _, err := db.Exec("DROP INDEX IF EXISTS " + canonicalAliasIndex); err != nil {
+			return fmt.Errorf("%s: drop index: %w", op, err)
+		}
+		return nil
+	}
+
+	if err := backfillCanonicalAliases(db); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err := db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS " + canonicalAliasIndex + " ON url(namespace, canonical_alias)")
+	if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+		return fmt.Errorf("%s: the database already has aliases that look alike, rename them or disable confusable alias protection: %w", op, err)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: create index: %w", op, err)
+	}
+
+	return nil
+}
+
+// backfillCanonicalAliases вычисляет canonical_alias для строк, где он ещё NULL, в одной транзакции.
+func backfillCanonicalAliases(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	rows, err := tx.Query("SELECT id, alias FROM url WHERE canonical_alias IS NULL")
+	if err != nil {
+		return fmt.Errorf("select aliases: %w", err)
+	}
+
+	canonical := make(map[int64]string)
+	for rows.Next() {
+		var (
+			id    int64
+			alias string
+		)
+		if err := rows.Scan(&id, &alias); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("scan alias: %w", err)
+		}
+		canonical[id] = storage.CanonicalAlias(alias)
+	}
+	if err := rows.Close(); err != nil {
+		return fmt.Errorf("iterate aliases: %w", err)
+	}
+
+	for id, alias := range canonical {
+		if _, err := tx.Exec("UPDATE url SET canonical_alias = ? WHERE id = ?", alias, id); err != nil {
+			return fmt.Errorf("update canonical alias: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// aliasCaseConflicts возвращает группы псевдонимов, совпадающих без учёта регистра, например "Docs, docs".
+func aliasCaseConflicts(tx *sql.Tx) ([]string, error) {
+	rows, err := tx.Query(`
+		SELECT group_concat(alias, ', ') FROM (SELECT alias FROM url ORDER BY alias)
+		GROUP BY lower(alias) HAVING count(*) > 1
+		ORDER BY lower(alias)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("find case conflicts: %w", err)
+	}
+	defer rows.Close()
+
+	var conflicts []string
+	for rows.Next() {
+		var group string
+		if err := rows.Scan(&group); err != nil {
+			return nil, fmt.Errorf("scan case conflict: %w", err)
+		}
+		conflicts = append(conflicts, group)
+	}
+
+	return conflicts, rows.Err()
+}
+
+// applyMigration выполняет миграцию и записывает её версию в одной транзакции.
+func applyMigration(db *sql.DB, m migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := m.up(tx); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("INSERT INTO schema_migrations(version) VALUES(?)", m.version); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// addColumnIfMissing добавляет колонку в таблицу, если её ещё нет.
+// SQLite не поддерживает "ADD COLUMN IF NOT EXISTS", поэтому наличие колонки проверяется через pragma_table_info.
+func addColumnIfMissing(tx *sql.Tx, table, column, definition string) error {
+	var count int
+	err := tx.QueryRow("SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", table, column).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("check column %s.%s: %w", table, column, err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		return fmt.Errorf("add column %s.%s: %w", table, column, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// schemaIndex - индекс, который должен быть в базе после всех миграций.
+type schemaIndex struct {
+	name  string
+	table string
+	ddl   string
+}
+
+// expectedIndexes - обязательные индексы схемы. Миграции создают их один раз, и если индекс пропал
+// (база создана версией с ошибкой в миграции или индекс удалён вручную), verifySchema создаёт его заново.
+// idx_alias из миграции 1 сюда не входит: миграция 8 пересоздала таблицу, и его заменил idx_namespace_alias.
+// Необязательные индексы (uniqueURLIndex, canonicalAliasIndex) создаются по Options и здесь не проверяются.
+var expectedIndexes = []schemaIndex{
+	{name: "idx_namespace_alias", table: "url", ddl: "CREATE UNIQUE INDEX IF NOT EXISTS idx_namespace_alias ON url(namespace, alias)"},
+	{name: "idx_creator_ip", table: "url", ddl: "CREATE INDEX IF NOT EXISTS idx_creator_ip ON url(creator_ip)"},
+	{name: "idx_created_at", table: "url", ddl: "CREATE INDEX IF NOT EXISTS idx_created_at ON url(created_at)"},
+	{name: "idx_url", table: "url", ddl: "CREATE INDEX IF NOT EXISTS idx_url ON url(url)"},
+}
+
+// verifySchema проверяет по sqlite_master, что в базе есть все индексы из expectedIndexes, и создаёт
+// недостающие с предупреждением в лог. Без индексов запросы работают, но медленно, а без idx_namespace_alias
+// перестаёт соблюдаться уникальность псевдонимов. Если уникальный индекс не создаётся из-за уже
+// появившихся дубликатов, возвращается ошибка: их нужно удалить вручную.
+func verifySchema(log *slog.Logger, db *sql.DB) error {
+	const op = "storage.sqlite.verifySchema"
+
+	existing := make(map[string]bool)
+
+	rows, err := db.Query("SELECT name FROM sqlite_master WHERE type = 'index'")
+	if err != nil {
+		return fmt.Errorf("%s: read indexes: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return fmt.Errorf("%s: scan index: %w", op, err)
+		}
+		existing[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("%s: read indexes: %w", op, err)
+	}
+
+	for _, idx := range expectedIndexes {
+		if existing[idx.name] {
+			continue
+		}
+
+		log.Warn("index is missing, recreating it",
+			slog.String("op", op),
+			slog.String("index", idx.name),
+			slog.String("table", idx.table),
+		)
+
+		_, err := db.Exec(idx.ddl)
+		if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return fmt.Errorf("%s: recreate %s: the table has duplicate rows, remove them manually: %w", op, idx.name, err)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: recreate %s: %w", op, idx.name, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package sqlite
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+	"time"
+)
+
+// slowLog - журнал медленных запросов: операции хранилища, выполнявшиеся дольше порога,
+// записываются в лог с уровнем Warn вместе с именем операции, длительностью и сводкой параметров.
+// nil или нулевой порог отключают журнал.
+type slowLog struct {
+	log       *slog.Logger
+	threshold time.Duration
+}
+
+// observe записывает в лог операцию op, начатую в start, если она выполнялась дольше порога.
+// Вызывается через defer в начале операции, поэтому без медленных запросов стоит одного вызова time.Since.
+// В params передаются только безопасные для лога значения: псевдонимы, количества, хост адреса.
+func (l *slowLog) observe(op string, start time.Time, params ...slog.Attr) {
+	if l == nil || l.threshold <= 0 {
+		return
+	}
+
+	duration := time.Since(start)
+	if duration < l.threshold {
+		return
+	}
+
+	l.log.LogAttrs(context.Background(), slog.LevelWarn, "slow query",
+		slog.String("op", op),
+		slog.Duration("duration", duration),
+		slog.Duration("threshold", l.threshold),
+		slog.Attr{Key: "params", Value: slog.GroupValue(params...)},
+	)
+}
+
+// urlHost возвращает только хост адреса: путь и параметры запроса могут содержать персональные данные.
+func urlHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+
+	return u.Host
+}
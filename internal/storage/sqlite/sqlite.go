@@ -3,9 +3,17 @@ package sqlite
 // Импортируем необходимые пакеты для работы с базой данных SQLite и обработки ошибок.
 // В этом коде:
 import (
+	"context"                        // Стандартный пакет для передачи дедлайнов и отмены запросов к базе данных.
 	"database/sql"                   // Стандартный пакет для работы с базами данных SQL в Go. Он предоставляет интерфейс для работы с любыми базами данных, поддерживающими SQL.
 	"errors"                         // Стандартный пакет для работы с ошибками. Мы будем использовать его для создания и проверки ошибок.
 	"fmt"                            // Стандартный пакет для форматированного вывода. Он используется для вывода строк, чисел и других данных в консоль.
+	"io/fs"                          // Стандартный пакет с ошибками файловой системы (проверка пути к базе данных).
+	"log/slog"                       // Стандартный пакет структурированного логирования (журнал медленных запросов).
+	"os"                             // Стандартный пакет для работы с файловой системой (создание каталога базы данных).
+	"path/filepath"                  // Стандартный пакет для работы с путями к файлам.
+	"slices"                         // Стандартный пакет для работы со срезами (колонки нарушенного индекса).
+	"strings"                        // Стандартный пакет для работы со строками.
+	"time"                           // Стандартный пакет для работы со временем (сроки действия ссылок).
 	"url-shortener/internal/storage" // Пакет приложения, вероятно, содержит структуры и функции для работы с хранилищем данных.
 
 	"github.com/mattn/go-sqlite3" // Внешний пакет для работы с SQLite. Он реализует драйвер для подключения Go-программы к базе данных SQLite.
@@ -16,74 +24,235 @@ import (
 // В этом коде:
 type Storage struct {
 	db *sql.DB // db - указатель на объект базы данных, который предоставляет интерфейс для выполнения SQL-запросов.
+
+	// queries - операции со ссылками, выполняемые напрямую через пул соединений db.
+	// Те же операции внутри транзакции предоставляет txStorage (см. WithTx).
+	queries
+
+	// ownsDB - соединение открыто в New, и Close закрывает его. Соединение из NewWithDB закрывает вызывающий код.
+	ownsDB bool
+}
+
+// Options - дополнительные параметры хранилища.
+type Options struct {
+	// SlowQueryThreshold - порог длительности, начиная с которого операция хранилища записывается в лог
+	// как медленный запрос. Значение 0 отключает журнал медленных запросов.
+	SlowQueryThreshold time.Duration
+
+	// Params - прагмы SQLite, которые добавляются в строку подключения, например {"foreign_keys": "on"}.
+	// Они переопределяют параметры по умолчанию (journal_mode=WAL, busy_timeout=5000). Неизвестные ключи
+	// отклоняются. Используются только в New: соединение, переданное в NewWithDB, уже открыто.
+	Params map[string]string
+
+	// UniqueURL включает режим дедупликации: уникальный индекс по url не даёт сохранить один адрес дважды,
+	// даже при одновременных запросах, и SaveURL возвращает storage.ErrURLDuplicate. При false индекс удаляется.
+	UniqueURL bool
+
+	// UniqueCanonicalAlias включает защиту от похожих псевдонимов: уникальный индекс по каноническому виду
+	// псевдонима (storage.CanonicalAlias) не даёт сохранить /rnail рядом с /mail. SaveURL и RotateAlias
+	// возвращают storage.ErrURLExists вместе с storage.ErrAliasConfusable. При false индекс удаляется.
+	UniqueCanonicalAlias bool
+
+	// VerifySchema включает проверку схемы при запуске: недостающие обязательные индексы (см. expectedIndexes)
+	// создаются заново с предупреждением в лог. Так чинятся базы, у которых индекс потерялся.
+	VerifySchema bool
+
+	// RecoverCorrupt - что делать, если файл базы повреждён или не является базой SQLite. При true файл
+	// (вместе с -wal и -shm) переименовывается в "<путь>.corrupt-<время>" и создаётся новая пустая база,
+	// при false New возвращает ошибку с путём к файлу. Используется только в New.
+	RecoverCorrupt bool
 }
 
 // New - функция, которая создает новое хранилище данных для работы с SQLite.
-// Она открывает соединение с базой данных, создает таблицу и индекс, если они не существуют, и возвращает новый экземпляр Storage.
-// В этом коде:
-func New(storagePath string) (*Storage, error) {
+// Она создаёт каталог для файла базы данных, если его ещё нет, открывает соединение
+// с базой данных по пути storagePath и передаёт его в NewWithDB.
+func New(log *slog.Logger, storagePath string, opts Options) (*Storage, error) {
 	const op = "storage.sqlite.New" // Определяем строку, которая будет использоваться для указания контекста в сообщении об ошибке.
 
+	// Без каталога SQLite не может создать файл базы и падает с невнятной ошибкой при первом запросе,
+	// поэтому каталог создаётся заранее (например, в свежем контейнере без подготовленного каталога данных).
+	if err := ensureDir(storagePath); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Каталог вместо файла или файл без права записи SQLite тоже обнаруживает только при первом запросе,
+	// поэтому путь проверяется заранее, чтобы сервис не запустился с понятной ошибкой.
+	if err := checkWritable(storagePath); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Строка подключения - путь к файлу базы данных с прагмами из opts.Params и параметрами по умолчанию.
+	dataSource, err := dsn(storagePath, opts.Params)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s, err := open(log, dataSource, opts)
+	if isCorrupt(err) {
+		// Повреждённый файл обнаруживается только при первом запросе (миграциях), поэтому проверка идёт здесь.
+		if err := handleCorrupt(log, storagePath, opts.RecoverCorrupt, err); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		s, err = open(log, dataSource, opts)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return s, nil
+}
+
+// open открывает базу по строке подключения dataSource и создаёт поверх неё хранилище.
+func open(log *slog.Logger, dataSource string, opts Options) (*Storage, error) {
 	// Открываем соединение с базой данных SQLite, используя путь к файлу базы данных.
 	// sql.Open открывает базу данных и возвращает объект *sql.DB, который используется для взаимодействия с базой данных.
-	db, err := sql.Open("sqlite3", storagePath)
+	db, err := sql.Open("sqlite3", dataSource)
 	if err != nil {
 		// Если ошибка при открытии базы данных, возвращаем ошибку с контекстом, добавленным с помощью %w.
+		return nil, err
+	}
+
+	s, err := NewWithDB(log, db, opts)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	s.ownsDB = true
+
+	return s, nil
+}
+
+// NewWithDB - функция, которая создает хранилище поверх уже открытого соединения *sql.DB.
+// Она применяет к базе миграции схемы и возвращает новый экземпляр Storage.
+// Это позволяет разделять пул соединений с приложением, в которое встроено хранилище,
+// и использовать в тестах базы ":memory:" (для них нужно ограничить пул одним соединением,
+// иначе каждое новое соединение откроет свою пустую базу).
+// Логгер log используется для журнала применённых миграций и журнала медленных запросов.
+func NewWithDB(log *slog.Logger, db *sql.DB, opts Options) (*Storage, error) {
+	const op = "storage.sqlite.NewWithDB"
+
+	// Применяем миграции схемы, которые ещё не были применены к этой базе.
+	if err := migrate(log, db); err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	// Готовим SQL-запрос для создания таблицы, если она не существует.
-	// Этот запрос создает таблицу url с полями id, alias и url.
-	// id - первичный ключ для уникальной идентификации каждой записи.
-	// alias - текстовое поле, уникальное, не может быть пустым.
-	// url - текстовое поле для хранения оригинального URL, не может быть пустым.
-	// Создаем индекс для alias для ускорения поиска по этому полю.
-	stmt, err := db.Prepare(
-		`CREATE TABLE IF NOT EXISTS url(
-			id INTEGER PRIMARY KEY,    
-			alias TEXT NOT NULL UNIQUE, 
-			url TEXT NOT NULL);         
-			CREATE INDEX IF NOT EXISTS idx_alias ON url(alias);) 
-	`)
+	if opts.VerifySchema {
+		if err := verifySchema(log, db); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
 
-	if err != nil {
-		// Если ошибка при подготовке запроса, возвращаем ошибку с контекстом.
+	if err := applyUniqueURLIndex(db, opts.UniqueURL); err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	// Выполняем подготовленный запрос для создания таблицы и индекса.
-	_, err = stmt.Exec()
+	if err := applyCanonicalAliasIndex(db, opts.UniqueCanonicalAlias); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Запросы горячего пути готовятся один раз, после миграций.
+	stmts, err := prepareStatements(db)
 	if err != nil {
-		// Если ошибка при выполнении запроса, возвращаем ошибку с контекстом.
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	// Возвращаем новый экземпляр Storage с открытым соединением db.
-	return &Storage{db: db}, nil
+	// Возвращаем новый экземпляр Storage с переданным соединением db.
+	slow := &slowLog{
+		log:       log.With(slog.String("component", "storage/sqlite")),
+		threshold: opts.SlowQueryThreshold,
+	}
+
+	return &Storage{db: db, queries: queries{q: db, slow: slow, stmts: stmts}}, nil
+}
+
+// Close - метод, который закрывает подготовленные запросы хранилища, а если соединение открыто в New, то и его.
+// После Close хранилище использовать нельзя.
+func (s *Storage) Close() error {
+	const op = "storage.sqlite.Close"
+
+	err := s.stmts.close()
+	if s.ownsDB {
+		err = errors.Join(err, s.db.Close())
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ensureDir создаёт родительский каталог файла базы данных. Базы в памяти (":memory:")
+// и пути в виде URI ("file:...") пропускаются: у них нет обычного пути к файлу.
+func ensureDir(storagePath string) error {
+	if storagePath == ":memory:" || strings.HasPrefix(storagePath, "file:") {
+		return nil
+	}
+
+	dir := filepath.Dir(storagePath)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("create storage directory %s: %w", dir, err)
+	}
+
+	return nil
+}
+
+// checkWritable проверяет, что по пути storagePath можно хранить базу данных: путь не является каталогом,
+// существующий файл открывается на запись, а для нового файла можно создать файл в его каталоге.
+// Базы в памяти и пути в виде URI пропускаются, как и в ensureDir.
+func checkWritable(storagePath string) error {
+	if storagePath == ":memory:" || strings.HasPrefix(storagePath, "file:") {
+		return nil
+	}
+
+	info, err := os.Stat(storagePath)
+	switch {
+	case err == nil && info.IsDir():
+		return fmt.Errorf("storage path %s is a directory, expected a database file path", storagePath)
+	case err == nil:
+		f, err := os.OpenFile(storagePath, os.O_WRONLY, 0)
+		if err != nil {
+			return fmt.Errorf("storage file %s is not writable: %w", storagePath, err)
+		}
+
+		return f.Close()
+	case errors.Is(err, fs.ErrNotExist):
+		dir := filepath.Dir(storagePath)
+
+		f, err := os.CreateTemp(dir, ".write-check-*")
+		if err != nil {
+			return fmt.Errorf("storage directory %s is not writable: %w", dir, err)
+		}
+		_ = f.Close()
+
+		return os.Remove(f.Name())
+	default:
+		return fmt.Errorf("stat storage path %s: %w", storagePath, err)
+	}
 }
 
 // SaveURL - метод, который сохраняет новый URL в базу данных с уникальным псевдонимом.
 // Он выполняет SQL-запрос для добавления записи в таблицу `url`, а затем возвращает ID вставленной строки или ошибку, если она возникла.
 // В этом коде:
-func (s *Storage) SaveURL(urlToSave, alias string) (int64, error) {
+func (c queries) SaveURL(urlToSave, alias string, opts storage.URLOptions) (int64, error) {
 	const op = "storage.sqlite.SaveURL" // Определяем строку для контекста ошибки, которая будет добавлена к ошибке, если она произойдет.
 
-	// Готовим SQL-запрос для вставки нового URL и псевдонима в таблицу `url`.
-	// Используем `?` для параметризированных запросов, чтобы избежать SQL-инъекций.
-	stmt, err := s.db.Prepare("INSERT INTO url(url, alias) VALUES(?, ?)")
-	if err != nil {
-		// Если не удалось подготовить запрос, возвращаем ошибку с контекстом.
-		return 0, fmt.Errorf("%s: %w", op, err)
-	}
+	defer c.slow.observe(op, time.Now(), slog.String("alias", alias), slog.String("namespace", opts.Namespace), slog.String("host", urlHost(urlToSave)))
 
-	// Выполняем подготовленный запрос, передавая urlToSave и alias в качестве параметров.
-	res, err := stmt.Exec(urlToSave, alias)
+	// Выполняем заранее подготовленный запрос вставки (см. stmts.go), передавая urlToSave, alias и параметры ссылки.
+	// Используем `?` для параметризированных запросов, чтобы избежать SQL-инъекций.
+	// Незаданные параметры (nil) записываются как NULL.
+	res, err := c.stmt(c.stmts.saveURL).Exec(urlToSave, storage.NormalizeAlias(alias), opts.Permanent, utcTime(opts.ExpiresAt), time.Now().UTC(), nullString(opts.CreatorIP), storage.NormalizeAlias(opts.Namespace), max(opts.MaxClicks, 0), encodeTags(opts.Tags), storage.CanonicalAlias(alias), opts.PreserveMethod, encodeHeaders(opts.Headers), opts.ForwardPath)
 	if err != nil {
 		// Проверяем, если ошибка связана с нарушением уникальности псевдонима.
 		// Если ошибка типа sqlite3.Error и код ошибки соответствует уникальному ограничению,
 		// то возвращаем ошибку с контекстом, что URL уже существует.
-		if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
-			return 0, fmt.Errorf("%s: %w", op, storage.ErrURLExists)
+		if sqliteErr, ok := isAliasConflict(err); ok {
+			// В режиме дедупликации уникален и сам адрес (uniqueURLIndex).
+			if slices.Equal(uniqueColumns(sqliteErr), []string{"url.url"}) {
+				return 0, fmt.Errorf("%s: %w", op, storage.ErrURLDuplicate)
+			}
+			return 0, fmt.Errorf("%s: %w", op, aliasConflict(sqliteErr))
 		}
 		// Если ошибка другая, возвращаем её с контекстом.
 		return 0, fmt.Errorf("%s: %w", op, err)
@@ -100,23 +269,70 @@ func (s *Storage) SaveURL(urlToSave, alias string) (int64, error) {
 	return id, nil
 }
 
+// isAliasConflict сообщает, отклонена ли запись псевдонима: нарушен уникальный индекс или псевдоним
+// удерживается резервацией (триггеры url_alias_held_*, см. holds.go).
+func isAliasConflict(err error) (sqlite3.Error, bool) {
+	sqliteErr, ok := err.(sqlite3.Error)
+	if !ok {
+		return sqlite3.Error{}, false
+	}
+
+	switch sqliteErr.ExtendedCode {
+	case sqlite3.ErrConstraintUnique:
+		return sqliteErr, true
+	case sqlite3.ErrConstraintTrigger:
+		return sqliteErr, sqliteErr.Error() == aliasHeldMessage
+	default:
+		return sqliteErr, false
+	}
+}
+
+// uniqueColumnsPrefix - начало текста ошибки SQLite о нарушении уникального индекса, за которым
+// через ", " перечислены колонки индекса в виде "таблица.колонка".
+const uniqueColumnsPrefix = "UNIQUE constraint failed: "
+
+// uniqueColumns возвращает колонки уникального индекса, нарушение которого вызвало ошибку, например
+// ["url.namespace", "url.alias"]. Код ошибки отдельных индексов не различает, поэтому колонки берутся из текста,
+// который SQLite формирует всегда одинаково. Для других ошибок возвращается nil.
+func uniqueColumns(err sqlite3.Error) []string {
+	if err.ExtendedCode != sqlite3.ErrConstraintUnique {
+		return nil
+	}
+
+	columns, ok := strings.CutPrefix(err.Error(), uniqueColumnsPrefix)
+	if !ok {
+		return nil
+	}
+
+	return strings.Split(columns, ", ")
+}
+
+// aliasConflict возвращает ошибку для отклонённой записи псевдонима (см. isAliasConflict): storage.ErrURLExists,
+// а вместе с ней storage.ErrAliasConfusable, если нарушен индекс по каноническому виду (canonicalAliasIndex),
+// или storage.ErrAliasHeld, если псевдоним зарезервирован.
+func aliasConflict(err sqlite3.Error) error {
+	if err.ExtendedCode == sqlite3.ErrConstraintTrigger {
+		return errors.Join(storage.ErrURLExists, storage.ErrAliasHeld)
+	}
+	if slices.Equal(uniqueColumns(err), []string{"url.namespace", "url.canonical_alias"}) {
+		return errors.Join(storage.ErrURLExists, storage.ErrAliasConfusable)
+	}
+
+	return storage.ErrURLExists
+}
+
 // GetURL - метод, который извлекает URL по псевдониму из базы данных.
 // Он выполняет SQL-запрос для получения URL, связанного с заданным псевдонимом, и возвращает его или ошибку.
 // В этом коде:
-func (s *Storage) GetURL(alias string) (string, error) {
+func (c queries) GetURL(alias string) (string, error) {
 	const op = "storage.sqlite.GetURL" // Строка, определяющая контекст ошибки для удобства отладки.
 
-	// Готовим SQL-запрос для выборки URL по псевдониму.
-	// Используем параметризированный запрос для предотвращения SQL-инъекций.
-	stmt, err := s.db.Prepare("SELECT url FROM url WHERE alias = ?")
-	if err != nil {
-		// Если не удалось подготовить запрос, возвращаем ошибку с контекстом.
-		return "", fmt.Errorf("%s: prepare statement: %w", op, err)
-	}
+	defer c.slow.observe(op, time.Now(), slog.String("alias", alias))
 
-	// Выполняем запрос и пытаемся получить результат в переменную resURL.
+	// Выполняем заранее подготовленный параметризированный запрос (см. stmts.go)
+	// и пытаемся получить результат в переменную resURL.
 	var resURL string
-	err = stmt.QueryRow(alias).Scan(&resURL)
+	err := c.stmt(c.stmts.getURL).QueryRow(storage.NormalizeAlias(alias)).Scan(&resURL)
 	if err != nil {
 		// Если ошибок не связаны с отсутствием строк, то возвращаем ошибку с контекстом.
 		if errors.Is(err, sql.ErrNoRows) {
@@ -131,11 +347,119 @@ func (s *Storage) GetURL(alias string) (string, error) {
 	return resURL, nil
 }
 
+// GetURLs - метод, который извлекает адреса сразу для нескольких псевдонимов одним запросом с IN.
+// Возвращает отображение псевдоним -> URL только для найденных ссылок пространства имён по умолчанию;
+// псевдонимов, которых нет, в отображении просто нет. Ключи - псевдонимы в нормализованном виде.
+func (c queries) GetURLs(aliases []string) (map[string]string, error) {
+	const op = "storage.sqlite.GetURLs"
+
+	defer c.slow.observe(op, time.Now(), slog.Int("aliases", len(aliases)))
+
+	urls := make(map[string]string, len(aliases))
+	if len(aliases) == 0 {
+		return urls, nil
+	}
+
+	args := make([]any, len(aliases))
+	for i, alias := range aliases {
+		args[i] = storage.NormalizeAlias(alias)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(aliases)), ", ")
+
+	rows, err := c.q.Query("SELECT alias, url FROM url WHERE namespace = '' AND alias IN ("+placeholders+")", args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var alias, url string
+		if err := rows.Scan(&alias, &url); err != nil {
+			return nil, fmt.Errorf("%s: scan row: %w", op, err)
+		}
+		urls[alias] = url
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: iterate rows: %w", op, err)
+	}
+
+	return urls, nil
+}
+
+// GetURLRecord - метод, который извлекает запись о ссылке целиком по псевдониму
+// в пространстве имён по умолчанию.
+// В отличие от GetURL возвращает также параметры ссылки, нужные для редиректа.
+func (c queries) GetURLRecord(alias string) (storage.URLRecord, error) {
+	return c.GetNamespacedURLRecord(context.Background(), "", alias)
+}
+
+// GetNamespacedURLRecord - метод, который извлекает запись о ссылке по пространству имён и псевдониму.
+// Пустое пространство имён - пространство имён по умолчанию.
+// Запрос прерывается, когда истекает ctx, например по таймауту обработки HTTP-запроса.
+func (c queries) GetNamespacedURLRecord(ctx context.Context, namespace, alias string) (storage.URLRecord, error) {
+	const op = "storage.sqlite.GetNamespacedURLRecord"
+
+	defer c.slow.observe(op, time.Now(), slog.String("namespace", namespace), slog.String("alias", alias))
+
+	// Запрос подготовлен заранее (см. stmts.go): через него проходит каждый редирект.
+	row := c.stmt(c.stmts.getRecord).QueryRowContext(ctx, storage.NormalizeAlias(namespace), storage.NormalizeAlias(alias))
+
+	rec, err := scanURLRecord(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.URLRecord{}, storage.ErrURLNotFound
+	}
+	if err != nil {
+		return storage.URLRecord{}, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	return rec, nil
+}
+
+// GetURLRecordByURL - метод, который извлекает самую раннюю ссылку на адрес urlToSave.
+// В режиме дедупликации такая ссылка одна, и по ней возвращается псевдоним уже сокращённого адреса.
+func (c queries) GetURLRecordByURL(urlToSave string) (storage.URLRecord, error) {
+	const op = "storage.sqlite.GetURLRecordByURL"
+
+	defer c.slow.observe(op, time.Now(), slog.String("host", urlHost(urlToSave)))
+
+	row := c.q.QueryRow("SELECT "+urlRecordColumns+" FROM url WHERE url = ? ORDER BY id LIMIT 1", urlToSave)
+
+	rec, err := scanURLRecord(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.URLRecord{}, storage.ErrURLNotFound
+	}
+	if err != nil {
+		return storage.URLRecord{}, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	return rec, nil
+}
+
+// AliasExists - метод, который проверяет, занят ли псевдоним.
+// Запрос выбирает только константу, не читая всю строку, поэтому проверка дешёвая.
+func (s *Storage) AliasExists(ctx context.Context, alias string) (bool, error) {
+	const op = "storage.sqlite.AliasExists"
+
+	defer s.slow.observe(op, time.Now(), slog.String("alias", alias))
+
+	var exists int
+	err := s.db.QueryRowContext(ctx, "SELECT 1 FROM url WHERE namespace = '' AND alias = ? LIMIT 1", storage.NormalizeAlias(alias)).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	return true, nil
+}
+
 // func (s *Storage) DeleteURL(alias string) error {
-	func (s *Storage) DeleteURL(alias string) (int64, error) {
+	func (c queries) DeleteURL(alias string) (int64, error) {
 		const fn = "storage.sqlite.DeleteURL"
+		defer c.slow.observe(fn, time.Now(), slog.String("alias", alias))
 	
-		result, err := s.db.Exec("DELETE FROM url WHERE alias = ?", alias)
+		result, err := c.stmt(c.stmts.deleteURL).Exec(storage.NormalizeAlias(alias))
 		if err != nil {
 			return 0, fmt.Errorf("%s: execute statement %w", fn, err)
 		}
@@ -146,4 +470,672 @@ func (s *Storage) GetURL(alias string) (string, error) {
 		}
 	
 		return rowsAffected, nil
-	}
\ No newline at end of file
+	}
+
+// BulkDeleteURL - метод, который удаляет ссылки по списку псевдонимов одним запросом с IN
+// и возвращает количество удалённых строк. Псевдонимы, которых нет, пропускаются; пустой список ничего не удаляет.
+func (c queries) BulkDeleteURL(aliases []string) (int64, error) {
+	const op = "storage.sqlite.BulkDeleteURL"
+
+	defer c.slow.observe(op, time.Now(), slog.Int("aliases", len(aliases)))
+
+	if len(aliases) == 0 {
+		return 0, nil
+	}
+
+	args := make([]any, len(aliases))
+	for i, alias := range aliases {
+		args[i] = storage.NormalizeAlias(alias)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(aliases)), ", ")
+
+	result, err := c.q.Exec("DELETE FROM url WHERE namespace = '' AND alias IN ("+placeholders+")", args...)
+	if err != nil {
+		return 0, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: get rows affected: %w", op, err)
+	}
+
+	return rowsAffected, nil
+}
+
+// DeleteURLRecord - метод, который удаляет ссылку и возвращает удалённую запись.
+// Чтение и удаление выполняются в одной транзакции, чтобы вернуть именно ту запись, которая была удалена.
+// Возвращает storage.ErrURLNotFound, если псевдонима нет.
+func (s *Storage) DeleteURLRecord(alias string) (storage.URLRecord, error) {
+	const op = "storage.sqlite.DeleteURLRecord"
+
+	defer s.slow.observe(op, time.Now(), slog.String("alias", alias))
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return storage.URLRecord{}, fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	row := tx.QueryRow("SELECT "+urlRecordColumns+" FROM url WHERE namespace = '' AND alias = ?", storage.NormalizeAlias(alias))
+
+	rec, err := scanURLRecord(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.URLRecord{}, storage.ErrURLNotFound
+	}
+	if err != nil {
+		return storage.URLRecord{}, fmt.Errorf("%s: find alias: %w", op, err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM url WHERE id = ?", rec.ID); err != nil {
+		return storage.URLRecord{}, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return storage.URLRecord{}, fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return rec, nil
+}
+
+// DeleteAll - метод, который удаляет все ссылки и возвращает количество удалённых строк.
+func (s *Storage) DeleteAll(ctx context.Context) (int64, error) {
+	const op = "storage.sqlite.DeleteAll"
+
+	defer s.slow.observe(op, time.Now())
+
+	result, err := s.db.ExecContext(ctx, "DELETE FROM url")
+	if err != nil {
+		return 0, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: get rows affected: %w", op, err)
+	}
+
+	return rowsAffected, nil
+}
+
+// CountURLs - метод, который возвращает количество сохранённых ссылок.
+// COUNT(*) обходит всю таблицу, поэтому на горячем пути результат стоит кэшировать.
+func (s *Storage) CountURLs(ctx context.Context) (int64, error) {
+	const op = "storage.sqlite.CountURLs"
+
+	defer s.slow.observe(op, time.Now())
+
+	var count int64
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM url").Scan(&count); err != nil {
+		return 0, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	return count, nil
+}
+
+// Ping - метод, который проверяет доступность базы простейшим запросом к таблице url.
+// В отличие от db.PingContext запрос читает файл базы, поэтому его время отражает задержку диска и блокировок.
+func (s *Storage) Ping(ctx context.Context) error {
+	const op = "storage.sqlite.Ping"
+
+	defer s.slow.observe(op, time.Now())
+
+	var exists bool
+	if err := s.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM url)").Scan(&exists); err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	return nil
+}
+
+// CountByCreator - метод, который возвращает количество ссылок, созданных с указанного IP-адреса.
+func (s *Storage) CountByCreator(ip string) (int, error) {
+	const op = "storage.sqlite.CountByCreator"
+
+	defer s.slow.observe(op, time.Now())
+
+	var count int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM url WHERE creator_ip = ?", ip).Scan(&count); err != nil {
+		return 0, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	return count, nil
+}
+
+// RotateAlias - метод, который переносит ссылку со старого псевдонима на новый в одной транзакции.
+// Ссылка продолжает вести на тот же адрес, а старый псевдоним сразу перестаёт существовать.
+// Возвращает storage.ErrURLNotFound, если старого псевдонима нет, и storage.ErrURLExists, если новый уже занят.
+func (s *Storage) RotateAlias(oldAlias, newAlias string) error {
+	return s.WithTx(context.Background(), func(tx storage.Tx) error {
+		return tx.RotateAlias(oldAlias, newAlias)
+	})
+}
+
+// RotateAlias - метод, который переносит ссылку со старого псевдонима на новый.
+// Поиск и обновление - два запроса, поэтому вне транзакции метод не используется: Storage.RotateAlias
+// оборачивает его в WithTx.
+func (c queries) RotateAlias(oldAlias, newAlias string) error {
+	const op = "storage.sqlite.RotateAlias"
+
+	defer c.slow.observe(op, time.Now(), slog.String("old_alias", oldAlias), slog.String("new_alias", newAlias))
+
+	var id int64
+	err := c.q.QueryRow("SELECT id FROM url WHERE namespace = '' AND alias = ?", storage.NormalizeAlias(oldAlias)).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.ErrURLNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("%s: find alias: %w", op, err)
+	}
+
+	_, err = c.q.Exec("UPDATE url SET alias = ?, canonical_alias = ? WHERE id = ?", storage.NormalizeAlias(newAlias), storage.CanonicalAlias(newAlias), id)
+	if err != nil {
+		if sqliteErr, ok := isAliasConflict(err); ok {
+			return fmt.Errorf("%s: %w", op, aliasConflict(sqliteErr))
+		}
+		return fmt.Errorf("%s: update alias: %w", op, err)
+	}
+
+	return nil
+}
+
+// SetEnabled - метод, который включает или выключает ссылку в пространстве имён по умолчанию.
+// Выключенная ссылка не удаляется: её данные и счётчик переходов сохраняются, а редирект по ней
+// не выполняется до повторного включения. Возвращает storage.ErrURLNotFound, если псевдонима нет.
+func (s *Storage) SetEnabled(alias string, enabled bool) error {
+	const op = "storage.sqlite.SetEnabled"
+
+	defer s.slow.observe(op, time.Now(), slog.String("alias", alias), slog.Bool("enabled", enabled))
+
+	result, err := s.db.Exec("UPDATE url SET enabled = ? WHERE namespace = '' AND alias = ?", enabled, storage.NormalizeAlias(alias))
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: get rows affected: %w", op, err)
+	}
+	if rowsAffected == 0 {
+		return storage.ErrURLNotFound
+	}
+
+	return nil
+}
+
+// SetExpiry - метод, который меняет срок действия ссылки в пространстве имён по умолчанию,
+// не трогая адрес и остальные параметры. nil снимает ограничение: ссылка становится бессрочной.
+// Возвращает storage.ErrURLNotFound, если псевдонима нет.
+func (s *Storage) SetExpiry(alias string, expiresAt *time.Time) error {
+	const op = "storage.sqlite.SetExpiry"
+
+	defer s.slow.observe(op, time.Now(), slog.String("alias", alias))
+
+	result, err := s.db.Exec("UPDATE url SET expires_at = ? WHERE namespace = '' AND alias = ?", utcTime(expiresAt), storage.NormalizeAlias(alias))
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: get rows affected: %w", op, err)
+	}
+	if rowsAffected == 0 {
+		return storage.ErrURLNotFound
+	}
+
+	return nil
+}
+
+// ListURLsAfter - метод, который возвращает до limit ссылок всех пространств имён с ID больше id, упорядоченных по ID.
+// Это постраничный вывод по курсору: следующая страница запрашивается с ID последней ссылки предыдущей.
+// В отличие от OFFSET, запрос идёт по первичному ключу и не просматривает пропущенные строки,
+// поэтому не замедляется на дальних страницах.
+func (s *Storage) ListURLsAfter(id int64, limit int) ([]storage.URLRecord, error) {
+	const op = "storage.sqlite.ListURLsAfter"
+
+	defer s.slow.observe(op, time.Now(), slog.Int64("after", id), slog.Int("limit", limit))
+
+	rows, err := s.db.Query("SELECT "+urlRecordColumns+" FROM url WHERE id > ? ORDER BY id LIMIT ?", id, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	defer rows.Close()
+
+	records, err := scanURLRecords(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return records, nil
+}
+
+// ListURLsByTag - метод, который возвращает до limit ссылок с меткой tag (точное совпадение) и ID больше id,
+// упорядоченных по ID. Постраничный вывод устроен так же, как в ListURLsAfter.
+func (s *Storage) ListURLsByTag(tag string, id int64, limit int) ([]storage.URLRecord, error) {
+	const op = "storage.sqlite.ListURLsByTag"
+
+	defer s.slow.observe(op, time.Now(), slog.String("tag", tag), slog.Int64("after", id), slog.Int("limit", limit))
+
+	rows, err := s.db.Query(
+		"SELECT "+urlRecordColumns+" FROM url WHERE id > ? AND instr(tags, ?) > 0 ORDER BY id LIMIT ?",
+		id, tagPattern(tag), limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	defer rows.Close()
+
+	records, err := scanURLRecords(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return records, nil
+}
+
+// ListByDateRange - метод, который возвращает ссылки всех пространств имён, созданные в промежутке [from, to],
+// упорядоченные по времени создания. Нулевое from или to означает, что промежуток с этой стороны не ограничен.
+// Ссылки, созданные до появления колонки created_at, в выборку не попадают: время их создания неизвестно.
+// limit и offset задают страницу результата.
+func (s *Storage) ListByDateRange(from, to time.Time, limit, offset int) ([]storage.URLRecord, error) {
+	const op = "storage.sqlite.ListByDateRange"
+
+	defer s.slow.observe(op, time.Now(), slog.Time("from", from), slog.Time("to", to), slog.Int("limit", limit), slog.Int("offset", offset))
+
+	query := "SELECT " + urlRecordColumns + " FROM url WHERE created_at IS NOT NULL"
+	var args []any
+	if !from.IsZero() {
+		query += " AND created_at >= ?"
+		args = append(args, from.UTC())
+	}
+	if !to.IsZero() {
+		query += " AND created_at <= ?"
+		args = append(args, to.UTC())
+	}
+	query += " ORDER BY created_at, id LIMIT ? OFFSET ?"
+	args = append(args, limit, offset)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	defer rows.Close()
+
+	records, err := scanURLRecords(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return records, nil
+}
+
+// Summary - метод, который считает сводные показатели для дашборда: число ссылок, сумму переходов,
+// число ссылок, созданных начиная с since, и top ссылок с наибольшим числом переходов.
+// Все запросы выполняются в одной транзакции, чтобы показатели были согласованы между собой.
+func (s *Storage) Summary(since time.Time, top int) (storage.Summary, error) {
+	const op = "storage.sqlite.Summary"
+
+	defer s.slow.observe(op, time.Now(), slog.Time("since", since), slog.Int("top", top))
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return storage.Summary{}, fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var summary storage.Summary
+
+	err = tx.QueryRow("SELECT COUNT(*), COALESCE(SUM(clicks), 0) FROM url").Scan(&summary.TotalLinks, &summary.TotalClicks)
+	if err != nil {
+		return storage.Summary{}, fmt.Errorf("%s: count links: %w", op, err)
+	}
+
+	err = tx.QueryRow("SELECT COUNT(*) FROM url WHERE created_at >= ?", since.UTC()).Scan(&summary.CreatedSince)
+	if err != nil {
+		return storage.Summary{}, fmt.Errorf("%s: count created links: %w", op, err)
+	}
+
+	rows, err := tx.Query("SELECT "+urlRecordColumns+" FROM url ORDER BY clicks DESC, id LIMIT ?", top)
+	if err != nil {
+		return storage.Summary{}, fmt.Errorf("%s: select top links: %w", op, err)
+	}
+	defer rows.Close()
+
+	summary.Top, err = scanURLRecords(rows)
+	if err != nil {
+		return storage.Summary{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return summary, nil
+}
+
+// pendingAlias - временный псевдоним ссылки, которой SaveURLSequential ещё не назначил псевдоним по ID.
+// Символ "#" не может оказаться в пути короткой ссылки, поэтому он не совпадёт ни с одним настоящим псевдонимом.
+// Ссылка с ним существует только внутри транзакции и другим клиентам не видна.
+const pendingAlias = "#pending"
+
+// sequentialAttempts - сколько ID подряд перебирает SaveURLSequential, если псевдонимы для них заняты.
+const sequentialAttempts = 100
+
+// SaveURLSequential - метод, который сохраняет ссылку с псевдонимом, вычисленным по её ID: ссылка вставляется
+// с временным псевдонимом, а после получения LastInsertId псевдоним заменяется на encode(id) в той же транзакции.
+// Разные ID дают разные псевдонимы, поэтому повторные попытки, как при случайных псевдонимах, не нужны.
+// Исключение - псевдоним, уже занятый пользователем, или пустой результат encode (например, для зарезервированного
+// псевдонима): тогда ссылка переносится на следующий свободный ID. Возвращает псевдоним и ID ссылки.
+func (s *Storage) SaveURLSequential(urlToSave string, opts storage.URLOptions, encode func(id int64) string) (string, int64, error) {
+	const op = "storage.sqlite.SaveURLSequential"
+
+	defer s.slow.observe(op, time.Now(), slog.String("namespace", opts.Namespace), slog.String("host", urlHost(urlToSave)))
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return "", 0, fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	id, err := s.txQueries(tx).SaveURL(urlToSave, pendingAlias, opts)
+	if err != nil {
+		return "", 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for range sequentialAttempts {
+		alias := storage.NormalizeAlias(encode(id))
+		if alias != "" {
+			_, err = tx.Exec("UPDATE url SET alias = ?, canonical_alias = ? WHERE id = ?", alias, storage.CanonicalAlias(alias), id)
+			if err == nil {
+				if err := tx.Commit(); err != nil {
+					return "", 0, fmt.Errorf("%s: commit: %w", op, err)
+				}
+				return alias, id, nil
+			}
+			if _, ok := isAliasConflict(err); !ok {
+				return "", 0, fmt.Errorf("%s: set alias: %w", op, err)
+			}
+		}
+
+		// Псевдоним для этого ID использовать нельзя: переносим ссылку на следующий ID.
+		// Вставленная строка - последняя в таблице, поэтому следующий ID - id + 1.
+		if _, err := tx.Exec("UPDATE url SET id = ? WHERE id = ?", id+1, id); err != nil {
+			return "", 0, fmt.Errorf("%s: move to next id: %w", op, err)
+		}
+		id++
+	}
+
+	return "", 0, fmt.Errorf("%s: %w", op, storage.ErrURLExists)
+}
+
+// ConsumeClick - метод, который засчитывает переход по ссылке с ограничением max_clicks.
+// Проверка остатка и увеличение счётчика выполняются одним условным UPDATE, поэтому при одновременных
+// переходах ссылка не пропустит больше max_clicks переходов. Если переходов не осталось,
+// возвращает storage.ErrClicksExhausted. Переходы по ссылкам без ограничения засчитываются пачками через AddClicks.
+func (s *Storage) ConsumeClick(id int64) error {
+	const op = "storage.sqlite.ConsumeClick"
+
+	defer s.slow.observe(op, time.Now(), slog.Int64("id", id))
+
+	result, err := s.db.Exec("UPDATE url SET clicks = clicks + 1 WHERE id = ? AND (max_clicks = 0 OR clicks < max_clicks)", id)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: get rows affected: %w", op, err)
+	}
+	if rowsAffected == 0 {
+		return storage.ErrClicksExhausted
+	}
+
+	return nil
+}
+
+// UpdateLastAccessed - метод, который записывает время последнего обращения к ссылкам.
+// Принимает пачку ID ссылок со временем обращения и обновляет их в одной транзакции.
+func (s *Storage) UpdateLastAccessed(accessed map[int64]time.Time) error {
+	const op = "storage.sqlite.UpdateLastAccessed"
+
+	defer s.slow.observe(op, time.Now(), slog.Int("links", len(accessed)))
+
+	if len(accessed) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmt, err := tx.Prepare("UPDATE url SET last_accessed_at = ? WHERE id = ?")
+	if err != nil {
+		return fmt.Errorf("%s: prepare statement: %w", op, err)
+	}
+	defer stmt.Close()
+
+	for id, at := range accessed {
+		if _, err := stmt.Exec(at.UTC(), id); err != nil {
+			return fmt.Errorf("%s: execute statement: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: commit: %w", op, err)
+	}
+
+	return nil
+}
+
+// AddClicks - метод, который прибавляет накопленные переходы к счётчикам ссылок.
+// Принимает пачку ID ссылок с числом переходов и обновляет их в одной транзакции.
+// Ссылки с ограничением max_clicks пропускаются: их переходы засчитывает ConsumeClick.
+func (s *Storage) AddClicks(clicks map[int64]int64) error {
+	const op = "storage.sqlite.AddClicks"
+
+	defer s.slow.observe(op, time.Now(), slog.Int("links", len(clicks)))
+
+	if len(clicks) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmt, err := tx.Prepare("UPDATE url SET clicks = clicks + ? WHERE id = ? AND max_clicks = 0")
+	if err != nil {
+		return fmt.Errorf("%s: prepare statement: %w", op, err)
+	}
+	defer stmt.Close()
+
+	for id, n := range clicks {
+		if _, err := stmt.Exec(n, id); err != nil {
+			return fmt.Errorf("%s: execute statement: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: commit: %w", op, err)
+	}
+
+	return nil
+}
+
+// ListStale - метод, который возвращает ссылки, к которым не обращались с момента olderThan.
+// Ссылка без обращений считается по времени создания; ссылки, у которых неизвестно
+// ни то, ни другое (созданы до появления этих колонок), считаются устаревшими.
+func (s *Storage) ListStale(olderThan time.Time) ([]storage.URLRecord, error) {
+	const op = "storage.sqlite.ListStale"
+
+	defer s.slow.observe(op, time.Now(), slog.Time("older_than", olderThan))
+
+	rows, err := s.db.Query(
+		"SELECT "+urlRecordColumns+" FROM url "+
+			"WHERE COALESCE(last_accessed_at, created_at) IS NULL OR COALESCE(last_accessed_at, created_at) < ? "+
+			"ORDER BY id",
+		olderThan.UTC(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	defer rows.Close()
+
+	records, err := scanURLRecords(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return records, nil
+}
+
+// ListAliasesByURL - метод, который возвращает псевдонимы всех ссылок на адрес urlToSave (точное совпадение)
+// в порядке создания. Псевдоним ссылки из пространства имён возвращается вместе с ним, как в её пути: "team/home".
+// Если ссылок нет, возвращает пустой список.
+func (s *Storage) ListAliasesByURL(urlToSave string) ([]string, error) {
+	const op = "storage.sqlite.ListAliasesByURL"
+
+	defer s.slow.observe(op, time.Now(), slog.String("host", urlHost(urlToSave)))
+
+	rows, err := s.db.Query("SELECT namespace, alias FROM url WHERE url = ? ORDER BY id", urlToSave)
+	if err != nil {
+		return nil, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	defer rows.Close()
+
+	aliases := []string{}
+	for rows.Next() {
+		var namespace, alias string
+		if err := rows.Scan(&namespace, &alias); err != nil {
+			return nil, fmt.Errorf("%s: scan row: %w", op, err)
+		}
+		if namespace != "" {
+			alias = namespace + "/" + alias
+		}
+		aliases = append(aliases, alias)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: read rows: %w", op, err)
+	}
+
+	return aliases, nil
+}
+
+// SearchURLs - метод, который возвращает до limit ссылок всех пространств имён, адрес или псевдоним которых
+// содержит term как подстроку (без учёта регистра латиницы), упорядоченных по ID. Символы % и _ в term ищутся
+// буквально. Поиск по подстроке не использует индексы и просматривает всю таблицу, поэтому на большой базе
+// он медленный: метод предназначен для редких административных запросов. Если совпадений нет, возвращает пустой список.
+func (s *Storage) SearchURLs(term string, limit int) ([]storage.URLRecord, error) {
+	const op = "storage.sqlite.SearchURLs"
+
+	// Сам term не пишется в лог: часть адреса может содержать персональные данные.
+	defer s.slow.observe(op, time.Now(), slog.Int("term_length", len(term)), slog.Int("limit", limit))
+
+	pattern := "%" + escapeLike(term) + "%"
+
+	rows, err := s.db.Query(
+		"SELECT "+urlRecordColumns+" FROM url WHERE url LIKE ? ESCAPE '\\' OR alias LIKE ? ESCAPE '\\' ORDER BY id LIMIT ?",
+		pattern, pattern, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	defer rows.Close()
+
+	records, err := scanURLRecords(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return records, nil
+}
+
+// escapeLike экранирует символом \ спецсимволы шаблона LIKE (%, _ и сам \), чтобы они совпадали буквально.
+// Запрос должен указывать ESCAPE '\'.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// urlRecordColumns - колонки таблицы url в порядке, который ожидает scanURLRecord.
+const urlRecordColumns = "id, namespace, alias, url, permanent, expires_at, created_at, last_accessed_at, max_clicks, clicks, enabled, tags, preserve_method, headers, forward_path"
+
+// rowScanner - общий интерфейс *sql.Row и *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanURLRecord читает запись о ссылке из строки результата, выбранной с колонками urlRecordColumns.
+func scanURLRecord(row rowScanner) (storage.URLRecord, error) {
+	var (
+		rec            storage.URLRecord
+		permanent      sql.NullBool
+		preserveMethod sql.NullBool
+		expiresAt      sql.NullTime
+		createdAt      sql.NullTime
+		lastAccessedAt sql.NullTime
+		enabled        bool
+		tags           string
+		headers        string
+	)
+
+	err := row.Scan(&rec.ID, &rec.Namespace, &rec.Alias, &rec.URL, &permanent, &expiresAt, &createdAt, &lastAccessedAt, &rec.MaxClicks, &rec.Clicks, &enabled, &tags, &preserveMethod, &headers, &rec.ForwardPath)
+	if err != nil {
+		return storage.URLRecord{}, err
+	}
+
+	rec.Disabled = !enabled
+	rec.Tags = decodeTags(tags)
+	rec.Headers = decodeHeaders(headers)
+
+	if permanent.Valid {
+		rec.Permanent = &permanent.Bool
+	}
+	if preserveMethod.Valid {
+		rec.PreserveMethod = &preserveMethod.Bool
+	}
+	if expiresAt.Valid {
+		rec.ExpiresAt = &expiresAt.Time
+	}
+	if createdAt.Valid {
+		rec.CreatedAt = &createdAt.Time
+	}
+	if lastAccessedAt.Valid {
+		rec.LastAccessedAt = &lastAccessedAt.Time
+	}
+
+	return rec, nil
+}
+
+// utcTime приводит время к UTC перед записью в базу, чтобы все значения хранились в одной зоне.
+func utcTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+
+	return t.UTC()
+}
+
+// nullString возвращает NULL для пустой строки, чтобы незаданные значения не отличались от старых записей.
+func nullString(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// scanURLRecords читает все записи о ссылках из результата запроса.
+func scanURLRecords(rows *sql.Rows) ([]storage.URLRecord, error) {
+	records := []storage.URLRecord{}
+
+	for rows.Next() {
+		rec, err := scanURLRecord(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan row: %w", err)
+		}
+		records = append(records, rec)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read rows: %w", err)
+	}
+
+	return records, nil
+}
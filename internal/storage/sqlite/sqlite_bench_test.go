@@ -0,0 +1,115 @@
+package sqlite_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/sqlite"
+)
+
+// benchLinks - сколько ссылок в базе при замерах чтения.
+const benchLinks = 1000
+
+// newBenchStorage создаёт файловое хранилище (как в работе сервиса) с benchLinks ссылками link0, link1, ...
+// и возвращает его вместе с путём к файлу базы.
+func newBenchStorage(b *testing.B) (*sqlite.Storage, string) {
+	b.Helper()
+
+	path := filepath.Join(b.TempDir(), "bench.db")
+
+	s, err := sqlite.New(slogdiscard.NewDiscardLogger(), path, sqlite.Options{})
+	require.NoError(b, err)
+	b.Cleanup(func() { require.NoError(b, s.Close()) })
+
+	for i := range benchLinks {
+		_, err := s.SaveURL("https://example.com/"+strconv.Itoa(i), "link"+strconv.Itoa(i), storage.URLOptions{})
+		require.NoError(b, err)
+	}
+
+	return s, path
+}
+
+func BenchmarkGetURL(b *testing.B) {
+	s, _ := newBenchStorage(b)
+
+	b.ReportAllocs()
+
+	for i := 0; b.Loop(); i++ {
+		if _, err := s.GetURL("link" + strconv.Itoa(i%benchLinks)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetURLParallel(b *testing.B) {
+	s, _ := newBenchStorage(b)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			if _, err := s.GetURL("link" + strconv.Itoa(i%benchLinks)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkGetNamespacedURLRecord замеряет чтение записи ссылки, через которое проходит каждый редирект.
+func BenchmarkGetNamespacedURLRecord(b *testing.B) {
+	s, _ := newBenchStorage(b)
+
+	b.ReportAllocs()
+
+	for i := 0; b.Loop(); i++ {
+		if _, err := s.GetNamespacedURLRecord(context.Background(), "", "link"+strconv.Itoa(i%benchLinks)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkGetNamespacedURLRecordUnprepared - прежняя реализация GetNamespacedURLRecord для сравнения
+// с BenchmarkGetNamespacedURLRecord: запрос без подготовки, database/sql готовит его на каждый вызов.
+func BenchmarkGetNamespacedURLRecordUnprepared(b *testing.B) {
+	_, path := newBenchStorage(b)
+
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(b, err)
+	b.Cleanup(func() { _ = db.Close() })
+
+	const query = "SELECT id, namespace, alias, url, permanent, expires_at, created_at, last_accessed_at, max_clicks, clicks, " +
+		"enabled, tags, preserve_method, headers, forward_path FROM url WHERE namespace = ? AND alias = ?"
+
+	dest := make([]any, 15)
+	for i := range dest {
+		dest[i] = new(any)
+	}
+
+	b.ReportAllocs()
+
+	for i := 0; b.Loop(); i++ {
+		if err := db.QueryRow(query, "", "link"+strconv.Itoa(i%benchLinks)).Scan(dest...); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSaveURL(b *testing.B) {
+	s, _ := newBenchStorage(b)
+
+	b.ReportAllocs()
+
+	for i := 0; b.Loop(); i++ {
+		if _, err := s.SaveURL("https://example.com/", "bench"+strconv.Itoa(i), storage.URLOptions{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
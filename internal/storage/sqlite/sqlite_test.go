@@ -0,0 +1,1409 @@
+package sqlite_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/sqlite"
+)
+
+func newStorage(t *testing.T) *sqlite.Storage {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	// Каждое соединение с ":memory:" открывает отдельную базу, поэтому пул ограничен одним соединением.
+	db.SetMaxOpenConns(1)
+
+	s, err := sqlite.NewWithDB(slogdiscard.NewDiscardLogger(), db, sqlite.Options{})
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, s.Close()) })
+
+	return s
+}
+
+func TestNew_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.db")
+
+	s, err := sqlite.New(slogdiscard.NewDiscardLogger(), path, sqlite.Options{})
+	require.NoError(t, err)
+
+	_, err = s.SaveURL("https://google.com", "google", storage.URLOptions{})
+	require.NoError(t, err)
+
+	// Повторное открытие не должно заново применять миграции и терять данные.
+	s, err = sqlite.New(slogdiscard.NewDiscardLogger(), path, sqlite.Options{})
+	require.NoError(t, err)
+
+	url, err := s.GetURL("google")
+	require.NoError(t, err)
+	require.Equal(t, "https://google.com", url)
+}
+
+func TestNew_Params(t *testing.T) {
+	cases := []struct {
+		name        string
+		params      map[string]string
+		journalMode string
+	}{
+		// Без параметров применяются значения по умолчанию.
+		{name: "Defaults", journalMode: "wal"},
+		// Заданный параметр переопределяет значение по умолчанию.
+		{name: "Override", params: map[string]string{"journal_mode": "DELETE", "foreign_keys": "on"}, journalMode: "delete"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "storage.db")
+
+			_, err := sqlite.New(slogdiscard.NewDiscardLogger(), path, sqlite.Options{Params: tc.params})
+			require.NoError(t, err)
+
+			// journal_mode хранится в самом файле базы, поэтому его видно из отдельного соединения.
+			db, err := sql.Open("sqlite3", path)
+			require.NoError(t, err)
+			t.Cleanup(func() { db.Close() })
+
+			var mode string
+			require.NoError(t, db.QueryRow("PRAGMA journal_mode").Scan(&mode))
+			require.Equal(t, tc.journalMode, mode)
+		})
+	}
+}
+
+func TestNew_UnknownParam(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.db")
+
+	_, err := sqlite.New(slogdiscard.NewDiscardLogger(), path, sqlite.Options{
+		Params: map[string]string{"foreign_keyz": "on"},
+	})
+	require.ErrorContains(t, err, `unknown sqlite parameter "foreign_keyz"`)
+}
+
+func TestNew_NestedDir(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "nested", "storage.db")
+
+	s, err := sqlite.New(slogdiscard.NewDiscardLogger(), path, sqlite.Options{})
+	require.NoError(t, err)
+
+	_, err = s.SaveURL("https://google.com", "google", storage.URLOptions{})
+	require.NoError(t, err)
+
+	require.FileExists(t, path)
+}
+
+func TestNew_DirCreationFails(t *testing.T) {
+	// Родительский "каталог" - обычный файл, поэтому создать каталог базы нельзя.
+	parent := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(parent, nil, 0o644))
+
+	_, err := sqlite.New(slogdiscard.NewDiscardLogger(), filepath.Join(parent, "data", "storage.db"), sqlite.Options{})
+	require.ErrorContains(t, err, "create storage directory")
+}
+
+func TestNew_PathIsDirectory(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := sqlite.New(slogdiscard.NewDiscardLogger(), dir, sqlite.Options{})
+	require.ErrorContains(t, err, "is a directory")
+}
+
+func TestNew_FileNotWritable(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root ignores file permissions")
+	}
+
+	path := filepath.Join(t.TempDir(), "storage.db")
+	require.NoError(t, os.WriteFile(path, nil, 0o444))
+
+	_, err := sqlite.New(slogdiscard.NewDiscardLogger(), path, sqlite.Options{})
+	require.ErrorContains(t, err, "is not writable")
+}
+
+func TestStorage_CaseInsensitiveAlias(t *testing.T) {
+	s := newStorage(t)
+
+	_, err := s.SaveURL("https://google.com", "MyLink", storage.URLOptions{})
+	require.NoError(t, err)
+
+	for _, alias := range []string{"MyLink", "mylink", "MYLINK", "myLiNk"} {
+		url, err := s.GetURL(alias)
+		require.NoError(t, err, alias)
+		require.Equal(t, "https://google.com", url)
+
+		rec, err := s.GetURLRecord(alias)
+		require.NoError(t, err, alias)
+		require.Equal(t, "mylink", rec.Alias)
+
+		exists, err := s.AliasExists(t.Context(), alias)
+		require.NoError(t, err, alias)
+		require.True(t, exists)
+	}
+
+	_, err = s.SaveURL("https://example.com", "MYLINK", storage.URLOptions{})
+	require.ErrorIs(t, err, storage.ErrURLExists)
+
+	deleted, err := s.DeleteURL("MYLINK")
+	require.NoError(t, err)
+	require.Equal(t, int64(1), deleted)
+
+	_, err = s.GetURL("mylink")
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+}
+
+func TestStorage_ExpiresAt(t *testing.T) {
+	s := newStorage(t)
+
+	expiresAt := time.Now().Add(time.Hour)
+
+	_, err := s.SaveURL("https://google.com", "expiring", storage.URLOptions{ExpiresAt: &expiresAt})
+	require.NoError(t, err)
+
+	_, err = s.SaveURL("https://google.com", "forever", storage.URLOptions{})
+	require.NoError(t, err)
+
+	rec, err := s.GetURLRecord("expiring")
+	require.NoError(t, err)
+	require.NotNil(t, rec.ExpiresAt)
+	require.True(t, expiresAt.Equal(*rec.ExpiresAt))
+	require.False(t, rec.Expired(time.Now()))
+	require.True(t, rec.Expired(expiresAt.Add(time.Second)))
+
+	rec, err = s.GetURLRecord("forever")
+	require.NoError(t, err)
+	require.Nil(t, rec.ExpiresAt)
+	require.False(t, rec.Expired(time.Now()))
+}
+
+func TestStorage_ListStale(t *testing.T) {
+	s := newStorage(t)
+
+	usedID, err := s.SaveURL("https://google.com", "used", storage.URLOptions{})
+	require.NoError(t, err)
+	_, err = s.SaveURL("https://google.com", "unused", storage.URLOptions{})
+	require.NoError(t, err)
+
+	cutoff := time.Now().Add(time.Minute)
+
+	require.NoError(t, s.UpdateLastAccessed(map[int64]time.Time{
+		usedID: cutoff.Add(time.Minute),
+	}))
+
+	rec, err := s.GetURLRecord("used")
+	require.NoError(t, err)
+	require.NotNil(t, rec.LastAccessedAt)
+
+	stale, err := s.ListStale(cutoff)
+	require.NoError(t, err)
+	require.Len(t, stale, 1)
+	require.Equal(t, "unused", stale[0].Alias)
+
+	stale, err = s.ListStale(time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	require.Empty(t, stale)
+}
+
+func TestStorage_RotateAlias(t *testing.T) {
+	s := newStorage(t)
+
+	_, err := s.SaveURL("https://google.com", "google", storage.URLOptions{})
+	require.NoError(t, err)
+	_, err = s.SaveURL("https://ya.ru", "yandex", storage.URLOptions{})
+	require.NoError(t, err)
+
+	require.NoError(t, s.RotateAlias("Google", "fresh"))
+
+	// Старый псевдоним сразу перестаёт работать, новый ведёт на тот же адрес.
+	_, err = s.GetURL("google")
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+
+	got, err := s.GetURL("fresh")
+	require.NoError(t, err)
+	require.Equal(t, "https://google.com", got)
+
+	// Занятый новый псевдоним не меняет ни одну из ссылок.
+	require.ErrorIs(t, s.RotateAlias("fresh", "yandex"), storage.ErrURLExists)
+
+	got, err = s.GetURL("fresh")
+	require.NoError(t, err)
+	require.Equal(t, "https://google.com", got)
+
+	require.ErrorIs(t, s.RotateAlias("missing", "other"), storage.ErrURLNotFound)
+}
+
+func TestStorage_DeleteURLRecord(t *testing.T) {
+	s := newStorage(t)
+
+	_, err := s.SaveURL("https://google.com", "google", storage.URLOptions{})
+	require.NoError(t, err)
+
+	rec, err := s.DeleteURLRecord("Google")
+	require.NoError(t, err)
+	require.Equal(t, "google", rec.Alias)
+	require.Equal(t, "https://google.com", rec.URL)
+
+	_, err = s.GetURL("google")
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+
+	_, err = s.DeleteURLRecord("google")
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+}
+
+func TestStorage_CountByCreator(t *testing.T) {
+	s := newStorage(t)
+
+	for _, alias := range []string{"a1", "a2"} {
+		_, err := s.SaveURL("https://google.com", alias, storage.URLOptions{CreatorIP: "192.0.2.1"})
+		require.NoError(t, err)
+	}
+	_, err := s.SaveURL("https://google.com", "b1", storage.URLOptions{CreatorIP: "192.0.2.2"})
+	require.NoError(t, err)
+	_, err = s.SaveURL("https://google.com", "anon", storage.URLOptions{})
+	require.NoError(t, err)
+
+	count, err := s.CountByCreator("192.0.2.1")
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+
+	count, err = s.CountByCreator("198.51.100.1")
+	require.NoError(t, err)
+	require.Equal(t, 0, count)
+}
+
+func TestStorage_Namespaces(t *testing.T) {
+	s := newStorage(t)
+
+	_, err := s.SaveURL("https://google.com", "home", storage.URLOptions{})
+	require.NoError(t, err)
+	_, err = s.SaveURL("https://ya.ru", "home", storage.URLOptions{Namespace: "Docs"})
+	require.NoError(t, err)
+	_, err = s.SaveURL("https://go.dev", "home", storage.URLOptions{Namespace: "blog"})
+	require.NoError(t, err)
+
+	// Псевдоним уникален только внутри пространства имён.
+	_, err = s.SaveURL("https://example.com", "HOME", storage.URLOptions{Namespace: "docs"})
+	require.ErrorIs(t, err, storage.ErrURLExists)
+
+	rec, err := s.GetNamespacedURLRecord(context.Background(), "docs", "home")
+	require.NoError(t, err)
+	require.Equal(t, "https://ya.ru", rec.URL)
+	require.Equal(t, "docs", rec.Namespace)
+
+	// Методы без пространства имён работают с пространством имён по умолчанию.
+	url, err := s.GetURL("home")
+	require.NoError(t, err)
+	require.Equal(t, "https://google.com", url)
+
+	_, err = s.DeleteURL("home")
+	require.NoError(t, err)
+
+	_, err = s.GetNamespacedURLRecord(context.Background(), "docs", "home")
+	require.NoError(t, err)
+
+	_, err = s.GetNamespacedURLRecord(context.Background(), "", "home")
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+}
+
+func TestStorage_GetNamespacedURLRecordContext(t *testing.T) {
+	s := newStorage(t)
+
+	_, err := s.SaveURL("https://google.com", "google", storage.URLOptions{})
+	require.NoError(t, err)
+
+	// Истёкший контекст запроса прерывает чтение, а не возвращает запись или ErrURLNotFound.
+	ctx, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+
+	_, err = s.GetNamespacedURLRecord(ctx, "", "google")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.NotErrorIs(t, err, storage.ErrURLNotFound)
+}
+
+func TestStorage_WithTx(t *testing.T) {
+	s := newStorage(t)
+
+	_, err := s.SaveURL("https://google.com", "google", storage.URLOptions{})
+	require.NoError(t, err)
+
+	// Ошибка функции откатывает все изменения транзакции и возвращается как есть.
+	errAbort := errors.New("abort")
+	err = s.WithTx(t.Context(), func(tx storage.Tx) error {
+		if _, err := tx.SaveURL("https://ya.ru", "yandex", storage.URLOptions{}); err != nil {
+			return err
+		}
+		if _, err := tx.DeleteURL("google"); err != nil {
+			return err
+		}
+
+		// Внутри транзакции её изменения уже видны.
+		_, err := tx.GetURL("yandex")
+		require.NoError(t, err)
+
+		return errAbort
+	})
+	require.ErrorIs(t, err, errAbort)
+
+	_, err = s.GetURL("yandex")
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+	_, err = s.GetURL("google")
+	require.NoError(t, err)
+
+	// При успешном завершении изменения фиксируются.
+	err = s.WithTx(t.Context(), func(tx storage.Tx) error {
+		if _, err := tx.SaveURL("https://ya.ru", "yandex", storage.URLOptions{}); err != nil {
+			return err
+		}
+		return tx.RotateAlias("google", "search")
+	})
+	require.NoError(t, err)
+
+	rec, err := s.GetURLRecord("search")
+	require.NoError(t, err)
+	require.Equal(t, "https://google.com", rec.URL)
+	_, err = s.GetURL("yandex")
+	require.NoError(t, err)
+}
+
+func TestStorage_BulkDeleteURL(t *testing.T) {
+	s := newStorage(t)
+
+	for _, alias := range []string{"a1", "a2", "keep"} {
+		_, err := s.SaveURL("https://google.com", alias, storage.URLOptions{})
+		require.NoError(t, err)
+	}
+	_, err := s.SaveURL("https://google.com", "a1", storage.URLOptions{Namespace: "docs"})
+	require.NoError(t, err)
+
+	deleted, err := s.BulkDeleteURL([]string{"A1", "a2", "missing"})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), deleted)
+
+	_, err = s.GetURL("a1")
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+	_, err = s.GetURL("keep")
+	require.NoError(t, err)
+
+	// Удаляются ссылки только пространства имён по умолчанию.
+	_, err = s.GetNamespacedURLRecord(context.Background(), "docs", "a1")
+	require.NoError(t, err)
+
+	deleted, err = s.BulkDeleteURL(nil)
+	require.NoError(t, err)
+	require.Zero(t, deleted)
+}
+
+func TestStorage_GetURLs(t *testing.T) {
+	s := newStorage(t)
+
+	_, err := s.SaveURL("https://google.com", "google", storage.URLOptions{})
+	require.NoError(t, err)
+	_, err = s.SaveURL("https://ya.ru", "ya", storage.URLOptions{})
+	require.NoError(t, err)
+	_, err = s.SaveURL("https://docs.example.com", "docs", storage.URLOptions{Namespace: "team"})
+	require.NoError(t, err)
+
+	// Отсутствующие псевдонимы и ссылки других пространств имён в результат не попадают.
+	urls, err := s.GetURLs([]string{"Google", "ya", "missing", "docs"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"google": "https://google.com",
+		"ya":     "https://ya.ru",
+	}, urls)
+
+	urls, err = s.GetURLs(nil)
+	require.NoError(t, err)
+	require.Empty(t, urls)
+}
+
+func TestStorage_ConsumeClick(t *testing.T) {
+	s := newStorage(t)
+
+	id, err := s.SaveURL("https://google.com", "promo", storage.URLOptions{MaxClicks: 2})
+	require.NoError(t, err)
+
+	require.NoError(t, s.ConsumeClick(id))
+	require.NoError(t, s.ConsumeClick(id))
+	require.ErrorIs(t, s.ConsumeClick(id), storage.ErrClicksExhausted)
+
+	rec, err := s.GetURLRecord("promo")
+	require.NoError(t, err)
+	require.Equal(t, int64(2), rec.MaxClicks)
+	require.Equal(t, int64(2), rec.Clicks)
+	require.True(t, rec.Exhausted())
+
+	// max_clicks = 0 - переходы не ограничены.
+	id, err = s.SaveURL("https://google.com", "unlimited", storage.URLOptions{})
+	require.NoError(t, err)
+
+	for range 5 {
+		require.NoError(t, s.ConsumeClick(id))
+	}
+
+	rec, err = s.GetURLRecord("unlimited")
+	require.NoError(t, err)
+	require.False(t, rec.Exhausted())
+}
+
+func TestStorage_AddClicks(t *testing.T) {
+	s := newStorage(t)
+
+	unlimitedID, err := s.SaveURL("https://google.com", "unlimited", storage.URLOptions{})
+	require.NoError(t, err)
+	limitedID, err := s.SaveURL("https://google.com", "promo", storage.URLOptions{MaxClicks: 2})
+	require.NoError(t, err)
+
+	require.NoError(t, s.AddClicks(map[int64]int64{unlimitedID: 3}))
+	require.NoError(t, s.AddClicks(map[int64]int64{unlimitedID: 2, limitedID: 5}))
+	require.NoError(t, s.AddClicks(nil))
+
+	rec, err := s.GetURLRecord("unlimited")
+	require.NoError(t, err)
+	require.Equal(t, int64(5), rec.Clicks)
+
+	// Переходы по ссылке с max_clicks засчитывает только ConsumeClick, пачка их не трогает.
+	rec, err = s.GetURLRecord("promo")
+	require.NoError(t, err)
+	require.Equal(t, int64(0), rec.Clicks)
+}
+
+func TestStorage_ConsumeClickConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.db")
+
+	s, err := sqlite.New(slogdiscard.NewDiscardLogger(), path, sqlite.Options{})
+	require.NoError(t, err)
+
+	id, err := s.SaveURL("https://google.com", "promo", storage.URLOptions{MaxClicks: 5})
+	require.NoError(t, err)
+
+	// Условный UPDATE не пропускает больше max_clicks переходов даже при одновременных запросах.
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		allowed int
+	)
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			err := s.ConsumeClick(id)
+			if err == nil {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, 5, allowed)
+}
+
+func TestStorage_ListURLsAfter(t *testing.T) {
+	s := newStorage(t)
+
+	var ids []int64
+	for _, alias := range []string{"a1", "a2", "a3"} {
+		id, err := s.SaveURL("https://google.com", alias, storage.URLOptions{})
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+	id, err := s.SaveURL("https://google.com", "a1", storage.URLOptions{Namespace: "docs"})
+	require.NoError(t, err)
+	ids = append(ids, id)
+
+	page, err := s.ListURLsAfter(0, 2)
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	require.Equal(t, ids[0], page[0].ID)
+	require.Equal(t, ids[1], page[1].ID)
+
+	// Следующая страница начинается после ID последней ссылки и включает другие пространства имён.
+	page, err = s.ListURLsAfter(page[1].ID, 2)
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	require.Equal(t, ids[2], page[0].ID)
+	require.Equal(t, "docs", page[1].Namespace)
+
+	page, err = s.ListURLsAfter(ids[3], 2)
+	require.NoError(t, err)
+	require.Empty(t, page)
+}
+
+func TestStorage_ListByDateRange(t *testing.T) {
+	s := newStorage(t)
+
+	_, err := s.SaveURL("https://google.com", "a1", storage.URLOptions{})
+	require.NoError(t, err)
+	middle := time.Now()
+	_, err = s.SaveURL("https://google.com", "a2", storage.URLOptions{})
+	require.NoError(t, err)
+	_, err = s.SaveURL("https://google.com", "a1", storage.URLOptions{Namespace: "docs"})
+	require.NoError(t, err)
+
+	aliases := func(records []storage.URLRecord) []string {
+		res := make([]string, 0, len(records))
+		for _, rec := range records {
+			res = append(res, rec.Namespace+"/"+rec.Alias)
+		}
+		return res
+	}
+
+	// Промежуток без границ возвращает все ссылки по порядку создания.
+	all, err := s.ListByDateRange(time.Time{}, time.Time{}, 10, 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"/a1", "/a2", "docs/a1"}, aliases(all))
+
+	// Ограничен только с одной стороны.
+	page, err := s.ListByDateRange(middle, time.Time{}, 10, 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"/a2", "docs/a1"}, aliases(page))
+
+	page, err = s.ListByDateRange(time.Time{}, middle, 10, 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"/a1"}, aliases(page))
+
+	// Границы включаются в промежуток.
+	page, err = s.ListByDateRange(*all[1].CreatedAt, *all[1].CreatedAt, 10, 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"/a2"}, aliases(page))
+
+	// Постраничный вывод.
+	page, err = s.ListByDateRange(time.Time{}, time.Now(), 2, 1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"/a2", "docs/a1"}, aliases(page))
+
+	page, err = s.ListByDateRange(time.Now().Add(time.Hour), time.Time{}, 10, 0)
+	require.NoError(t, err)
+	require.Empty(t, page)
+}
+
+func TestStorage_Summary(t *testing.T) {
+	s := newStorage(t)
+
+	empty, err := s.Summary(time.Time{}, 10)
+	require.NoError(t, err)
+	require.Zero(t, empty.TotalLinks)
+	require.Zero(t, empty.TotalClicks)
+	require.Empty(t, empty.Top)
+
+	clicks := map[string]int{"a1": 1, "a2": 3, "a3": 0}
+	for _, alias := range []string{"a1", "a2", "a3"} {
+		id, err := s.SaveURL("https://google.com", alias, storage.URLOptions{})
+		require.NoError(t, err)
+		for range clicks[alias] {
+			require.NoError(t, s.ConsumeClick(id))
+		}
+	}
+
+	summary, err := s.Summary(time.Now().Add(-time.Hour), 2)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), summary.TotalLinks)
+	require.Equal(t, int64(4), summary.TotalClicks)
+	require.Equal(t, int64(3), summary.CreatedSince)
+
+	// В топ попадают ссылки с наибольшим числом переходов, не больше top.
+	require.Len(t, summary.Top, 2)
+	require.Equal(t, "a2", summary.Top[0].Alias)
+	require.Equal(t, int64(3), summary.Top[0].Clicks)
+	require.Equal(t, "a1", summary.Top[1].Alias)
+
+	summary, err = s.Summary(time.Now().Add(time.Hour), 2)
+	require.NoError(t, err)
+	require.Zero(t, summary.CreatedSince)
+}
+
+func TestStorage_SaveURLSequential(t *testing.T) {
+	s := newStorage(t)
+
+	encode := func(id int64) string {
+		// Псевдоним для ID 4 считается зарезервированным.
+		if id == 4 {
+			return ""
+		}
+		return "s" + strconv.FormatInt(id, 10)
+	}
+
+	alias, id, err := s.SaveURLSequential("https://google.com", storage.URLOptions{}, encode)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), id)
+	require.Equal(t, "s1", alias)
+
+	// Псевдоним следующего ID уже занят пользователем, а следующий за ним зарезервирован,
+	// поэтому ссылка переносится через оба ID.
+	_, err = s.SaveURL("https://ya.ru", "s3", storage.URLOptions{})
+	require.NoError(t, err)
+
+	alias, id, err = s.SaveURLSequential("https://google.com/2", storage.URLOptions{}, encode)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), id)
+	require.Equal(t, "s5", alias)
+
+	got, err := s.GetURL("s5")
+	require.NoError(t, err)
+	require.Equal(t, "https://google.com/2", got)
+
+	rec, err := s.GetURLRecord("s5")
+	require.NoError(t, err)
+	require.Equal(t, id, rec.ID)
+
+	// Временный псевдоним не остаётся в базе.
+	_, err = s.GetURL("#pending")
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+}
+
+func TestStorage_MigrationLog(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	db.SetMaxOpenConns(1)
+
+	var logs bytes.Buffer
+	_, err = sqlite.NewWithDB(slog.New(slog.NewJSONHandler(&logs, nil)), db, sqlite.Options{})
+	require.NoError(t, err)
+
+	// Миграции пишутся в логгер хранилища, а не в slog.Default().
+	require.Contains(t, logs.String(), `"msg":"applied migration"`)
+	require.Contains(t, logs.String(), `"op":"storage.sqlite.migrate"`)
+}
+
+func TestStorage_MigrationLowercaseAliases(t *testing.T) {
+	// legacyDB создаёт базу в том виде, в каком она была до миграций: псевдонимы чувствительны к регистру.
+	legacyDB := func(t *testing.T, aliases ...string) *sql.DB {
+		db, err := sql.Open("sqlite3", ":memory:")
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = db.Close() })
+		db.SetMaxOpenConns(1)
+
+		_, err = db.Exec("CREATE TABLE url(id INTEGER PRIMARY KEY, alias TEXT NOT NULL UNIQUE, url TEXT NOT NULL)")
+		require.NoError(t, err)
+		for _, alias := range aliases {
+			_, err = db.Exec("INSERT INTO url(alias, url) VALUES (?, ?)", alias, "https://example.com/"+alias)
+			require.NoError(t, err)
+		}
+
+		return db
+	}
+
+	t.Run("No conflicts", func(t *testing.T) {
+		s, err := sqlite.NewWithDB(slogdiscard.NewDiscardLogger(), legacyDB(t, "Docs", "blog"), sqlite.Options{})
+		require.NoError(t, err)
+
+		rec, err := s.GetURLRecord("DOCS")
+		require.NoError(t, err)
+		require.Equal(t, "docs", rec.Alias)
+		require.Equal(t, "https://example.com/Docs", rec.URL)
+	})
+
+	t.Run("Conflicts", func(t *testing.T) {
+		// Ссылки, различающиеся только регистром, не пропускаются молча: миграция падает и называет их.
+		_, err := sqlite.NewWithDB(slogdiscard.NewDiscardLogger(), legacyDB(t, "Docs", "docs", "DOCS", "Blog", "blog", "news"), sqlite.Options{})
+		require.ErrorContains(t, err, "aliases differ only in case")
+		require.ErrorContains(t, err, "Blog, blog")
+		require.ErrorContains(t, err, "DOCS, Docs, docs")
+		require.NotContains(t, err.Error(), "news")
+	})
+}
+
+func TestStorage_SlowQueryLog(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	db.SetMaxOpenConns(1)
+
+	var logs bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&logs, nil))
+
+	// Порог в 1нс превышает любой запрос.
+	s, err := sqlite.NewWithDB(log, db, sqlite.Options{SlowQueryThreshold: time.Nanosecond})
+	require.NoError(t, err)
+
+	_, err = s.SaveURL("https://google.com/search?q=secret", "google", storage.URLOptions{})
+	require.NoError(t, err)
+
+	require.Contains(t, logs.String(), `"msg":"slow query"`)
+	require.Contains(t, logs.String(), `"op":"storage.sqlite.SaveURL"`)
+	require.Contains(t, logs.String(), `"host":"google.com"`)
+	// В лог попадает только хост адреса, без пути и параметров.
+	require.NotContains(t, logs.String(), "secret")
+
+	// С нулевым порогом журнал выключен.
+	logs.Reset()
+	s, err = sqlite.NewWithDB(log, db, sqlite.Options{})
+	require.NoError(t, err)
+
+	_, err = s.GetURL("google")
+	require.NoError(t, err)
+	require.Empty(t, logs.String())
+}
+
+func TestStorage_UniqueURL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.db")
+
+	s, err := sqlite.New(slogdiscard.NewDiscardLogger(), path, sqlite.Options{UniqueURL: true})
+	require.NoError(t, err)
+
+	id, err := s.SaveURL("https://google.com", "google", storage.URLOptions{})
+	require.NoError(t, err)
+
+	// Тот же адрес под другим псевдонимом отклоняется, а занятый псевдоним по-прежнему даёт ErrURLExists.
+	_, err = s.SaveURL("https://google.com", "other", storage.URLOptions{})
+	require.ErrorIs(t, err, storage.ErrURLDuplicate)
+
+	_, err = s.SaveURL("https://ya.ru", "google", storage.URLOptions{})
+	require.ErrorIs(t, err, storage.ErrURLExists)
+
+	_, _, err = s.SaveURLSequential("https://google.com", storage.URLOptions{}, func(id int64) string { return "s" })
+	require.ErrorIs(t, err, storage.ErrURLDuplicate)
+
+	rec, err := s.GetURLRecordByURL("https://google.com")
+	require.NoError(t, err)
+	require.Equal(t, id, rec.ID)
+	require.Equal(t, "google", rec.Alias)
+
+	_, err = s.GetURLRecordByURL("https://ya.ru")
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+
+	// После выключения режима индекс удаляется и дубликаты снова разрешены.
+	s, err = sqlite.New(slogdiscard.NewDiscardLogger(), path, sqlite.Options{})
+	require.NoError(t, err)
+
+	_, err = s.SaveURL("https://google.com", "other", storage.URLOptions{})
+	require.NoError(t, err)
+
+	// Включить режим при уже существующих дубликатах нельзя.
+	_, err = sqlite.New(slogdiscard.NewDiscardLogger(), path, sqlite.Options{UniqueURL: true})
+	require.ErrorContains(t, err, "duplicate urls")
+}
+
+func TestStorage_UniqueCanonicalAlias(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.db")
+
+	// Похожие псевдонимы, сохранённые до включения режима, заполняются при включении и не дают создать индекс.
+	s, err := sqlite.New(slogdiscard.NewDiscardLogger(), path, sqlite.Options{})
+	require.NoError(t, err)
+
+	_, err = s.SaveURL("https://mail.example.com", "mail", storage.URLOptions{})
+	require.NoError(t, err)
+	_, err = s.SaveURL("https://evil.example.com", "rnail", storage.URLOptions{})
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	_, err = sqlite.New(slogdiscard.NewDiscardLogger(), path, sqlite.Options{UniqueCanonicalAlias: true})
+	require.ErrorContains(t, err, "aliases that look alike")
+
+	s, err = sqlite.New(slogdiscard.NewDiscardLogger(), path, sqlite.Options{})
+	require.NoError(t, err)
+	_, err = s.DeleteURL("rnail")
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	s, err = sqlite.New(slogdiscard.NewDiscardLogger(), path, sqlite.Options{UniqueCanonicalAlias: true})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = s.Close() })
+
+	cases := []struct {
+		alias string
+		ok    bool
+	}{
+		{alias: "rnail", ok: false},
+		{alias: "MAIL", ok: false},
+		{alias: "g00gle", ok: true},
+		{alias: "google", ok: false},
+		{alias: "goog1e", ok: false},
+		{alias: "vvide", ok: true},
+		{alias: "wide", ok: false},
+		{alias: "other", ok: true},
+	}
+
+	for _, tc := range cases {
+		_, err := s.SaveURL("https://example.com/"+tc.alias, tc.alias, storage.URLOptions{})
+		if tc.ok {
+			require.NoError(t, err, tc.alias)
+			continue
+		}
+
+		// Псевдоним, совпадающий дословно, - обычная коллизия, похожий - ещё и ErrAliasConfusable.
+		require.ErrorIs(t, err, storage.ErrURLExists, tc.alias)
+		if tc.alias != "MAIL" {
+			require.ErrorIs(t, err, storage.ErrAliasConfusable, tc.alias)
+		}
+	}
+
+	// Похожие псевдонимы в разных пространствах имён не конфликтуют.
+	_, err = s.SaveURL("https://example.com/docs", "rnail", storage.URLOptions{Namespace: "docs"})
+	require.NoError(t, err)
+
+	// Ротация тоже проверяет канонический вид нового псевдонима.
+	err = s.RotateAlias("other", "go0gle")
+	require.ErrorIs(t, err, storage.ErrAliasConfusable)
+	require.NoError(t, s.RotateAlias("other", "fresh"))
+	_, err = s.SaveURL("https://example.com/fre5h", "fre5h", storage.URLOptions{})
+	require.ErrorIs(t, err, storage.ErrAliasConfusable)
+}
+
+func TestNew_VerifySchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.db")
+
+	s, err := sqlite.New(slogdiscard.NewDiscardLogger(), path, sqlite.Options{})
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	indexExists := func(name string) bool {
+		var count int
+		require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND name = ?", name).Scan(&count))
+		return count > 0
+	}
+
+	// Индексы пропали, как в базе, созданной версией с ошибкой в миграции.
+	_, err = db.Exec("DROP INDEX idx_namespace_alias; DROP INDEX idx_url")
+	require.NoError(t, err)
+
+	// Без проверки схемы индексы не восстанавливаются.
+	s, err = sqlite.New(slogdiscard.NewDiscardLogger(), path, sqlite.Options{})
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+	require.False(t, indexExists("idx_namespace_alias"))
+
+	var logs bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&logs, nil))
+
+	s, err = sqlite.New(log, path, sqlite.Options{VerifySchema: true})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = s.Close() })
+
+	require.True(t, indexExists("idx_namespace_alias"))
+	require.True(t, indexExists("idx_url"))
+	require.Equal(t, 2, strings.Count(logs.String(), `"msg":"index is missing, recreating it"`))
+
+	// Восстановленный индекс снова не даёт занять псевдоним дважды.
+	_, err = s.SaveURL("https://google.com", "google", storage.URLOptions{})
+	require.NoError(t, err)
+	_, err = s.SaveURL("https://ya.ru", "google", storage.URLOptions{})
+	require.ErrorIs(t, err, storage.ErrURLExists)
+}
+
+func TestNew_VerifySchemaDuplicates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.db")
+
+	s, err := sqlite.New(slogdiscard.NewDiscardLogger(), path, sqlite.Options{})
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	// Пока уникального индекса не было, псевдоним успели занять дважды.
+	_, err = db.Exec(`
+		DROP INDEX idx_namespace_alias;
+		INSERT INTO url(url, alias) VALUES('https://google.com', 'google'), ('https://ya.ru', 'google');
+	`)
+	require.NoError(t, err)
+
+	_, err = sqlite.New(slogdiscard.NewDiscardLogger(), path, sqlite.Options{VerifySchema: true})
+	require.ErrorContains(t, err, "duplicate rows")
+}
+
+func TestStorage_APIKeys(t *testing.T) {
+	s := newStorage(t)
+
+	saved, err := s.SaveAPIKey("ci", "0123456789ab", []byte("hash"))
+	require.NoError(t, err)
+	require.NotZero(t, saved.ID)
+
+	key, err := s.GetAPIKey("0123456789ab")
+	require.NoError(t, err)
+	require.Equal(t, saved.ID, key.ID)
+	require.Equal(t, "ci", key.Name)
+	require.Equal(t, []byte("hash"), key.Hash)
+	require.WithinDuration(t, time.Now(), key.CreatedAt, time.Minute)
+
+	// Префикс уникален.
+	_, err = s.SaveAPIKey("other", "0123456789ab", []byte("hash2"))
+	require.Error(t, err)
+
+	_, err = s.GetAPIKey("ba9876543210")
+	require.ErrorIs(t, err, storage.ErrAPIKeyNotFound)
+
+	// Отозванный ключ больше не находится, повторный отзыв - ErrAPIKeyNotFound.
+	require.NoError(t, s.DeleteAPIKey(saved.ID))
+
+	_, err = s.GetAPIKey("0123456789ab")
+	require.ErrorIs(t, err, storage.ErrAPIKeyNotFound)
+
+	require.ErrorIs(t, s.DeleteAPIKey(saved.ID), storage.ErrAPIKeyNotFound)
+}
+
+func TestStorage_ListAliasesByURL(t *testing.T) {
+	s := newStorage(t)
+
+	_, err := s.SaveURL("https://google.com", "google", storage.URLOptions{})
+	require.NoError(t, err)
+	_, err = s.SaveURL("https://ya.ru", "ya", storage.URLOptions{})
+	require.NoError(t, err)
+	_, err = s.SaveURL("https://google.com", "home", storage.URLOptions{Namespace: "team"})
+	require.NoError(t, err)
+	// Адрес сравнивается точно: другой путь - другой адрес.
+	_, err = s.SaveURL("https://google.com/", "slash", storage.URLOptions{})
+	require.NoError(t, err)
+
+	aliases, err := s.ListAliasesByURL("https://google.com")
+	require.NoError(t, err)
+	require.Equal(t, []string{"google", "team/home"}, aliases)
+
+	// Для адреса без ссылок возвращается пустой список, а не nil.
+	aliases, err = s.ListAliasesByURL("https://example.com")
+	require.NoError(t, err)
+	require.NotNil(t, aliases)
+	require.Empty(t, aliases)
+}
+
+func TestStorage_SetEnabled(t *testing.T) {
+	s := newStorage(t)
+
+	id, err := s.SaveURL("https://google.com", "google", storage.URLOptions{})
+	require.NoError(t, err)
+	require.NoError(t, s.ConsumeClick(id))
+
+	// Новая ссылка включена.
+	rec, err := s.GetURLRecord("google")
+	require.NoError(t, err)
+	require.False(t, rec.Disabled)
+
+	require.NoError(t, s.SetEnabled("Google", false))
+
+	// Выключенная ссылка остаётся в хранилище вместе со счётчиком переходов.
+	rec, err = s.GetURLRecord("google")
+	require.NoError(t, err)
+	require.True(t, rec.Disabled)
+	require.Equal(t, int64(1), rec.Clicks)
+
+	// Повторное выключение не является ошибкой.
+	require.NoError(t, s.SetEnabled("google", false))
+
+	require.NoError(t, s.SetEnabled("google", true))
+
+	rec, err = s.GetURLRecord("google")
+	require.NoError(t, err)
+	require.False(t, rec.Disabled)
+
+	require.ErrorIs(t, s.SetEnabled("missing", false), storage.ErrURLNotFound)
+}
+
+func TestStorage_SetExpiry(t *testing.T) {
+	s := newStorage(t)
+
+	_, err := s.SaveURL("https://google.com", "google", storage.URLOptions{})
+	require.NoError(t, err)
+
+	expiresAt := time.Now().Add(72 * time.Hour).Truncate(time.Second)
+	require.NoError(t, s.SetExpiry("google", &expiresAt))
+
+	rec, err := s.GetURLRecord("google")
+	require.NoError(t, err)
+	require.NotNil(t, rec.ExpiresAt)
+	require.True(t, expiresAt.Equal(*rec.ExpiresAt))
+
+	// nil снимает ограничение срока.
+	require.NoError(t, s.SetExpiry("google", nil))
+
+	rec, err = s.GetURLRecord("google")
+	require.NoError(t, err)
+	require.Nil(t, rec.ExpiresAt)
+
+	require.ErrorIs(t, s.SetExpiry("missing", nil), storage.ErrURLNotFound)
+}
+
+func TestStorage_PreserveMethod(t *testing.T) {
+	s := newStorage(t)
+
+	preserve := true
+	_, err := s.SaveURL("https://api.example.com/v1", "api1", storage.URLOptions{PreserveMethod: &preserve})
+	require.NoError(t, err)
+	_, err = s.SaveURL("https://google.com", "google", storage.URLOptions{})
+	require.NoError(t, err)
+
+	rec, err := s.GetURLRecord("api1")
+	require.NoError(t, err)
+	require.NotNil(t, rec.PreserveMethod)
+	require.True(t, *rec.PreserveMethod)
+
+	// Без явного значения действует глобальная настройка redirect.preserve_method.
+	rec, err = s.GetURLRecord("google")
+	require.NoError(t, err)
+	require.Nil(t, rec.PreserveMethod)
+}
+
+func TestStorage_Ping(t *testing.T) {
+	s := newStorage(t)
+
+	require.NoError(t, s.Ping(context.Background()))
+
+	// Отменённый контекст прерывает запрос.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.Error(t, s.Ping(ctx))
+}
+
+func TestStorage_Headers(t *testing.T) {
+	s := newStorage(t)
+
+	headers := map[string]string{"Referrer-Policy": "no-referrer", "X-Robots-Tag": ""}
+	_, err := s.SaveURL("https://partner.example.com", "partner", storage.URLOptions{Headers: headers})
+	require.NoError(t, err)
+	_, err = s.SaveURL("https://google.com", "google", storage.URLOptions{})
+	require.NoError(t, err)
+
+	// Пустое значение сохраняется: оно убирает глобальный заголовок у ссылки.
+	rec, err := s.GetURLRecord("partner")
+	require.NoError(t, err)
+	require.Equal(t, headers, rec.Headers)
+
+	rec, err = s.GetURLRecord("google")
+	require.NoError(t, err)
+	require.Nil(t, rec.Headers)
+}
+
+func TestStorage_ForwardPath(t *testing.T) {
+	s := newStorage(t)
+
+	_, err := s.SaveURL("https://example.com/guide", "guide", storage.URLOptions{ForwardPath: true})
+	require.NoError(t, err)
+	_, err = s.SaveURL("https://google.com", "google", storage.URLOptions{})
+	require.NoError(t, err)
+
+	rec, err := s.GetNamespacedURLRecord(context.Background(), "", "guide")
+	require.NoError(t, err)
+	require.True(t, rec.ForwardPath)
+
+	rec, err = s.GetNamespacedURLRecord(context.Background(), "", "google")
+	require.NoError(t, err)
+	require.False(t, rec.ForwardPath)
+}
+
+func TestStorage_SearchURLs(t *testing.T) {
+	s := newStorage(t)
+
+	for alias, url := range map[string]string{
+		"spring":   "https://shop.example.com/sale/spring",
+		"percent":  "https://example.com/discount?off=50%25",
+		"under":    "https://example.com/new_arrivals",
+		"underish": "https://example.com/newXarrivals",
+		"campaign": "https://google.com",
+	} {
+		_, err := s.SaveURL(url, alias, storage.URLOptions{})
+		require.NoError(t, err)
+	}
+	_, err := s.SaveURL("https://example.com/team", "home", storage.URLOptions{Namespace: "team"})
+	require.NoError(t, err)
+
+	aliases := func(records []storage.URLRecord) []string {
+		res := []string{}
+		for _, rec := range records {
+			res = append(res, rec.Alias)
+		}
+		slices.Sort(res)
+		return res
+	}
+
+	records, err := s.SearchURLs("SHOP.example", 10)
+	require.NoError(t, err)
+	require.Equal(t, []string{"spring"}, aliases(records))
+
+	// Поиск идёт и по псевдониму, во всех пространствах имён.
+	records, err = s.SearchURLs("campaign", 10)
+	require.NoError(t, err)
+	require.Equal(t, []string{"campaign"}, aliases(records))
+
+	records, err = s.SearchURLs("/team", 10)
+	require.NoError(t, err)
+	require.Equal(t, []string{"home"}, aliases(records))
+
+	// % и _ ищутся буквально, а не как шаблон LIKE.
+	records, err = s.SearchURLs("new_arr", 10)
+	require.NoError(t, err)
+	require.Equal(t, []string{"under"}, aliases(records))
+
+	records, err = s.SearchURLs("50%", 10)
+	require.NoError(t, err)
+	require.Equal(t, []string{"percent"}, aliases(records))
+
+	records, err = s.SearchURLs("example", 2)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+
+	records, err = s.SearchURLs("missing", 10)
+	require.NoError(t, err)
+	require.Empty(t, records)
+	require.NotNil(t, records)
+}
+
+func TestStorage_Tags(t *testing.T) {
+	s := newStorage(t)
+
+	_, err := s.SaveURL("https://google.com", "google", storage.URLOptions{Tags: []string{"docs", "campaign", "docs"}})
+	require.NoError(t, err)
+	_, err = s.SaveURL("https://ya.ru", "yandex", storage.URLOptions{Tags: []string{"old-docs"}})
+	require.NoError(t, err)
+	_, err = s.SaveURL("https://example.com", "example", storage.URLOptions{})
+	require.NoError(t, err)
+	_, err = s.SaveURL("https://go.dev", "go", storage.URLOptions{Tags: []string{"docs"}})
+	require.NoError(t, err)
+
+	// Метки возвращаются упорядоченными и без повторов.
+	rec, err := s.GetURLRecord("google")
+	require.NoError(t, err)
+	require.Equal(t, []string{"campaign", "docs"}, rec.Tags)
+
+	rec, err = s.GetURLRecord("example")
+	require.NoError(t, err)
+	require.Nil(t, rec.Tags)
+
+	// Совпадение точное: "docs" не находит ссылку с меткой "old-docs".
+	records, err := s.ListURLsByTag("docs", 0, 10)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	require.Equal(t, "google", records[0].Alias)
+	require.Equal(t, "go", records[1].Alias)
+
+	// Постраничный вывод по курсору.
+	records, err = s.ListURLsByTag("docs", records[0].ID, 10)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.Equal(t, "go", records[0].Alias)
+
+	records, err = s.ListURLsByTag("doc", 0, 10)
+	require.NoError(t, err)
+	require.Empty(t, records)
+}
+
+func TestNew_CorruptFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "storage.db")
+
+	// Мусор вместо базы: SQLite отвечает "file is not a database".
+	garbage := bytes.Repeat([]byte("not a sqlite database "), 100)
+	require.NoError(t, os.WriteFile(path, garbage, 0o644))
+
+	_, err := sqlite.New(slogdiscard.NewDiscardLogger(), path, sqlite.Options{})
+	require.ErrorContains(t, err, "database file "+path+" is corrupted")
+	require.ErrorContains(t, err, "recover_corrupt")
+
+	// Без recover_corrupt файл не трогается.
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, garbage, data)
+
+	s, err := sqlite.New(slogdiscard.NewDiscardLogger(), path, sqlite.Options{RecoverCorrupt: true})
+	require.NoError(t, err)
+
+	_, err = s.SaveURL("https://google.com", "google", storage.URLOptions{})
+	require.NoError(t, err)
+
+	// Повреждённый файл сохранён рядом для разбора.
+	moved, err := filepath.Glob(path + ".corrupt-*")
+	require.NoError(t, err)
+	require.Len(t, moved, 1)
+
+	data, err = os.ReadFile(moved[0])
+	require.NoError(t, err)
+	require.Equal(t, garbage, data)
+}
+
+func TestStorage_ReserveAlias(t *testing.T) {
+	s := newStorage(t)
+
+	_, err := s.SaveURL("https://google.com", "google", storage.URLOptions{})
+	require.NoError(t, err)
+
+	// Занятый псевдоним зарезервировать нельзя.
+	_, err = s.ReserveAlias("google", time.Minute)
+	require.ErrorIs(t, err, storage.ErrURLExists)
+	require.NotErrorIs(t, err, storage.ErrAliasHeld)
+
+	token, err := s.ReserveAlias("Vanity", time.Minute)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+
+	// Пока резервация действует, псевдоним не достаётся ни повторной резервации, ни сохранению, ни ротации.
+	_, err = s.ReserveAlias("vanity", time.Minute)
+	require.ErrorIs(t, err, storage.ErrAliasHeld)
+
+	_, err = s.SaveURL("https://example.com", "vanity", storage.URLOptions{})
+	require.ErrorIs(t, err, storage.ErrURLExists)
+	require.ErrorIs(t, err, storage.ErrAliasHeld)
+
+	err = s.RotateAlias("google", "vanity")
+	require.ErrorIs(t, err, storage.ErrAliasHeld)
+
+	// Резервация действует только в пространстве имён по умолчанию.
+	_, err = s.SaveURL("https://example.com", "vanity", storage.URLOptions{Namespace: "docs"})
+	require.NoError(t, err)
+
+	_, err = s.ConfirmReservation("wrong-token", "https://example.com", storage.URLOptions{})
+	require.ErrorIs(t, err, storage.ErrReservationNotFound)
+
+	alias, err := s.ConfirmReservation(token, "https://example.com/vanity", storage.URLOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "vanity", alias)
+
+	url, err := s.GetURL("vanity")
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/vanity", url)
+
+	// Токен одноразовый.
+	_, err = s.ConfirmReservation(token, "https://example.com", storage.URLOptions{})
+	require.ErrorIs(t, err, storage.ErrReservationNotFound)
+}
+
+func TestStorage_ReserveAliasExpired(t *testing.T) {
+	s := newStorage(t)
+
+	token, err := s.ReserveAlias("vanity", time.Millisecond)
+	require.NoError(t, err)
+
+	time.Sleep(5 * time.Millisecond)
+
+	// Истёкшая резервация не удерживает псевдоним и не подтверждается.
+	_, err = s.ConfirmReservation(token, "https://example.com", storage.URLOptions{})
+	require.ErrorIs(t, err, storage.ErrReservationNotFound)
+
+	_, err = s.ReserveAlias("vanity", time.Minute)
+	require.NoError(t, err)
+}
+
+func TestStorage_ReserveAliasExpiredFreesSave(t *testing.T) {
+	s := newStorage(t)
+
+	_, err := s.ReserveAlias("vanity", time.Millisecond)
+	require.NoError(t, err)
+
+	time.Sleep(5 * time.Millisecond)
+
+	_, err = s.SaveURL("https://example.com", "vanity", storage.URLOptions{})
+	require.NoError(t, err)
+}
+
+func TestStorage_ConfirmReservationKeepsHoldOnFailure(t *testing.T) {
+	s, err := sqlite.New(slogdiscard.NewDiscardLogger(), filepath.Join(t.TempDir(), "storage.db"), sqlite.Options{UniqueURL: true})
+	require.NoError(t, err)
+
+	_, err = s.SaveURL("https://google.com", "google", storage.URLOptions{})
+	require.NoError(t, err)
+
+	token, err := s.ReserveAlias("vanity", time.Minute)
+	require.NoError(t, err)
+
+	// Сохранение не удалось - резервация остаётся, и токен можно использовать снова.
+	_, err = s.ConfirmReservation(token, "https://google.com", storage.URLOptions{})
+	require.ErrorIs(t, err, storage.ErrURLDuplicate)
+
+	_, err = s.ReserveAlias("vanity", time.Minute)
+	require.ErrorIs(t, err, storage.ErrAliasHeld)
+
+	alias, err := s.ConfirmReservation(token, "https://example.com", storage.URLOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "vanity", alias)
+}
+
+func TestStorage_IdempotencyKey(t *testing.T) {
+	s := newStorage(t)
+
+	_, err := s.ClaimIdempotencyKey("key-1", "fp", time.Minute)
+	require.NoError(t, err)
+
+	// Пока первый запрос выполняется, ключ занят без ссылки.
+	rec, err := s.ClaimIdempotencyKey("key-1", "fp", time.Minute)
+	require.ErrorIs(t, err, storage.ErrIdempotencyKeyExists)
+	require.Equal(t, storage.IdempotencyRecord{Fingerprint: "fp"}, rec)
+
+	require.NoError(t, s.CompleteIdempotencyKey("key-1", "docs", "Promo"))
+
+	// Повтор получает ссылку первого запроса, псевдоним - в том виде, в каком его вернул первый ответ.
+	rec, err = s.ClaimIdempotencyKey("key-1", "other", time.Minute)
+	require.ErrorIs(t, err, storage.ErrIdempotencyKeyExists)
+	require.Equal(t, storage.IdempotencyRecord{Fingerprint: "fp", Namespace: "docs", Alias: "Promo"}, rec)
+
+	// Завершённый ключ не освобождается.
+	require.NoError(t, s.ReleaseIdempotencyKey("key-1"))
+	_, err = s.ClaimIdempotencyKey("key-1", "fp", time.Minute)
+	require.ErrorIs(t, err, storage.ErrIdempotencyKeyExists)
+
+	// Освобождённый незавершённый ключ можно занять снова.
+	_, err = s.ClaimIdempotencyKey("key-2", "fp", time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, s.ReleaseIdempotencyKey("key-2"))
+	_, err = s.ClaimIdempotencyKey("key-2", "fp", time.Minute)
+	require.NoError(t, err)
+
+	require.Error(t, s.CompleteIdempotencyKey("missing", "", "promo"))
+}
+
+func TestStorage_IdempotencyKeyExpired(t *testing.T) {
+	s := newStorage(t)
+
+	_, err := s.ClaimIdempotencyKey("key", "fp", time.Millisecond)
+	require.NoError(t, err)
+	require.NoError(t, s.CompleteIdempotencyKey("key", "", "promo"))
+
+	time.Sleep(5 * time.Millisecond)
+
+	// Истёкший ключ забыт: запрос с ним выполняется как новый.
+	_, err = s.ClaimIdempotencyKey("key", "other", time.Minute)
+	require.NoError(t, err)
+}
+
+func TestStorage_IdempotencyKeyConcurrent(t *testing.T) {
+	s, err := sqlite.New(slogdiscard.NewDiscardLogger(), filepath.Join(t.TempDir(), "storage.db"), sqlite.Options{})
+	require.NoError(t, err)
+
+	// Из одновременных запросов с одним ключом его занимает только один.
+	var (
+		wg              sync.WaitGroup
+		mu              sync.Mutex
+		claimed, exists int
+	)
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := s.ClaimIdempotencyKey("key", "fp", time.Minute)
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				claimed++
+			case errors.Is(err, storage.ErrIdempotencyKeyExists):
+				exists++
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, 1, claimed)
+	require.Equal(t, 19, exists)
+}
@@ -0,0 +1,75 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// statements - запросы горячего пути, подготовленные один раз при создании хранилища.
+// Подготовка запроса на каждый вызов - лишний разбор SQL и обращение к базе, поэтому эти запросы
+// не готовятся заново. *sql.Stmt безопасен для одновременного использования из разных горутин:
+// database/sql сам готовит запрос на каждом соединении пула, которому он понадобился.
+type statements struct {
+	getURL    *sql.Stmt
+	getRecord *sql.Stmt
+	saveURL   *sql.Stmt
+	deleteURL *sql.Stmt
+}
+
+// prepareStatements готовит запросы горячего пути на пуле соединений db.
+// Вызывается после миграций: запросы ссылаются на колонки, которые добавляют миграции.
+func prepareStatements(db *sql.DB) (*statements, error) {
+	var (
+		st  statements
+		err error
+	)
+
+	prepare := func(dst **sql.Stmt, query string) {
+		if err != nil {
+			return
+		}
+		*dst, err = db.Prepare(query)
+		if err != nil {
+			err = fmt.Errorf("prepare %q: %w", query, err)
+		}
+	}
+
+	prepare(&st.getURL, "SELECT url FROM url WHERE namespace = '' AND alias = ?")
+	// Запись ссылки целиком читается на каждом редиректе.
+	prepare(&st.getRecord, "SELECT "+urlRecordColumns+" FROM url WHERE namespace = ? AND alias = ?")
+	prepare(&st.saveURL, "INSERT INTO url(url, alias, permanent, expires_at, created_at, creator_ip, namespace, max_clicks, tags, canonical_alias, preserve_method, headers, forward_path) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	prepare(&st.deleteURL, "DELETE FROM url WHERE namespace = '' AND alias = ?")
+
+	if err != nil {
+		_ = st.close()
+		return nil, err
+	}
+
+	return &st, nil
+}
+
+// close закрывает подготовленные запросы. Неподготовленные (nil) пропускаются.
+func (st *statements) close() error {
+	var errs []error
+	for _, stmt := range []*sql.Stmt{st.getURL, st.getRecord, st.saveURL, st.deleteURL} {
+		if stmt == nil {
+			continue
+		}
+		if err := stmt.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// stmt возвращает подготовленный запрос для выполнения через c: внутри транзакции - его копию,
+// привязанную к транзакции (она закрывается вместе с транзакцией), иначе - сам запрос.
+func (c queries) stmt(stmt *sql.Stmt) *sql.Stmt {
+	if c.tx != nil {
+		return c.tx.Stmt(stmt)
+	}
+
+	return stmt
+}
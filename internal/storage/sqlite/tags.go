@@ -0,0 +1,39 @@
+package sqlite
+
+import (
+	"slices"
+	"strings"
+)
+
+// Метки ссылки хранятся в колонке tags одной строкой, обрамлённой запятыми: ",campaign,docs,".
+// Благодаря обрамлению точное совпадение метки проверяется поиском подстроки ",метка,"
+// и не срабатывает на метках, которые лишь содержат искомую ("docs" и "old-docs").
+// Пустая строка - у ссылки нет меток.
+
+// encodeTags упорядочивает метки, убирает повторы и кодирует их для колонки tags.
+func encodeTags(tags []string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+
+	sorted := slices.Clone(tags)
+	slices.Sort(sorted)
+	sorted = slices.Compact(sorted)
+
+	return "," + strings.Join(sorted, ",") + ","
+}
+
+// decodeTags возвращает метки из значения колонки tags. Для ссылки без меток - nil.
+func decodeTags(encoded string) []string {
+	encoded = strings.Trim(encoded, ",")
+	if encoded == "" {
+		return nil
+	}
+
+	return strings.Split(encoded, ",")
+}
+
+// tagPattern возвращает подстроку, по которой в колонке tags ищется метка tag.
+func tagPattern(tag string) string {
+	return "," + tag + ","
+}
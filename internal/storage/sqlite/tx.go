@@ -0,0 +1,67 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"url-shortener/internal/storage"
+)
+
+// querier - общие методы *sql.DB и *sql.Tx, через которые выполняются запросы к ссылкам.
+// Благодаря ему одни и те же запросы работают и напрямую с пулом соединений, и внутри транзакции.
+type querier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// queries - операции со ссылками поверх querier.
+// tx - та же транзакция, что и q, если запросы выполняются внутри неё, иначе nil.
+// stmts - подготовленные запросы хранилища (см. stmts.go), общие для пула соединений и транзакций.
+type queries struct {
+	q     querier
+	tx    *sql.Tx
+	slow  *slowLog
+	stmts *statements
+}
+
+// txStorage - операции со ссылками, привязанные к одной транзакции.
+// Реализует storage.Tx и передаётся в функцию, переданную в WithTx.
+type txStorage struct {
+	queries
+}
+
+// WithTx - метод, который выполняет fn в одной транзакции.
+// Если fn возвращает nil, транзакция фиксируется, иначе откатывается, а ошибка fn возвращается как есть,
+// чтобы вызывающий код мог проверить её через errors.Is. Транзакция откатывается и при панике в fn.
+func (s *Storage) WithTx(ctx context.Context, fn func(tx storage.Tx) error) error {
+	const op = "storage.sqlite.WithTx"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(txStorage{s.txQueries(tx)}); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return nil
+}
+
+// txQueries возвращает операции со ссылками, выполняемые внутри транзакции tx.
+func (s *Storage) txQueries(tx *sql.Tx) queries {
+	return queries{q: tx, tx: tx, slow: s.slow, stmts: s.stmts}
+}
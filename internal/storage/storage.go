@@ -1,8 +1,15 @@
 package storage
 
-// Импортируем пакет errors, который предоставляет функции для работы с ошибками.
-// Мы используем его для создания и проверки ошибок в программе.
-import "errors"
+// Импортируем пакет errors, который предоставляет функции для работы с ошибками,
+// и пакет strings для нормализации псевдонимов.
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
 
 // Определяем переменные для ошибок, которые могут возникнуть при работе с URL в базе данных.
 // ErrURLNotFound - ошибка, которая возникает, когда не удается найти URL по заданному псевдониму.
@@ -10,3 +17,248 @@ var ErrURLNotFound = errors.New("url not found")
 
 // ErrURLExists - ошибка, которая возникает, если попытаться вставить URL с уже существующим псевдонимом.
 var ErrURLExists = errors.New("url already exists")
+
+// ErrURLDuplicate - ошибка, которая возникает в режиме дедупликации, если такой адрес уже сокращён.
+// Ссылку на него можно получить через URLStorage.GetURLRecordByURL.
+var ErrURLDuplicate = errors.New("url already shortened")
+
+// ErrAliasConfusable - ошибка, которая возникает в режиме защиты от похожих псевдонимов, если псевдоним
+// отличается от уже занятого только похожими на вид символами (rn и m, 0 и o). Возвращается вместе с ErrURLExists:
+// для генератора псевдонимов это обычная коллизия.
+var ErrAliasConfusable = errors.New("alias is confusable with an existing alias")
+
+// ErrAliasHeld - ошибка, которая возникает, если псевдоним удерживается действующей резервацией
+// (см. URLStorage.ReserveAlias). Возвращается вместе с ErrURLExists: для генератора псевдонимов это обычная коллизия.
+var ErrAliasHeld = errors.New("alias is held by a reservation")
+
+// ErrReservationNotFound - ошибка, которая возникает, если резервации с таким токеном нет или её срок истёк.
+var ErrReservationNotFound = errors.New("reservation not found")
+
+// ErrIdempotencyKeyExists - ошибка, которая возникает, если ключ идемпотентности уже занят другим запросом
+// (см. URLStorage.ClaimIdempotencyKey). Вместе с ней возвращается запись ключа с результатом первого запроса.
+var ErrIdempotencyKeyExists = errors.New("idempotency key already exists")
+
+// ErrAPIKeyNotFound - ошибка, которая возникает, когда API-ключа с заданным префиксом или ID нет.
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+// ErrClicksExhausted - ошибка, которая возникает, если у ссылки с ограничением max_clicks не осталось переходов.
+var ErrClicksExhausted = errors.New("url clicks exhausted")
+
+// URLStorage - интерфейс хранилища ссылок, который реализует каждый бэкенд.
+// Приложение работает с хранилищем только через него, поэтому не зависит от конкретного бэкенда.
+type URLStorage interface {
+	SaveURL(urlToSave, alias string, opts URLOptions) (int64, error)
+	GetURL(alias string) (string, error)
+	GetURLs(aliases []string) (map[string]string, error)
+	GetURLRecord(alias string) (URLRecord, error)
+	GetNamespacedURLRecord(ctx context.Context, namespace, alias string) (URLRecord, error)
+	GetURLRecordByURL(urlToSave string) (URLRecord, error)
+	AliasExists(ctx context.Context, alias string) (bool, error)
+	CountURLs(ctx context.Context) (int64, error)
+	// Ping выполняет простейший запрос к хранилищу, чтобы проверить его доступность и задержку.
+	Ping(ctx context.Context) error
+	CountByCreator(ip string) (int, error)
+	DeleteURL(alias string) (int64, error)
+	BulkDeleteURL(aliases []string) (int64, error)
+	DeleteURLRecord(alias string) (URLRecord, error)
+	DeleteAll(ctx context.Context) (int64, error)
+	RotateAlias(oldAlias, newAlias string) error
+	SetEnabled(alias string, enabled bool) error
+	// SetExpiry меняет срок действия ссылки пространства имён по умолчанию. nil делает ссылку бессрочной.
+	SetExpiry(alias string, expiresAt *time.Time) error
+	WithTx(ctx context.Context, fn func(tx Tx) error) error
+	UpdateLastAccessed(accessed map[int64]time.Time) error
+	// AddClicks прибавляет накопленные переходы к счётчикам ссылок без ограничения max_clicks.
+	AddClicks(clicks map[int64]int64) error
+	ConsumeClick(id int64) error
+	ListStale(olderThan time.Time) ([]URLRecord, error)
+	ListAliasesByURL(urlToSave string) ([]string, error)
+	// SearchURLs ищет ссылки, адрес или псевдоним которых содержит term. Просматривает всю таблицу.
+	SearchURLs(term string, limit int) ([]URLRecord, error)
+	ListURLsAfter(id int64, limit int) ([]URLRecord, error)
+	ListURLsByTag(tag string, id int64, limit int) ([]URLRecord, error)
+	ListByDateRange(from, to time.Time, limit, offset int) ([]URLRecord, error)
+	Summary(since time.Time, top int) (Summary, error)
+	SaveURLSequential(urlToSave string, opts URLOptions, encode func(id int64) string) (string, int64, error)
+	// ReserveAlias удерживает свободный псевдоним пространства имён по умолчанию на время ttl: пока резервация
+	// действует, ссылку с ним можно создать только через ConfirmReservation с возвращённым токеном.
+	ReserveAlias(alias string, ttl time.Duration) (token string, err error)
+	// ConfirmReservation создаёт ссылку на urlToSave под зарезервированным псевдонимом и снимает резервацию.
+	// Возвращает псевдоним или ErrReservationNotFound, если токен неизвестен или резервация истекла.
+	ConfirmReservation(token, urlToSave string, opts URLOptions) (alias string, err error)
+	// ClaimIdempotencyKey занимает ключ идемпотентности для запроса с отпечатком fingerprint на время ttl.
+	// Из одновременных запросов с одним ключом его занимает только один; остальные получают ErrIdempotencyKeyExists
+	// и запись ключа - с результатом первого запроса, если он уже завершён.
+	ClaimIdempotencyKey(key, fingerprint string, ttl time.Duration) (IdempotencyRecord, error)
+	// CompleteIdempotencyKey записывает в занятый ключ созданную по запросу ссылку.
+	CompleteIdempotencyKey(key, namespace, alias string) error
+	// ReleaseIdempotencyKey освобождает занятый, но не завершённый ключ, если запрос не создал ссылку.
+	ReleaseIdempotencyKey(key string) error
+	SaveAPIKey(name, prefix string, hash []byte) (APIKey, error)
+	GetAPIKey(prefix string) (APIKey, error)
+	DeleteAPIKey(id int64) error
+	// Close освобождает ресурсы хранилища. Вызывается при остановке приложения, после последнего обращения.
+	Close() error
+}
+
+// Tx - операции со ссылками внутри одной транзакции хранилища (см. URLStorage.WithTx).
+// Изменения становятся видны другим клиентам только после фиксации транзакции.
+type Tx interface {
+	SaveURL(urlToSave, alias string, opts URLOptions) (int64, error)
+	GetURL(alias string) (string, error)
+	GetURLRecord(alias string) (URLRecord, error)
+	DeleteURL(alias string) (int64, error)
+	BulkDeleteURL(aliases []string) (int64, error)
+	RotateAlias(oldAlias, newAlias string) error
+}
+
+// URLOptions - дополнительные параметры ссылки, которые задаются при сохранении.
+type URLOptions struct {
+	// Permanent - признак постоянного редиректа (301). nil означает, что используется значение по умолчанию из конфигурации.
+	Permanent *bool
+	// PreserveMethod - редирект с сохранением метода и тела запроса (307/308 вместо 302/301).
+	// nil означает, что используется значение по умолчанию из конфигурации.
+	PreserveMethod *bool
+	// ExpiresAt - момент, после которого ссылка перестаёт работать. nil - ссылка бессрочная.
+	ExpiresAt *time.Time
+	// CreatorIP - IP-адрес клиента, создавшего ссылку. Пустая строка - не записывается.
+	CreatorIP string
+	// Namespace - пространство имён ссылки. Псевдонимы уникальны внутри пространства имён.
+	// Пустая строка - пространство имён по умолчанию с короткими ссылками вида /{alias}.
+	Namespace string
+	// MaxClicks - сколько переходов допускает ссылка, после чего отвечает 410. 0 - без ограничения.
+	MaxClicks int64
+	// Tags - метки ссылки для группировки и фильтрации (GET /url?tag=...). Повторы не сохраняются.
+	// Метка не может содержать запятую.
+	Tags []string
+	// Headers - заголовки, которые добавляются к редиректу этой ссылки поверх глобальных redirect.headers.
+	// Пустое значение убирает глобальный заголовок. Имена и значения проверяются до сохранения (redirectheaders.Validate).
+	Headers map[string]string
+	// ForwardPath - ссылка с переносом пути: /{alias}/остаток ведёт на адрес ссылки с дописанным остатком пути.
+	// Только для пространства имён по умолчанию.
+	ForwardPath bool
+}
+
+// URLRecord - запись о сокращённой ссылке в хранилище.
+type URLRecord struct {
+	ID             int64      `json:"id"`
+	Namespace      string     `json:"namespace,omitempty"`
+	Alias          string     `json:"alias"`
+	URL            string     `json:"url"`
+	Permanent      *bool      `json:"permanent,omitempty"`
+	PreserveMethod *bool      `json:"preserve_method,omitempty"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	CreatedAt      *time.Time `json:"created_at,omitempty"`
+	LastAccessedAt *time.Time `json:"last_accessed_at,omitempty"`
+	// MaxClicks - ограничение числа переходов, 0 - без ограничения.
+	// Clicks - число переходов по ссылке.
+	MaxClicks int64 `json:"max_clicks,omitempty"`
+	Clicks    int64 `json:"clicks,omitempty"`
+	// Disabled - ссылка временно выключена (см. URLStorage.SetEnabled): данные сохраняются, но редиректа нет.
+	Disabled bool `json:"disabled,omitempty"`
+	// Tags - метки ссылки в алфавитном порядке.
+	Tags []string `json:"tags,omitempty"`
+	// Headers - собственные заголовки редиректа ссылки (см. URLOptions.Headers).
+	Headers map[string]string `json:"headers,omitempty"`
+	// ForwardPath - ссылка переносит остаток пути в адрес назначения (см. URLOptions.ForwardPath).
+	ForwardPath bool `json:"forward_path,omitempty"`
+}
+
+// IdempotencyRecord - запись ключа идемпотентности: отпечаток запроса, занявшего ключ, и созданная им ссылка.
+type IdempotencyRecord struct {
+	// Fingerprint - отпечаток запроса. Повтор с тем же ключом, но другим отпечатком - другой запрос.
+	Fingerprint string
+	// Namespace и Alias - созданная ссылка. Пустой Alias - первый запрос ещё выполняется.
+	Namespace string
+	Alias     string
+}
+
+// Summary - сводные показатели по всем ссылкам для дашборда.
+type Summary struct {
+	// TotalLinks - число ссылок во всех пространствах имён.
+	TotalLinks int64 `json:"total_links"`
+	// TotalClicks - суммарное число переходов по ссылкам.
+	TotalClicks int64 `json:"total_clicks"`
+	// CreatedSince - число ссылок, созданных начиная с момента since (например, с начала суток).
+	CreatedSince int64 `json:"created_since"`
+	// Top - ссылки с наибольшим числом переходов, по убыванию.
+	Top []URLRecord `json:"top"`
+}
+
+// APIKey - API-ключ для доступа к маршрутам /url. Сам ключ не хранится, только его bcrypt-хэш.
+type APIKey struct {
+	ID int64 `json:"id"`
+	// Name - произвольное описание владельца ключа, например "ci".
+	Name string `json:"name,omitempty"`
+	// Prefix - открытая часть ключа, по которой находится его хэш. Её можно показывать и писать в лог.
+	Prefix    string    `json:"prefix"`
+	Hash      []byte    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Expired сообщает, истёк ли срок действия ссылки к моменту now.
+func (r URLRecord) Expired(now time.Time) bool {
+	return r.ExpiresAt != nil && !now.Before(*r.ExpiresAt)
+}
+
+// Exhausted сообщает, израсходованы ли все переходы ссылки с ограничением max_clicks.
+func (r URLRecord) Exhausted() bool {
+	return r.MaxClicks > 0 && r.Clicks >= r.MaxClicks
+}
+
+// Reusable сообщает, можно ли в режиме дедупликации вернуть эту ссылку вместо создания новой с параметрами opts.
+// Ссылка должна работать (не выключена, не истекла и не израсходовала переходы) и вести себя так же,
+// как новая: то же пространство имён, тот же тип редиректа, заголовки, перенос пути и метки.
+// Ссылки со сроком действия или ограничением переходов не переиспользуются ни с какой стороны:
+// у новой ссылки был бы свой срок и свой счётчик.
+func (r URLRecord) Reusable(opts URLOptions, now time.Time) bool {
+	if r.Disabled || r.Expired(now) || r.Exhausted() {
+		return false
+	}
+
+	if r.ExpiresAt != nil || opts.ExpiresAt != nil || r.MaxClicks > 0 || opts.MaxClicks > 0 {
+		return false
+	}
+
+	tags := slices.Compact(slices.Sorted(slices.Values(opts.Tags)))
+
+	return r.Namespace == NormalizeAlias(opts.Namespace) &&
+		equalBoolPtr(r.Permanent, opts.Permanent) &&
+		equalBoolPtr(r.PreserveMethod, opts.PreserveMethod) &&
+		r.ForwardPath == opts.ForwardPath &&
+		maps.Equal(r.Headers, opts.Headers) &&
+		slices.Equal(r.Tags, tags)
+}
+
+func equalBoolPtr(a, b *bool) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// NormalizeAlias приводит псевдоним к каноническому виду, в котором он хранится и ищется.
+// Псевдонимы нечувствительны к регистру: /MyLink и /mylink ведут на одну и ту же ссылку,
+// поэтому хранилища обязаны применять эту функцию при сохранении, поиске и удалении.
+func NormalizeAlias(alias string) string {
+	return strings.ToLower(alias)
+}
+
+// ConfusableChars - символы, которые путаются на вид с другими символами или последовательностями
+// (0 и o, 1, l и i, 5 и s, 2 и z, u и v, rn и m). Генератор псевдонимов в режиме защиты от похожих псевдонимов
+// не использует их вовсе; c остаётся, потому что cl без l не получится.
+const ConfusableChars = "0125ilorsuvz"
+
+// confusableRunes - замены одиночных символов в CanonicalAlias на канонический символ группы.
+var confusableRunes = strings.NewReplacer("0", "o", "1", "l", "i", "l", "5", "s", "2", "z", "u", "v")
+
+// confusableSequences - замены последовательностей, которые выглядят как один символ.
+// Применяются после confusableRunes, поэтому c1 и cl одинаково дают d.
+var confusableSequences = strings.NewReplacer("rn", "m", "vv", "w", "cl", "d")
+
+// CanonicalAlias приводит псевдоним к виду, в котором неразличимые на глаз псевдонимы совпадают:
+// /rnail и /mail, /g00gle и /google дают одинаковый результат. По нему хранилища проверяют уникальность
+// в режиме защиты от похожих псевдонимов, чтобы нельзя было выпустить ссылку, похожую на чужую.
+func CanonicalAlias(alias string) string {
+	return confusableSequences.Replace(confusableRunes.Replace(NormalizeAlias(alias)))
+}
@@ -67,24 +67,28 @@ func TestURLShortener_SaveRedirectRemove(t *testing.T) {
 	testCases := []struct {
 		name  string
 		url   string
-		alias string
-		error string
+		alias  string
+		error  string
+		status int
 	}{
 		{
-			name:  "Valid URL",
-			url:   gofakeit.URL(),
-			alias: gofakeit.Word() + gofakeit.Word(),
+			name:   "Valid URL",
+			url:    gofakeit.URL(),
+			alias:  gofakeit.Word() + gofakeit.Word(),
+			status: http.StatusOK,
 		},
 		{
-			name:  "Invalid URL",
-			url:   "invalid_url",
-			alias: gofakeit.Word(),
-			error: "field URL is not a valid URL",
+			name:   "Invalid URL",
+			url:    "invalid_url",
+			alias:  gofakeit.Word(),
+			error:  "field URL is not a valid URL",
+			status: http.StatusBadRequest,
 		},
 		{
-			name:  "Empty Alias",
-			url:   gofakeit.URL(),
-			alias: "",
+			name:   "Empty Alias",
+			url:    gofakeit.URL(),
+			alias:  "",
+			status: http.StatusOK,
 		},
 		// TODO: add more test cases
 	}
@@ -105,7 +109,7 @@ func TestURLShortener_SaveRedirectRemove(t *testing.T) {
 					Alias: tc.alias,
 				}).
 				WithBasicAuth(getAuth("user"), getAuth("password")).
-				Expect().Status(http.StatusOK).
+				Expect().Status(tc.status).
 				JSON().Object()
 
 			if tc.error != "" {